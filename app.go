@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"image"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,9 +15,12 @@ import (
 	"EscritorioRemoto-Cliente/internal/infrastructure/patterns/factory"
 	"EscritorioRemoto-Cliente/internal/infrastructure/patterns/observer"
 	"EscritorioRemoto-Cliente/internal/infrastructure/patterns/singleton"
+	"EscritorioRemoto-Cliente/internal/model/valueobjects"
 	"EscritorioRemoto-Cliente/pkg/api"
+	"EscritorioRemoto-Cliente/pkg/audit"
 	"EscritorioRemoto-Cliente/pkg/filetransfer"
 	"EscritorioRemoto-Cliente/pkg/remotecontrol"
+	"EscritorioRemoto-Cliente/pkg/session"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -73,9 +79,39 @@ type App struct {
 
 	// FileTransferAgent para transferencia de archivos
 	fileTransferAgent *filetransfer.FileTransferAgent
+	incomingPrompts   *remotecontrol.ConsentPrompts
+
+	// UploadAgent para enviar al servidor archivos que solicita (con consentimiento)
+	uploadAgent   *filetransfer.UploadAgent
+	uploadPrompts *remotecontrol.ConsentPrompts
 
 	// AutoLoginCredentials almacena credenciales para login automático
 	autoLoginCredentials *AutoLoginCredentials
+
+	// SessionManager persiste el token para reanudar la sesión al reiniciar
+	sessionManager *session.SessionManager
+
+	// Política de consentimiento para solicitudes de control remoto
+	consentPolicy  *remotecontrol.ConsentPolicy
+	consentPrompts *remotecontrol.ConsentPrompts
+
+	// Log de auditoría encadenado (nil si no se pudo abrir)
+	auditLogger *audit.Logger
+	inputAudit  *audit.InputSummarizer
+
+	// Historial de transferencias terminadas (nil si no se pudo abrir)
+	transferHistory *filetransfer.History
+
+	// Modo de control (solo visualización) decidido al aceptar cada sesión
+	controlModes      map[string]controlMode
+	controlModesMutex sync.Mutex
+}
+
+// controlMode indica si una sesión es solo de visualización y si el usuario puede cambiarlo
+type controlMode struct {
+	viewOnly bool
+	locked   bool // Impuesto por la política
+	trusted  bool // Administrador de confianza según la política
 }
 
 // getDownloadsDirectory detecta el directorio de descargas del usuario
@@ -107,11 +143,13 @@ func getDownloadsDirectory() string {
 
 // NewApp crea una nueva instancia de App usando MVC (versión legacy)
 func NewApp() *App {
-	return NewAppWithConfig("http://localhost:8080", "", "", "")
+	return NewAppWithConfig("http://localhost:8080", "", nil, "")
 }
 
 // NewAppWithConfig crea una nueva instancia de App con configuración personalizada
-func NewAppWithConfig(serverURL, username, password, pcName string) *App {
+// La contraseña se recibe como []byte para ponerla a cero en cuanto se usa; el login
+// trabaja con string, así que la copia que se le pasa no se puede borrar.
+func NewAppWithConfig(serverURL, username string, password []byte, pcName string) *App {
 	// Inicializar singletons
 	configManager := singleton.GetConfigManager()
 	
@@ -144,10 +182,43 @@ func NewAppWithConfig(serverURL, username, password, pcName string) *App {
 		remoteControlAgent: remotecontrol.NewRemoteControlAgent(),
 		videoRecorder:      remotecontrol.NewVideoRecorder(remotecontrol.DefaultVideoConfig()),
 		fileTransferAgent:  filetransfer.NewFileTransferAgent(downloadDir),
+		incomingPrompts:    remotecontrol.NewConsentPrompts(),
+		uploadAgent:        filetransfer.NewUploadAgent(),
+		uploadPrompts:      remotecontrol.NewConsentPrompts(),
+		sessionManager:     session.NewSessionManager(),
+		consentPolicy:      loadConsentPolicy(),
+		consentPrompts:     remotecontrol.NewConsentPrompts(),
+		controlModes:       make(map[string]controlMode),
+	}
+
+	// Aplicar configuración de recepción y subida de archivos
+	transferConfig := loadFileTransferConfig()
+	if err := app.fileTransferAgent.SetConfig(transferConfig); err != nil {
+		fmt.Printf("⚠️ Configuración de transferencias inválida, usando la predeterminada: %v\n", err)
+	}
+	if err := app.uploadAgent.SetConfig(transferConfig); err != nil {
+		fmt.Printf("⚠️ Configuración de subidas inválida, no se permitirán subidas: %v\n", err)
+	}
+	app.fileTransferAgent.SetTrustedSessionCheck(app.isTrustedSession)
+
+	// Aplicar límites de sincronización del portapapeles
+	if err := app.remoteControlAgent.SetClipboardConfig(loadClipboardConfig()); err != nil {
+		fmt.Printf("⚠️ Configuración del portapapeles inválida, usando la predeterminada: %v\n", err)
+	}
+
+	// Abrir log de auditoría
+	if auditLogger, err := audit.NewLogger(audit.DefaultConfig(getAuditDirectory())); err != nil {
+		fmt.Printf("⚠️ No se pudo abrir el log de auditoría, no se registrarán eventos: %v\n", err)
+	} else {
+		app.auditLogger = auditLogger
+		app.inputAudit = audit.NewInputSummarizer(auditLogger, audit.DefaultInputFlushInterval)
 	}
 
+	// Abrir historial de transferencias
+	app.transferHistory = loadTransferHistory()
+
 	// Configurar credenciales para auto-login si se proporcionaron
-	if username != "" && password != "" {
+	if username != "" && len(password) > 0 {
 		app.autoLoginCredentials = &AutoLoginCredentials{
 			Username: username,
 			Password: password,
@@ -158,13 +229,157 @@ func NewAppWithConfig(serverURL, username, password, pcName string) *App {
 	return app
 }
 
+// loadConsentPolicy carga la política de consentimiento de ~/.escritorio-remoto/consent_policy.json
+func loadConsentPolicy() *remotecontrol.ConsentPolicy {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+
+	policyFile := filepath.Join(homeDir, ".escritorio-remoto", "consent_policy.json")
+	policy, err := remotecontrol.LoadConsentPolicy(policyFile)
+	if err != nil {
+		fmt.Printf("⚠️ Política de consentimiento inválida, usando la predeterminada: %v\n", err)
+		policy, _ = remotecontrol.NewConsentPolicy(remotecontrol.DefaultConsentPolicyConfig())
+	}
+
+	return policy
+}
+
+// loadFileTransferConfig carga la configuración de recepción de ~/.escritorio-remoto/file_transfer.json
+func loadFileTransferConfig() filetransfer.Config {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+
+	configFile := filepath.Join(homeDir, ".escritorio-remoto", "file_transfer.json")
+	config, err := filetransfer.LoadConfig(configFile)
+	if err != nil {
+		fmt.Printf("⚠️ Configuración de transferencias inválida, usando la predeterminada: %v\n", err)
+	}
+
+	return config
+}
+
+// loadClipboardConfig carga la configuración del portapapeles de ~/.escritorio-remoto/clipboard.json
+func loadClipboardConfig() remotecontrol.ClipboardConfig {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+
+	configFile := filepath.Join(homeDir, ".escritorio-remoto", "clipboard.json")
+	config, err := remotecontrol.LoadClipboardConfig(configFile)
+	if err != nil {
+		fmt.Printf("⚠️ Configuración del portapapeles inválida, usando la predeterminada: %v\n", err)
+	}
+
+	return config
+}
+
+// loadTransferHistory abre el historial de ~/.escritorio-remoto/transfer_history.json
+func loadTransferHistory() *filetransfer.History {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+
+	historyFile := filepath.Join(homeDir, ".escritorio-remoto", "transfer_history.json")
+	history, err := filetransfer.OpenHistory(historyFile, filetransfer.DefaultHistoryEntries)
+	if err != nil {
+		fmt.Printf("⚠️ No se pudo leer el historial de transferencias, se empieza uno nuevo: %v\n", err)
+	}
+
+	return history
+}
+
+// getAuditDirectory retorna ~/.escritorio-remoto/audit
+func getAuditDirectory() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+
+	return filepath.Join(homeDir, ".escritorio-remoto", "audit")
+}
+
+// recordAudit registra un evento en el log de auditoría si está disponible
+func (a *App) recordAudit(eventType, sessionID, actor string, data map[string]interface{}) {
+	if a.auditLogger == nil {
+		return
+	}
+
+	if err := a.auditLogger.Record(eventType, sessionID, actor, data); err != nil {
+		fmt.Printf("❌ Error registrando evento de auditoría %s: %v\n", eventType, err)
+	}
+}
+
+// recordTransferHistory guarda una transferencia terminada y avisa al frontend
+func (a *App) recordTransferHistory(entry filetransfer.HistoryEntry) {
+	if a.transferHistory == nil {
+		return
+	}
+
+	entry, err := a.transferHistory.Add(entry)
+	if err != nil {
+		fmt.Printf("❌ Error guardando historial de transferencias: %v\n", err)
+	}
+	runtime.EventsEmit(a.ctx, "transfer_history_updated", historyEntryData(entry))
+}
+
+// auditSessionEnded vuelca la actividad de entrada pendiente y registra el fin de sesión
+func (a *App) auditSessionEnded(sessionID, reason string) {
+	if sessionID == "" {
+		return
+	}
+
+	if a.inputAudit != nil {
+		a.inputAudit.FlushSession(sessionID)
+	}
+	a.recordAudit(audit.EventSessionEnded, sessionID, "", map[string]interface{}{
+		"reason": reason,
+	})
+}
+
+// cancelSessionTransfers cancela las transferencias de archivos de una sesión que terminó;
+// cada una se notifica como fallida al servidor y a la UI
+func (a *App) cancelSessionTransfers(sessionID, reason string) {
+	if cancelled := a.fileTransferAgent.CancelSessionTransfers(sessionID, reason); cancelled > 0 {
+		runtime.LogInfof(a.ctx, "🗑️ %d transferencia(s) canceladas al terminar la sesión %s", cancelled, sessionID)
+	}
+
+	// Las que aún esperaban la decisión del usuario ya no la necesitan
+	for _, incoming := range a.fileTransferAgent.DiscardSessionIncoming(sessionID) {
+		a.dismissIncomingPrompt(incoming.TransferID)
+		a.reportDeclinedTransfer(incoming, "La sesión terminó: "+reason, filetransfer.ReasonSessionEnded, decidedBySession)
+	}
+}
+
+// sessionIDFromEvent extrae el session_id de un evento de sesión, o usa la sesión activa
+func (a *App) sessionIDFromEvent(data interface{}) string {
+	if sessionData, ok := data.(map[string]interface{}); ok {
+		if sessionID, ok := sessionData["session_id"].(string); ok && sessionID != "" {
+			return sessionID
+		}
+	}
+	return a.remoteControlAgent.GetActiveSessionID()
+}
+
 // AutoLoginCredentials almacena credenciales para login automático
 type AutoLoginCredentials struct {
 	Username string
-	Password string
+	Password []byte
 	PCName   string
 }
 
+// Wipe pone a cero el buffer de la contraseña una vez consumida. No alcanza a la
+// copia en string que recibe Login ni a las que se hacen al enviarla al servidor.
+func (c *AutoLoginCredentials) Wipe() {
+	session.Wipe(c.Password)
+	c.Password = nil
+}
+
 // startup es llamado cuando la app inicia (Wails)
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
@@ -204,6 +419,18 @@ func (a *App) setupRemoteControlHandler() {
 					runtime.LogInfof(a.ctx, "🔍 DEBUG: APIClient cast successful")
 					a.apiClient = apiClient
 
+					// Los frames de pantalla salen por el sender adaptativo del agente
+					a.remoteControlAgent.SetFrameTransport(apiClient)
+
+					// El portapapeles local se envía al admin por el mismo cliente
+					a.remoteControlAgent.SetClipboardTransport(apiClient)
+					a.remoteControlAgent.SetClipboardSentCallback(func(update api.ClipboardUpdate) {
+						// Solo se audita el formato, nunca el contenido
+						if a.inputAudit != nil {
+							a.inputAudit.Observe(update.SessionID, "clipboard", "sent")
+						}
+					})
+
 					// INYECTAR APIClient en VideoRecorder para upload de frames
 					if a.videoRecorder != nil {
 						a.videoRecorder.SetAPIClient(apiClient)
@@ -212,15 +439,8 @@ func (a *App) setupRemoteControlHandler() {
 					// Configurar handler para solicitudes de control remoto
 					runtime.LogInfof(a.ctx, "🔍 DEBUG: Setting up remote control handler")
 					apiClient.SetRemoteControlHandler(func(request api.RemoteControlRequest) {
-						// Emitir evento a la UI
-						runtime.EventsEmit(a.ctx, "incoming_control_request", map[string]interface{}{
-							"sessionId":     request.SessionID,
-							"adminUserId":   request.AdminUserID,
-							"adminUsername": request.AdminUsername,
-							"clientPcId":    request.ClientPCID,
-						})
-
 						runtime.LogInfof(a.ctx, "Remote control request received from admin: %s", request.AdminUsername)
+						a.handleControlRequest(request)
 					})
 
 					// Configurar handler para eventos de sesión
@@ -237,20 +457,33 @@ func (a *App) setupRemoteControlHandler() {
 							// Extraer sessionID del data
 							if sessionData, ok := data.(map[string]interface{}); ok {
 								if sessionID, ok := sessionData["session_id"].(string); ok {
-									// Iniciar RemoteControlAgent
-									err := a.remoteControlAgent.StartSession(sessionID)
+									// Iniciar RemoteControlAgent con el modo decidido al aceptar
+									mode := a.getControlMode(sessionID)
+									err := a.remoteControlAgent.StartSessionWithOptions(sessionID, remotecontrol.SessionOptions{
+										ViewOnly:      mode.viewOnly,
+										ClipboardSync: a.remoteControlAgent.GetClipboardConfig().EnabledByDefault,
+									})
 									if err != nil {
 										runtime.LogErrorf(a.ctx, "Failed to start remote control session: %v", err)
 									} else {
 										runtime.LogInfof(a.ctx, "Remote control session started: %s", sessionID)
+										a.recordAudit(audit.EventSessionStarted, sessionID, "", map[string]interface{}{
+											"view_only": mode.viewOnly,
+										})
+										a.emitControlMode(sessionID, mode)
+										a.sendClipboardState(sessionID, "")
+										a.emitClipboardState(sessionID)
 
 										// 🎬 INICIAR GRABACIÓN DE VIDEO AUTOMÁTICAMENTE
 										if videoErr := a.StartVideoRecording(sessionID); videoErr != nil {
 											runtime.LogErrorf(a.ctx, "Failed to start video recording: %v", videoErr)
 										}
 
-										// Iniciar goroutine para enviar frames
-										go a.startScreenStreaming()
+										// Iniciar goroutine para enviar frames de esta sesión
+										go a.startScreenStreaming(sessionID, a.remoteControlAgent.GetFrameOutput())
+
+										// Informar al admin de las pantallas disponibles
+										a.sendDisplayList(sessionID, "")
 									}
 								}
 							}
@@ -259,6 +492,9 @@ func (a *App) setupRemoteControlHandler() {
 							runtime.LogInfof(a.ctx, "🔍 DEBUG: Processing session_ended event - type: %s", eventType)
 							runtime.LogInfof(a.ctx, "🔚 Sesión terminada - tipo de evento: %s", eventType)
 							runtime.EventsEmit(a.ctx, "control_session_ended", data)
+							a.auditSessionEnded(a.sessionIDFromEvent(data), eventType)
+							a.clearControlMode(a.sessionIDFromEvent(data))
+							a.cancelSessionTransfers(a.sessionIDFromEvent(data), eventType)
 
 							// 🎬 DETENER GRABACIÓN DE VIDEO ANTES DE CERRAR LA SESIÓN
 							if a.IsVideoRecording() {
@@ -288,6 +524,9 @@ func (a *App) setupRemoteControlHandler() {
 							runtime.LogInfof(a.ctx, "🔍 DEBUG: Processing session_failed event")
 							runtime.LogInfof(a.ctx, "❌ Sesión falló")
 							runtime.EventsEmit(a.ctx, "control_session_failed", data)
+							a.auditSessionEnded(a.sessionIDFromEvent(data), eventType)
+							a.clearControlMode(a.sessionIDFromEvent(data))
+							a.cancelSessionTransfers(a.sessionIDFromEvent(data), eventType)
 
 							// 🎬 DETENER GRABACIÓN SI FALLA LA SESIÓN
 							if a.IsVideoRecording() {
@@ -313,11 +552,46 @@ func (a *App) setupRemoteControlHandler() {
 
 						// Procesar comando a través del RemoteControlAgent
 						err := a.remoteControlAgent.ProcessInputCommand(command)
-						if err != nil {
+
+						// Solo se audita el tipo de acción, nunca el contenido
+						if a.inputAudit != nil {
+							if errors.Is(err, remotecontrol.ErrViewOnly) {
+								a.inputAudit.Observe(command.SessionID, "blocked", command.EventType)
+							} else {
+								a.inputAudit.Observe(command.SessionID, command.EventType, command.Action)
+							}
+						}
+
+						// En modo solo visualización el rechazo es esperado y no se registra como error
+						if err != nil && !errors.Is(err, remotecontrol.ErrViewOnly) {
 							runtime.LogErrorf(a.ctx, "Failed to process input command: %v", err)
 						}
 					})
 
+					// Configurar handler para el portapapeles enviado por el admin
+					apiClient.SetClipboardUpdateHandler(a.handleClipboardUpdate)
+
+					// Configurar handlers para selección de pantalla
+					apiClient.SetDisplayListRequestHandler(func(request api.DisplayListRequest) {
+						a.sendDisplayList(request.SessionID, "")
+					})
+
+					apiClient.SetSelectDisplayHandler(func(request api.SelectDisplayRequest) {
+						if request.SessionID != a.remoteControlAgent.GetActiveSessionID() {
+							a.sendDisplayList(request.SessionID, "session is not active")
+							return
+						}
+
+						if err := a.remoteControlAgent.SelectDisplay(request.DisplayIndex); err != nil {
+							runtime.LogErrorf(a.ctx, "Failed to select display %d: %v", request.DisplayIndex, err)
+							a.sendDisplayList(request.SessionID, err.Error())
+							return
+						}
+
+						runtime.LogInfof(a.ctx, "📺 Streaming display %d", request.DisplayIndex)
+						a.sendDisplayList(request.SessionID, "")
+					})
+
 					// Configurar handlers para transferencia de archivos
 					runtime.LogInfof(a.ctx, "🔍 DEBUG: Setting up file transfer handlers")
 
@@ -330,13 +604,12 @@ func (a *App) setupRemoteControlHandler() {
 						err := a.fileTransferAgent.HandleFileTransferRequest(request)
 						if err != nil {
 							runtime.LogErrorf(a.ctx, "Failed to handle file transfer request: %v", err)
-
-							// Enviar acknowledgment de error
-							if a.apiClient != nil {
-								a.apiClient.SendFileTransferAcknowledgement(
-									request.TransferID, request.SessionID, false,
-									err.Error(), "", "")
-							}
+							a.reportRejectedTransfer(filetransfer.IncomingTransfer{
+								TransferID: request.TransferID,
+								SessionID:  request.SessionID,
+								FileName:   request.FileName,
+								SizeBytes:  request.FileSizeBytes,
+							}, err)
 						}
 					})
 
@@ -352,20 +625,45 @@ func (a *App) setupRemoteControlHandler() {
 						}
 					})
 
+					// Preguntar al usuario antes de recibir cada archivo
+					a.fileTransferAgent.SetIncomingTransferCallback(a.handleIncomingTransfer)
+
 					// Configurar callback para cuando una transferencia se completa
-					a.fileTransferAgent.SetTransferCompletedCallback(func(transferID, fileName, filePath string, success bool, errorMsg string) {
+					a.fileTransferAgent.SetTransferCompletedCallback(func(result filetransfer.TransferResult) {
+						transferID, fileName, filePath := result.TransferID, result.FileName, result.FilePath
+						success, errorMsg := result.Success, result.ErrorMessage
 						runtime.LogInfof(a.ctx, "📁 File transfer completed: %s, Success: %v", fileName, success)
 
-						// Enviar acknowledgment al servidor
-						if a.apiClient != nil {
-							fileChecksum := ""
-							if success {
-								// Para MVP, no calculamos checksum en el callback
-								fileChecksum = "mvp-checksum"
-							}
+						if success {
+							a.recordAudit(audit.EventFileReceived, result.SessionID, "", map[string]interface{}{
+								"transfer_id":   transferID,
+								"file_name":     fileName,
+								"file_path":     filePath,
+								"size_bytes":    result.SizeBytes,
+								"checksum":      result.Checksum,
+								"checksum_algo": result.ChecksumAlgo,
+								"duration_ms":   result.Duration.Milliseconds(),
+								"is_directory":  result.IsDirectory,
+								"entries":       result.Entries,
+								"scan_status":   result.ScanStatus,
+							})
+						} else {
+							a.recordAudit(audit.EventFileFailed, result.SessionID, "", map[string]interface{}{
+								"transfer_id":     transferID,
+								"file_name":       fileName,
+								"error":           errorMsg,
+								"reason_code":     string(result.ReasonCode),
+								"scan_status":     result.ScanStatus,
+								"quarantine_path": result.QuarantinePath,
+								"checksum":        result.Checksum,
+							})
+						}
+						a.recordTransferHistory(filetransfer.HistoryEntryFromResult(result))
 
+						// Enviar acknowledgment al servidor con el SHA-256 real del archivo
+						if a.apiClient != nil {
 							err := a.apiClient.SendFileTransferAcknowledgement(
-								transferID, "", success, errorMsg, filePath, fileChecksum)
+								transferID, result.SessionID, success, errorMsg, string(result.ReasonCode), filePath, result.Checksum)
 							if err != nil {
 								runtime.LogErrorf(a.ctx, "Failed to send file transfer acknowledgement: %v", err)
 							}
@@ -373,11 +671,16 @@ func (a *App) setupRemoteControlHandler() {
 
 						// Emitir evento al frontend
 						eventData := map[string]interface{}{
-							"transfer_id": transferID,
-							"file_name":   fileName,
-							"file_path":   filePath,
-							"success":     success,
-							"error":       errorMsg,
+							"transfer_id":     transferID,
+							"file_name":       fileName,
+							"file_path":       filePath,
+							"success":         success,
+							"error":           errorMsg,
+							"is_directory":    result.IsDirectory,
+							"entries":         result.Entries,
+							"reason_code":     string(result.ReasonCode),
+							"scan_status":     result.ScanStatus,
+							"quarantine_path": result.QuarantinePath,
 						}
 
 						if success {
@@ -387,6 +690,132 @@ func (a *App) setupRemoteControlHandler() {
 						}
 					})
 
+					// Progreso por entrada de las transferencias de directorio
+					a.fileTransferAgent.SetArchiveEntryCallback(func(entry filetransfer.ArchiveEntryProgress) {
+						runtime.EventsEmit(a.ctx, "file_transfer_entry", map[string]interface{}{
+							"transfer_id":     entry.TransferID,
+							"entry_name":      entry.EntryName,
+							"entry_size":      entry.EntrySize,
+							"is_directory":    entry.IsDirectory,
+							"entries_done":    entry.EntriesDone,
+							"bytes_extracted": entry.BytesExtracted,
+						})
+					})
+
+					// Pedir al servidor el reenvío de chunks que no pasaron la verificación
+					a.fileTransferAgent.SetChunkRetransmitCallback(func(retransmit api.FileChunkRetransmit) {
+						if a.apiClient == nil {
+							return
+						}
+						if err := a.apiClient.SendFileChunkRetransmit(retransmit); err != nil {
+							runtime.LogErrorf(a.ctx, "Failed to request chunk retransmission for transfer %s: %v", retransmit.TransferID, err)
+						}
+					})
+
+					// Pedir al servidor los chunks que faltan de transferencias interrumpidas
+					a.fileTransferAgent.SetTransferResumedCallback(func(resume api.FileTransferResume) {
+						if a.apiClient == nil {
+							return
+						}
+						if err := a.apiClient.SendFileTransferResume(resume); err != nil {
+							runtime.LogErrorf(a.ctx, "Failed to request missing chunks for transfer %s: %v", resume.TransferID, err)
+						}
+					})
+
+					// Subidas de archivos pedidas por el servidor (requieren consentimiento del usuario)
+					a.uploadAgent.SetSender(apiClient)
+					apiClient.SetFileUploadRequestHandler(a.handleUploadRequest)
+					apiClient.SetFileUploadCancelHandler(func(cancel api.FileUploadCancel) {
+						a.cancelUpload(cancel.TransferID, "Cancelada por el servidor: "+cancel.Reason)
+					})
+
+					a.uploadAgent.SetUploadProgressCallback(func(progress filetransfer.UploadProgress) {
+						runtime.EventsEmit(a.ctx, "file_upload_progress", map[string]interface{}{
+							"transfer_id": progress.TransferID,
+							"file_name":   progress.FileName,
+							"bytes_sent":  progress.BytesSent,
+							"total_bytes": progress.TotalBytes,
+							"chunks_sent": progress.ChunksSent,
+							"chunks":      progress.TotalChunks,
+						})
+					})
+
+					a.uploadAgent.SetUploadFinishedCallback(func(result filetransfer.UploadResult) {
+						eventData := map[string]interface{}{
+							"transfer_id": result.TransferID,
+							"file_name":   result.FileName,
+							"file_path":   result.FilePath,
+							"bytes_sent":  result.BytesSent,
+							"success":     result.Success,
+							"cancelled":   result.Cancelled,
+							"error":       result.ErrorMessage,
+						}
+
+						if result.Success {
+							a.recordAudit(audit.EventFileUploaded, result.SessionID, result.RequestedBy, map[string]interface{}{
+								"transfer_id": result.TransferID,
+								"file_path":   result.FilePath,
+								"size_bytes":  result.BytesSent,
+								"checksum":    result.Checksum,
+								"duration_ms": result.Duration.Milliseconds(),
+							})
+							runtime.EventsEmit(a.ctx, "file_upload_completed", eventData)
+						} else {
+							a.recordAudit(audit.EventFileUploadFailed, result.SessionID, result.RequestedBy, map[string]interface{}{
+								"transfer_id": result.TransferID,
+								"file_path":   result.FilePath,
+								"bytes_sent":  result.BytesSent,
+								"cancelled":   result.Cancelled,
+								"error":       result.ErrorMessage,
+							})
+							runtime.EventsEmit(a.ctx, "file_upload_failed", eventData)
+						}
+						a.recordTransferHistory(filetransfer.HistoryEntryFromUpload(result))
+					})
+
+					// Configurar handler para cambios de estado de conexión (reconexión automática)
+					apiClient.SetConnectionStatusHandler(func(status *valueobjects.ConnectionStatus) {
+						runtime.LogInfof(a.ctx, "🔌 Connection status changed: %s", status.Status())
+
+						// Las transferencias se suspenden al caer la conexión y se reanudan al volver.
+						// Se suspenden antes de limpiar la sesión para que no se cancelen con ella.
+						if status.IsReconnecting() {
+							a.fileTransferAgent.SuspendTransfers()
+						} else if status.IsConnected() {
+							a.fileTransferAgent.ResumeTransfers()
+						}
+
+						// Una caída de conexión invalida la sesión de control remoto en curso
+						if status.IsReconnecting() && a.remoteControlAgent.IsActive() {
+							a.cleanupSession()
+						}
+
+						runtime.EventsEmit(a.ctx, "connection_status_update", map[string]interface{}{
+							"isConnected":  status.IsConnected(),
+							"status":       strings.ToLower(status.Status()),
+							"serverUrl":    status.ServerURL(),
+							"errorMessage": status.ErrorMessage(),
+						})
+					})
+
+					// Si el token se rechaza al reconectar, la sesión no puede reanudarse:
+					// volver a la pantalla de login en lugar de reintentar con la contraseña
+					apiClient.SetSessionExpiredHandler(func(reason string) {
+						runtime.LogWarningf(a.ctx, "🔑 Session expired, login required: %s", reason)
+
+						a.stopHeartbeat()
+						a.cleanupSession()
+						a.appController.Logout()
+
+						if err := a.sessionManager.ClearSession(); err != nil {
+							runtime.LogWarningf(a.ctx, "Failed to clear persisted session: %v", err)
+						}
+
+						runtime.EventsEmit(a.ctx, "session_expired", map[string]interface{}{
+							"reason": reason,
+						})
+					})
+
 					runtime.LogInfof(a.ctx, "Remote control handlers configured successfully")
 				} else {
 					runtime.LogInfof(a.ctx, "❌ DEBUG: APIClient is nil or cast failed")
@@ -404,12 +833,25 @@ func (a *App) setupRemoteControlHandler() {
 
 // shutdown es llamado cuando la app se cierra (Wails)
 func (a *App) shutdown(ctx context.Context) {
+	// Guardar el estado de las transferencias en curso para reanudarlas al volver
+	// (antes de limpiar la sesión, que cancelaría las de la sesión activa)
+	a.fileTransferAgent.SuspendTransfers()
+	a.fileTransferAgent.Close()
+
 	// Limpiar sesión antes del shutdown
 	a.cleanupSession()
 
 	// Detener heartbeat automático
 	a.stopHeartbeat()
 
+	// Volcar la actividad pendiente y cerrar el log de auditoría
+	if a.inputAudit != nil {
+		a.inputAudit.Close()
+	}
+	if a.auditLogger != nil {
+		a.auditLogger.Close()
+	}
+
 	if err := a.appController.Shutdown(); err != nil {
 		runtime.LogErrorf(ctx, "Error during shutdown: %v", err)
 	}
@@ -489,6 +931,13 @@ func (a *App) Login(username, password string) map[string]interface{} {
 						}
 					}
 
+					// Persistir token (solo tras el login local) para reanudar la sesión en el próximo inicio
+					if authResponse.Token != "" {
+						if err := a.sessionManager.StoreToken(authResponse.Token, authResponse.UserID, username); err != nil {
+							runtime.LogWarningf(a.ctx, "Failed to persist session token: %v", err)
+						}
+					}
+
 					// 5. Emitir evento de login exitoso
 					runtime.EventsEmit(a.ctx, "login_successful", map[string]interface{}{
 						"username":  username,
@@ -500,6 +949,9 @@ func (a *App) Login(username, password string) map[string]interface{} {
 					// 6. Iniciar heartbeat automático
 					a.startHeartbeat()
 
+					// Reanudar transferencias interrumpidas antes del reinicio
+					a.fileTransferAgent.ResumeTransfers()
+
 					// NOTA: Ya no registramos automáticamente el PC aquí
 					// El usuario debe usar el botón "Registrar PC" en la UI
 					runtime.LogInfof(a.ctx, "Login completed. Use 'Register PC' button to register this computer.")
@@ -525,6 +977,168 @@ func (a *App) Login(username, password string) map[string]interface{} {
 	}
 }
 
+// ResumeSession intenta reanudar la sesión persistida usando el token guardado.
+// Si el servidor rechaza el token, la sesión guardada se descarta.
+func (a *App) ResumeSession() map[string]interface{} {
+	if !a.sessionManager.IsAuthenticated() {
+		// Sin sesión guardada, usar las credenciales de arranque una única vez
+		if creds := a.autoLoginCredentials; creds != nil {
+			a.autoLoginCredentials = nil
+			// Login solo acepta string: esta copia queda fuera del alcance de Wipe
+			password := string(creds.Password)
+			creds.Wipe()
+
+			runtime.LogInfof(a.ctx, "🔑 Auto-login para usuario: %s", creds.Username)
+			return a.Login(creds.Username, password)
+		}
+
+		return map[string]interface{}{
+			"success": false,
+			"error":   "No hay sesión guardada",
+		}
+	}
+
+	sessionData := a.sessionManager.GetSessionData()
+	serverURL := a.configManager.GetServerURL()
+	runtime.LogInfof(a.ctx, "🔑 Reanudando sesión guardada para usuario: %s", sessionData.Username)
+
+	// 1. Conectar al servidor si no lo está
+	connectionStatus := a.appController.GetConnectionStatus()
+	if connectionStatus.ConnectionInfo == nil || !connectionStatus.ConnectionInfo.IsConnected {
+		connectResponse := a.appController.Connect(serverURL)
+		if !connectResponse.Success {
+			// Sin conexión no se puede validar el token; conservar la sesión para otro intento
+			return map[string]interface{}{
+				"success": false,
+				"error":   "No se pudo conectar al servidor: " + connectResponse.Error,
+			}
+		}
+	}
+
+	// 2. Configurar handlers (también obtiene el APIClient)
+	a.setupRemoteControlHandler()
+	if a.apiClient == nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "Error interno: No se pudo obtener cliente API",
+		}
+	}
+
+	// 3. Autenticar con el token
+	resumed, err := resumeStoredSession(a.apiClient, a.sessionManager, func(format string, args ...interface{}) {
+		runtime.LogWarningf(a.ctx, format, args...)
+	})
+	var expired *sessionExpiredError
+	if errors.As(err, &expired) {
+		runtime.LogWarningf(a.ctx, "Stored session rejected by server: %s", expired.reason)
+		return map[string]interface{}{
+			"success":         false,
+			"session_expired": true,
+			"error":           "Sesión expirada: " + expired.reason,
+		}
+	}
+	if err != nil {
+		runtime.LogErrorf(a.ctx, "Token authentication failed: %v", err)
+		return map[string]interface{}{
+			"success": false,
+			"error":   "Error de autenticación: " + err.Error(),
+		}
+	}
+
+	// 4. Restaurar la sesión local
+	localAuthResponse := a.appController.LoginWithToken(resumed.Username, resumed.Token)
+	if !localAuthResponse.Success {
+		runtime.LogErrorf(a.ctx, "Local session restore failed: %s", localAuthResponse.Error)
+		return map[string]interface{}{
+			"success": false,
+			"error":   "Error de autenticación local: " + localAuthResponse.Error,
+		}
+	}
+
+	// El servidor puede rotar el token y reportar el usuario al reanudar
+	userID := resumed.UserID
+
+	runtime.EventsEmit(a.ctx, "login_successful", map[string]interface{}{
+		"username":  sessionData.Username,
+		"userId":    userID,
+		"serverUrl": serverURL,
+		"resumed":   true,
+	})
+
+	a.startHeartbeat()
+
+	// Reanudar transferencias interrumpidas antes del reinicio
+	a.fileTransferAgent.ResumeTransfers()
+
+	result := map[string]interface{}{
+		"success": true,
+		"resumed": true,
+		"message": "Sesión reanudada",
+		"user": map[string]interface{}{
+			"id":       userID,
+			"username": sessionData.Username,
+		},
+		"server_url": serverURL,
+	}
+
+	// 5. Re-registrar el PC si ya estaba registrado en la sesión anterior
+	if resumed.PCID != "" {
+		registration := a.RegisterPC()
+		if success, _ := registration["success"].(bool); success {
+			result["pc_info"] = registration["pc_info"]
+		} else {
+			runtime.LogWarningf(a.ctx, "PC re-registration after resume failed: %v", registration["error"])
+		}
+	}
+
+	runtime.LogInfof(a.ctx, "✅ Sesión reanudada para usuario: %s", sessionData.Username)
+	return result
+}
+
+// tokenAuthenticator valida un token de sesión contra el servidor (el APIClient salvo en tests)
+type tokenAuthenticator interface {
+	AuthenticateWithToken(token string) (*api.ClientAuthResponse, error)
+}
+
+// sessionExpiredError indica que el servidor rechazó el token de la sesión guardada
+type sessionExpiredError struct {
+	reason string // Error reportado por el servidor
+}
+
+func (e *sessionExpiredError) Error() string {
+	return "stored session rejected by server: " + e.reason
+}
+
+// resumeStoredSession valida el token de la sesión guardada. Si el servidor lo
+// rechaza, la sesión guardada se descarta; si lo acepta, se guarda el token vigente
+// (el servidor puede rotarlo) conservando el PC registrado. Un error de
+// conexión conserva la sesión para otro intento.
+func resumeStoredSession(client tokenAuthenticator, sessions *session.SessionManager, warnf func(format string, args ...interface{})) (session.SessionData, error) {
+	sessionData := sessions.GetSessionData()
+
+	authResponse, err := client.AuthenticateWithToken(sessionData.Token)
+	if err != nil {
+		return sessionData, err
+	}
+
+	if !authResponse.Success {
+		if err := sessions.ClearSession(); err != nil {
+			warnf("Failed to clear persisted session: %v", err)
+		}
+		return session.SessionData{}, &sessionExpiredError{reason: authResponse.Error}
+	}
+
+	sessionData.Token = authResponse.Token
+	if authResponse.UserID != "" {
+		sessionData.UserID = authResponse.UserID
+	}
+	if err := sessions.StoreToken(sessionData.Token, sessionData.UserID, sessionData.Username); err != nil {
+		warnf("Failed to persist session token: %v", err)
+	}
+
+	return sessionData, nil
+}
+
 // Logout maneja el logout del usuario
 func (a *App) Logout() map[string]interface{} {
 	runtime.LogInfof(a.ctx, "Starting logout process")
@@ -541,6 +1155,11 @@ func (a *App) Logout() map[string]interface{} {
 	// 3. Logout local
 	logoutResponse := a.appController.Logout()
 
+	// Olvidar la sesión persistida para no reanudarla en el próximo inicio
+	if err := a.sessionManager.ClearSession(); err != nil {
+		runtime.LogWarningf(a.ctx, "Failed to clear persisted session: %v", err)
+	}
+
 	// 4. Emitir evento de logout
 	runtime.EventsEmit(a.ctx, "logout_completed", map[string]interface{}{
 		"reason": "user_requested",
@@ -625,6 +1244,12 @@ func (a *App) RegisterPC() map[string]interface{} {
 			"arch":       response.PCInfo.Architecture(),
 			"ip":         response.PCInfo.IPAddress(),
 		}
+
+		if response.Success {
+			if err := a.sessionManager.StorePCID(response.PCInfo.Identifier()); err != nil {
+				runtime.LogWarningf(a.ctx, "Failed to persist PC ID: %v", err)
+			}
+		}
 	}
 
 	return result
@@ -726,18 +1351,99 @@ func (a *App) stopHeartbeat() {
 
 // ===== MÉTODOS DE CONTROL REMOTO =====
 
-// AcceptControlRequest acepta una solicitud de control remoto
-func (a *App) AcceptControlRequest(sessionID string) map[string]interface{} {
-	if a.apiClient == nil {
-		return map[string]interface{}{
-			"success": false,
-			"error":   "No hay conexión con el servidor",
-		}
-	}
+// handleControlRequest aplica la política de consentimiento antes de mostrar el diálogo
+func (a *App) handleControlRequest(request api.RemoteControlRequest) {
+	decision := a.consentPolicy.Evaluate(request, time.Now())
 
-	err := a.apiClient.AcceptRemoteControlSession(sessionID)
-	if err != nil {
-		runtime.LogErrorf(a.ctx, "Failed to accept control request: %v", err)
+	a.recordAudit(audit.EventControlRequested, request.SessionID, request.AdminUsername, map[string]interface{}{
+		"admin_user_id": request.AdminUserID,
+		"client_pc_id":  request.ClientPCID,
+		"policy_action": string(decision.Action),
+		"policy_reason": decision.Reason,
+	})
+
+	a.setControlMode(request.SessionID, controlMode{
+		viewOnly: decision.ViewOnly,
+		locked:   decision.ViewOnlyLocked,
+		trusted:  decision.Trusted,
+	})
+
+	switch decision.Action {
+	case remotecontrol.ConsentAccept:
+		runtime.LogInfof(a.ctx, "🛡️ Política: solicitud de %s aceptada automáticamente (%s)", request.AdminUsername, decision.Reason)
+		a.acceptControlRequest(request.SessionID, decidedByPolicy, decision.ViewOnly)
+
+	case remotecontrol.ConsentReject:
+		runtime.LogInfof(a.ctx, "🛡️ Política: solicitud de %s rechazada automáticamente (%s)", request.AdminUsername, decision.Reason)
+		a.rejectControlRequest(request.SessionID, decision.Reason, decidedByPolicy)
+
+	default:
+		// Preguntar al usuario; sin respuesta a tiempo se aplica la acción por defecto
+		a.consentPrompts.Begin(request.SessionID, decision.PromptTimeout, func() {
+			runtime.LogInfof(a.ctx, "⏰ Solicitud de control %s sin respuesta, acción: %s", request.SessionID, decision.TimeoutAction)
+			runtime.EventsEmit(a.ctx, "control_request_expired", map[string]interface{}{
+				"sessionId": request.SessionID,
+				"action":    string(decision.TimeoutAction),
+			})
+
+			if decision.TimeoutAction == remotecontrol.ConsentAccept {
+				a.acceptControlRequest(request.SessionID, decidedByTimeout, decision.ViewOnly)
+			} else {
+				a.rejectControlRequest(request.SessionID, "Tiempo de espera agotado sin respuesta del usuario", decidedByTimeout)
+			}
+		})
+
+		runtime.EventsEmit(a.ctx, "incoming_control_request", map[string]interface{}{
+			"sessionId":      request.SessionID,
+			"adminUserId":    request.AdminUserID,
+			"adminUsername":  request.AdminUsername,
+			"clientPcId":     request.ClientPCID,
+			"timeoutSeconds": int(decision.PromptTimeout.Seconds()),
+			"timeoutAction":  string(decision.TimeoutAction),
+			"viewOnly":       decision.ViewOnly,
+			"viewOnlyLocked": decision.ViewOnlyLocked,
+		})
+	}
+}
+
+// AcceptControlRequest acepta una solicitud de control remoto.
+// Con viewOnly el admin solo podrá ver la pantalla.
+func (a *App) AcceptControlRequest(sessionID string, viewOnly bool) map[string]interface{} {
+	if !a.consentPrompts.Resolve(sessionID) {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "La solicitud ya expiró o fue respondida",
+		}
+	}
+
+	return a.acceptControlRequest(sessionID, decidedByUser, viewOnly)
+}
+
+// Origen de la decisión sobre una solicitud de control, registrado en auditoría
+const (
+	decidedByUser    = "user"
+	decidedByPolicy  = "policy"
+	decidedByTimeout = "timeout"
+	decidedBySession = "session_ended"
+)
+
+// acceptControlRequest envía la aceptación al servidor
+func (a *App) acceptControlRequest(sessionID, decidedBy string, viewOnly bool) map[string]interface{} {
+	if a.apiClient == nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "No hay conexión con el servidor",
+		}
+	}
+
+	// La política puede imponer el modo solo visualización
+	mode := a.getControlMode(sessionID)
+	mode.viewOnly = viewOnly || mode.locked
+	a.setControlMode(sessionID, mode)
+
+	err := a.apiClient.AcceptRemoteControlSession(sessionID, mode.viewOnly)
+	if err != nil {
+		runtime.LogErrorf(a.ctx, "Failed to accept control request: %v", err)
 		return map[string]interface{}{
 			"success": false,
 			"error":   err.Error(),
@@ -745,10 +1451,15 @@ func (a *App) AcceptControlRequest(sessionID string) map[string]interface{} {
 	}
 
 	runtime.LogInfof(a.ctx, "Control request accepted for session: %s", sessionID)
+	a.recordAudit(audit.EventSessionAccepted, sessionID, decidedBy, map[string]interface{}{
+		"view_only": mode.viewOnly,
+	})
 
 	// Emitir evento de sesión aceptada
 	runtime.EventsEmit(a.ctx, "control_session_accepted", map[string]interface{}{
-		"sessionId": sessionID,
+		"sessionId":      sessionID,
+		"viewOnly":       mode.viewOnly,
+		"viewOnlyLocked": mode.locked,
 	})
 
 	return map[string]interface{}{
@@ -759,6 +1470,18 @@ func (a *App) AcceptControlRequest(sessionID string) map[string]interface{} {
 
 // RejectControlRequest rechaza una solicitud de control remoto
 func (a *App) RejectControlRequest(sessionID string, reason string) map[string]interface{} {
+	if !a.consentPrompts.Resolve(sessionID) {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "La solicitud ya expiró o fue respondida",
+		}
+	}
+
+	return a.rejectControlRequest(sessionID, reason, decidedByUser)
+}
+
+// rejectControlRequest envía el rechazo al servidor
+func (a *App) rejectControlRequest(sessionID, reason, decidedBy string) map[string]interface{} {
 	if a.apiClient == nil {
 		return map[string]interface{}{
 			"success": false,
@@ -780,6 +1503,10 @@ func (a *App) RejectControlRequest(sessionID string, reason string) map[string]i
 	}
 
 	runtime.LogInfof(a.ctx, "Control request rejected for session: %s, reason: %s", sessionID, reason)
+	a.clearControlMode(sessionID)
+	a.recordAudit(audit.EventSessionRejected, sessionID, decidedBy, map[string]interface{}{
+		"reason": reason,
+	})
 
 	// Emitir evento de sesión rechazada
 	runtime.EventsEmit(a.ctx, "control_session_rejected", map[string]interface{}{
@@ -793,6 +1520,225 @@ func (a *App) RejectControlRequest(sessionID string, reason string) map[string]i
 	}
 }
 
+// SetViewOnlyMode revoca (true) o concede (false) el control de input en la sesión activa
+func (a *App) SetViewOnlyMode(viewOnly bool) map[string]interface{} {
+	sessionID := a.remoteControlAgent.GetActiveSessionID()
+	if sessionID == "" {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "No hay una sesión de control remoto activa",
+		}
+	}
+
+	mode := a.getControlMode(sessionID)
+	if mode.locked && !viewOnly {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "La política solo permite sesiones de visualización",
+		}
+	}
+
+	if err := a.remoteControlAgent.SetViewOnly(viewOnly); err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+	}
+
+	mode.viewOnly = viewOnly
+	a.setControlMode(sessionID, mode)
+
+	reason := "Usuario concedió el control"
+	if viewOnly {
+		reason = "Usuario revocó el control"
+	}
+
+	if a.apiClient != nil {
+		if err := a.apiClient.SendControlModeChanged(sessionID, viewOnly, reason); err != nil {
+			runtime.LogErrorf(a.ctx, "Failed to send control mode change: %v", err)
+		}
+	}
+
+	runtime.LogInfof(a.ctx, "👁️ %s (sesión %s)", reason, sessionID)
+	a.recordAudit(audit.EventControlModeChanged, sessionID, decidedByUser, map[string]interface{}{
+		"view_only": viewOnly,
+	})
+	a.emitControlMode(sessionID, mode)
+
+	return map[string]interface{}{
+		"success":  true,
+		"viewOnly": viewOnly,
+	}
+}
+
+// getControlMode retorna el modo de control registrado para una sesión
+func (a *App) getControlMode(sessionID string) controlMode {
+	a.controlModesMutex.Lock()
+	defer a.controlModesMutex.Unlock()
+	return a.controlModes[sessionID]
+}
+
+// setControlMode registra el modo de control de una sesión
+func (a *App) setControlMode(sessionID string, mode controlMode) {
+	a.controlModesMutex.Lock()
+	defer a.controlModesMutex.Unlock()
+	a.controlModes[sessionID] = mode
+}
+
+// clearControlMode olvida el modo de control de una sesión terminada
+func (a *App) clearControlMode(sessionID string) {
+	a.controlModesMutex.Lock()
+	defer a.controlModesMutex.Unlock()
+	delete(a.controlModes, sessionID)
+}
+
+// emitControlMode informa a la UI del modo de control actual
+func (a *App) emitControlMode(sessionID string, mode controlMode) {
+	runtime.EventsEmit(a.ctx, "control_mode_changed", map[string]interface{}{
+		"sessionId":      sessionID,
+		"viewOnly":       mode.viewOnly,
+		"viewOnlyLocked": mode.locked,
+	})
+}
+
+// ===== MÉTODOS DE PORTAPAPELES =====
+
+// SetClipboardSync activa o desactiva la sincronización del portapapeles en la sesión activa
+func (a *App) SetClipboardSync(enabled bool) map[string]interface{} {
+	sessionID := a.remoteControlAgent.GetActiveSessionID()
+	if sessionID == "" {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "No hay una sesión de control remoto activa",
+		}
+	}
+
+	if err := a.remoteControlAgent.SetClipboardSyncEnabled(enabled); err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+	}
+
+	runtime.LogInfof(a.ctx, "📋 Sincronización del portapapeles: %v (sesión %s)", enabled, sessionID)
+	a.recordAudit(audit.EventClipboardChanged, sessionID, decidedByUser, map[string]interface{}{
+		"enabled": enabled,
+	})
+	a.sendClipboardState(sessionID, "")
+	a.emitClipboardState(sessionID)
+
+	return map[string]interface{}{
+		"success": true,
+		"enabled": enabled,
+	}
+}
+
+// handleClipboardUpdate copia al portapapeles local el contenido enviado por el admin
+func (a *App) handleClipboardUpdate(update api.ClipboardUpdate) {
+	err := a.remoteControlAgent.ApplyClipboardUpdate(update)
+
+	// Solo se audita el formato, nunca el contenido
+	if a.inputAudit != nil {
+		if err != nil {
+			a.inputAudit.Observe(update.SessionID, "clipboard", "blocked")
+		} else {
+			a.inputAudit.Observe(update.SessionID, "clipboard", "received")
+		}
+	}
+
+	if err != nil {
+		// En modo solo visualización o con la sincronización desactivada el rechazo es esperado
+		if !errors.Is(err, remotecontrol.ErrViewOnly) && !errors.Is(err, remotecontrol.ErrClipboardDisabled) {
+			runtime.LogErrorf(a.ctx, "Failed to apply clipboard update: %v", err)
+		}
+		a.sendClipboardState(update.SessionID, err.Error())
+	}
+}
+
+// sendClipboardState informa al admin del estado de la sincronización del portapapeles
+func (a *App) sendClipboardState(sessionID, errorMessage string) {
+	if a.apiClient == nil {
+		return
+	}
+
+	state := a.remoteControlAgent.GetClipboardSyncState()
+	state.SessionID = sessionID
+	state.Error = errorMessage
+
+	if err := a.apiClient.SendClipboardSyncState(state); err != nil {
+		runtime.LogErrorf(a.ctx, "Failed to send clipboard sync state: %v", err)
+	}
+}
+
+// emitClipboardState informa a la UI del estado de la sincronización del portapapeles
+func (a *App) emitClipboardState(sessionID string) {
+	config := a.remoteControlAgent.GetClipboardConfig()
+	runtime.EventsEmit(a.ctx, "clipboard_sync_changed", map[string]interface{}{
+		"sessionId":   sessionID,
+		"enabled":     a.remoteControlAgent.IsClipboardSyncEnabled(),
+		"allowImages": config.AllowImages,
+	})
+}
+
+// ===== MÉTODOS DE AUDITORÍA =====
+
+// VerifyAuditLog comprueba la integridad de la cadena de hashes del log de auditoría
+func (a *App) VerifyAuditLog() map[string]interface{} {
+	result, err := audit.Verify(getAuditDirectory())
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+	}
+
+	if !result.Valid {
+		runtime.LogErrorf(a.ctx, "⚠️ Log de auditoría alterado en la entrada %d: %s", result.BrokenAt, result.Error)
+	}
+
+	return map[string]interface{}{
+		"success":   true,
+		"valid":     result.Valid,
+		"entries":   result.Entries,
+		"files":     result.Files,
+		"firstSeq":  result.FirstSeq,
+		"lastSeq":   result.LastSeq,
+		"brokenAt":  result.BrokenAt,
+		"errorInfo": result.Error,
+	}
+}
+
+// ExportAuditLog exporta el log de auditoría completo como JSONL.
+// Si destPath está vacío se guarda en el directorio de descargas.
+func (a *App) ExportAuditLog(destPath string) map[string]interface{} {
+	if destPath == "" {
+		destPath = filepath.Join(a.fileTransferAgent.GetDownloadDirectory(),
+			fmt.Sprintf("audit-export-%s.jsonl", time.Now().Format("20060102-150405")))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+	}
+
+	if err := audit.ExportToFile(getAuditDirectory(), destPath); err != nil {
+		runtime.LogErrorf(a.ctx, "Failed to export audit log: %v", err)
+		return map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+	}
+
+	runtime.LogInfof(a.ctx, "📋 Log de auditoría exportado a %s", destPath)
+
+	return map[string]interface{}{
+		"success": true,
+		"path":    destPath,
+	}
+}
+
 // ===== CONFIGURACIÓN DE EVENTOS PARA UI =====
 
 // setupUIEventBindings configura los eventos para la UI
@@ -838,15 +1784,9 @@ func (w *WailsUIObserver) GetID() string {
 
 // ===== STREAMING DE PANTALLA =====
 
-// startScreenStreaming inicia el streaming de pantalla durante una sesión activa
-func (a *App) startScreenStreaming() {
-	runtime.LogInfof(a.ctx, "📹 Starting screen streaming...")
-
-	// Obtener canal de frames del RemoteControlAgent
-	frameOutput := a.remoteControlAgent.GetFrameOutput()
-
-	// Obtener el sessionID actual al inicio del streaming
-	currentSessionID := a.remoteControlAgent.GetActiveSessionID()
+// startScreenStreaming envía los frames de una sesión; termina cuando el
+// agente cierra el canal de la sesión al detenerla
+func (a *App) startScreenStreaming(currentSessionID string, frameOutput <-chan api.ScreenFrame) {
 	runtime.LogInfof(a.ctx, "📹 Screen streaming for session: %s", currentSessionID)
 
 	for frame := range frameOutput {
@@ -856,33 +1796,24 @@ func (a *App) startScreenStreaming() {
 			break
 		}
 
-		// Verificar que el frame pertenece a la sesión actual
-		if frame.SessionID != currentSessionID {
-			runtime.LogWarningf(a.ctx, "⚠️ Dropping frame for old session %s (current: %s)",
-				frame.SessionID, currentSessionID)
-			continue
-		}
-
-		// Verificar que aún coincide con la sesión activa del agente
-		activeSessionID := a.remoteControlAgent.GetActiveSessionID()
-		if frame.SessionID != activeSessionID {
-			runtime.LogWarningf(a.ctx, "⚠️ Dropping frame for mismatched session %s (active: %s)",
-				frame.SessionID, activeSessionID)
-			continue
+		// Un frame de otra sesión significa que esta ya terminó: salir en vez de
+		// descartarlo, porque perder un delta deja la imagen del admin desfasada
+		if frame.SessionID != currentSessionID ||
+			frame.SessionID != a.remoteControlAgent.GetActiveSessionID() {
+			runtime.LogInfof(a.ctx, "🔚 Screen streaming stopped - session %s replaced", currentSessionID)
+			break
 		}
 
 		// 🎬 AGREGAR FRAME AL VIDEORECORDER SI ESTÁ GRABANDO
-		if a.IsVideoRecording() {
-			if err := a.AddVideoFrame(frame.FrameData); err != nil {
+		if a.IsVideoRecording() && frame.FullFrameData != nil {
+			if err := a.AddVideoFrame(frame.FullFrameData); err != nil {
 				runtime.LogWarningf(a.ctx, "⚠️ Failed to add frame to video recording: %v", err)
 			}
 		}
 
-		// Enviar frame al servidor de forma asíncrona
-		if a.apiClient != nil {
-			a.apiClient.SendScreenFrameAsync(frame)
-		} else {
-			runtime.LogWarningf(a.ctx, "⚠️ Cannot send screen frame: API client is nil")
+		// Encolar frame para envío (el más reciente reemplaza al pendiente)
+		if err := a.remoteControlAgent.SendFrame(frame); err != nil {
+			runtime.LogWarningf(a.ctx, "⚠️ Cannot send screen frame: %v", err)
 			break
 		}
 	}
@@ -890,6 +1821,45 @@ func (a *App) startScreenStreaming() {
 	runtime.LogInfof(a.ctx, "📹 Screen streaming ended for session: %s", currentSessionID)
 }
 
+// sendDisplayList envía al admin las pantallas del cliente y la seleccionada
+func (a *App) sendDisplayList(sessionID, errorMessage string) {
+	if a.apiClient == nil {
+		return
+	}
+
+	displays := a.remoteControlAgent.ListDisplays()
+	list := api.DisplayListMessage{
+		SessionID:       sessionID,
+		Displays:        make([]api.DisplayInfo, 0, len(displays)),
+		SelectedDisplay: a.remoteControlAgent.GetSelectedDisplay(),
+		Error:           errorMessage,
+	}
+
+	var union image.Rectangle
+	for _, display := range displays {
+		list.Displays = append(list.Displays, api.DisplayInfo{
+			Index:   display.Index,
+			X:       display.X,
+			Y:       display.Y,
+			Width:   display.Width,
+			Height:  display.Height,
+			Primary: display.Primary,
+		})
+		union = union.Union(image.Rect(display.X, display.Y, display.X+display.Width, display.Y+display.Height))
+	}
+	list.VirtualDesktop = api.DisplayInfo{
+		Index:  api.DisplayVirtualDesktop,
+		X:      union.Min.X,
+		Y:      union.Min.Y,
+		Width:  union.Dx(),
+		Height: union.Dy(),
+	}
+
+	if err := a.apiClient.SendDisplayList(list); err != nil {
+		runtime.LogWarningf(a.ctx, "⚠️ Failed to send display list: %v", err)
+	}
+}
+
 // AddVideoFrame agrega un frame a la grabación (llamado desde startScreenStreaming)
 func (a *App) AddVideoFrame(frameData []byte) error {
 	if a.videoRecorder == nil || !a.videoRecorder.IsRecording() {
@@ -1016,6 +1986,9 @@ func (a *App) StartVideoRecording(sessionID string) error {
 		return err
 	}
 
+	// Con codificación delta la grabación necesita el frame completo en cada tick
+	a.remoteControlAgent.SetFullFrameCapture(true)
+
 	// Actualizar estado
 	videoStateMutex.Lock()
 	videoState = VideoRecordingState{
@@ -1058,6 +2031,7 @@ func (a *App) StopVideoRecording() error {
 	runtime.LogInfof(a.ctx, "📹 Finalizando grabación activa...")
 
 	result, err := a.videoRecorder.StopRecording()
+	a.remoteControlAgent.SetFullFrameCapture(false)
 
 	// ✅ LIMPIAR ESTADO SIEMPRE, INCLUSO SI HAY ERROR
 	videoStateMutex.Lock()
@@ -1281,6 +2255,544 @@ func (a *App) GetFileTransferDirectory() map[string]interface{} {
 	}
 }
 
+// handleIncomingTransfer pregunta al usuario si acepta un archivo que el servidor quiere enviar
+func (a *App) handleIncomingTransfer(incoming filetransfer.IncomingTransfer) {
+	a.recordAudit(audit.EventFileIncoming, incoming.SessionID, "", map[string]interface{}{
+		"transfer_id":  incoming.TransferID,
+		"file_name":    incoming.FileName,
+		"size_bytes":   incoming.SizeBytes,
+		"is_directory": incoming.IsDirectory,
+	})
+
+	timeout := a.fileTransferAgent.IncomingPromptTimeout()
+	a.incomingPrompts.Begin(incoming.TransferID, timeout, func() {
+		runtime.LogInfof(a.ctx, "⏰ Transferencia %s sin respuesta, se rechaza", incoming.TransferID)
+		runtime.EventsEmit(a.ctx, "file_transfer_incoming_expired", map[string]interface{}{
+			"transfer_id": incoming.TransferID,
+		})
+		a.declineIncomingTransfer(incoming.TransferID, "Tiempo de espera agotado sin respuesta del usuario", decidedByTimeout)
+	})
+
+	runtime.EventsEmit(a.ctx, "file_transfer_incoming", map[string]interface{}{
+		"transfer_id":     incoming.TransferID,
+		"session_id":      incoming.SessionID,
+		"file_name":       incoming.FileName,
+		"size_bytes":      incoming.SizeBytes,
+		"total_chunks":    incoming.TotalChunks,
+		"is_directory":    incoming.IsDirectory,
+		"timeout_seconds": int(timeout.Seconds()),
+	})
+}
+
+// AcceptFileTransfer permite recibir un archivo entrante
+func (a *App) AcceptFileTransfer(transferID string) map[string]interface{} {
+	if !a.incomingPrompts.Resolve(transferID) {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "La solicitud ya expiró o fue respondida",
+		}
+	}
+
+	incoming, err := a.fileTransferAgent.AcceptIncoming(transferID)
+	if err != nil {
+		runtime.LogErrorf(a.ctx, "Error iniciando transferencia %s: %v", transferID, err)
+		if incoming.TransferID != "" {
+			a.reportRejectedTransfer(incoming, err)
+		}
+		return map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+	}
+
+	a.recordAudit(audit.EventFileAccepted, incoming.SessionID, decidedByUser, map[string]interface{}{
+		"transfer_id": transferID,
+		"file_name":   incoming.FileName,
+		"size_bytes":  incoming.SizeBytes,
+	})
+
+	return map[string]interface{}{
+		"success": true,
+		"message": "Transferencia aceptada",
+	}
+}
+
+// DeclineFileTransfer rechaza un archivo entrante
+func (a *App) DeclineFileTransfer(transferID, reason string) map[string]interface{} {
+	if !a.incomingPrompts.Resolve(transferID) {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "La solicitud ya expiró o fue respondida",
+		}
+	}
+
+	if reason == "" {
+		reason = "Usuario rechazó el archivo"
+	}
+	a.declineIncomingTransfer(transferID, reason, decidedByUser)
+
+	return map[string]interface{}{
+		"success": true,
+		"message": "Transferencia rechazada",
+	}
+}
+
+// declineIncomingTransfer descarta una transferencia pendiente e informa al servidor
+func (a *App) declineIncomingTransfer(transferID, reason, decidedBy string) {
+	incoming, err := a.fileTransferAgent.DeclineIncoming(transferID)
+	if err != nil {
+		runtime.LogErrorf(a.ctx, "Error rechazando transferencia %s: %v", transferID, err)
+		return
+	}
+
+	a.reportDeclinedTransfer(incoming, reason, filetransfer.ReasonDeclined, decidedBy)
+}
+
+// reportDeclinedTransfer envía el rechazo al servidor y lo registra en auditoría e historial
+func (a *App) reportDeclinedTransfer(incoming filetransfer.IncomingTransfer, reason string, code filetransfer.ReasonCode, decidedBy string) {
+	if a.apiClient != nil {
+		err := a.apiClient.SendFileTransferAcknowledgement(
+			incoming.TransferID, incoming.SessionID, false, reason, string(code), "", "")
+		if err != nil {
+			runtime.LogErrorf(a.ctx, "Failed to send file transfer acknowledgement: %v", err)
+		}
+	}
+
+	a.recordAudit(audit.EventFileDeclined, incoming.SessionID, decidedBy, map[string]interface{}{
+		"transfer_id": incoming.TransferID,
+		"file_name":   incoming.FileName,
+		"reason":      reason,
+		"reason_code": string(code),
+	})
+	a.recordTransferHistory(filetransfer.HistoryEntry{
+		TransferID:  incoming.TransferID,
+		Direction:   filetransfer.HistoryReceived,
+		FileName:    incoming.FileName,
+		SizeBytes:   incoming.SizeBytes,
+		SessionID:   incoming.SessionID,
+		IsDirectory: incoming.IsDirectory,
+		Outcome:     filetransfer.OutcomeDeclined,
+		Error:       reason,
+		ReasonCode:  string(code),
+	})
+}
+
+// reportRejectedTransfer informa al servidor que una transferencia no se pudo iniciar.
+// Los rechazos de la política también se registran y se muestran al usuario.
+func (a *App) reportRejectedTransfer(incoming filetransfer.IncomingTransfer, err error) {
+	// Enviar acknowledgment de error con el código de rechazo de la política
+	reasonCode := string(filetransfer.ReasonCodeOf(err))
+	if a.apiClient != nil {
+		a.apiClient.SendFileTransferAcknowledgement(
+			incoming.TransferID, incoming.SessionID, false,
+			err.Error(), reasonCode, "", "")
+	}
+
+	if reasonCode == "" {
+		return
+	}
+
+	a.recordAudit(audit.EventFileFailed, incoming.SessionID, "", map[string]interface{}{
+		"transfer_id": incoming.TransferID,
+		"file_name":   incoming.FileName,
+		"error":       err.Error(),
+		"reason_code": reasonCode,
+	})
+	a.recordTransferHistory(filetransfer.HistoryEntry{
+		TransferID: incoming.TransferID,
+		Direction:  filetransfer.HistoryReceived,
+		FileName:   incoming.FileName,
+		SizeBytes:  incoming.SizeBytes,
+		SessionID:  incoming.SessionID,
+		Outcome:    filetransfer.OutcomeFailed,
+		Error:      err.Error(),
+		ReasonCode: reasonCode,
+	})
+	runtime.EventsEmit(a.ctx, "file_transfer_failed", map[string]interface{}{
+		"transfer_id": incoming.TransferID,
+		"file_name":   incoming.FileName,
+		"success":     false,
+		"error":       err.Error(),
+		"reason_code": reasonCode,
+	})
+}
+
+// dismissIncomingPrompt cierra el diálogo de una transferencia que ya no necesita respuesta
+func (a *App) dismissIncomingPrompt(transferID string) {
+	if a.incomingPrompts.Resolve(transferID) {
+		runtime.EventsEmit(a.ctx, "file_transfer_incoming_expired", map[string]interface{}{
+			"transfer_id": transferID,
+		})
+	}
+}
+
+// isTrustedSession indica si la sesión la pidió un administrador de confianza
+func (a *App) isTrustedSession(sessionID string) bool {
+	return a.getControlMode(sessionID).trusted
+}
+
+// handleUploadRequest valida una solicitud de subida y pregunta al usuario
+func (a *App) handleUploadRequest(request api.FileUploadRequest) {
+	info, err := a.uploadAgent.HandleUploadRequest(request)
+
+	auditData := map[string]interface{}{
+		"transfer_id": request.TransferID,
+		"file_path":   request.FilePath,
+	}
+	if err != nil {
+		// El agente ya rechazó la solicitud ante el servidor
+		runtime.LogErrorf(a.ctx, "📤 Solicitud de subida %s rechazada: %v", request.TransferID, err)
+		auditData["error"] = err.Error()
+		a.recordAudit(audit.EventFileUploadRequested, request.SessionID, request.RequestedBy, auditData)
+		return
+	}
+	auditData["size_bytes"] = info.SizeBytes
+	a.recordAudit(audit.EventFileUploadRequested, request.SessionID, request.RequestedBy, auditData)
+
+	timeout := a.uploadAgent.PromptTimeout()
+	a.uploadPrompts.Begin(request.TransferID, timeout, func() {
+		runtime.LogInfof(a.ctx, "⏰ Solicitud de subida %s sin respuesta, se rechaza", request.TransferID)
+		runtime.EventsEmit(a.ctx, "file_upload_request_expired", map[string]interface{}{
+			"transfer_id": request.TransferID,
+		})
+		a.declineUpload(request.TransferID, "Tiempo de espera agotado sin respuesta del usuario", decidedByTimeout)
+	})
+
+	runtime.EventsEmit(a.ctx, "file_upload_request", map[string]interface{}{
+		"transfer_id":     info.TransferID,
+		"session_id":      info.SessionID,
+		"requested_by":    info.RequestedBy,
+		"file_name":       info.FileName,
+		"file_path":       info.FilePath,
+		"size_bytes":      info.SizeBytes,
+		"timeout_seconds": int(timeout.Seconds()),
+	})
+}
+
+// AcceptFileUpload permite enviar al servidor el archivo solicitado
+func (a *App) AcceptFileUpload(transferID string) map[string]interface{} {
+	if !a.uploadPrompts.Resolve(transferID) {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "La solicitud ya expiró o fue respondida",
+		}
+	}
+
+	info, err := a.uploadAgent.Accept(transferID)
+	if err != nil {
+		runtime.LogErrorf(a.ctx, "Error iniciando subida %s: %v", transferID, err)
+		a.recordAudit(audit.EventFileUploadFailed, info.SessionID, info.RequestedBy, map[string]interface{}{
+			"transfer_id": transferID,
+			"error":       err.Error(),
+		})
+		return map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+	}
+
+	a.recordAudit(audit.EventFileUploadAccepted, info.SessionID, decidedByUser, map[string]interface{}{
+		"transfer_id": transferID,
+		"file_path":   info.FilePath,
+		"size_bytes":  info.SizeBytes,
+	})
+
+	return map[string]interface{}{
+		"success": true,
+		"message": "Subida iniciada",
+	}
+}
+
+// DeclineFileUpload rechaza una solicitud de subida pendiente
+func (a *App) DeclineFileUpload(transferID, reason string) map[string]interface{} {
+	if !a.uploadPrompts.Resolve(transferID) {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "La solicitud ya expiró o fue respondida",
+		}
+	}
+
+	if reason == "" {
+		reason = "Usuario rechazó la subida"
+	}
+	a.declineUpload(transferID, reason, decidedByUser)
+
+	return map[string]interface{}{
+		"success": true,
+		"message": "Subida rechazada",
+	}
+}
+
+// declineUpload rechaza una subida pendiente y lo registra en auditoría
+func (a *App) declineUpload(transferID, reason, decidedBy string) {
+	info, err := a.uploadAgent.Decline(transferID, reason)
+	if err != nil {
+		runtime.LogErrorf(a.ctx, "Error rechazando subida %s: %v", transferID, err)
+	}
+
+	a.recordAudit(audit.EventFileUploadDeclined, info.SessionID, decidedBy, map[string]interface{}{
+		"transfer_id": transferID,
+		"reason":      reason,
+	})
+}
+
+// CancelFileUpload detiene una subida en curso
+func (a *App) CancelFileUpload(transferID string) map[string]interface{} {
+	if !a.cancelUpload(transferID, "Cancelada por el usuario") {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "La subida no existe o ya terminó",
+		}
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"message": "Subida cancelada",
+	}
+}
+
+// cancelUpload detiene una subida en curso o descarta una pendiente de decisión
+func (a *App) cancelUpload(transferID, reason string) bool {
+	a.dismissUploadPrompt(transferID)
+	return a.uploadAgent.Cancel(transferID, reason) == nil
+}
+
+// dismissUploadPrompt cierra el diálogo de una solicitud que ya no necesita respuesta
+func (a *App) dismissUploadPrompt(transferID string) {
+	if a.uploadPrompts.Resolve(transferID) {
+		runtime.EventsEmit(a.ctx, "file_upload_request_expired", map[string]interface{}{
+			"transfer_id": transferID,
+		})
+	}
+}
+
+// GetActiveUploads retorna las subidas en curso
+func (a *App) GetActiveUploads() map[string]interface{} {
+	uploads := make([]map[string]interface{}, 0)
+	for _, info := range a.uploadAgent.GetActiveUploads() {
+		uploads = append(uploads, map[string]interface{}{
+			"transfer_id":  info.TransferID,
+			"file_name":    info.FileName,
+			"file_path":    info.FilePath,
+			"size_bytes":   info.SizeBytes,
+			"requested_by": info.RequestedBy,
+		})
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"uploads": uploads,
+	}
+}
+
+// ===== HISTORIAL DE TRANSFERENCIAS =====
+
+// ListTransferHistory retorna el historial de transferencias, de la más reciente a la más antigua
+func (a *App) ListTransferHistory(offset, limit int) map[string]interface{} {
+	return a.queryTransferHistory(filetransfer.HistoryQuery{Offset: offset, Limit: limit})
+}
+
+// FilterTransferHistory filtra el historial por dirección ("received"/"sent"), resultado
+// ("completed"/"failed"/"quarantined"/"cancelled"), sesión y rango de fechas.
+// Las fechas aceptan "2006-01-02" (día completo, hora local) o RFC3339; vacío = sin filtro.
+func (a *App) FilterTransferHistory(direction, outcome, sessionID, fromDate, toDate string, offset, limit int) map[string]interface{} {
+	since, err := parseHistoryDate(fromDate, false)
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+	}
+	until, err := parseHistoryDate(toDate, true)
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+	}
+
+	return a.queryTransferHistory(filetransfer.HistoryQuery{
+		Direction: direction,
+		Outcome:   outcome,
+		SessionID: sessionID,
+		Since:     since,
+		Until:     until,
+		Offset:    offset,
+		Limit:     limit,
+	})
+}
+
+// SearchTransferHistory busca texto en el nombre, la ruta, el ID de transferencia o quien la pidió
+func (a *App) SearchTransferHistory(text string, offset, limit int) map[string]interface{} {
+	return a.queryTransferHistory(filetransfer.HistoryQuery{Search: text, Offset: offset, Limit: limit})
+}
+
+// OpenTransferFolder abre en el explorador la carpeta de una transferencia del historial
+func (a *App) OpenTransferFolder(entryID string) map[string]interface{} {
+	if a.transferHistory == nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "Historial de transferencias no disponible",
+		}
+	}
+
+	entry, ok := a.transferHistory.Get(entryID)
+	if !ok {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "La entrada no existe en el historial",
+		}
+	}
+
+	path := entry.FilePath
+	if path == "" {
+		path = entry.QuarantinePath
+	}
+	if path == "" {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "La transferencia no dejó ningún archivo",
+		}
+	}
+
+	if err := filetransfer.RevealInFileManager(path); err != nil {
+		runtime.LogErrorf(a.ctx, "Error abriendo carpeta de %s: %v", path, err)
+		return map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"path":    path,
+	}
+}
+
+// DeleteTransferHistoryEntry elimina una entrada del historial (el archivo no se borra)
+func (a *App) DeleteTransferHistoryEntry(entryID string) map[string]interface{} {
+	if a.transferHistory == nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "Historial de transferencias no disponible",
+		}
+	}
+
+	deleted, err := a.transferHistory.Delete(entryID)
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+	}
+	if deleted == 0 {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "La entrada no existe en el historial",
+		}
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"message": "Entrada eliminada del historial",
+	}
+}
+
+// ClearTransferHistory elimina todas las entradas del historial
+func (a *App) ClearTransferHistory() map[string]interface{} {
+	if a.transferHistory == nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "Historial de transferencias no disponible",
+		}
+	}
+
+	if err := a.transferHistory.Clear(); err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"message": "Historial eliminado",
+	}
+}
+
+// queryTransferHistory ejecuta una consulta y la convierte al formato del frontend
+func (a *App) queryTransferHistory(query filetransfer.HistoryQuery) map[string]interface{} {
+	if a.transferHistory == nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "Historial de transferencias no disponible",
+		}
+	}
+
+	entries, total := a.transferHistory.Query(query)
+	items := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, historyEntryData(entry))
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"entries": items,
+		"total":   total,
+	}
+}
+
+// historyEntryData convierte una entrada del historial para el frontend
+func historyEntryData(entry filetransfer.HistoryEntry) map[string]interface{} {
+	fileExists := false
+	if entry.FilePath != "" {
+		_, err := os.Stat(entry.FilePath)
+		fileExists = err == nil
+	}
+
+	return map[string]interface{}{
+		"id":              entry.ID,
+		"transfer_id":     entry.TransferID,
+		"direction":       entry.Direction,
+		"file_name":       entry.FileName,
+		"size_bytes":      entry.SizeBytes,
+		"session_id":      entry.SessionID,
+		"requested_by":    entry.RequestedBy,
+		"file_path":       entry.FilePath,
+		"file_exists":     fileExists,
+		"quarantine_path": entry.QuarantinePath,
+		"is_directory":    entry.IsDirectory,
+		"checksum":        entry.Checksum,
+		"duration_ms":     entry.DurationMs,
+		"outcome":         entry.Outcome,
+		"error":           entry.Error,
+		"reason_code":     entry.ReasonCode,
+		"finished_at":     entry.FinishedAt.Format(time.RFC3339),
+	}
+}
+
+// parseHistoryDate interpreta una fecha de filtro. Con endOfDay, una fecha sin hora
+// incluye el día completo.
+func parseHistoryDate(value string, endOfDay bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("fecha inválida %q: use AAAA-MM-DD", value)
+	}
+	if endOfDay {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
+
 func (a *App) cleanupSession() {
 	runtime.LogInfof(a.ctx, "🧹 Limpiando estado de sesión...")
 
@@ -1294,12 +2806,21 @@ func (a *App) cleanupSession() {
 
 	// Detener RemoteControlAgent si está activo
 	if a.remoteControlAgent != nil && a.remoteControlAgent.IsActive() {
+		a.auditSessionEnded(a.remoteControlAgent.GetActiveSessionID(), "disconnected")
+		a.clearControlMode(a.remoteControlAgent.GetActiveSessionID())
+		a.cancelSessionTransfers(a.remoteControlAgent.GetActiveSessionID(), "disconnected")
+
 		runtime.LogInfof(a.ctx, "🛑 Deteniendo RemoteControlAgent...")
 		if err := a.remoteControlAgent.StopSession(); err != nil {
 			runtime.LogErrorf(a.ctx, "Error deteniendo RemoteControlAgent en cleanup: %v", err)
 		}
 	}
 
+	// Las subidas pedidas durante la sesión no deben continuar tras ella
+	for _, transferID := range a.uploadAgent.CancelAll("La sesión terminó") {
+		a.dismissUploadPrompt(transferID)
+	}
+
 	// 🔧 FORZAR LIMPIEZA COMPLETA DEL ESTADO DE VIDEO
 	runtime.LogInfof(a.ctx, "🔧 Forzando limpieza completa del estado de video...")
 	videoStateMutex.Lock()
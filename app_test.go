@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+	"EscritorioRemoto-Cliente/pkg/session"
+
+	"github.com/gorilla/websocket"
+)
+
+// memoryStore guarda los secretos en memoria
+type memoryStore map[string][]byte
+
+func (m memoryStore) Save(name string, data []byte) error {
+	m[name] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m memoryStore) Load(name string) ([]byte, error) {
+	data, ok := m[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+func (m memoryStore) Delete(name string) error {
+	delete(m, name)
+	return nil
+}
+
+// newAuthServer es un servidor WebSocket que responde cada autenticación con
+// response y publica el token recibido en tokens
+func newAuthServer(t *testing.T, response api.ClientAuthResponse) (*httptest.Server, chan string) {
+	t.Helper()
+
+	tokens := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var message struct {
+				Type string          `json:"type"`
+				Data json.RawMessage `json:"data"`
+			}
+			if err := conn.ReadJSON(&message); err != nil {
+				return
+			}
+			if message.Type == api.MessageTypeClientAuth {
+				var request api.ClientAuthRequest
+				json.Unmarshal(message.Data, &request)
+				tokens <- request.Token
+				conn.WriteJSON(api.WebSocketMessage{Type: api.MessageTypeClientAuthResp, Data: response})
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, tokens
+}
+
+// newStoredSession crea un gestor con la sesión de un PC ya registrado
+func newStoredSession(t *testing.T, store memoryStore) *session.SessionManager {
+	t.Helper()
+
+	sessions := session.NewSessionManagerWithStore(store)
+	if err := sessions.StoreToken("stored", "42", "ana"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sessions.StorePCID("pc-1"); err != nil {
+		t.Fatal(err)
+	}
+	return sessions
+}
+
+func TestResumeStoredSession(t *testing.T) {
+	tests := []struct {
+		name     string
+		response api.ClientAuthResponse
+		want     session.SessionData
+	}{
+		{
+			name:     "server keeps the token",
+			response: api.ClientAuthResponse{Success: true},
+			want:     session.SessionData{Token: "stored", UserID: "42", Username: "ana", PCID: "pc-1"},
+		},
+		{
+			name:     "server rotates the token",
+			response: api.ClientAuthResponse{Success: true, Token: "rotated", UserID: "43"},
+			want:     session.SessionData{Token: "rotated", UserID: "43", Username: "ana", PCID: "pc-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, tokens := newAuthServer(t, tt.response)
+			client := api.NewAPIClient(server.URL)
+			t.Cleanup(func() { client.Disconnect() })
+
+			store := memoryStore{}
+			sessions := newStoredSession(t, store)
+
+			resumed, err := resumeStoredSession(client, sessions, t.Logf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := <-tokens; got != "stored" {
+				t.Errorf("authenticated with %q, want the stored token", got)
+			}
+
+			// El PC registrado se conserva para volver a registrarlo
+			if resumed != tt.want {
+				t.Errorf("resumed = %+v, want %+v", resumed, tt.want)
+			}
+			if got := session.NewSessionManagerWithStore(store).GetSessionData(); got != tt.want {
+				t.Errorf("persisted session = %+v, want %+v", got, tt.want)
+			}
+			if got := client.GetAuthToken(); got != tt.want.Token {
+				t.Errorf("client token = %q, want %q", got, tt.want.Token)
+			}
+		})
+	}
+}
+
+func TestResumeStoredSessionRejected(t *testing.T) {
+	server, _ := newAuthServer(t, api.ClientAuthResponse{Success: false, Error: "token expired"})
+	client := api.NewAPIClient(server.URL)
+	t.Cleanup(func() { client.Disconnect() })
+
+	store := memoryStore{}
+	sessions := newStoredSession(t, store)
+
+	_, err := resumeStoredSession(client, sessions, t.Logf)
+	var expired *sessionExpiredError
+	if !errors.As(err, &expired) || expired.reason != "token expired" {
+		t.Fatalf("resumeStoredSession() = %v, want the session to expire with the server error", err)
+	}
+
+	if sessions.IsAuthenticated() || sessions.IsRegistered() {
+		t.Errorf("session kept after rejection: %+v", sessions.GetSessionData())
+	}
+	if got := session.NewSessionManagerWithStore(store).GetSessionData(); got != (session.SessionData{}) {
+		t.Errorf("persisted session = %+v, want none", got)
+	}
+}
+
+func TestResumeStoredSessionUnreachable(t *testing.T) {
+	server, _ := newAuthServer(t, api.ClientAuthResponse{Success: true})
+	server.Close()
+	client := api.NewAPIClient(server.URL)
+	t.Cleanup(func() { client.Disconnect() })
+
+	store := memoryStore{}
+	sessions := newStoredSession(t, store)
+	want := sessions.GetSessionData()
+
+	_, err := resumeStoredSession(client, sessions, t.Logf)
+	var expired *sessionExpiredError
+	if err == nil || errors.As(err, &expired) {
+		t.Fatalf("resumeStoredSession() = %v, want a connection error", err)
+	}
+
+	// Sin respuesta del servidor la sesión se conserva para otro intento
+	if got := session.NewSessionManagerWithStore(store).GetSessionData(); got != want {
+		t.Errorf("persisted session = %+v, want %+v", got, want)
+	}
+}
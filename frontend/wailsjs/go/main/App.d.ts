@@ -2,16 +2,36 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 
-export function AcceptControlRequest(arg1:string):Promise<Record<string, any>>;
+export function AcceptControlRequest(arg1:string,arg2:boolean):Promise<Record<string, any>>;
+
+export function AcceptFileTransfer(arg1:string):Promise<Record<string, any>>;
+
+export function AcceptFileUpload(arg1:string):Promise<Record<string, any>>;
 
 export function AddVideoFrame(arg1:Array<number>):Promise<void>;
 
+export function CancelFileUpload(arg1:string):Promise<Record<string, any>>;
+
+export function ClearTransferHistory():Promise<Record<string, any>>;
+
 export function Connect(arg1:string):Promise<Record<string, any>>;
 
+export function DeclineFileTransfer(arg1:string,arg2:string):Promise<Record<string, any>>;
+
+export function DeclineFileUpload(arg1:string,arg2:string):Promise<Record<string, any>>;
+
+export function DeleteTransferHistoryEntry(arg1:string):Promise<Record<string, any>>;
+
 export function Disconnect():Promise<Record<string, any>>;
 
+export function ExportAuditLog(arg1:string):Promise<Record<string, any>>;
+
+export function FilterTransferHistory(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:number,arg7:number):Promise<Record<string, any>>;
+
 export function GetActiveFileTransfers():Promise<Record<string, any>>;
 
+export function GetActiveUploads():Promise<Record<string, any>>;
+
 export function GetAppStatus():Promise<Record<string, any>>;
 
 export function GetConnectionStatus():Promise<Record<string, any>>;
@@ -32,18 +52,32 @@ export function IsAuthenticated():Promise<boolean>;
 
 export function IsVideoRecording():Promise<boolean>;
 
+export function ListTransferHistory(arg1:number,arg2:number):Promise<Record<string, any>>;
+
 export function Login(arg1:string,arg2:string):Promise<Record<string, any>>;
 
 export function Logout():Promise<Record<string, any>>;
 
+export function OpenTransferFolder(arg1:string):Promise<Record<string, any>>;
+
 export function RegisterPC():Promise<Record<string, any>>;
 
 export function RejectControlRequest(arg1:string,arg2:string):Promise<Record<string, any>>;
 
+export function ResumeSession():Promise<Record<string, any>>;
+
+export function SearchTransferHistory(arg1:string,arg2:number,arg3:number):Promise<Record<string, any>>;
+
+export function SetClipboardSync(arg1:boolean):Promise<Record<string, any>>;
+
 export function SetRemoteControlSettings(arg1:number,arg2:number):Promise<Record<string, any>>;
 
+export function SetViewOnlyMode(arg1:boolean):Promise<Record<string, any>>;
+
 export function StartVideoRecording(arg1:string):Promise<void>;
 
 export function StopVideoRecording():Promise<void>;
 
 export function TestRemoteControlCapabilities():Promise<Record<string, any>>;
+
+export function VerifyAuditLog():Promise<Record<string, any>>;
@@ -2,26 +2,66 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function AcceptControlRequest(arg1) {
-  return window['go']['main']['App']['AcceptControlRequest'](arg1);
+export function AcceptControlRequest(arg1, arg2) {
+  return window['go']['main']['App']['AcceptControlRequest'](arg1, arg2);
+}
+
+export function AcceptFileTransfer(arg1) {
+  return window['go']['main']['App']['AcceptFileTransfer'](arg1);
+}
+
+export function AcceptFileUpload(arg1) {
+  return window['go']['main']['App']['AcceptFileUpload'](arg1);
 }
 
 export function AddVideoFrame(arg1) {
   return window['go']['main']['App']['AddVideoFrame'](arg1);
 }
 
+export function CancelFileUpload(arg1) {
+  return window['go']['main']['App']['CancelFileUpload'](arg1);
+}
+
+export function ClearTransferHistory() {
+  return window['go']['main']['App']['ClearTransferHistory']();
+}
+
 export function Connect(arg1) {
   return window['go']['main']['App']['Connect'](arg1);
 }
 
+export function DeclineFileTransfer(arg1, arg2) {
+  return window['go']['main']['App']['DeclineFileTransfer'](arg1, arg2);
+}
+
+export function DeclineFileUpload(arg1, arg2) {
+  return window['go']['main']['App']['DeclineFileUpload'](arg1, arg2);
+}
+
+export function DeleteTransferHistoryEntry(arg1) {
+  return window['go']['main']['App']['DeleteTransferHistoryEntry'](arg1);
+}
+
 export function Disconnect() {
   return window['go']['main']['App']['Disconnect']();
 }
 
+export function ExportAuditLog(arg1) {
+  return window['go']['main']['App']['ExportAuditLog'](arg1);
+}
+
+export function FilterTransferHistory(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['FilterTransferHistory'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+}
+
 export function GetActiveFileTransfers() {
   return window['go']['main']['App']['GetActiveFileTransfers']();
 }
 
+export function GetActiveUploads() {
+  return window['go']['main']['App']['GetActiveUploads']();
+}
+
 export function GetAppStatus() {
   return window['go']['main']['App']['GetAppStatus']();
 }
@@ -62,6 +102,10 @@ export function IsVideoRecording() {
   return window['go']['main']['App']['IsVideoRecording']();
 }
 
+export function ListTransferHistory(arg1, arg2) {
+  return window['go']['main']['App']['ListTransferHistory'](arg1, arg2);
+}
+
 export function Login(arg1, arg2) {
   return window['go']['main']['App']['Login'](arg1, arg2);
 }
@@ -70,6 +114,10 @@ export function Logout() {
   return window['go']['main']['App']['Logout']();
 }
 
+export function OpenTransferFolder(arg1) {
+  return window['go']['main']['App']['OpenTransferFolder'](arg1);
+}
+
 export function RegisterPC() {
   return window['go']['main']['App']['RegisterPC']();
 }
@@ -78,10 +126,26 @@ export function RejectControlRequest(arg1, arg2) {
   return window['go']['main']['App']['RejectControlRequest'](arg1, arg2);
 }
 
+export function ResumeSession() {
+  return window['go']['main']['App']['ResumeSession']();
+}
+
+export function SearchTransferHistory(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SearchTransferHistory'](arg1, arg2, arg3);
+}
+
+export function SetClipboardSync(arg1) {
+  return window['go']['main']['App']['SetClipboardSync'](arg1);
+}
+
 export function SetRemoteControlSettings(arg1, arg2) {
   return window['go']['main']['App']['SetRemoteControlSettings'](arg1, arg2);
 }
 
+export function SetViewOnlyMode(arg1) {
+  return window['go']['main']['App']['SetViewOnlyMode'](arg1);
+}
+
 export function StartVideoRecording(arg1) {
   return window['go']['main']['App']['StartVideoRecording'](arg1);
 }
@@ -93,3 +157,7 @@ export function StopVideoRecording() {
 export function TestRemoteControlCapabilities() {
   return window['go']['main']['App']['TestRemoteControlCapabilities']();
 }
+
+export function VerifyAuditLog() {
+  return window['go']['main']['App']['VerifyAuditLog']();
+}
@@ -103,6 +103,21 @@ func (ac *AppController) Login(username, password string) LoginResponse {
 	})
 }
 
+// LoginWithToken delega al AuthController
+func (ac *AppController) LoginWithToken(username, token string) LoginResponse {
+	if !ac.isInitialized {
+		return LoginResponse{
+			Success: false,
+			Error:   "Application not initialized",
+		}
+	}
+
+	return ac.authController.LoginWithToken(TokenLoginRequest{
+		Username: username,
+		Token:    token,
+	})
+}
+
 // Logout delega al AuthController
 func (ac *AppController) Logout() LogoutResponse {
 	return ac.authController.Logout()
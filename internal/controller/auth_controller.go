@@ -18,6 +18,7 @@ type AuthController struct {
 // AuthService interface para el servicio de autenticación
 type AuthService interface {
 	Login(username, password string) (*entities.User, error)
+	LoginWithToken(username, token string) (*entities.User, error)
 	Logout() error
 	IsAuthenticated() bool
 	GetCurrentUser() *entities.User
@@ -30,6 +31,12 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// TokenLoginRequest representa la solicitud de login con un token de sesión persistido
+type TokenLoginRequest struct {
+	Username string `json:"username"`
+	Token    string `json:"token"`
+}
+
 // LoginResponse representa la respuesta de login
 type LoginResponse struct {
 	Success   bool            `json:"success"`
@@ -114,6 +121,55 @@ func (ac *AuthController) Login(request LoginRequest) LoginResponse {
 	}
 }
 
+// LoginWithToken restaura la sesión local de un usuario ya validado por el servidor vía token
+func (ac *AuthController) LoginWithToken(request TokenLoginRequest) LoginResponse {
+	if request.Username == "" {
+		return LoginResponse{
+			Success: false,
+			Error:   "username cannot be empty",
+		}
+	}
+	if request.Token == "" {
+		return LoginResponse{
+			Success: false,
+			Error:   "token cannot be empty",
+		}
+	}
+
+	// Verificar si ya está autenticado
+	if ac.authService.IsAuthenticated() {
+		return LoginResponse{
+			Success: false,
+			Error:   "User is already logged in",
+		}
+	}
+
+	user, err := ac.authService.LoginWithToken(request.Username, request.Token)
+	if err != nil {
+		return LoginResponse{
+			Success: false,
+			Error:   fmt.Sprintf("token login failed: %v", err),
+		}
+	}
+
+	// Publicar evento de login completado
+	ac.eventManager.Publish(observer.Event{
+		Type: "auth_login_completed",
+		Data: map[string]interface{}{
+			"username": user.Username(),
+			"user_id":  user.ID(),
+			"role":     user.Role(),
+			"method":   "token",
+		},
+	})
+
+	return LoginResponse{
+		Success:   true,
+		User:      user,
+		SessionID: generateSessionID(),
+	}
+}
+
 // Logout maneja la solicitud de logout del usuario
 func (ac *AuthController) Logout() LogoutResponse {
 	// Verificar si está autenticado
@@ -45,6 +45,8 @@ func (lc *LoginCommand) Execute() error {
 
 	// Ejecutar login
 	user, err := lc.authService.Login(lc.username, lc.password)
+	// El comando queda en el historial; no retener la referencia a la contraseña
+	lc.password = ""
 	if err != nil {
 		return fmt.Errorf("login failed: %w", err)
 	}
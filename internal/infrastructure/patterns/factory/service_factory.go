@@ -278,6 +278,10 @@ func (m *MockAuthService) Login(username, password string) (*entities.User, erro
 	return user, nil
 }
 
+func (m *MockAuthService) LoginWithToken(username, token string) (*entities.User, error) {
+	return m.Login(username, "")
+}
+
 func (m *MockAuthService) Logout() error {
 	m.authenticated = false
 	m.currentUser = nil
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 
+	"EscritorioRemoto-Cliente/pkg/session"
+
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
@@ -20,7 +22,8 @@ func main() {
 		serverURL = flag.String("server-url", "http://localhost:8080", "URL del servidor (ej: http://192.168.1.100:8080)")
 		showHelp  = flag.Bool("help", false, "Mostrar ayuda")
 		username  = flag.String("username", "", "Usuario para autenticación automática")
-		password  = flag.String("password", "", "Contraseña para autenticación automática")
+		password  = flag.String("password", "", "No soportado: visible en la lista de procesos; use --password-file o "+session.PasswordEnvVar)
+		pwFile    = flag.String("password-file", "", "Archivo con la contraseña para autenticación automática")
 		pcName    = flag.String("pc-name", "", "Nombre del PC para registro automático")
 	)
 
@@ -31,7 +34,8 @@ func main() {
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nEjemplos:\n")
 		fmt.Fprintf(os.Stderr, "  %s --server-url http://192.168.1.100:8080\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s --server-url http://10.0.0.5:8080 --username usuario --password pass\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --server-url http://10.0.0.5:8080 --username usuario --password-file ~/.escritorio-pass\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s=pass %s --username usuario\n", session.PasswordEnvVar, os.Args[0])
 		fmt.Fprintf(os.Stderr, "\nSi no se especifica server-url, se usa localhost:8080 por defecto.\n")
 	}
 
@@ -42,6 +46,19 @@ func main() {
 		return
 	}
 
+	// La contraseña en argv queda expuesta en la lista de procesos: no se acepta
+	if *password != "" {
+		fmt.Fprintf(os.Stderr, "❌ --password ya no se admite porque expone la contraseña en la lista de procesos; use --password-file o %s\n", session.PasswordEnvVar)
+		os.Exit(2)
+	}
+
+	// Resolver la contraseña sin que pase por argv
+	autoLoginPassword, err := session.ResolvePassword(*pwFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+
 	// Mostrar configuración
 	fmt.Printf("🌐 Servidor configurado: %s\n", *serverURL)
 	if *username != "" {
@@ -53,10 +70,10 @@ func main() {
 
 	// Create an instance of the app structure using MVC architecture
 	// Pasar la configuración desde línea de comandos
-	app := NewAppWithConfig(*serverURL, *username, *password, *pcName)
+	app := NewAppWithConfig(*serverURL, *username, autoLoginPassword, *pcName)
 
 	// Create application with options
-	err := wails.Run(&options.App{
+	err = wails.Run(&options.App{
 		Title:  "EscritorioRemoto-Cliente",
 		Width:  1024,
 		Height: 768,
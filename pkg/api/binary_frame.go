@@ -0,0 +1,205 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// CapabilityBinaryScreenFrames se anuncia en CLIENT_AUTH_REQUEST; si el servidor la
+// devuelve en CLIENT_AUTH_RESPONSE los frames se envían como mensajes binarios
+const CapabilityBinaryScreenFrames = "binary_screen_frames"
+
+// Formato binario de un frame de pantalla (big-endian):
+//
+//	magic        [3]byte  "ERF"
+//	version      uint8    binaryFrameVersion
+//	flags        uint8    bit 0 = keyframe
+//	format       uint8    binaryFormatJPEG | binaryFormatJPEGTiles
+//	sessionIDLen uint8
+//	sessionID    [sessionIDLen]byte
+//	sequenceNum  int64
+//	timestamp    int64
+//	width        uint32
+//	height       uint32
+//	quality      uint8
+//	payload      JPEG crudo ("jpeg") o lista de tiles ("jpeg-tiles"):
+//	  tileCount  uint16
+//	  por tile:  x, y, width, height uint16; dataLen uint32; data [dataLen]byte
+var binaryFrameMagic = []byte("ERF")
+
+const (
+	binaryFrameVersion = 1
+
+	binaryFlagKeyframe = 1 << 0
+
+	binaryFormatJPEG      = 0
+	binaryFormatJPEGTiles = 1
+)
+
+// EncodeBinaryScreenFrame serializa un frame en el formato binario
+func EncodeBinaryScreenFrame(frame ScreenFrame) ([]byte, error) {
+	if len(frame.SessionID) > 255 {
+		return nil, fmt.Errorf("session ID too long for binary frame: %d bytes", len(frame.SessionID))
+	}
+
+	var format uint8
+	switch frame.Format {
+	case FrameFormatJPEG:
+		format = binaryFormatJPEG
+	case FrameFormatJPEGTiles:
+		format = binaryFormatJPEGTiles
+	default:
+		return nil, fmt.Errorf("unsupported binary frame format: %s", frame.Format)
+	}
+
+	if !fitsUint(frame.Width, 0xFFFFFFFF) || !fitsUint(frame.Height, 0xFFFFFFFF) {
+		return nil, fmt.Errorf("frame size out of range for binary frame: %dx%d", frame.Width, frame.Height)
+	}
+	if !fitsUint(frame.Quality, 0xFF) {
+		return nil, fmt.Errorf("frame quality out of range for binary frame: %d", frame.Quality)
+	}
+
+	var flags uint8
+	if frame.IsKeyframe {
+		flags |= binaryFlagKeyframe
+	}
+
+	size := len(binaryFrameMagic) + 4 + len(frame.SessionID) + 8 + 8 + 4 + 4 + 1 + len(frame.FrameData)
+	for _, tile := range frame.Tiles {
+		size += 12 + len(tile.Data)
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, size+2))
+	buf.Write(binaryFrameMagic)
+	buf.WriteByte(binaryFrameVersion)
+	buf.WriteByte(flags)
+	buf.WriteByte(format)
+	buf.WriteByte(uint8(len(frame.SessionID)))
+	buf.WriteString(frame.SessionID)
+	binary.Write(buf, binary.BigEndian, frame.SequenceNum)
+	binary.Write(buf, binary.BigEndian, frame.Timestamp)
+	binary.Write(buf, binary.BigEndian, uint32(frame.Width))
+	binary.Write(buf, binary.BigEndian, uint32(frame.Height))
+	buf.WriteByte(uint8(frame.Quality))
+
+	if format == binaryFormatJPEG {
+		buf.Write(frame.FrameData)
+		return buf.Bytes(), nil
+	}
+
+	if len(frame.Tiles) > 0xFFFF {
+		return nil, fmt.Errorf("too many tiles for binary frame: %d", len(frame.Tiles))
+	}
+	for i, tile := range frame.Tiles {
+		// Un valor fuera de rango se truncaría en silencio y corrompería el frame
+		if !fitsUint(tile.X, 0xFFFF) || !fitsUint(tile.Y, 0xFFFF) ||
+			!fitsUint(tile.Width, 0xFFFF) || !fitsUint(tile.Height, 0xFFFF) {
+			return nil, fmt.Errorf("tile %d out of range for binary frame: %dx%d at (%d,%d)",
+				i, tile.Width, tile.Height, tile.X, tile.Y)
+		}
+	}
+	binary.Write(buf, binary.BigEndian, uint16(len(frame.Tiles)))
+	for _, tile := range frame.Tiles {
+		binary.Write(buf, binary.BigEndian, [4]uint16{
+			uint16(tile.X), uint16(tile.Y), uint16(tile.Width), uint16(tile.Height),
+		})
+		binary.Write(buf, binary.BigEndian, uint32(len(tile.Data)))
+		buf.Write(tile.Data)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// fitsUint verifica que value quepa en un entero sin signo con el máximo dado
+func fitsUint(value int, max uint64) bool {
+	return value >= 0 && uint64(value) <= max
+}
+
+// DecodeBinaryScreenFrame reconstruye un frame a partir del formato binario
+func DecodeBinaryScreenFrame(data []byte) (*ScreenFrame, error) {
+	r := bytes.NewReader(data)
+
+	header := make([]byte, len(binaryFrameMagic)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("binary frame too short")
+	}
+	if !bytes.Equal(header[:len(binaryFrameMagic)], binaryFrameMagic) {
+		return nil, fmt.Errorf("invalid binary frame magic")
+	}
+
+	version, flags, format, idLen := header[3], header[4], header[5], int(header[6])
+	if version != binaryFrameVersion {
+		return nil, fmt.Errorf("unsupported binary frame version: %d", version)
+	}
+
+	sessionID := make([]byte, idLen)
+	if _, err := io.ReadFull(r, sessionID); err != nil {
+		return nil, fmt.Errorf("binary frame truncated in session ID")
+	}
+
+	var fixed struct {
+		SequenceNum int64
+		Timestamp   int64
+		Width       uint32
+		Height      uint32
+		Quality     uint8
+	}
+	if err := binary.Read(r, binary.BigEndian, &fixed); err != nil {
+		return nil, fmt.Errorf("binary frame truncated in header: %w", err)
+	}
+
+	frame := &ScreenFrame{
+		SessionID:   string(sessionID),
+		SequenceNum: fixed.SequenceNum,
+		Timestamp:   fixed.Timestamp,
+		Width:       int(fixed.Width),
+		Height:      int(fixed.Height),
+		Quality:     int(fixed.Quality),
+		IsKeyframe:  flags&binaryFlagKeyframe != 0,
+	}
+
+	switch format {
+	case binaryFormatJPEG:
+		frame.Format = FrameFormatJPEG
+		frame.FrameData = data[len(data)-r.Len():]
+		return frame, nil
+	case binaryFormatJPEGTiles:
+		frame.Format = FrameFormatJPEGTiles
+	default:
+		return nil, fmt.Errorf("unknown binary frame format: %d", format)
+	}
+
+	var tileCount uint16
+	if err := binary.Read(r, binary.BigEndian, &tileCount); err != nil {
+		return nil, fmt.Errorf("binary frame truncated in tile count: %w", err)
+	}
+
+	frame.Tiles = make([]TileData, 0, tileCount)
+	for i := 0; i < int(tileCount); i++ {
+		var rect [4]uint16
+		var dataLen uint32
+		if err := binary.Read(r, binary.BigEndian, &rect); err != nil {
+			return nil, fmt.Errorf("binary frame truncated in tile %d: %w", i, err)
+		}
+		if err := binary.Read(r, binary.BigEndian, &dataLen); err != nil {
+			return nil, fmt.Errorf("binary frame truncated in tile %d: %w", i, err)
+		}
+		if int(dataLen) > r.Len() {
+			return nil, fmt.Errorf("binary frame truncated in tile %d data", i)
+		}
+
+		offset := len(data) - r.Len()
+		frame.Tiles = append(frame.Tiles, TileData{
+			X:      int(rect[0]),
+			Y:      int(rect[1]),
+			Width:  int(rect[2]),
+			Height: int(rect[3]),
+			Data:   data[offset : offset+int(dataLen)],
+		})
+		r.Seek(int64(dataLen), io.SeekCurrent)
+	}
+
+	return frame, nil
+}
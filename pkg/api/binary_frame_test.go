@@ -0,0 +1,124 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestBinaryScreenFrameRoundTrip codifica y decodifica frames completos y por tiles
+func TestBinaryScreenFrameRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame ScreenFrame
+	}{
+		{
+			name: "keyframe",
+			frame: ScreenFrame{
+				SessionID:   "session-1",
+				Timestamp:   1700000000000,
+				Width:       1920,
+				Height:      1080,
+				Format:      FrameFormatJPEG,
+				Quality:     75,
+				FrameData:   []byte{0xFF, 0xD8, 0x01, 0x02, 0xFF, 0xD9},
+				SequenceNum: 42,
+				IsKeyframe:  true,
+			},
+		},
+		{
+			name: "tiles",
+			frame: ScreenFrame{
+				SessionID:   "session-2",
+				Timestamp:   1700000000500,
+				Width:       65535,
+				Height:      2160,
+				Format:      FrameFormatJPEGTiles,
+				Quality:     60,
+				SequenceNum: 43,
+				Tiles: []TileData{
+					{X: 0, Y: 0, Width: 64, Height: 64, Data: []byte{1, 2, 3}},
+					{X: 65535, Y: 65535, Width: 65535, Height: 65535, Data: []byte{}},
+					{X: 128, Y: 64, Width: 32, Height: 16, Data: []byte{4, 5}},
+				},
+			},
+		},
+		{
+			name: "no tiles",
+			frame: ScreenFrame{
+				SessionID:   "",
+				Width:       800,
+				Height:      600,
+				Format:      FrameFormatJPEGTiles,
+				SequenceNum: 44,
+				Tiles:       []TileData{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeBinaryScreenFrame(tt.frame)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			decoded, err := DecodeBinaryScreenFrame(data)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			if !reflect.DeepEqual(*decoded, tt.frame) {
+				t.Errorf("round trip mismatch:\n got  %+v\n want %+v", *decoded, tt.frame)
+			}
+		})
+	}
+}
+
+// TestEncodeBinaryScreenFrameOutOfRange rechaza valores que no caben en el formato
+// en lugar de truncarlos
+func TestEncodeBinaryScreenFrameOutOfRange(t *testing.T) {
+	tiles := func(tile TileData) ScreenFrame {
+		return ScreenFrame{Width: 100000, Height: 100, Format: FrameFormatJPEGTiles, Tiles: []TileData{tile}}
+	}
+
+	tests := []struct {
+		name  string
+		frame ScreenFrame
+	}{
+		{"tile x", tiles(TileData{X: 65536, Width: 64, Height: 64})},
+		{"tile y", tiles(TileData{Y: 70000, Width: 64, Height: 64})},
+		{"tile width", tiles(TileData{Width: 65536, Height: 64})},
+		{"tile height", tiles(TileData{Width: 64, Height: 65536})},
+		{"negative tile", tiles(TileData{X: -1, Width: 64, Height: 64})},
+		{"negative width", ScreenFrame{Width: -1, Height: 100, Format: FrameFormatJPEG}},
+		{"quality", ScreenFrame{Width: 100, Height: 100, Quality: 256, Format: FrameFormatJPEG}},
+		{"session id", ScreenFrame{SessionID: string(make([]byte, 256)), Format: FrameFormatJPEG}},
+		{"format", ScreenFrame{Format: "png"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := EncodeBinaryScreenFrame(tt.frame); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// TestDecodeBinaryScreenFrameTruncated rechaza frames cortados en cualquier punto
+func TestDecodeBinaryScreenFrameTruncated(t *testing.T) {
+	data, err := EncodeBinaryScreenFrame(ScreenFrame{
+		SessionID: "session",
+		Format:    FrameFormatJPEGTiles,
+		Tiles:     []TileData{{X: 1, Y: 2, Width: 3, Height: 4, Data: []byte{5, 6, 7}}},
+	})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	for n := 0; n < len(data); n++ {
+		if _, err := DecodeBinaryScreenFrame(data[:n]); err == nil {
+			t.Errorf("decoding %d of %d bytes: expected an error", n, len(data))
+		}
+	}
+}
@@ -27,6 +27,21 @@ type FileTransferRequestHandler func(request FileTransferRequest)
 // FileChunkHandler es el callback para manejar chunks de archivos recibidos
 type FileChunkHandler func(chunk FileChunk)
 
+// FileUploadRequestHandler es el callback para solicitudes de subida de archivos al servidor
+type FileUploadRequestHandler func(request FileUploadRequest)
+
+// FileUploadCancelHandler es el callback para cancelaciones de subida pedidas por el servidor
+type FileUploadCancelHandler func(cancel FileUploadCancel)
+
+// DisplayListRequestHandler es el callback para solicitudes de lista de pantallas
+type DisplayListRequestHandler func(request DisplayListRequest)
+
+// SelectDisplayHandler es el callback para cambios de pantalla transmitida
+type SelectDisplayHandler func(request SelectDisplayRequest)
+
+// ClipboardUpdateHandler es el callback para contenido de portapapeles enviado por el admin
+type ClipboardUpdateHandler func(update ClipboardUpdate)
+
 // APIClient maneja la comunicación WebSocket con el servidor
 type APIClient struct {
 	serverURL   string
@@ -51,8 +66,39 @@ type APIClient struct {
 	// Handlers para transferencia de archivos
 	fileTransferRequestHandler FileTransferRequestHandler
 	fileChunkHandler           FileChunkHandler
+	fileUploadRequestHandler   FileUploadRequestHandler
+	fileUploadCancelHandler    FileUploadCancelHandler
+
+	// Handlers para selección de pantalla
+	displayListRequestHandler DisplayListRequestHandler
+	selectDisplayHandler      SelectDisplayHandler
+
+	// Handler para el portapapeles remoto
+	clipboardUpdateHandler ClipboardUpdateHandler
+
+	// Handler para cambios de estado de conexión (reconexión automática)
+	connectionStatusHandler ConnectionStatusHandler
+
+	// Handler para cuando el servidor rechaza el token al reconectar
+	sessionExpiredHandler SessionExpiredHandler
+
+	// Datos para reanudar la sesión tras una caída de conexión
+	authToken    string
+	pcIdentifier string
+
+	// Supervisor de reconexión
+	reconnectConfig ReconnectConfig
+	reconnecting    bool
+	userClosed      bool // true si la desconexión fue solicitada explícitamente
+	stopReconnect   chan struct{}
+	after           func(d time.Duration) <-chan time.Time // Espera del backoff (time.After salvo en tests)
+
+	// Protocolo binario para frames de pantalla (negociado al autenticar)
+	binaryFramesEnabled bool // Ofrecer la capacidad al servidor
+	binaryFrames        bool // El servidor la aceptó en la conexión actual
 
 	// Configuración
+	dial           dialFunc // Abre el WebSocket (dialWebSocket salvo en tests)
 	connectTimeout time.Duration
 	readTimeout    time.Duration
 	writeTimeout   time.Duration
@@ -61,12 +107,16 @@ type APIClient struct {
 // NewAPIClient crea un nuevo cliente API
 func NewAPIClient(serverURL string) *APIClient {
 	return &APIClient{
-		serverURL:      serverURL,
-		authResponse:   make(chan ClientAuthResponse, 1),
-		regResponse:    make(chan PCRegistrationResponse, 1),
-		connectTimeout: 10 * time.Second,
-		readTimeout:    90 * time.Second,
-		writeTimeout:   10 * time.Second,
+		serverURL:           serverURL,
+		authResponse:        make(chan ClientAuthResponse, 1),
+		regResponse:         make(chan PCRegistrationResponse, 1),
+		reconnectConfig:     DefaultReconnectConfig(),
+		after:               time.After,
+		dial:                dialWebSocket,
+		binaryFramesEnabled: true,
+		connectTimeout:      10 * time.Second,
+		readTimeout:         90 * time.Second,
+		writeTimeout:        10 * time.Second,
 	}
 }
 
@@ -105,8 +155,52 @@ func (c *APIClient) SetFileChunkHandler(handler FileChunkHandler) {
 	c.fileChunkHandler = handler
 }
 
+// SetFileUploadRequestHandler establece el handler para solicitudes de subida de archivos
+func (c *APIClient) SetFileUploadRequestHandler(handler FileUploadRequestHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.fileUploadRequestHandler = handler
+}
+
+// SetFileUploadCancelHandler establece el handler para cancelaciones de subida
+func (c *APIClient) SetFileUploadCancelHandler(handler FileUploadCancelHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.fileUploadCancelHandler = handler
+}
+
+// SetDisplayListRequestHandler establece el handler para solicitudes de lista de pantallas
+func (c *APIClient) SetDisplayListRequestHandler(handler DisplayListRequestHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.displayListRequestHandler = handler
+}
+
+// SetSelectDisplayHandler establece el handler para cambios de pantalla transmitida
+func (c *APIClient) SetSelectDisplayHandler(handler SelectDisplayHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.selectDisplayHandler = handler
+}
+
+// SetClipboardUpdateHandler establece el handler para contenido de portapapeles enviado por el admin
+func (c *APIClient) SetClipboardUpdateHandler(handler ClipboardUpdateHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.clipboardUpdateHandler = handler
+}
+
 // Connect establece la conexión WebSocket con el servidor
 func (c *APIClient) Connect() error {
+	c.mutex.Lock()
+	c.userClosed = false
+	c.mutex.Unlock()
+
+	return c.connect()
+}
+
+// connect realiza el dial del WebSocket sin alterar la intención del usuario
+func (c *APIClient) connect() error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -133,32 +227,49 @@ func (c *APIClient) Connect() error {
 	log.Printf("Connecting to WebSocket: %s", u.String())
 
 	// Conectar con timeout
-	dialer := websocket.DefaultDialer
-	dialer.HandshakeTimeout = c.connectTimeout
-
-	conn, _, err := dialer.Dial(u.String(), nil)
+	conn, err := c.dial(u.String(), c.connectTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to connect to WebSocket: %w", err)
 	}
 
 	c.conn = conn
 	c.isConnected = true
+	c.binaryFrames = false // Se renegocia en cada autenticación
 
 	// Iniciar goroutine para leer mensajes
-	go c.readMessages()
+	go c.readMessages(conn)
 
 	// Iniciar keep-alive con pings
-	go c.startKeepAlive()
+	go c.startKeepAlive(conn)
 
 	log.Println("WebSocket connection established")
 	return nil
 }
 
+// dialFunc abre una conexión WebSocket con el servidor
+type dialFunc func(url string, timeout time.Duration) (*websocket.Conn, error)
+
+// dialWebSocket abre la conexión con el dialer por defecto y el timeout indicado
+func dialWebSocket(url string, timeout time.Duration) (*websocket.Conn, error) {
+	dialer := *websocket.DefaultDialer
+	dialer.HandshakeTimeout = timeout
+
+	conn, _, err := dialer.Dial(url, nil)
+	return conn, err
+}
+
 // Disconnect cierra la conexión WebSocket
 func (c *APIClient) Disconnect() error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	// Una desconexión explícita cancela cualquier reconexión en curso
+	c.userClosed = true
+	if c.stopReconnect != nil {
+		close(c.stopReconnect)
+		c.stopReconnect = nil
+	}
+
 	if !c.isConnected || c.conn == nil {
 		return nil
 	}
@@ -181,6 +292,18 @@ func (c *APIClient) Disconnect() error {
 	return nil
 }
 
+// dropConnection cierra la conexión actual sin marcarla como cierre del usuario
+func (c *APIClient) dropConnection() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	c.isConnected = false
+}
+
 // IsConnected verifica si está conectado
 func (c *APIClient) IsConnected() bool {
 	c.mutex.RLock()
@@ -195,6 +318,53 @@ func (c *APIClient) GetServerURL() string {
 
 // ConnectAndAuthenticate establece conexión y autentica al usuario
 func (c *APIClient) ConnectAndAuthenticate(username, password string) (*ClientAuthResponse, error) {
+	response, err := c.authenticate(ClientAuthRequest{
+		AuthMethod: AuthMethodPassword,
+		Username:   username,
+		Password:   password,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// La contraseña no se conserva: las reconexiones usan solo el token emitido
+	return response, nil
+}
+
+// AuthenticateWithToken establece conexión y autentica usando un token de sesión previo
+func (c *APIClient) AuthenticateWithToken(token string) (*ClientAuthResponse, error) {
+	if token == "" {
+		return nil, fmt.Errorf("token cannot be empty")
+	}
+
+	response, err := c.authenticate(ClientAuthRequest{
+		AuthMethod: AuthMethodToken,
+		Token:      token,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// El servidor puede no emitir un token nuevo al reanudar; conservar el usado
+	if response.Success && response.Token == "" {
+		response.Token = token
+		c.mutex.Lock()
+		c.authToken = token
+		c.mutex.Unlock()
+	}
+
+	return response, nil
+}
+
+// GetAuthToken obtiene el token de la última autenticación exitosa
+func (c *APIClient) GetAuthToken() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.authToken
+}
+
+// authenticate envía un CLIENT_AUTH_REQUEST y espera la respuesta
+func (c *APIClient) authenticate(request ClientAuthRequest) (*ClientAuthResponse, error) {
 	// Conectar si no está conectado
 	if !c.IsConnected() {
 		if err := c.Connect(); err != nil {
@@ -202,13 +372,22 @@ func (c *APIClient) ConnectAndAuthenticate(username, password string) (*ClientAu
 		}
 	}
 
+	// Descartar respuestas atrasadas de intentos anteriores
+	select {
+	case <-c.authResponse:
+	default:
+	}
+
+	c.mutex.RLock()
+	if c.binaryFramesEnabled {
+		request.Capabilities = append(request.Capabilities, CapabilityBinaryScreenFrames)
+	}
+	c.mutex.RUnlock()
+
 	// Enviar solicitud de autenticación
 	authReq := WebSocketMessage{
 		Type: MessageTypeClientAuth,
-		Data: ClientAuthRequest{
-			Username: username,
-			Password: password,
-		},
+		Data: request,
 	}
 
 	if err := c.sendMessage(authReq); err != nil {
@@ -218,6 +397,19 @@ func (c *APIClient) ConnectAndAuthenticate(username, password string) (*ClientAu
 	// Esperar respuesta con timeout
 	select {
 	case response := <-c.authResponse:
+		if response.Success {
+			c.mutex.Lock()
+			if response.Token != "" {
+				c.authToken = response.Token
+			}
+			c.binaryFrames = c.binaryFramesEnabled && hasCapability(response.Capabilities, CapabilityBinaryScreenFrames)
+			binaryFrames := c.binaryFrames
+			c.mutex.Unlock()
+
+			if binaryFrames {
+				log.Println("📦 Server accepted binary screen frames")
+			}
+		}
 		return &response, nil
 	case <-time.After(c.readTimeout):
 		return nil, fmt.Errorf("authentication timeout")
@@ -233,6 +425,11 @@ func (c *APIClient) RegisterPC(pcIdentifier string) (*PCRegistrationResponse, er
 	// Obtener IP del cliente (por ahora usar 127.0.0.1 para desarrollo local)
 	clientIP := "127.0.0.1"
 
+	select {
+	case <-c.regResponse:
+	default:
+	}
+
 	// Enviar solicitud de registro
 	regReq := WebSocketMessage{
 		Type: MessageTypePCRegistration,
@@ -249,6 +446,11 @@ func (c *APIClient) RegisterPC(pcIdentifier string) (*PCRegistrationResponse, er
 	// Esperar respuesta con timeout
 	select {
 	case response := <-c.regResponse:
+		if response.Success {
+			c.mutex.Lock()
+			c.pcIdentifier = pcIdentifier
+			c.mutex.Unlock()
+		}
 		return &response, nil
 	case <-time.After(c.readTimeout):
 		return nil, fmt.Errorf("registration timeout")
@@ -276,11 +478,11 @@ func (c *APIClient) SendHeartbeat() error {
 	if err != nil {
 		// Solo marcar como desconectado si es un error grave de WebSocket
 		if websocket.IsCloseError(err, websocket.CloseAbnormalClosure, websocket.CloseGoingAway) {
+			// La goroutine de lectura termina la limpieza y dispara la reconexión
 			c.mutex.Lock()
 			c.isConnected = false
 			if c.conn != nil {
 				c.conn.Close()
-				c.conn = nil
 			}
 			c.mutex.Unlock()
 			log.Printf("Heartbeat failed with close error, marking as disconnected: %v", err)
@@ -315,28 +517,63 @@ func (c *APIClient) sendMessage(message WebSocketMessage) error {
 	return conn.WriteJSON(message)
 }
 
+// sendBinary envía un mensaje binario por el WebSocket
+func (c *APIClient) sendBinary(data []byte) error {
+	c.mutex.RLock()
+	if !c.isConnected || c.conn == nil {
+		c.mutex.RUnlock()
+		return fmt.Errorf("not connected")
+	}
+	conn := c.conn
+	c.mutex.RUnlock()
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+
+	return conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// hasCapability verifica si una capacidad está en la lista
+func hasCapability(capabilities []string, capability string) bool {
+	for _, c := range capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
 // readMessages lee mensajes del WebSocket en un goroutine
-func (c *APIClient) readMessages() {
+func (c *APIClient) readMessages(conn *websocket.Conn) {
 	defer func() {
 		c.mutex.Lock()
-		c.isConnected = false
-		if c.conn != nil {
+		owned := c.conn == conn
+		if owned {
+			c.isConnected = false
 			c.conn.Close()
 			c.conn = nil
 		}
+		// Solo reconectar si esta era la conexión vigente y el usuario no la cerró
+		shouldReconnect := owned && !c.userClosed
 		c.mutex.Unlock()
 		log.Println("WebSocket read goroutine ended")
+
+		if shouldReconnect {
+			c.startReconnect()
+		}
 	}()
 
 	// Configurar ping handler para mantener conexión viva
-	c.conn.SetPingHandler(func(appData string) error {
+	conn.SetPingHandler(func(appData string) error {
 		log.Println("Received ping, sending pong")
 
 		// Usar mutex de escritura para la respuesta pong
 		c.writeMutex.Lock()
 		defer c.writeMutex.Unlock()
 
-		return c.conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(10*time.Second))
+		return conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(10*time.Second))
 	})
 
 	consecutiveErrors := 0
@@ -344,14 +581,13 @@ func (c *APIClient) readMessages() {
 
 	for {
 		c.mutex.RLock()
-		conn := c.conn
+		current := c.conn == conn
 		connected := c.isConnected
 		c.mutex.RUnlock()
 
-		if !connected || conn == nil {
+		if !connected || !current {
 			break
 		}
-
 		// Establecer timeout de lectura más largo
 		conn.SetReadDeadline(time.Now().Add(c.readTimeout))
 
@@ -517,6 +753,66 @@ func (c *APIClient) handleMessage(message WebSocketMessage) {
 			log.Printf("Failed to marshal input command data: %v", err)
 		}
 
+	case MessageTypeDisplayListRequest:
+		var request DisplayListRequest
+		if data, err := json.Marshal(message.Data); err == nil {
+			if err := json.Unmarshal(data, &request); err == nil {
+				c.mutex.RLock()
+				handler := c.displayListRequestHandler
+				c.mutex.RUnlock()
+
+				if handler != nil {
+					log.Printf("📺 Received display list request for session %s", request.SessionID)
+					handler(request)
+				} else {
+					log.Println("📺 Received display list request but no handler set")
+				}
+			} else {
+				log.Printf("❌ Failed to unmarshal display list request: %v", err)
+			}
+		}
+
+	case MessageTypeSelectDisplay:
+		var request SelectDisplayRequest
+		if data, err := json.Marshal(message.Data); err == nil {
+			if err := json.Unmarshal(data, &request); err == nil {
+				c.mutex.RLock()
+				handler := c.selectDisplayHandler
+				c.mutex.RUnlock()
+
+				if handler != nil {
+					log.Printf("📺 Received select display %d for session %s", request.DisplayIndex, request.SessionID)
+					handler(request)
+				} else {
+					log.Println("📺 Received select display but no handler set")
+				}
+			} else {
+				log.Printf("❌ Failed to unmarshal select display request: %v", err)
+			}
+		}
+
+	case MessageTypeClipboardUpdate:
+		// Manejar contenido de portapapeles enviado por el admin (nunca se registra el contenido)
+		var update ClipboardUpdate
+		if data, err := json.Marshal(message.Data); err == nil {
+			if err := json.Unmarshal(data, &update); err == nil {
+				c.mutex.RLock()
+				handler := c.clipboardUpdateHandler
+				c.mutex.RUnlock()
+
+				if handler != nil {
+					log.Printf("📋 Received clipboard update (%s, %d bytes) for session %s", update.Format, update.SizeBytes, update.SessionID)
+					handler(update)
+				} else {
+					log.Println("📋 Received clipboard update but no handler set")
+				}
+			} else {
+				log.Printf("❌ Failed to unmarshal clipboard update: %v", err)
+			}
+		} else {
+			log.Printf("❌ Failed to marshal clipboard update data: %v", err)
+		}
+
 	case "video_recording_finalized":
 		log.Printf("🔍 DEBUG: Processing video recording finalized confirmation")
 		// Confirmación del backend de que la grabación fue procesada exitosamente
@@ -570,23 +866,67 @@ func (c *APIClient) handleMessage(message WebSocketMessage) {
 			log.Printf("❌ Failed to marshal file chunk data: %v", err)
 		}
 
+	case MessageTypeFileUploadRequest:
+		// Manejar solicitud de subida de archivo al servidor
+		var request FileUploadRequest
+		if data, err := json.Marshal(message.Data); err == nil {
+			if err := json.Unmarshal(data, &request); err == nil {
+				c.mutex.RLock()
+				handler := c.fileUploadRequestHandler
+				c.mutex.RUnlock()
+
+				if handler != nil {
+					log.Printf("📤 Received file upload request %s for %s", request.TransferID, request.FilePath)
+					handler(request)
+				} else {
+					log.Println("📤 Received file upload request but no handler set")
+				}
+			} else {
+				log.Printf("❌ Failed to unmarshal file upload request: %v", err)
+			}
+		} else {
+			log.Printf("❌ Failed to marshal file upload request data: %v", err)
+		}
+
+	case MessageTypeFileUploadCancel:
+		// Manejar cancelación de subida pedida por el servidor
+		var cancel FileUploadCancel
+		if data, err := json.Marshal(message.Data); err == nil {
+			if err := json.Unmarshal(data, &cancel); err == nil {
+				c.mutex.RLock()
+				handler := c.fileUploadCancelHandler
+				c.mutex.RUnlock()
+
+				if handler != nil {
+					log.Printf("📤 Received cancellation for upload %s", cancel.TransferID)
+					handler(cancel)
+				} else {
+					log.Println("📤 Received upload cancellation but no handler set")
+				}
+			} else {
+				log.Printf("❌ Failed to unmarshal file upload cancel: %v", err)
+			}
+		} else {
+			log.Printf("❌ Failed to marshal file upload cancel data: %v", err)
+		}
+
 	default:
 		log.Printf("🔍 DEBUG: Unknown message type: %s", message.Type)
 	}
 }
 
 // startKeepAlive envía pings periódicos para mantener la conexión viva
-func (c *APIClient) startKeepAlive() {
+func (c *APIClient) startKeepAlive(conn *websocket.Conn) {
 	ticker := time.NewTicker(20 * time.Second) // Ping cada 20 segundos
 	defer ticker.Stop()
 
 	for range ticker.C {
 		c.mutex.RLock()
-		conn := c.conn
+		current := c.conn == conn
 		connected := c.isConnected
 		c.mutex.RUnlock()
 
-		if !connected || conn == nil {
+		if !connected || !current {
 			log.Println("Keep-alive stopped: not connected")
 			break
 		}
@@ -606,7 +946,7 @@ func (c *APIClient) startKeepAlive() {
 }
 
 // AcceptRemoteControlSession acepta una sesión de control remoto
-func (c *APIClient) AcceptRemoteControlSession(sessionID string) error {
+func (c *APIClient) AcceptRemoteControlSession(sessionID string, viewOnly bool) error {
 	if !c.IsConnected() {
 		return fmt.Errorf("not connected to server")
 	}
@@ -615,12 +955,63 @@ func (c *APIClient) AcceptRemoteControlSession(sessionID string) error {
 		Type: MessageTypeSessionAccepted,
 		Data: SessionAcceptedMessage{
 			SessionID: sessionID,
+			ViewOnly:  viewOnly,
+		},
+	}
+
+	return c.sendMessage(message)
+}
+
+// SendControlModeChanged notifica al admin que el control de input fue revocado o concedido
+func (c *APIClient) SendControlModeChanged(sessionID string, viewOnly bool, reason string) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to server")
+	}
+
+	message := WebSocketMessage{
+		Type: MessageTypeControlModeChanged,
+		Data: ControlModeMessage{
+			SessionID: sessionID,
+			ViewOnly:  viewOnly,
+			Reason:    reason,
 		},
 	}
 
 	return c.sendMessage(message)
 }
 
+// SendClipboardUpdate envía al admin el nuevo contenido del portapapeles local
+func (c *APIClient) SendClipboardUpdate(update ClipboardUpdate) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to server")
+	}
+
+	update.Timestamp = time.Now().Unix()
+
+	message := WebSocketMessage{
+		Type: MessageTypeClipboardUpdate,
+		Data: update,
+	}
+
+	return c.sendMessage(message)
+}
+
+// SendClipboardSyncState informa al admin si la sincronización del portapapeles está activa
+func (c *APIClient) SendClipboardSyncState(state ClipboardSyncState) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to server")
+	}
+
+	state.Timestamp = time.Now().Unix()
+
+	message := WebSocketMessage{
+		Type: MessageTypeClipboardSyncState,
+		Data: state,
+	}
+
+	return c.sendMessage(message)
+}
+
 // RejectRemoteControlSession rechaza una sesión de control remoto
 func (c *APIClient) RejectRemoteControlSession(sessionID, reason string) error {
 	if !c.IsConnected() {
@@ -660,6 +1051,18 @@ func (c *APIClient) SendScreenFrame(frame ScreenFrame) error {
 		return fmt.Errorf("not connected to server")
 	}
 
+	c.mutex.RLock()
+	binaryFrames := c.binaryFrames
+	c.mutex.RUnlock()
+
+	if binaryFrames {
+		data, err := EncodeBinaryScreenFrame(frame)
+		if err == nil {
+			return c.sendBinary(data)
+		}
+		log.Printf("⚠️ Binary frame encoding failed, falling back to JSON: %v", err)
+	}
+
 	message := WebSocketMessage{
 		Type: MessageTypeScreenFrame,
 		Data: frame,
@@ -668,13 +1071,33 @@ func (c *APIClient) SendScreenFrame(frame ScreenFrame) error {
 	return c.sendMessage(message)
 }
 
-// SendScreenFrameAsync envía un frame de pantalla de forma asíncrona
-func (c *APIClient) SendScreenFrameAsync(frame ScreenFrame) {
-	go func() {
-		if err := c.SendScreenFrame(frame); err != nil {
-			log.Printf("Error sending screen frame async: %v", err)
-		}
-	}()
+// SetBinaryFramesEnabled define si se ofrece el protocolo binario de frames al
+// autenticar; el cambio aplica a partir de la próxima autenticación
+func (c *APIClient) SetBinaryFramesEnabled(enabled bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.binaryFramesEnabled = enabled
+}
+
+// IsBinaryFramesActive verifica si los frames se están enviando en binario
+func (c *APIClient) IsBinaryFramesActive() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.binaryFrames
+}
+
+// SendDisplayList envía la lista de pantallas y la seleccionada al servidor
+func (c *APIClient) SendDisplayList(list DisplayListMessage) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to server")
+	}
+
+	message := WebSocketMessage{
+		Type: MessageTypeDisplayList,
+		Data: list,
+	}
+
+	return c.sendMessage(message)
 }
 
 // SendVideoChunk envía un chunk de video al servidor
@@ -773,7 +1196,8 @@ func (c *APIClient) SendVideoRecordingComplete(videoID string, sessionID string,
 }
 
 // SendFileTransferAcknowledgement envía confirmación de recepción de archivo al servidor
-func (c *APIClient) SendFileTransferAcknowledgement(transferID, sessionID string, success bool, errorMessage, filePath, fileChecksum string) error {
+// reasonCode es un código estable del motivo de rechazo (vacío si no aplica)
+func (c *APIClient) SendFileTransferAcknowledgement(transferID, sessionID string, success bool, errorMessage, reasonCode, filePath, fileChecksum string) error {
 	if !c.IsConnected() {
 		return fmt.Errorf("not connected to server")
 	}
@@ -783,6 +1207,7 @@ func (c *APIClient) SendFileTransferAcknowledgement(transferID, sessionID string
 		SessionID:    sessionID,
 		Success:      success,
 		ErrorMessage: errorMessage,
+		ReasonCode:   reasonCode,
 		FilePath:     filePath,
 		FileChecksum: fileChecksum,
 		Timestamp:    time.Now().Unix(),
@@ -796,3 +1221,88 @@ func (c *APIClient) SendFileTransferAcknowledgement(transferID, sessionID string
 	log.Printf("📤 Sending file transfer acknowledgement: Transfer=%s, Success=%v", transferID, success)
 	return c.sendMessage(message)
 }
+
+// SendFileChunkRetransmit solicita al servidor que reenvíe un chunk que no pasó la verificación
+func (c *APIClient) SendFileChunkRetransmit(retransmit FileChunkRetransmit) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to server")
+	}
+
+	retransmit.Timestamp = time.Now().Unix()
+
+	message := WebSocketMessage{
+		Type: MessageTypeFileChunkRetransmit,
+		Data: retransmit,
+	}
+
+	log.Printf("📤 Requesting retransmission of chunk %d for transfer %s: %s",
+		retransmit.ChunkIndex, retransmit.TransferID, retransmit.Reason)
+	return c.sendMessage(message)
+}
+
+// SendFileTransferResume solicita al servidor los chunks que faltan de una transferencia interrumpida
+func (c *APIClient) SendFileTransferResume(resume FileTransferResume) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to server")
+	}
+
+	resume.Timestamp = time.Now().Unix()
+
+	message := WebSocketMessage{
+		Type: MessageTypeFileTransferResume,
+		Data: resume,
+	}
+
+	log.Printf("📤 Requesting missing chunks for transfer %s: %d range(s)", resume.TransferID, len(resume.MissingRanges))
+	return c.sendMessage(message)
+}
+
+// SendFileUploadResponse informa al servidor si el usuario permitió la subida
+func (c *APIClient) SendFileUploadResponse(response FileUploadResponse) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to server")
+	}
+
+	response.Timestamp = time.Now().Unix()
+
+	message := WebSocketMessage{
+		Type: MessageTypeFileUploadResponse,
+		Data: response,
+	}
+
+	log.Printf("📤 Sending upload response for %s (accepted: %t)", response.TransferID, response.Accepted)
+	return c.sendMessage(message)
+}
+
+// SendFileUploadChunk envía un chunk de un archivo que el servidor pidió subir
+func (c *APIClient) SendFileUploadChunk(chunk FileChunk) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to server")
+	}
+
+	chunk.Timestamp = time.Now().Unix()
+
+	message := WebSocketMessage{
+		Type: MessageTypeFileUploadChunk,
+		Data: chunk,
+	}
+
+	return c.sendMessage(message)
+}
+
+// SendFileUploadComplete informa al servidor del resultado final de una subida
+func (c *APIClient) SendFileUploadComplete(complete FileUploadComplete) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to server")
+	}
+
+	complete.Timestamp = time.Now().Unix()
+
+	message := WebSocketMessage{
+		Type: MessageTypeFileUploadComplete,
+		Data: complete,
+	}
+
+	log.Printf("📤 Upload %s finished (success: %t, %d bytes)", complete.TransferID, complete.Success, complete.BytesSent)
+	return c.sendMessage(message)
+}
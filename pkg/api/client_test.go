@@ -0,0 +1,118 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuthenticateWithToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    ClientAuthResponse
+		wantSuccess bool
+		wantToken   string
+	}{
+		{
+			name:        "accepted without a new token keeps the stored one",
+			response:    ClientAuthResponse{Success: true, UserID: "42"},
+			wantSuccess: true,
+			wantToken:   "stored",
+		},
+		{
+			name:        "accepted with a rotated token",
+			response:    ClientAuthResponse{Success: true, Token: "rotated", UserID: "42"},
+			wantSuccess: true,
+			wantToken:   "rotated",
+		},
+		{
+			name:        "rejected",
+			response:    ClientAuthResponse{Success: false, Error: "token expired"},
+			wantSuccess: false,
+			wantToken:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(request ClientAuthRequest) ClientAuthResponse {
+				return tt.response
+			})
+			client, _, _ := newTestClient(t, server)
+
+			response, err := client.AuthenticateWithToken("stored")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			request := server.nextAuth(t)
+			if request.AuthMethod != AuthMethodToken || request.Token != "stored" {
+				t.Errorf("auth = %s with token %q, want token auth with stored", request.AuthMethod, request.Token)
+			}
+			if request.Username != "" || request.Password != "" {
+				t.Errorf("auth sent credentials %q/%q, want token only", request.Username, request.Password)
+			}
+
+			if response.Success != tt.wantSuccess {
+				t.Fatalf("Success = %v, want %v (%s)", response.Success, tt.wantSuccess, response.Error)
+			}
+			if tt.wantSuccess && response.Token != tt.wantToken {
+				t.Errorf("response token = %q, want %q", response.Token, tt.wantToken)
+			}
+			if !tt.wantSuccess && response.Error != tt.response.Error {
+				t.Errorf("response error = %q, want %q", response.Error, tt.response.Error)
+			}
+			if got := client.GetAuthToken(); got != tt.wantToken {
+				t.Errorf("GetAuthToken() = %q, want %q", got, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestAuthenticateWithTokenRequiresToken(t *testing.T) {
+	server := newTestServer(t, func(request ClientAuthRequest) ClientAuthResponse {
+		return ClientAuthResponse{Success: true}
+	})
+	client, _, dials := newTestClient(t, server)
+
+	if _, err := client.AuthenticateWithToken(""); err == nil {
+		t.Error("an empty token was sent")
+	}
+	if got := dials.Load(); got != 0 {
+		t.Errorf("dials = %d, want 0", got)
+	}
+	server.expectNoAuth(t)
+}
+
+func TestResumedSessionReregistersPC(t *testing.T) {
+	server := newTestServer(t, func(request ClientAuthRequest) ClientAuthResponse {
+		// Al reanudar, el servidor no emite un token nuevo
+		return ClientAuthResponse{Success: true}
+	})
+	client, clock, _ := newTestClient(t, server)
+
+	response, err := client.AuthenticateWithToken("stored")
+	if err != nil || !response.Success {
+		t.Fatalf("resume = %+v, %v", response, err)
+	}
+	server.nextAuth(t)
+
+	registration, err := client.RegisterPC("pc-1")
+	if err != nil || !registration.Success {
+		t.Fatalf("RegisterPC() = %+v, %v", registration, err)
+	}
+	if got := server.nextRegistration(t); got != "pc-1" {
+		t.Errorf("registered %q, want pc-1", got)
+	}
+
+	// Tras una caída, la reconexión usa el token conservado y vuelve a registrar el PC
+	server.dropClient(t)
+	clock.next(t).fire <- time.Now()
+
+	if request := server.nextAuth(t); request.AuthMethod != AuthMethodToken || request.Token != "stored" {
+		t.Errorf("re-auth = %s with token %q, want token auth with stored", request.AuthMethod, request.Token)
+	}
+	if got := server.nextRegistration(t); got != "pc-1" {
+		t.Errorf("re-registered %q, want pc-1", got)
+	}
+	waitFor(t, "reconnected", func() bool { return !client.IsReconnecting() && client.IsConnected() })
+}
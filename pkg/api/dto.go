@@ -40,10 +40,31 @@ const (
 	MessageTypeVideoFrameUpload       = "video_frame_upload"
 	MessageTypeVideoRecordingComplete = "video_recording_complete"
 
+	// Display Selection Messages
+	MessageTypeDisplayListRequest = "display_list_request"
+	MessageTypeDisplayList        = "display_list"
+	MessageTypeSelectDisplay      = "select_display"
+
+	// Control mode (view-only) messages
+	MessageTypeControlModeChanged = "control_mode_changed"
+
+	// Clipboard synchronization messages
+	MessageTypeClipboardUpdate    = "clipboard_update"     // Both directions
+	MessageTypeClipboardSyncState = "clipboard_sync_state" // Client to server
+
 	// File Transfer Messages
 	MessageTypeFileTransferRequest = "file_transfer_request"
 	MessageTypeFileChunk           = "file_chunk"
 	MessageTypeFileTransferAck     = "file_transfer_acknowledgement"
+	MessageTypeFileTransferResume  = "file_transfer_resume"
+	MessageTypeFileChunkRetransmit = "file_chunk_retransmit"
+
+	// Message types for client-to-server uploads
+	MessageTypeFileUploadRequest  = "file_upload_request"
+	MessageTypeFileUploadResponse = "file_upload_response"
+	MessageTypeFileUploadChunk    = "file_upload_chunk"
+	MessageTypeFileUploadComplete = "file_upload_complete"
+	MessageTypeFileUploadCancel   = "file_upload_cancel"
 )
 
 // Base message structure
@@ -52,10 +73,20 @@ type WebSocketMessage struct {
 	Data interface{} `json:"data"`
 }
 
+// Métodos de autenticación soportados en CLIENT_AUTH_REQUEST
+const (
+	AuthMethodPassword = "password"
+	AuthMethodToken    = "token"
+)
+
 // Client Authentication Messages
 type ClientAuthRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	AuthMethod string `json:"auth_method,omitempty"` // "password" (por defecto) o "token"
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	Token      string `json:"token,omitempty"` // Solo para auth_method "token"
+	// Capacidades de protocolo que ofrece el cliente (ej. "binary_screen_frames")
+	Capabilities []string `json:"capabilities,omitempty"`
 }
 
 type ClientAuthResponse struct {
@@ -63,6 +94,8 @@ type ClientAuthResponse struct {
 	Token   string `json:"token,omitempty"`
 	UserID  string `json:"userId,omitempty"`
 	Error   string `json:"error,omitempty"`
+	// Capacidades aceptadas por el servidor; servidores antiguos no la envían
+	Capabilities []string `json:"capabilities,omitempty"`
 }
 
 // PC Registration Messages
@@ -97,6 +130,7 @@ type RemoteControlRequest struct {
 
 type SessionAcceptedMessage struct {
 	SessionID string `json:"session_id"`
+	ViewOnly  bool   `json:"view_only"` // El admin solo puede ver la pantalla
 }
 
 type SessionRejectedMessage struct {
@@ -104,16 +138,36 @@ type SessionRejectedMessage struct {
 	Reason    string `json:"reason,omitempty"`
 }
 
+// Screen frame formats
+const (
+	FrameFormatJPEG      = "jpeg"       // Full frame (keyframe)
+	FrameFormatJPEGTiles = "jpeg-tiles" // Only the tiles that changed since the previous frame
+)
+
 // ScreenFrame represents a captured screen frame
 type ScreenFrame struct {
-	SessionID   string `json:"session_id"`
-	Timestamp   int64  `json:"timestamp"`
-	Width       int    `json:"width"`
-	Height      int    `json:"height"`
-	Format      string `json:"format"`            // "jpeg", "png", etc.
-	Quality     int    `json:"quality,omitempty"` // For JPEG compression (1-100)
-	FrameData   []byte `json:"frame_data"`        // Base64 encoded image data
-	SequenceNum int64  `json:"sequence_num"`
+	SessionID   string     `json:"session_id"`
+	Timestamp   int64      `json:"timestamp"`
+	Width       int        `json:"width"`
+	Height      int        `json:"height"`
+	Format      string     `json:"format"`            // "jpeg", "jpeg-tiles", etc.
+	Quality     int        `json:"quality,omitempty"` // For JPEG compression (1-100)
+	FrameData   []byte     `json:"frame_data"`        // Base64 encoded image data
+	SequenceNum int64      `json:"sequence_num"`
+	IsKeyframe  bool       `json:"is_keyframe,omitempty"` // Full frame the viewer can resync from
+	Tiles       []TileData `json:"tiles,omitempty"`       // For "jpeg-tiles" frames
+
+	// FullFrameData is the full JPEG kept locally for recording; never sent
+	FullFrameData []byte `json:"-"`
+}
+
+// TileData is a JPEG-encoded screen tile positioned relative to the frame origin
+type TileData struct {
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Data   []byte `json:"data"` // Base64 encoded JPEG data
 }
 
 // InputCommand represents a remote input command (mouse/keyboard)
@@ -121,7 +175,7 @@ type InputCommand struct {
 	SessionID string                 `json:"session_id"`
 	Timestamp int64                  `json:"timestamp"`
 	EventType string                 `json:"event_type"` // "mouse", "keyboard"
-	Action    string                 `json:"action"`     // "move", "click", "scroll", "keydown", "keyup", "type"
+	Action    string                 `json:"action"`     // "move", "down", "up", "click", "dblclick", "scroll", "keydown", "keyup", "type"
 	Payload   map[string]interface{} `json:"payload"`    // Event-specific data
 }
 
@@ -129,8 +183,10 @@ type InputCommand struct {
 type MouseEventPayload struct {
 	X      int    `json:"x"`
 	Y      int    `json:"y"`
-	Button string `json:"button,omitempty"` // "left", "right", "middle"
-	Delta  int    `json:"delta,omitempty"`  // For scroll events
+	Button string `json:"button,omitempty"`  // "left", "right", "middle"
+	Delta  int    `json:"delta,omitempty"`   // Vertical scroll; legacy alias of DeltaY
+	DeltaX int    `json:"delta_x,omitempty"` // Horizontal scroll, positive = right
+	DeltaY int    `json:"delta_y,omitempty"` // Vertical scroll, positive = up
 }
 
 // Keyboard Event Payload Fields
@@ -141,6 +197,74 @@ type KeyboardEventPayload struct {
 	Modifiers []string `json:"modifiers,omitempty"` // ["ctrl", "alt", "shift", "meta"]
 }
 
+// DisplayVirtualDesktop selecciona la unión de todas las pantallas
+const DisplayVirtualDesktop = -1
+
+// DisplayInfo describes one client display in desktop coordinates
+type DisplayInfo struct {
+	Index   int  `json:"index"`
+	X       int  `json:"x"`
+	Y       int  `json:"y"`
+	Width   int  `json:"width"`
+	Height  int  `json:"height"`
+	Primary bool `json:"primary"`
+}
+
+// DisplayListRequest asks the client for its displays
+type DisplayListRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// DisplayListMessage reports the client's displays and the one being streamed
+type DisplayListMessage struct {
+	SessionID       string        `json:"session_id"`
+	Displays        []DisplayInfo `json:"displays"`
+	SelectedDisplay int           `json:"selected_display"` // -1 = virtual desktop
+	VirtualDesktop  DisplayInfo   `json:"virtual_desktop"`  // Union of all displays
+	Error           string        `json:"error,omitempty"`  // Set when a selection failed
+}
+
+// SelectDisplayRequest switches the streamed display
+type SelectDisplayRequest struct {
+	SessionID    string `json:"session_id"`
+	DisplayIndex int    `json:"display_index"` // -1 = virtual desktop
+}
+
+// ControlModeMessage informa al admin de que el control de input fue revocado o concedido
+type ControlModeMessage struct {
+	SessionID string `json:"session_id"`
+	ViewOnly  bool   `json:"view_only"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Clipboard content formats
+const (
+	ClipboardFormatText = "text"
+	ClipboardFormatPNG  = "image/png"
+)
+
+// ClipboardUpdate carries new clipboard content from one side of the session to the other
+type ClipboardUpdate struct {
+	SessionID string `json:"session_id"`
+	Format    string `json:"format"`         // "text" or "image/png"
+	Text      string `json:"text,omitempty"` // For "text"
+	Data      []byte `json:"data,omitempty"` // Base64 encoded PNG for "image/png"
+	Hash      string `json:"hash"`           // Hex SHA-256 of the content
+	SizeBytes int    `json:"size_bytes"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// ClipboardSyncState tells the admin whether clipboard sync is on and its limits
+type ClipboardSyncState struct {
+	SessionID     string `json:"session_id"`
+	Enabled       bool   `json:"enabled"`
+	AllowImages   bool   `json:"allow_images"`
+	MaxTextBytes  int    `json:"max_text_bytes"`
+	MaxImageBytes int    `json:"max_image_bytes,omitempty"`
+	Error         string `json:"error,omitempty"` // Set when a clipboard_update was rejected
+	Timestamp     int64  `json:"timestamp"`
+}
+
 // VideoFrameUpload representa un frame de video individual para subir
 type VideoFrameUpload struct {
 	SessionID  string `json:"session_id"`
@@ -169,6 +293,17 @@ type FileTransferRequest struct {
 	TotalChunks     int     `json:"total_chunks"`
 	DestinationPath string  `json:"destination_path"`
 	Timestamp       int64   `json:"timestamp"`
+
+	// Opcionales: permiten ubicar cada chunk por offset y validar el tamaño final
+	FileSizeBytes int64 `json:"file_size_bytes,omitempty"`
+	ChunkSize     int   `json:"chunk_size,omitempty"` // Tamaño de todos los chunks salvo el último
+
+	// SHA-256 (hex) esperado del archivo completo; vacío = no se compara
+	FileChecksum string `json:"file_checksum,omitempty"`
+
+	// "tar" o "tar.gz": el contenido es un directorio empaquetado que se extrae
+	// en una carpeta llamada FileName (sin la extensión del paquete)
+	ArchiveFormat string `json:"archive_format,omitempty"`
 }
 
 // FileChunk represents a chunk of file data being transferred
@@ -179,17 +314,88 @@ type FileChunk struct {
 	TotalChunks   int    `json:"total_chunks"`
 	ChunkData     []byte `json:"chunk_data"`
 	IsLastChunk   bool   `json:"is_last_chunk"`
-	ChunkChecksum string `json:"chunk_checksum,omitempty"`
+	ChunkChecksum string `json:"chunk_checksum,omitempty"` // Hex SHA-256 (o MD5) de los datos decodificados
 	Timestamp     int64  `json:"timestamp"`
 }
 
+// ChunkRange is an inclusive range of chunk indices
+type ChunkRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// FileTransferResume asks the server to re-send only the chunks the client is missing
+// after a disconnect or restart
+type FileTransferResume struct {
+	TransferID     string       `json:"transfer_id"`
+	SessionID      string       `json:"session_id"`
+	TotalChunks    int          `json:"total_chunks"`
+	ReceivedChunks int          `json:"received_chunks"`
+	MissingRanges  []ChunkRange `json:"missing_ranges"`
+	Timestamp      int64        `json:"timestamp"`
+}
+
+// FileChunkRetransmit asks the server to re-send a chunk that failed verification
+type FileChunkRetransmit struct {
+	TransferID string `json:"transfer_id"`
+	SessionID  string `json:"session_id"`
+	ChunkIndex int    `json:"chunk_index"`
+	Reason     string `json:"reason"`
+	Attempt    int    `json:"attempt"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
 // FileTransferAcknowledgement represents the client's response after receiving a file
 type FileTransferAcknowledgement struct {
 	TransferID   string `json:"transfer_id"`
 	SessionID    string `json:"session_id"`
 	Success      bool   `json:"success"`
 	ErrorMessage string `json:"error_message,omitempty"`
+	ReasonCode   string `json:"reason_code,omitempty"` // e.g. "insufficient_disk_space", "extension_blocked"
 	FilePath     string `json:"file_path,omitempty"`
-	FileChecksum string `json:"file_checksum,omitempty"`
+	FileChecksum string `json:"file_checksum,omitempty"` // Hex SHA-256 del archivo recibido
+	Timestamp    int64  `json:"timestamp"`
+}
+
+// FileUploadRequest asks the client to send a local file to the server
+type FileUploadRequest struct {
+	TransferID  string `json:"transfer_id"`
+	SessionID   string `json:"session_id"`
+	FilePath    string `json:"file_path"` // Absolute path on the client machine
+	RequestedBy string `json:"requested_by,omitempty"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+// FileUploadResponse tells the server whether the user allowed the upload.
+// When accepted, file_upload_chunk messages follow with the same TransferID.
+type FileUploadResponse struct {
+	TransferID    string `json:"transfer_id"`
+	SessionID     string `json:"session_id"`
+	Accepted      bool   `json:"accepted"`
+	Reason        string `json:"reason,omitempty"`
+	FileName      string `json:"file_name,omitempty"`
+	FileSizeBytes int64  `json:"file_size_bytes,omitempty"`
+	TotalChunks   int    `json:"total_chunks,omitempty"`
+	ChunkSize     int    `json:"chunk_size,omitempty"`
+	Timestamp     int64  `json:"timestamp"`
+}
+
+// FileUploadComplete closes an upload, successful or not
+type FileUploadComplete struct {
+	TransferID   string `json:"transfer_id"`
+	SessionID    string `json:"session_id"`
+	Success      bool   `json:"success"`
+	Cancelled    bool   `json:"cancelled,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+	BytesSent    int64  `json:"bytes_sent"`
+	FileChecksum string `json:"file_checksum,omitempty"` // Hex SHA-256 of the whole file
 	Timestamp    int64  `json:"timestamp"`
 }
+
+// FileUploadCancel stops an upload that is waiting for consent or in progress
+type FileUploadCancel struct {
+	TransferID string `json:"transfer_id"`
+	SessionID  string `json:"session_id"`
+	Reason     string `json:"reason,omitempty"`
+	Timestamp  int64  `json:"timestamp"`
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"EscritorioRemoto-Cliente/internal/model/valueobjects"
+)
+
+// ConnectionStatusHandler es el callback para cambios en el estado de la conexión
+type ConnectionStatusHandler func(status *valueobjects.ConnectionStatus)
+
+// SessionExpiredHandler es el callback para cuando la sesión ya no puede
+// reanudarse con el token y el usuario debe volver a iniciar sesión
+type SessionExpiredHandler func(reason string)
+
+// ReconnectConfig define la política de reconexión automática del APIClient
+type ReconnectConfig struct {
+	Enabled      bool
+	InitialDelay time.Duration // Espera antes del primer reintento
+	MaxDelay     time.Duration // Tope de la espera entre reintentos
+	Multiplier   float64       // Factor de crecimiento exponencial
+	Jitter       float64       // Variación aleatoria (0-1) aplicada a cada espera
+	MaxAttempts  int           // 0 = reintentar indefinidamente
+}
+
+// DefaultReconnectConfig retorna la política de reconexión por defecto
+func DefaultReconnectConfig() ReconnectConfig {
+	return ReconnectConfig{
+		Enabled:      true,
+		InitialDelay: 1 * time.Second,
+		MaxDelay:     60 * time.Second,
+		Multiplier:   2.0,
+		Jitter:       0.2,
+		MaxAttempts:  0,
+	}
+}
+
+// backoffDelay calcula la espera para el intento dado (base 1) con jitter
+func (rc ReconnectConfig) backoffDelay(attempt int) time.Duration {
+	delay := float64(rc.InitialDelay)
+	for i := 1; i < attempt; i++ {
+		delay *= rc.Multiplier
+		if delay >= float64(rc.MaxDelay) {
+			delay = float64(rc.MaxDelay)
+			break
+		}
+	}
+
+	if rc.Jitter > 0 {
+		delay += delay * rc.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	return time.Duration(delay)
+}
+
+// SetReconnectConfig establece la política de reconexión automática
+func (c *APIClient) SetReconnectConfig(config ReconnectConfig) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.reconnectConfig = config
+}
+
+// SetConnectionStatusHandler establece el handler para cambios de estado de conexión
+func (c *APIClient) SetConnectionStatusHandler(handler ConnectionStatusHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.connectionStatusHandler = handler
+}
+
+// SetSessionExpiredHandler establece el handler para sesiones que requieren un nuevo login
+func (c *APIClient) SetSessionExpiredHandler(handler SessionExpiredHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.sessionExpiredHandler = handler
+}
+
+// IsReconnecting verifica si el supervisor de reconexión está activo
+func (c *APIClient) IsReconnecting() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.reconnecting
+}
+
+// emitSessionExpired notifica que el usuario debe volver a autenticarse
+func (c *APIClient) emitSessionExpired(reason string) {
+	c.mutex.RLock()
+	handler := c.sessionExpiredHandler
+	c.mutex.RUnlock()
+
+	if handler != nil {
+		handler(reason)
+	}
+}
+
+// emitConnectionStatus notifica un cambio de estado de conexión
+func (c *APIClient) emitConnectionStatus(status *valueobjects.ConnectionStatus) {
+	c.mutex.RLock()
+	handler := c.connectionStatusHandler
+	c.mutex.RUnlock()
+
+	if handler != nil {
+		handler(status)
+	}
+}
+
+// startReconnect lanza el supervisor de reconexión si no hay uno en marcha.
+// Debe llamarse sin tener tomado c.mutex.
+func (c *APIClient) startReconnect() {
+	c.mutex.Lock()
+	if c.reconnecting || c.userClosed || !c.reconnectConfig.Enabled {
+		c.mutex.Unlock()
+		return
+	}
+	if c.authToken == "" {
+		// Sin token no hay forma de reanudar la sesión sin pedir la contraseña
+		c.mutex.Unlock()
+		c.expireSession("no session token to re-authenticate")
+		return
+	}
+	c.reconnecting = true
+	c.stopReconnect = make(chan struct{})
+	stop := c.stopReconnect
+	c.mutex.Unlock()
+
+	go c.reconnectLoop(stop)
+}
+
+// reconnectLoop reintenta la conexión con backoff exponencial hasta lograrlo,
+// agotar los intentos o recibir la señal de parada
+func (c *APIClient) reconnectLoop(stop <-chan struct{}) {
+	connected := false
+	defer func() {
+		// El supervisor se da por terminado antes de avisar de la conexión: si la
+		// nueva conexión cae mientras el app procesa el aviso, su cierre lanza otro
+		c.mutex.Lock()
+		c.reconnecting = false
+		dropped := connected && !c.isConnected
+		c.mutex.Unlock()
+
+		if dropped {
+			// Cayó antes de terminar el supervisor y su cierre no pudo lanzar otro
+			c.startReconnect()
+			return
+		}
+		if connected {
+			c.emitConnectionStatus(valueobjects.NewConnectedStatus(c.serverURL))
+		}
+	}()
+
+	c.mutex.RLock()
+	config := c.reconnectConfig
+	after := c.after
+	c.mutex.RUnlock()
+
+	log.Printf("🔄 Connection lost, starting reconnect supervisor")
+	c.emitConnectionStatus(valueobjects.NewReconnectingStatus(c.serverURL))
+
+	for attempt := 1; config.MaxAttempts == 0 || attempt <= config.MaxAttempts; attempt++ {
+		delay := config.backoffDelay(attempt)
+		log.Printf("🔄 Reconnect attempt %d in %v", attempt, delay)
+
+		select {
+		case <-stop:
+			log.Println("🔄 Reconnect cancelled")
+			return
+		case <-after(delay):
+		}
+
+		err := c.resumeConnection()
+		if err == nil {
+			// Si el usuario se desconectó mientras reconectábamos, respetar su decisión
+			select {
+			case <-stop:
+				c.Disconnect()
+				return
+			default:
+			}
+
+			log.Printf("✅ Reconnected to server after %d attempt(s)", attempt)
+			connected = true
+			return
+		}
+
+		log.Printf("❌ Reconnect attempt %d failed: %v", attempt, err)
+
+		if _, rejected := err.(*authRejectedError); rejected {
+			// Reintentar con un token rechazado no tiene sentido
+			c.expireSession(err.Error())
+			return
+		}
+	}
+
+	log.Printf("❌ Giving up reconnect after %d attempts", config.MaxAttempts)
+	c.emitConnectionStatus(valueobjects.NewErrorStatus("reconnect attempts exhausted"))
+}
+
+// resumeConnection vuelve a conectar, autenticar y registrar el PC
+func (c *APIClient) resumeConnection() error {
+	c.mutex.RLock()
+	pcIdentifier := c.pcIdentifier
+	c.mutex.RUnlock()
+
+	if err := c.connect(); err != nil {
+		return err
+	}
+
+	if err := c.reauthenticate(); err != nil {
+		c.dropConnection()
+		return err
+	}
+
+	if pcIdentifier != "" {
+		regResp, err := c.RegisterPC(pcIdentifier)
+		if err != nil {
+			c.dropConnection()
+			return err
+		}
+		if !regResp.Success {
+			c.dropConnection()
+			return fmt.Errorf("PC re-registration failed: %s", regResp.Error)
+		}
+	}
+
+	return nil
+}
+
+// reauthenticate autentica con el token de la última autenticación exitosa.
+// Nunca recurre a la contraseña: si el token se rechaza hay que iniciar sesión de nuevo.
+func (c *APIClient) reauthenticate() error {
+	c.mutex.RLock()
+	token := c.authToken
+	c.mutex.RUnlock()
+
+	if token == "" {
+		return &authRejectedError{reason: "no session token"}
+	}
+
+	authResp, err := c.AuthenticateWithToken(token)
+	if err != nil {
+		return err
+	}
+	if !authResp.Success {
+		log.Printf("⚠️ Stored token rejected on reconnect: %s", authResp.Error)
+		return &authRejectedError{reason: authResp.Error}
+	}
+
+	return nil
+}
+
+// expireSession descarta el token, emite el estado de error y avisa de que
+// el usuario debe volver a la pantalla de login
+func (c *APIClient) expireSession(reason string) {
+	c.mutex.Lock()
+	c.authToken = ""
+	c.mutex.Unlock()
+
+	c.emitConnectionStatus(valueobjects.NewErrorStatus(reason))
+	c.emitSessionExpired(reason)
+}
+
+// authRejectedError indica que el servidor rechazó las credenciales almacenadas
+type authRejectedError struct {
+	reason string
+}
+
+func (e *authRejectedError) Error() string {
+	return fmt.Sprintf("authentication rejected: %s", e.reason)
+}
@@ -0,0 +1,452 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"EscritorioRemoto-Cliente/internal/model/valueobjects"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	config := ReconnectConfig{
+		InitialDelay: time.Second,
+		MaxDelay:     10 * time.Second,
+		Multiplier:   2,
+	}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 1 * time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 3, want: 4 * time.Second},
+		{attempt: 4, want: 8 * time.Second},
+		{attempt: 5, want: 10 * time.Second}, // Tope
+		{attempt: 50, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := config.backoffDelay(tt.attempt); got != tt.want {
+			t.Errorf("backoffDelay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffDelayJitter(t *testing.T) {
+	config := ReconnectConfig{
+		InitialDelay: time.Second,
+		MaxDelay:     10 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+	}
+
+	for _, tt := range []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{attempt: 1, base: time.Second},
+		{attempt: 20, base: 10 * time.Second}, // El jitter se aplica también sobre el tope
+	} {
+		low := time.Duration(float64(tt.base) * (1 - config.Jitter))
+		high := time.Duration(float64(tt.base) * (1 + config.Jitter))
+
+		minDelay, maxDelay := high, low
+		for i := 0; i < 1000; i++ {
+			delay := config.backoffDelay(tt.attempt)
+			if delay < low || delay > high {
+				t.Fatalf("backoffDelay(%d) = %v, want within [%v, %v]", tt.attempt, delay, low, high)
+			}
+			minDelay = min(minDelay, delay)
+			maxDelay = max(maxDelay, delay)
+		}
+		if minDelay >= tt.base || maxDelay <= tt.base {
+			t.Errorf("attempt %d: delays in [%v, %v], want spread on both sides of %v", tt.attempt, minDelay, maxDelay, tt.base)
+		}
+	}
+}
+
+func TestReconnectReauthenticatesWithTokenOnly(t *testing.T) {
+	tests := []struct {
+		name          string
+		tokenAccepted bool
+	}{
+		{name: "token accepted", tokenAccepted: true},
+		{name: "token rejected", tokenAccepted: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(request ClientAuthRequest) ClientAuthResponse {
+				if request.AuthMethod == AuthMethodToken && !tt.tokenAccepted {
+					return ClientAuthResponse{Success: false, Error: "token expired"}
+				}
+				return ClientAuthResponse{Success: true, Token: "tok-1"}
+			})
+			client, clock, _ := newTestClient(t, server)
+
+			expired := make(chan string, 1)
+			client.SetSessionExpiredHandler(func(reason string) {
+				expired <- reason
+			})
+
+			connectAndRegister(t, client, server)
+
+			// El servidor corta la conexión: el supervisor espera el primer backoff
+			server.dropClient(t)
+			wait := clock.next(t)
+			if wait.delay != time.Second {
+				t.Errorf("first backoff = %v, want 1s", wait.delay)
+			}
+			wait.fire <- time.Now()
+
+			request := server.nextAuth(t)
+			if request.AuthMethod != AuthMethodToken || request.Token != "tok-1" {
+				t.Errorf("re-auth = %s with token %q, want token auth with tok-1", request.AuthMethod, request.Token)
+			}
+			if request.Password != "" || request.Username != "" {
+				t.Errorf("re-auth sent credentials %q/%q, want token only", request.Username, request.Password)
+			}
+
+			if tt.tokenAccepted {
+				if got := server.nextRegistration(t); got != "pc-1" {
+					t.Errorf("re-registered %q, want pc-1", got)
+				}
+				waitFor(t, "reconnected", func() bool { return !client.IsReconnecting() && client.IsConnected() })
+			} else {
+				select {
+				case reason := <-expired:
+					if !strings.Contains(reason, "token expired") {
+						t.Errorf("expired reason = %q, want the server error", reason)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("session did not expire")
+				}
+				waitFor(t, "supervisor stopped", func() bool { return !client.IsReconnecting() })
+				if token := client.GetAuthToken(); token != "" {
+					t.Errorf("token after expiry = %q, want empty", token)
+				}
+				if client.IsConnected() {
+					t.Error("client still connected after the token was rejected")
+				}
+			}
+
+			// Ni reintentos ni otra autenticación (y nunca con contraseña)
+			clock.expectNoWait(t)
+			server.expectNoAuth(t)
+			select {
+			case reason := <-expired:
+				if tt.tokenAccepted {
+					t.Errorf("session expired after a successful reconnect: %s", reason)
+				}
+			default:
+			}
+		})
+	}
+}
+
+func TestDisconnectStopsReconnectBackoff(t *testing.T) {
+	server := newTestServer(t, func(request ClientAuthRequest) ClientAuthResponse {
+		return ClientAuthResponse{Success: true, Token: "tok-1"}
+	})
+	client, clock, dials := newTestClient(t, server)
+	client.SetReconnectConfig(ReconnectConfig{
+		Enabled:      true,
+		InitialDelay: time.Second,
+		MaxDelay:     5 * time.Second,
+		Multiplier:   2,
+	})
+
+	connectAndRegister(t, client, server)
+
+	// A partir de ahora el servidor no es alcanzable
+	var dialFailures atomic.Int32
+	client.dial = func(url string, timeout time.Duration) (*websocket.Conn, error) {
+		dialFailures.Add(1)
+		return nil, errors.New("connection refused")
+	}
+	server.dropClient(t)
+
+	// El backoff crece hasta el tope entre intentos fallidos
+	for i, want := range []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		wait := clock.next(t)
+		if wait.delay != want {
+			t.Errorf("backoff %d = %v, want %v", i+1, wait.delay, want)
+		}
+		wait.fire <- time.Now()
+	}
+
+	// El supervisor queda esperando el quinto intento
+	wait := clock.next(t)
+	if err := client.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "supervisor stopped", func() bool { return !client.IsReconnecting() })
+
+	if got := dialFailures.Load(); got != 4 {
+		t.Errorf("dials after the drop = %d, want 4", got)
+	}
+	if got := dials.Load(); got != 1 {
+		t.Errorf("successful dials = %d, want 1", got)
+	}
+
+	// Un disparo tardío del reloj no debe reanudar los intentos
+	wait.fire <- time.Now()
+	clock.expectNoWait(t)
+	if got := dialFailures.Load(); got != 4 {
+		t.Errorf("dials after Disconnect = %d, want 4", got)
+	}
+}
+
+func TestReconnectRestartsWhenDroppedDuringConnectedHandler(t *testing.T) {
+	server := newTestServer(t, func(request ClientAuthRequest) ClientAuthResponse {
+		return ClientAuthResponse{Success: true, Token: "tok-1"}
+	})
+	client, clock, _ := newTestClient(t, server)
+	connectAndRegister(t, client, server)
+
+	// El handler de "conectado" se queda ocupado como lo hace el app al reanudar transferencias
+	connected := make(chan struct{}, 4)
+	proceed := make(chan struct{})
+	client.SetConnectionStatusHandler(func(status *valueobjects.ConnectionStatus) {
+		if status.IsConnected() {
+			connected <- struct{}{}
+			<-proceed
+		}
+	})
+
+	server.dropClient(t)
+	clock.next(t).fire <- time.Now()
+	server.nextAuth(t)
+	server.nextRegistration(t)
+
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reconnection was not reported")
+	}
+
+	// La nueva conexión cae mientras el handler sigue ocupado
+	server.dropClient(t)
+	waitFor(t, "drop noticed", func() bool { return !client.IsConnected() })
+	close(proceed)
+
+	// Otro supervisor toma el relevo y vuelve a conectar
+	clock.next(t).fire <- time.Now()
+	if request := server.nextAuth(t); request.AuthMethod != AuthMethodToken {
+		t.Errorf("re-auth = %s, want token auth", request.AuthMethod)
+	}
+	server.nextRegistration(t)
+	waitFor(t, "reconnected again", func() bool { return !client.IsReconnecting() && client.IsConnected() })
+}
+
+// testServer es un servidor WebSocket que responde la autenticación con auth
+// y acepta cualquier registro de PC
+type testServer struct {
+	*httptest.Server
+	auth          func(request ClientAuthRequest) ClientAuthResponse
+	authRequests  chan ClientAuthRequest
+	registrations chan string
+	conns         chan *websocket.Conn
+}
+
+func newTestServer(t *testing.T, auth func(request ClientAuthRequest) ClientAuthResponse) *testServer {
+	t.Helper()
+
+	server := &testServer{
+		auth:          auth,
+		authRequests:  make(chan ClientAuthRequest, 16),
+		registrations: make(chan string, 16),
+		conns:         make(chan *websocket.Conn, 16),
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serve))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// serve atiende una conexión del cliente hasta que se cierra
+func (s *testServer) serve(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	s.conns <- conn
+
+	for {
+		var message struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := conn.ReadJSON(&message); err != nil {
+			return
+		}
+
+		switch message.Type {
+		case MessageTypeClientAuth:
+			var request ClientAuthRequest
+			json.Unmarshal(message.Data, &request)
+			s.authRequests <- request
+			conn.WriteJSON(WebSocketMessage{Type: MessageTypeClientAuthResp, Data: s.auth(request)})
+		case MessageTypePCRegistration:
+			var request PCRegistrationRequest
+			json.Unmarshal(message.Data, &request)
+			s.registrations <- request.PCIdentifier
+			conn.WriteJSON(WebSocketMessage{Type: MessageTypePCRegistrationResp, Data: PCRegistrationResponse{Success: true}})
+		}
+	}
+}
+
+// dropClient cierra la última conexión aceptada como lo haría un servidor al reiniciarse
+func (s *testServer) dropClient(t *testing.T) {
+	t.Helper()
+	select {
+	case conn := <-s.conns:
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "restart"), time.Now().Add(time.Second))
+		conn.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("no client connection to drop")
+	}
+}
+
+func (s *testServer) nextAuth(t *testing.T) ClientAuthRequest {
+	t.Helper()
+	select {
+	case request := <-s.authRequests:
+		return request
+	case <-time.After(5 * time.Second):
+		t.Fatal("no authentication request")
+		return ClientAuthRequest{}
+	}
+}
+
+func (s *testServer) nextRegistration(t *testing.T) string {
+	t.Helper()
+	select {
+	case pcIdentifier := <-s.registrations:
+		return pcIdentifier
+	case <-time.After(5 * time.Second):
+		t.Fatal("no PC registration request")
+		return ""
+	}
+}
+
+func (s *testServer) expectNoAuth(t *testing.T) {
+	t.Helper()
+	select {
+	case request := <-s.authRequests:
+		t.Errorf("unexpected authentication request: %+v", request)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// manualClock sustituye a time.After: cada espera se publica en waits y solo
+// termina cuando el test envía a fire
+type manualClock struct {
+	waits chan clockWait
+}
+
+type clockWait struct {
+	delay time.Duration
+	fire  chan<- time.Time
+}
+
+func (m *manualClock) after(d time.Duration) <-chan time.Time {
+	fire := make(chan time.Time, 1)
+	m.waits <- clockWait{delay: d, fire: fire}
+	return fire
+}
+
+func (m *manualClock) next(t *testing.T) clockWait {
+	t.Helper()
+	select {
+	case wait := <-m.waits:
+		return wait
+	case <-time.After(5 * time.Second):
+		t.Fatal("reconnect supervisor is not waiting")
+		return clockWait{}
+	}
+}
+
+func (m *manualClock) expectNoWait(t *testing.T) {
+	t.Helper()
+	select {
+	case wait := <-m.waits:
+		t.Errorf("unexpected reconnect wait of %v", wait.delay)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// newTestClient crea un cliente contra server con un reloj manual, backoff sin
+// jitter y un contador de conexiones establecidas
+func newTestClient(t *testing.T, server *testServer) (*APIClient, *manualClock, *atomic.Int32) {
+	t.Helper()
+
+	clock := &manualClock{waits: make(chan clockWait, 16)}
+	dials := &atomic.Int32{}
+
+	client := NewAPIClient(server.URL)
+	client.after = clock.after
+	client.dial = func(url string, timeout time.Duration) (*websocket.Conn, error) {
+		conn, err := dialWebSocket(url, timeout)
+		if err == nil {
+			dials.Add(1)
+		}
+		return conn, err
+	}
+	client.SetReconnectConfig(ReconnectConfig{
+		Enabled:      true,
+		InitialDelay: time.Second,
+		MaxDelay:     time.Minute,
+		Multiplier:   2,
+	})
+	t.Cleanup(func() { client.Disconnect() })
+
+	return client, clock, dials
+}
+
+// connectAndRegister hace el login con contraseña y registra el PC "pc-1"
+func connectAndRegister(t *testing.T, client *APIClient, server *testServer) {
+	t.Helper()
+
+	response, err := client.ConnectAndAuthenticate("user", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Success {
+		t.Fatalf("login failed: %s", response.Error)
+	}
+	if request := server.nextAuth(t); request.AuthMethod != AuthMethodPassword {
+		t.Fatalf("login used %q, want password", request.AuthMethod)
+	}
+
+	registration, err := client.RegisterPC("pc-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !registration.Success {
+		t.Fatalf("registration failed: %s", registration.Error)
+	}
+	server.nextRegistration(t)
+}
+
+// waitFor espera a que cond se cumpla
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting: %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
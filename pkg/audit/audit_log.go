@@ -0,0 +1,467 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tipos de evento registrados en el log de auditoría
+const (
+	EventControlRequested    = "control_requested"
+	EventSessionAccepted     = "session_accepted"
+	EventSessionRejected     = "session_rejected"
+	EventSessionStarted      = "session_started"
+	EventSessionEnded        = "session_ended"
+	EventControlModeChanged  = "control_mode_changed"
+	EventClipboardChanged    = "clipboard_sync_changed"
+	EventInputActivity       = "input_activity"
+	EventFileIncoming        = "file_transfer_incoming"
+	EventFileAccepted        = "file_transfer_accepted"
+	EventFileDeclined        = "file_transfer_declined"
+	EventFileReceived        = "file_received"
+	EventFileFailed          = "file_transfer_failed"
+	EventFileUploadRequested = "file_upload_requested"
+	EventFileUploadAccepted  = "file_upload_accepted"
+	EventFileUploadDeclined  = "file_upload_declined"
+	EventFileUploaded        = "file_uploaded"
+	EventFileUploadFailed    = "file_upload_failed"
+	EventLogRecovered        = "audit_log_recovered"
+)
+
+// genesisHash es el PrevHash de la primera entrada de la cadena
+const genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+const (
+	currentLogName = "audit.jsonl"
+	rotatedPrefix  = "audit-"
+)
+
+// Entry es un registro del log de auditoría. Hash cubre todos los demás campos,
+// incluido PrevHash, de modo que alterar o borrar una entrada rompe la cadena.
+type Entry struct {
+	Seq       int64                  `json:"seq"`
+	Timestamp string                 `json:"timestamp"` // RFC3339Nano UTC
+	Type      string                 `json:"type"`
+	SessionID string                 `json:"session_id,omitempty"`
+	Actor     string                 `json:"actor,omitempty"` // Admin o "user"/"policy"
+	Data      map[string]interface{} `json:"data,omitempty"`
+	PrevHash  string                 `json:"prev_hash"`
+	Hash      string                 `json:"hash"`
+}
+
+// computeHash calcula el SHA-256 de la entrada sin el campo Hash
+func (e Entry) computeHash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Config define la ubicación y rotación del log de auditoría
+type Config struct {
+	Dir         string
+	MaxFileSize int64 // Bytes antes de rotar; 0 = sin rotación
+	MaxFiles    int   // Archivos rotados a conservar; 0 = todos
+}
+
+// DefaultConfig retorna la configuración por defecto en el directorio indicado
+func DefaultConfig(dir string) Config {
+	return Config{
+		Dir:         dir,
+		MaxFileSize: 10 * 1024 * 1024, // 10 MB
+		MaxFiles:    0,
+	}
+}
+
+// Logger escribe entradas encadenadas por hash en un archivo JSONL de solo anexado
+type Logger struct {
+	config   Config
+	file     *os.File
+	size     int64
+	lastSeq  int64
+	lastHash string
+	mutex    sync.Mutex
+}
+
+// NewLogger abre (o crea) el log y recupera el final de la cadena existente.
+// Si el final del log está dañado (p. ej. una línea a medio escribir tras un cierre
+// inesperado), el archivo se aparta tal cual, la cadena continúa desde la última
+// entrada válida y la primera entrada nueva deja constancia; Verify reporta la rotura.
+func NewLogger(config Config) (*Logger, error) {
+	if err := os.MkdirAll(config.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	l := &Logger{
+		config:   config,
+		lastHash: genesisHash,
+	}
+
+	// La última entrada puede estar en el archivo actual o en el último rotado
+	files, err := logFiles(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	damagedFile := ""
+	for i := len(files) - 1; i >= 0; i-- {
+		last, damaged, err := lastEntry(files[i])
+		if err != nil {
+			return nil, err
+		}
+		if damaged && damagedFile == "" {
+			damagedFile = files[i]
+		}
+		if last != nil {
+			l.lastSeq = last.Seq
+			l.lastHash = last.Hash
+			break
+		}
+	}
+
+	// Anexar al archivo dañado pegaría la entrada nueva a la línea rota
+	current := filepath.Join(config.Dir, currentLogName)
+	if damagedFile == current {
+		damagedFile = l.rotatedPath()
+		if err := os.Rename(current, damagedFile); err != nil {
+			return nil, fmt.Errorf("failed to set aside damaged audit log: %w", err)
+		}
+	}
+
+	if err := l.openCurrent(); err != nil {
+		return nil, err
+	}
+
+	if damagedFile != "" {
+		if err := l.Record(EventLogRecovered, "", "", map[string]interface{}{
+			"damaged_file":   filepath.Base(damagedFile),
+			"last_valid_seq": l.lastSeq,
+		}); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+// Record añade una entrada al log
+func (l *Logger) Record(eventType, sessionID, actor string, data map[string]interface{}) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return fmt.Errorf("audit log is closed")
+	}
+
+	entry := Entry{
+		Seq:       l.lastSeq + 1,
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Type:      eventType,
+		SessionID: sessionID,
+		Actor:     actor,
+		Data:      data,
+		PrevHash:  l.lastHash,
+	}
+
+	hash, err := entry.computeHash()
+	if err != nil {
+		return fmt.Errorf("failed to hash audit entry: %w", err)
+	}
+	entry.Hash = hash
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	if l.config.MaxFileSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.config.MaxFileSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+
+	l.lastSeq = entry.Seq
+	l.lastHash = entry.Hash
+	return nil
+}
+
+// Close cierra el archivo del log
+func (l *Logger) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Dir retorna el directorio del log
+func (l *Logger) Dir() string {
+	return l.config.Dir
+}
+
+// openCurrent abre el archivo actual en modo anexado (requiere l.mutex o construcción)
+func (l *Logger) openCurrent() error {
+	path := filepath.Join(l.config.Dir, currentLogName)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// rotate renombra el archivo actual y abre uno nuevo; la cadena continúa (requiere l.mutex)
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+
+	current := filepath.Join(l.config.Dir, currentLogName)
+	if err := os.Rename(current, l.rotatedPath()); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+
+	if l.config.MaxFiles > 0 {
+		rotatedFiles, err := rotatedLogFiles(l.config.Dir)
+		if err == nil {
+			for len(rotatedFiles) > l.config.MaxFiles {
+				os.Remove(rotatedFiles[0])
+				rotatedFiles = rotatedFiles[1:]
+			}
+		}
+	}
+
+	return l.openCurrent()
+}
+
+// rotatedPath retorna el nombre con el que se archiva el log actual
+func (l *Logger) rotatedPath() string {
+	return filepath.Join(l.config.Dir, fmt.Sprintf("%s%s-%08d.jsonl",
+		rotatedPrefix, time.Now().UTC().Format("20060102T150405"), l.lastSeq))
+}
+
+// VerifyResult resume la verificación de la cadena
+type VerifyResult struct {
+	Valid    bool   `json:"valid"`
+	Entries  int    `json:"entries"`
+	Files    int    `json:"files"`
+	FirstSeq int64  `json:"first_seq"`
+	LastSeq  int64  `json:"last_seq"`
+	BrokenAt int64  `json:"broken_at,omitempty"` // Seq de la primera entrada inválida
+	Error    string `json:"error,omitempty"`
+}
+
+// Verify recorre todos los archivos del log y comprueba secuencia, enlaces y hashes.
+// Si se borraron archivos rotados antiguos, la cadena se ancla en la primera entrada disponible.
+func Verify(dir string) (*VerifyResult, error) {
+	files, err := logFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VerifyResult{Valid: true, Files: len(files)}
+	var prev *Entry
+
+	fail := func(seq int64, format string, args ...interface{}) (*VerifyResult, error) {
+		result.Valid = false
+		result.BrokenAt = seq
+		result.Error = fmt.Sprintf(format, args...)
+		return result, nil
+	}
+
+	for _, path := range files {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		lineNum := 0
+		for scanner.Scan() {
+			lineNum++
+			var entry Entry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				file.Close()
+				seq := int64(0)
+				if prev != nil {
+					seq = prev.Seq + 1
+				}
+				return fail(seq, "%s:%d: malformed entry: %v", filepath.Base(path), lineNum, err)
+			}
+
+			hash, err := entry.computeHash()
+			if err != nil || hash != entry.Hash {
+				file.Close()
+				return fail(entry.Seq, "entry %d: hash mismatch", entry.Seq)
+			}
+
+			if prev == nil {
+				result.FirstSeq = entry.Seq
+				if entry.Seq == 1 && entry.PrevHash != genesisHash {
+					file.Close()
+					return fail(entry.Seq, "entry 1 does not start from the genesis hash")
+				}
+			} else {
+				if entry.Seq != prev.Seq+1 {
+					file.Close()
+					return fail(entry.Seq, "sequence gap: %d follows %d", entry.Seq, prev.Seq)
+				}
+				if entry.PrevHash != prev.Hash {
+					file.Close()
+					return fail(entry.Seq, "entry %d does not link to entry %d", entry.Seq, prev.Seq)
+				}
+			}
+
+			e := entry
+			prev = &e
+			result.Entries++
+			result.LastSeq = entry.Seq
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// Export escribe todas las entradas, en orden, como JSONL
+func Export(dir string, w io.Writer) error {
+	files, err := logFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		// Un archivo apartado por una línea rota no termina en salto de línea: sin él,
+		// la primera entrada del siguiente quedaría pegada a la línea rota
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			data = append(data, '\n')
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExportToFile exporta el log completo a un archivo JSONL
+func ExportToFile(dir, destPath string) error {
+	out, err := os.OpenFile(destPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	if err := Export(dir, out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// logFiles retorna los archivos rotados en orden cronológico seguidos del actual
+func logFiles(dir string) ([]string, error) {
+	files, err := rotatedLogFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	current := filepath.Join(dir, currentLogName)
+	if _, err := os.Stat(current); err == nil {
+		files = append(files, current)
+	}
+	return files, nil
+}
+
+// rotatedLogFiles retorna los archivos rotados ordenados (el nombre incluye fecha y secuencia)
+func rotatedLogFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, rotatedPrefix) && strings.HasSuffix(name, ".jsonl") {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// lastEntry lee la última entrada válida de un archivo (nil si no hay ninguna).
+// damaged indica que alguna línea no se pudo leer como entrada.
+func lastEntry(path string) (last *Entry, damaged bool, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			damaged = true
+			continue
+		}
+		last = &entry
+	}
+	if err := scanner.Err(); err != nil {
+		if err != bufio.ErrTooLong {
+			return nil, false, err
+		}
+		damaged = true
+	}
+	return last, damaged, nil
+}
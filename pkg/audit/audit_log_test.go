@@ -0,0 +1,221 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordEntries abre un logger, registra n entradas y lo cierra
+func recordEntries(t *testing.T, config Config, n int) {
+	t.Helper()
+
+	logger, err := NewLogger(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+
+	for i := 0; i < n; i++ {
+		if err := logger.Record(EventInputActivity, "session-1", "admin", map[string]interface{}{"i": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// rewriteCurrent aplica edit a las líneas del archivo actual del log
+func rewriteCurrent(t *testing.T, dir string, edit func(lines [][]byte) [][]byte) {
+	t.Helper()
+
+	path := filepath.Join(dir, currentLogName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	lines = edit(lines)
+	if err := os.WriteFile(path, append(bytes.Join(lines, []byte("\n")), '\n'), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readEntries lee todas las entradas del log en orden
+func readEntries(t *testing.T, dir string) []Entry {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := Export(dir, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []Entry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue // Línea rota
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      func(dir string) Config
+		entries     int
+		tamper      func(t *testing.T, dir string)
+		wantValid   bool
+		wantFiles   int // 0 = no se comprueba
+		wantFirst   int64
+		wantBroken  int64
+		wantErrText string
+	}{
+		{
+			name:      "single file",
+			config:    DefaultConfig,
+			entries:   5,
+			wantValid: true,
+			wantFiles: 1,
+			wantFirst: 1,
+		},
+		{
+			name: "across rotations",
+			config: func(dir string) Config {
+				// Cada entrada ocupa más de 100 bytes: una por archivo
+				return Config{Dir: dir, MaxFileSize: 100}
+			},
+			entries:   5,
+			wantValid: true,
+			wantFiles: 5,
+			wantFirst: 1,
+		},
+		{
+			name: "pruned rotations",
+			config: func(dir string) Config {
+				return Config{Dir: dir, MaxFileSize: 100, MaxFiles: 2}
+			},
+			entries:   6,
+			wantValid: true,
+			wantFiles: 3,
+			wantFirst: 4, // La cadena se ancla en la primera entrada conservada
+		},
+		{
+			name:    "tampered entry",
+			config:  DefaultConfig,
+			entries: 5,
+			tamper: func(t *testing.T, dir string) {
+				rewriteCurrent(t, dir, func(lines [][]byte) [][]byte {
+					lines[2] = bytes.Replace(lines[2], []byte(`"actor":"admin"`), []byte(`"actor":"other"`), 1)
+					return lines
+				})
+			},
+			wantBroken:  3,
+			wantErrText: "hash mismatch",
+		},
+		{
+			name:    "deleted middle entry",
+			config:  DefaultConfig,
+			entries: 5,
+			tamper: func(t *testing.T, dir string) {
+				rewriteCurrent(t, dir, func(lines [][]byte) [][]byte {
+					return append(lines[:2], lines[3:]...)
+				})
+			},
+			wantBroken:  4,
+			wantErrText: "sequence gap",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			recordEntries(t, tt.config(dir), tt.entries)
+			if tt.tamper != nil {
+				tt.tamper(t, dir)
+			}
+
+			result, err := Verify(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Valid != tt.wantValid {
+				t.Fatalf("Valid = %v, want %v (%s)", result.Valid, tt.wantValid, result.Error)
+			}
+			if tt.wantValid {
+				if result.FirstSeq != tt.wantFirst || result.LastSeq != int64(tt.entries) {
+					t.Errorf("seq range = %d..%d, want %d..%d", result.FirstSeq, result.LastSeq, tt.wantFirst, tt.entries)
+				}
+				if tt.wantFiles > 0 && result.Files != tt.wantFiles {
+					t.Errorf("Files = %d, want %d", result.Files, tt.wantFiles)
+				}
+				return
+			}
+			if result.BrokenAt != tt.wantBroken {
+				t.Errorf("BrokenAt = %d, want %d (%s)", result.BrokenAt, tt.wantBroken, result.Error)
+			}
+			if !strings.Contains(result.Error, tt.wantErrText) {
+				t.Errorf("Error = %q, want it to mention %q", result.Error, tt.wantErrText)
+			}
+		})
+	}
+}
+
+func TestNewLoggerRecoversTornLine(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultConfig(dir)
+	recordEntries(t, config, 3)
+
+	// Cierre inesperado a mitad de escribir la cuarta entrada
+	lastValid := readEntries(t, dir)[2]
+	file, err := os.OpenFile(filepath.Join(dir, currentLogName), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"seq":4,"timestamp":"2024-`)
+	file.Close()
+
+	// Al reiniciar, el archivo dañado se aparta y el log sigue aceptando entradas
+	recordEntries(t, config, 1)
+
+	entries := readEntries(t, dir)
+	if len(entries) != 5 {
+		t.Fatalf("got %d entries, want 5", len(entries))
+	}
+
+	recovered := entries[3]
+	if recovered.Type != EventLogRecovered {
+		t.Fatalf("entry 4 type = %q, want %q", recovered.Type, EventLogRecovered)
+	}
+	if recovered.Seq != lastValid.Seq+1 || recovered.PrevHash != lastValid.Hash {
+		t.Errorf("recovery entry (seq %d, prev %s) does not link to entry %d (%s)",
+			recovered.Seq, recovered.PrevHash, lastValid.Seq, lastValid.Hash)
+	}
+	if seq, _ := recovered.Data["last_valid_seq"].(float64); int64(seq) != lastValid.Seq {
+		t.Errorf("last_valid_seq = %v, want %d", recovered.Data["last_valid_seq"], lastValid.Seq)
+	}
+	damaged, _ := recovered.Data["damaged_file"].(string)
+	if !strings.HasPrefix(damaged, rotatedPrefix) {
+		t.Errorf("damaged_file = %q, want a rotated file", damaged)
+	}
+	if _, err := os.Stat(filepath.Join(dir, damaged)); err != nil {
+		t.Errorf("damaged file was not kept: %v", err)
+	}
+
+	if entries[4].Seq != recovered.Seq+1 || entries[4].PrevHash != recovered.Hash {
+		t.Error("entry after the recovery does not continue the chain")
+	}
+
+	// La rotura queda a la vista del verificador
+	result, err := Verify(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.BrokenAt != lastValid.Seq+1 || !strings.Contains(result.Error, "malformed entry") {
+		t.Errorf("Verify = %+v, want a malformed entry after seq %d", result, lastValid.Seq)
+	}
+}
@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultInputFlushInterval es cada cuánto se registra el resumen de entrada
+const DefaultInputFlushInterval = 10 * time.Second
+
+// inputWindow acumula la actividad de una sesión desde el último volcado
+type inputWindow struct {
+	counts map[string]int // "mouse.click", "keyboard.keypress"...
+	first  time.Time
+	last   time.Time
+}
+
+// InputSummarizer agrega los InputCommand por sesión y los registra como una
+// sola entrada periódica. Nunca guarda el contenido de las teclas ni del texto.
+type InputSummarizer struct {
+	logger   *Logger
+	interval time.Duration
+	windows  map[string]*inputWindow
+	mutex    sync.Mutex
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewInputSummarizer crea el agregador y arranca su volcado periódico
+func NewInputSummarizer(logger *Logger, interval time.Duration) *InputSummarizer {
+	if interval <= 0 {
+		interval = DefaultInputFlushInterval
+	}
+
+	s := &InputSummarizer{
+		logger:   logger,
+		interval: interval,
+		windows:  make(map[string]*inputWindow),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go s.flushLoop()
+	return s
+}
+
+// Observe contabiliza un comando de entrada
+func (s *InputSummarizer) Observe(sessionID, eventType, action string) {
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	window, exists := s.windows[sessionID]
+	if !exists {
+		window = &inputWindow{counts: make(map[string]int), first: now}
+		s.windows[sessionID] = window
+	}
+	window.counts[eventType+"."+action]++
+	window.last = now
+}
+
+// FlushSession registra inmediatamente la actividad pendiente de una sesión
+func (s *InputSummarizer) FlushSession(sessionID string) {
+	s.mutex.Lock()
+	window := s.windows[sessionID]
+	delete(s.windows, sessionID)
+	s.mutex.Unlock()
+
+	s.record(sessionID, window)
+}
+
+// Close vuelca toda la actividad pendiente y detiene el volcado periódico
+func (s *InputSummarizer) Close() {
+	close(s.stop)
+	<-s.done
+	s.flushAll()
+}
+
+// flushLoop vuelca los resúmenes cada intervalo
+func (s *InputSummarizer) flushLoop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.flushAll()
+		}
+	}
+}
+
+// flushAll registra y reinicia todas las ventanas
+func (s *InputSummarizer) flushAll() {
+	s.mutex.Lock()
+	windows := s.windows
+	s.windows = make(map[string]*inputWindow)
+	s.mutex.Unlock()
+
+	for sessionID, window := range windows {
+		s.record(sessionID, window)
+	}
+}
+
+// record escribe una ventana como entrada input_activity
+func (s *InputSummarizer) record(sessionID string, window *inputWindow) {
+	if window == nil || len(window.counts) == 0 {
+		return
+	}
+
+	total := 0
+	counts := make(map[string]interface{}, len(window.counts))
+	for key, count := range window.counts {
+		counts[key] = count
+		total += count
+	}
+
+	err := s.logger.Record(EventInputActivity, sessionID, "", map[string]interface{}{
+		"from":   window.first.UTC().Format(time.RFC3339Nano),
+		"to":     window.last.UTC().Format(time.RFC3339Nano),
+		"total":  total,
+		"counts": counts,
+	})
+	if err != nil {
+		log.Printf("❌ Error recording input activity for session %s: %v", sessionID, err)
+	}
+}
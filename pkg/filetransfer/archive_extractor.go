@@ -0,0 +1,299 @@
+package filetransfer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ArchiveFormat es el formato de una transferencia de directorio
+type ArchiveFormat string
+
+const (
+	ArchiveNone    ArchiveFormat = ""       // Un único archivo
+	ArchiveTar     ArchiveFormat = "tar"    // Directorio empaquetado en tar
+	ArchiveTarGzip ArchiveFormat = "tar.gz" // Directorio empaquetado en tar comprimido con gzip
+)
+
+// extractingSuffix es el sufijo del directorio donde se extrae un archivo hasta completar
+const extractingSuffix = ".extracting"
+
+// errExtractionAborted detiene la extracción de una transferencia fallida o suspendida
+var errExtractionAborted = errors.New("extraction aborted")
+
+// ArchiveEntryProgress describe una entrada ya extraída de una transferencia de directorio
+type ArchiveEntryProgress struct {
+	TransferID     string
+	SessionID      string
+	EntryName      string // Ruta relativa saneada dentro del directorio
+	EntrySize      int64
+	IsDirectory    bool
+	EntriesDone    int
+	BytesExtracted int64
+}
+
+// parseArchiveFormat interpreta el formato enviado por el servidor
+func parseArchiveFormat(format string) (ArchiveFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "":
+		return ArchiveNone, nil
+	case "tar":
+		return ArchiveTar, nil
+	case "tar.gz", "tgz", "gzip":
+		return ArchiveTarGzip, nil
+	default:
+		return ArchiveNone, fmt.Errorf("unsupported archive format: %s", format)
+	}
+}
+
+// archiveDirName deriva el nombre del directorio destino del nombre del archivo empaquetado
+func archiveDirName(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar"} {
+		if strings.HasSuffix(lower, ext) && len(name) > len(ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// extractingPathFor retorna el directorio de extracción de una transferencia de directorio
+func extractingPathFor(stagingPath string) string {
+	return stagingPath + extractingSuffix
+}
+
+// archiveExtractor extrae un tar a medida que llegan sus bytes en orden.
+// Todo se escribe en un directorio de extracción que solo se publica al completar,
+// así que una transferencia fallida se revierte borrando ese directorio.
+type archiveExtractor struct {
+	dir    string
+	format ArchiveFormat
+	policy *TransferPolicy
+	writer *io.PipeWriter
+	done   chan struct{}
+
+	// Resultado de la extracción; solo se leen tras cerrar done
+	err     error
+	entries int
+	bytes   int64
+
+	onEntry func(entry ArchiveEntryProgress)
+}
+
+// newArchiveExtractor crea el directorio de extracción y arranca la extracción
+func newArchiveExtractor(format ArchiveFormat, dir string, policy *TransferPolicy, onEntry func(entry ArchiveEntryProgress)) (*archiveExtractor, error) {
+	// Una extracción anterior (por ejemplo, antes de un reinicio) se descarta
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to clear extraction directory: %w", err)
+	}
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create extraction directory: %w", err)
+	}
+
+	reader, writer := io.Pipe()
+	e := &archiveExtractor{
+		dir:     dir,
+		format:  format,
+		policy:  policy,
+		writer:  writer,
+		done:    make(chan struct{}),
+		onEntry: onEntry,
+	}
+	go e.run(reader)
+	return e, nil
+}
+
+// Write entrega los siguientes bytes del archivo empaquetado; bloquea hasta que se procesan
+func (e *archiveExtractor) Write(data []byte) (int, error) {
+	return e.writer.Write(data)
+}
+
+// finish indica que no hay más datos y espera a que termine la extracción
+func (e *archiveExtractor) finish() error {
+	e.writer.Close()
+	<-e.done
+	return e.err
+}
+
+// abort detiene la extracción; el directorio de extracción queda para que se elimine
+func (e *archiveExtractor) abort() {
+	e.writer.CloseWithError(errExtractionAborted)
+	<-e.done
+}
+
+// run extrae el flujo y descarta el relleno que sigue al final del tar
+func (e *archiveExtractor) run(reader *io.PipeReader) {
+	defer close(e.done)
+
+	err := e.extract(reader)
+	if err == nil {
+		_, err = io.Copy(io.Discard, reader)
+	}
+	if err != nil {
+		// Los siguientes Write fallan con este error en lugar de bloquear
+		reader.CloseWithError(err)
+		e.err = err
+	}
+}
+
+// extract recorre las entradas del tar y las escribe bajo e.dir
+func (e *archiveExtractor) extract(source io.Reader) error {
+	if e.format == ArchiveTarGzip {
+		gz, err := gzip.NewReader(source)
+		if err != nil {
+			return fmt.Errorf("invalid gzip stream: %w", err)
+		}
+		defer gz.Close()
+		source = gz
+	}
+
+	tr := tar.NewReader(source)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid archive: %w", err)
+		}
+
+		// La entrada raíz ("./") no crea nada
+		if strings.Trim(header.Name, `./\`) == "" {
+			continue
+		}
+
+		// Mismas reglas que DestinationPath: relativo, sin "..", componentes saneados
+		relative, err := cleanRelativePath(header.Name)
+		if err != nil {
+			return fmt.Errorf("unsafe archive entry %q: %w", header.Name, err)
+		}
+		target := filepath.Join(e.dir, relative)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", relative, err)
+			}
+
+		case tar.TypeReg:
+			// La política se aplica a cada archivo y al total extraído (evita bombas gzip)
+			if err := e.policy.CheckExtension(relative); err != nil {
+				return err
+			}
+			if err := e.policy.CheckSize(e.bytes + header.Size); err != nil {
+				return err
+			}
+			if err := e.policy.CheckFreeSpace(e.dir, header.Size); err != nil {
+				return err
+			}
+
+			checkContent := func(head []byte) error {
+				return e.policy.CheckContent(relative, head)
+			}
+			if err := extractArchiveFile(target, tr, header, checkContent); err != nil {
+				return fmt.Errorf("failed to extract %s: %w", relative, err)
+			}
+			e.bytes += header.Size
+
+		default:
+			// Enlaces y dispositivos podrían apuntar fuera del destino: nunca se crean
+			fmt.Printf("⚠️ Skipping archive entry %s (type %q)\n", header.Name, header.Typeflag)
+			continue
+		}
+
+		e.entries++
+		if e.onEntry != nil {
+			e.onEntry(ArchiveEntryProgress{
+				EntryName:      filepath.ToSlash(relative),
+				EntrySize:      header.Size,
+				IsDirectory:    header.Typeflag == tar.TypeDir,
+				EntriesDone:    e.entries,
+				BytesExtracted: e.bytes,
+			})
+		}
+	}
+}
+
+// extractArchiveFile escribe una entrada regular conservando solo el bit de ejecución.
+// checkContent recibe los primeros bytes antes de crear el archivo.
+func extractArchiveFile(target string, source io.Reader, header *tar.Header, checkContent func(head []byte) error) error {
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(source, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	head = head[:n]
+	if err := checkContent(head); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if header.Mode&0111 != 0 {
+		mode = 0755
+	}
+
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	written, err := io.Copy(file, io.MultiReader(bytes.NewReader(head), source))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written != header.Size {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// attachExtractor arranca la extracción de una transferencia de directorio
+func (fta *FileTransferAgent) attachExtractor(t *FileTransfer) error {
+	if t.archiveFormat == ArchiveNone {
+		return nil
+	}
+
+	onEntry := func(entry ArchiveEntryProgress) {
+		entry.TransferID = t.TransferID
+		entry.SessionID = t.SessionID
+		if fta.onArchiveEntry != nil {
+			fta.onArchiveEntry(entry)
+		}
+	}
+
+	extractor, err := newArchiveExtractor(t.archiveFormat, extractingPathFor(t.stagingPath), t.policy, onEntry)
+	if err != nil {
+		return err
+	}
+	t.extractor = extractor
+	return nil
+}
+
+// publish termina la extracción (si la hay), mueve el resultado completo con place,
+// que recibe su ruta actual, y retorna las entradas extraídas
+func (t *FileTransfer) publish(place func(source string) error) (int, error) {
+	if t.extractor == nil {
+		return 0, place(partPathFor(t.stagingPath))
+	}
+
+	extractor := t.extractor
+	t.extractor = nil
+	if err := extractor.finish(); err != nil {
+		return 0, fmt.Errorf("extraction failed: %w", err)
+	}
+
+	if err := place(extractor.dir); err != nil {
+		return 0, err
+	}
+	return extractor.entries, nil
+}
@@ -0,0 +1,235 @@
+package filetransfer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// archiveTestEntry es una entrada del tar que se construye para cada caso
+type archiveTestEntry struct {
+	name     string
+	typeflag byte
+	body     string
+	linkname string
+}
+
+// buildTar empaqueta las entradas tal cual, sin normalizar sus nombres
+func buildTar(t *testing.T, entries []archiveTestEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.name,
+			Typeflag: entry.typeflag,
+			Linkname: entry.linkname,
+			Mode:     0644,
+			Size:     int64(len(entry.body)),
+		}
+		if entry.typeflag != tar.TypeReg {
+			header.Size = 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("write header %q: %v", entry.name, err)
+		}
+		if header.Size > 0 {
+			if _, err := tw.Write([]byte(entry.body)); err != nil {
+				t.Fatalf("write body %q: %v", entry.name, err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// extractTestArchive extrae data en <root>/out.extracting y retorna el error de la extracción
+func extractTestArchive(t *testing.T, root string, format ArchiveFormat, data []byte) (string, error) {
+	t.Helper()
+
+	policy := NewTransferPolicy(Config{})
+	policy.freeSpace = func(string) (uint64, error) { return 1 << 40, nil }
+
+	dir := extractingPathFor(filepath.Join(root, "out"))
+	extractor, err := newArchiveExtractor(format, dir, policy, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Si la extracción falla a mitad, Write retorna el mismo error que finish
+	extractor.Write(data)
+	return dir, extractor.finish()
+}
+
+func TestArchiveExtractorEntries(t *testing.T) {
+	outside := t.TempDir()
+	escapeTarget := filepath.Join(outside, "pwned.txt")
+
+	tests := []struct {
+		name    string
+		entries []archiveTestEntry
+		wantErr bool
+		files   []string // Deben existir como archivos dentro del destino
+		dirs    []string // Deben existir como directorios reales (no enlaces)
+		absent  []string // No deben existir dentro del destino
+	}{
+		{
+			name: "regular tree",
+			entries: []archiveTestEntry{
+				{name: "./", typeflag: tar.TypeDir},
+				{name: "docs/", typeflag: tar.TypeDir},
+				{name: "docs/readme.txt", typeflag: tar.TypeReg, body: "hello"},
+				{name: "src/main.go", typeflag: tar.TypeReg, body: "package main"},
+			},
+			files: []string{"docs/readme.txt", "src/main.go"},
+			dirs:  []string{"docs", "src"},
+		},
+		{
+			name:    "parent traversal",
+			entries: []archiveTestEntry{{name: "../pwned.txt", typeflag: tar.TypeReg, body: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "nested traversal",
+			entries: []archiveTestEntry{{name: "docs/../../pwned.txt", typeflag: tar.TypeReg, body: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "windows traversal",
+			entries: []archiveTestEntry{{name: `docs\..\..\pwned.txt`, typeflag: tar.TypeReg, body: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "absolute path",
+			entries: []archiveTestEntry{{name: escapeTarget, typeflag: tar.TypeReg, body: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "windows absolute path",
+			entries: []archiveTestEntry{{name: `\pwned.txt`, typeflag: tar.TypeReg, body: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "reserved name",
+			entries: []archiveTestEntry{{name: "docs/CON.txt", typeflag: tar.TypeReg, body: "x"}},
+			files:   []string{"docs/_CON.txt"},
+			absent:  []string{"docs/CON.txt"},
+		},
+		{
+			name: "symlink then file through it",
+			entries: []archiveTestEntry{
+				{name: "link", typeflag: tar.TypeSymlink, linkname: outside},
+				{name: "link/pwned.txt", typeflag: tar.TypeReg, body: "x"},
+			},
+			files: []string{"link/pwned.txt"},
+			dirs:  []string{"link"},
+		},
+		{
+			name: "relative symlink",
+			entries: []archiveTestEntry{
+				{name: "up", typeflag: tar.TypeSymlink, linkname: "../.."},
+			},
+			absent: []string{"up"},
+		},
+		{
+			name: "hardlink",
+			entries: []archiveTestEntry{
+				{name: "hard", typeflag: tar.TypeLink, linkname: escapeTarget},
+				{name: "hard2", typeflag: tar.TypeLink, linkname: "../pwned.txt"},
+			},
+			absent: []string{"hard", "hard2"},
+		},
+		{
+			name: "file then directory",
+			entries: []archiveTestEntry{
+				{name: "docs", typeflag: tar.TypeReg, body: "x"},
+				{name: "docs/", typeflag: tar.TypeDir},
+			},
+			wantErr: true,
+		},
+		{
+			name: "file then file inside it",
+			entries: []archiveTestEntry{
+				{name: "docs", typeflag: tar.TypeReg, body: "x"},
+				{name: "docs/readme.txt", typeflag: tar.TypeReg, body: "y"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "directory then file",
+			entries: []archiveTestEntry{
+				{name: "docs/", typeflag: tar.TypeDir},
+				{name: "docs", typeflag: tar.TypeReg, body: "x"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir, err := extractTestArchive(t, root, ArchiveTar, buildTar(t, tt.entries))
+
+			if tt.wantErr && err == nil {
+				t.Error("expected the extraction to fail")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("extraction failed: %v", err)
+			}
+
+			// Nada puede escribirse fuera del directorio de extracción
+			if _, err := os.Lstat(escapeTarget); err == nil {
+				t.Fatalf("archive wrote outside the destination: %s", escapeTarget)
+			}
+			rootEntries, _ := os.ReadDir(root)
+			for _, entry := range rootEntries {
+				if entry.Name() != filepath.Base(dir) {
+					t.Errorf("archive wrote %s next to the destination", entry.Name())
+				}
+			}
+
+			for _, name := range tt.files {
+				info, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(name)))
+				if err != nil || !info.Mode().IsRegular() {
+					t.Errorf("%s: expected a regular file (%v)", name, err)
+				}
+			}
+			for _, name := range tt.dirs {
+				info, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(name)))
+				if err != nil || !info.IsDir() {
+					t.Errorf("%s: expected a real directory (%v)", name, err)
+				}
+			}
+			for _, name := range tt.absent {
+				if _, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+					t.Errorf("%s: expected no entry", name)
+				}
+			}
+		})
+	}
+}
+
+func TestArchiveExtractorGzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(buildTar(t, []archiveTestEntry{
+		{name: "a/b.txt", typeflag: tar.TypeReg, body: "compressed"},
+	}))
+	gz.Close()
+
+	dir, err := extractTestArchive(t, t.TempDir(), ArchiveTarGzip, buf.Bytes())
+	if err != nil {
+		t.Fatalf("extraction failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "a", "b.txt"))
+	if err != nil || string(data) != "compressed" {
+		t.Errorf("extracted content = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,113 @@
+package filetransfer
+
+import (
+	"fmt"
+	"math/bits"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// ChunkBitmap registra qué índices de chunk se han recibido usando un bit por chunk
+type ChunkBitmap struct {
+	words []uint64
+	total int
+	count int
+}
+
+// NewChunkBitmap crea un bitmap vacío para total chunks
+func NewChunkBitmap(total int) *ChunkBitmap {
+	return &ChunkBitmap{
+		words: make([]uint64, (total+63)/64),
+		total: total,
+	}
+}
+
+// Set marca un índice como recibido; retorna false si ya lo estaba o está fuera de rango
+func (b *ChunkBitmap) Set(index int) bool {
+	if index < 0 || index >= b.total {
+		return false
+	}
+
+	word, mask := index/64, uint64(1)<<(uint(index)%64)
+	if b.words[word]&mask != 0 {
+		return false
+	}
+
+	b.words[word] |= mask
+	b.count++
+	return true
+}
+
+// Has indica si un índice ya fue recibido
+func (b *ChunkBitmap) Has(index int) bool {
+	if index < 0 || index >= b.total {
+		return false
+	}
+	return b.words[index/64]&(uint64(1)<<(uint(index)%64)) != 0
+}
+
+// Count retorna el número de chunks recibidos
+func (b *ChunkBitmap) Count() int {
+	return b.count
+}
+
+// Total retorna el número de chunks esperados
+func (b *ChunkBitmap) Total() int {
+	return b.total
+}
+
+// Complete indica si se recibieron todos los chunks
+func (b *ChunkBitmap) Complete() bool {
+	return b.count == b.total
+}
+
+// Words retorna una copia de los bits para persistirlos
+func (b *ChunkBitmap) Words() []uint64 {
+	words := make([]uint64, len(b.words))
+	copy(words, b.words)
+	return words
+}
+
+// ChunkBitmapFromWords reconstruye un bitmap persistido con Words
+func ChunkBitmapFromWords(total int, words []uint64) (*ChunkBitmap, error) {
+	if len(words) != (total+63)/64 {
+		return nil, fmt.Errorf("bitmap has %d words, expected %d for %d chunks", len(words), (total+63)/64, total)
+	}
+
+	b := &ChunkBitmap{words: make([]uint64, len(words)), total: total}
+	copy(b.words, words)
+
+	// Descartar bits más allá del último chunk
+	if rem := total % 64; rem != 0 {
+		b.words[len(b.words)-1] &= (uint64(1) << uint(rem)) - 1
+	}
+
+	for _, word := range b.words {
+		b.count += bits.OnesCount64(word)
+	}
+	return b, nil
+}
+
+// MissingRanges retorna los rangos de índices (inclusivos) que faltan por recibir
+func (b *ChunkBitmap) MissingRanges() []api.ChunkRange {
+	var ranges []api.ChunkRange
+
+	start := -1
+	for i := 0; i < b.total; i++ {
+		if !b.Has(i) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			ranges = append(ranges, api.ChunkRange{Start: start, End: i - 1})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		ranges = append(ranges, api.ChunkRange{Start: start, End: b.total - 1})
+	}
+
+	return ranges
+}
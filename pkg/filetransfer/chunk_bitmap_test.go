@@ -0,0 +1,131 @@
+package filetransfer
+
+import (
+	"reflect"
+	"testing"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+func TestChunkBitmapWordBoundaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int
+		set     []int
+		missing []api.ChunkRange
+	}{
+		{name: "63 chunks, last bit of the word", total: 63, set: []int{62}, missing: []api.ChunkRange{{Start: 0, End: 61}}},
+		{name: "63 chunks, all", total: 63, set: seq(0, 62)},
+		{name: "64 chunks, last bit of the word", total: 64, set: []int{63}, missing: []api.ChunkRange{{Start: 0, End: 62}}},
+		{name: "64 chunks, all", total: 64, set: seq(0, 63)},
+		{name: "65 chunks, first bit of the second word", total: 65, set: []int{64}, missing: []api.ChunkRange{{Start: 0, End: 63}}},
+		{name: "65 chunks, across the word boundary", total: 65, set: []int{0, 63, 64}, missing: []api.ChunkRange{{Start: 1, End: 62}}},
+		{name: "65 chunks, all but the boundary", total: 65, set: append(seq(0, 62), 64), missing: []api.ChunkRange{{Start: 63, End: 63}}},
+		{name: "65 chunks, all", total: 65, set: seq(0, 64)},
+		{name: "65 chunks, none", total: 65, missing: []api.ChunkRange{{Start: 0, End: 64}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewChunkBitmap(tt.total)
+			for _, index := range tt.set {
+				if !b.Set(index) {
+					t.Fatalf("Set(%d) = false on first set", index)
+				}
+			}
+
+			for _, index := range tt.set {
+				if b.Set(index) {
+					t.Errorf("Set(%d) = true on second set", index)
+				}
+				if !b.Has(index) {
+					t.Errorf("Has(%d) = false after Set", index)
+				}
+			}
+			for _, r := range tt.missing {
+				for index := r.Start; index <= r.End; index++ {
+					if b.Has(index) {
+						t.Errorf("Has(%d) = true, never set", index)
+					}
+				}
+			}
+
+			if b.Count() != len(tt.set) {
+				t.Errorf("Count() = %d, want %d", b.Count(), len(tt.set))
+			}
+			if b.Complete() != (len(tt.set) == tt.total) {
+				t.Errorf("Complete() = %v with %d of %d chunks", b.Complete(), len(tt.set), tt.total)
+			}
+			if got := b.MissingRanges(); !reflect.DeepEqual(got, tt.missing) {
+				t.Errorf("MissingRanges() = %v, want %v", got, tt.missing)
+			}
+		})
+	}
+}
+
+func TestChunkBitmapRejectsOutOfRange(t *testing.T) {
+	b := NewChunkBitmap(64)
+	for _, index := range []int{-1, 64, 65, 128} {
+		if b.Set(index) {
+			t.Errorf("Set(%d) = true on a 64-chunk bitmap", index)
+		}
+		if b.Has(index) {
+			t.Errorf("Has(%d) = true on a 64-chunk bitmap", index)
+		}
+	}
+	if b.Count() != 0 {
+		t.Errorf("Count() = %d after out-of-range sets", b.Count())
+	}
+}
+
+func TestChunkBitmapFromWords(t *testing.T) {
+	tests := []struct {
+		name      string
+		total     int
+		words     []uint64
+		wantCount int
+		wantWords []uint64
+		wantErr   bool
+	}{
+		{name: "63 chunks masks the top bit", total: 63, words: []uint64{^uint64(0)}, wantCount: 63, wantWords: []uint64{^uint64(0) >> 1}},
+		{name: "64 chunks keeps every bit", total: 64, words: []uint64{^uint64(0)}, wantCount: 64, wantWords: []uint64{^uint64(0)}},
+		{name: "65 chunks masks the second word", total: 65, words: []uint64{0, ^uint64(0)}, wantCount: 1, wantWords: []uint64{0, 1}},
+		{name: "stray bits only", total: 3, words: []uint64{0xF8}, wantCount: 0, wantWords: []uint64{0}},
+		{name: "too few words", total: 65, words: []uint64{0}, wantErr: true},
+		{name: "too many words", total: 64, words: []uint64{0, 0}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := ChunkBitmapFromWords(tt.total, tt.words)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ChunkBitmapFromWords(%d, %v) succeeded, want error", tt.total, tt.words)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ChunkBitmapFromWords(%d, %v) error: %v", tt.total, tt.words, err)
+			}
+
+			if b.Count() != tt.wantCount {
+				t.Errorf("Count() = %d, want %d", b.Count(), tt.wantCount)
+			}
+			if got := b.Words(); !reflect.DeepEqual(got, tt.wantWords) {
+				t.Errorf("Words() = %#x, want %#x", got, tt.wantWords)
+			}
+			if b.Has(tt.total) {
+				t.Errorf("Has(%d) = true past the last chunk", tt.total)
+			}
+		})
+	}
+}
+
+// seq retorna los enteros de from a to, ambos incluidos
+func seq(from, to int) []int {
+	var out []int
+	for i := from; i <= to; i++ {
+		out = append(out, i)
+	}
+	return out
+}
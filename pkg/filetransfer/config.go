@@ -0,0 +1,205 @@
+package filetransfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CollisionPolicy decide qué hacer cuando ya existe un archivo con el mismo nombre
+type CollisionPolicy string
+
+const (
+	CollisionRename    CollisionPolicy = "rename"    // Guardar como "nombre (1).ext"
+	CollisionOverwrite CollisionPolicy = "overwrite" // Reemplazar el existente al completar
+	CollisionReject    CollisionPolicy = "reject"    // Rechazar la transferencia
+)
+
+// Config es la configuración de recepción de archivos
+type Config struct {
+	CollisionPolicy CollisionPolicy `json:"collision_policy"`
+
+	// Subcarpetas (relativas al directorio de descarga) en las que el servidor
+	// puede dejar archivos mediante DestinationPath; vacío = solo la raíz
+	AllowedSubdirectories []string `json:"allowed_subdirectories"`
+
+	// Tamaño máximo por transferencia en MB (0 = sin límite); en carpetas cuenta lo extraído
+	MaxFileSizeMB float64 `json:"max_file_size_mb"`
+
+	// Espacio en MB que debe quedar libre en el disco después de recibir
+	MinFreeSpaceMB float64 `json:"min_free_space_mb"`
+
+	// Extensiones (".exe" o "exe") permitidas y bloqueadas; sin permitidas se aceptan
+	// todas salvo las bloqueadas
+	AllowedExtensions []string `json:"allowed_extensions"`
+	BlockedExtensions []string `json:"blocked_extensions"`
+
+	// Tipos MIME detectados por contenido ("image/*" admite toda la familia)
+	AllowedMIMETypes []string `json:"allowed_mime_types"`
+	BlockedMIMETypes []string `json:"blocked_mime_types"`
+
+	// Transferencias que se aceptan sin preguntar: "never" (predeterminado), "trusted"
+	// (sesiones de administradores de confianza) o "always"
+	IncomingAutoAccept IncomingAutoAccept `json:"incoming_auto_accept,omitempty"`
+
+	// Segundos que se espera la decisión del usuario sobre una transferencia entrante (0 = 60)
+	IncomingPromptTimeoutSeconds int `json:"incoming_prompt_timeout_seconds,omitempty"`
+
+	// Segundos sin recibir chunks tras los que una transferencia se cancela (0 = 120)
+	TransferIdleTimeoutSeconds int `json:"transfer_idle_timeout_seconds,omitempty"`
+
+	// Directorio (ruta absoluta) donde se reciben y escanean los archivos antes de
+	// publicarlos; vacío = ~/.escritorio-remoto/quarantine. Conviene que esté fuera
+	// del directorio de descarga; en otro disco la publicación copia en lugar de renombrar.
+	QuarantineDir string `json:"quarantine_dir,omitempty"`
+
+	// Escáneres de cuarentena; sin ninguno los archivos se publican directamente
+	Scan ScanConfig `json:"scan"`
+
+	// Directorios (rutas absolutas) de los que el servidor puede pedir archivos;
+	// vacío = no se permite ninguna subida
+	UploadDirectories []string `json:"upload_directories"`
+
+	// Tamaño de chunk de las subidas en bytes (0 = DefaultUploadChunkSize)
+	UploadChunkSize int `json:"upload_chunk_size,omitempty"`
+
+	// Segundos que se espera la respuesta del usuario a una subida (0 = 60)
+	UploadPromptTimeoutSeconds int `json:"upload_prompt_timeout_seconds,omitempty"`
+}
+
+const (
+	// DefaultUploadChunkSize mantiene cada mensaje por debajo de ~350 KB en base64
+	DefaultUploadChunkSize = 256 * 1024
+	maxUploadChunkSize     = 4 * 1024 * 1024
+
+	defaultUploadPromptTimeout   = 60 * time.Second
+	defaultIncomingPromptTimeout = 60 * time.Second
+	defaultTransferIdleTimeout   = 120 * time.Second
+)
+
+// DefaultConfig renombra en caso de colisión, solo usa la raíz de descargas, limita
+// cada transferencia a 4 GB y reserva 512 MB de disco
+func DefaultConfig() Config {
+	return Config{
+		CollisionPolicy: CollisionRename,
+		MaxFileSizeMB:   4096,
+		MinFreeSpaceMB:  512,
+	}
+}
+
+// LoadConfig lee la configuración de un archivo JSON; si no existe retorna la predeterminada
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return config, fmt.Errorf("failed to read file transfer config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return DefaultConfig(), fmt.Errorf("invalid file transfer config %s: %w", path, err)
+	}
+
+	if err := config.validate(); err != nil {
+		return DefaultConfig(), err
+	}
+	return config, nil
+}
+
+// validate comprueba los valores que no se pueden aplicar
+func (c Config) validate() error {
+	switch c.CollisionPolicy {
+	case "", CollisionRename, CollisionOverwrite, CollisionReject:
+	default:
+		return fmt.Errorf("invalid collision_policy: %s", c.CollisionPolicy)
+	}
+
+	for _, dir := range c.AllowedSubdirectories {
+		if _, err := cleanRelativePath(dir); err != nil {
+			return fmt.Errorf("invalid allowed subdirectory: %w", err)
+		}
+	}
+
+	if c.MaxFileSizeMB < 0 || c.MinFreeSpaceMB < 0 {
+		return fmt.Errorf("max_file_size_mb and min_free_space_mb cannot be negative")
+	}
+
+	for _, mimeType := range append(append([]string{}, c.AllowedMIMETypes...), c.BlockedMIMETypes...) {
+		if !strings.Contains(mimeType, "/") {
+			return fmt.Errorf("invalid MIME type: %q", mimeType)
+		}
+	}
+
+	switch c.IncomingAutoAccept {
+	case "", IncomingAutoAcceptNever, IncomingAutoAcceptTrusted, IncomingAutoAcceptAlways:
+	default:
+		return fmt.Errorf("invalid incoming_auto_accept: %s", c.IncomingAutoAccept)
+	}
+	if c.IncomingPromptTimeoutSeconds < 0 {
+		return fmt.Errorf("incoming_prompt_timeout_seconds cannot be negative")
+	}
+
+	if c.TransferIdleTimeoutSeconds < 0 {
+		return fmt.Errorf("transfer_idle_timeout_seconds cannot be negative")
+	}
+
+	if c.QuarantineDir != "" && !filepath.IsAbs(c.QuarantineDir) {
+		return fmt.Errorf("quarantine_dir %q must be an absolute path", c.QuarantineDir)
+	}
+	if c.Scan.TimeoutSeconds < 0 {
+		return fmt.Errorf("scan timeout_seconds cannot be negative")
+	}
+
+	for _, dir := range c.UploadDirectories {
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("upload directory %q must be an absolute path", dir)
+		}
+	}
+
+	if c.UploadChunkSize < 0 || c.UploadChunkSize > maxUploadChunkSize {
+		return fmt.Errorf("upload_chunk_size must be between 1 and %d bytes", maxUploadChunkSize)
+	}
+	if c.UploadPromptTimeoutSeconds < 0 {
+		return fmt.Errorf("upload_prompt_timeout_seconds cannot be negative")
+	}
+
+	return nil
+}
+
+// uploadChunkSize retorna el tamaño de chunk efectivo para las subidas
+func (c Config) uploadChunkSize() int {
+	if c.UploadChunkSize > 0 {
+		return c.UploadChunkSize
+	}
+	return DefaultUploadChunkSize
+}
+
+// UploadPromptTimeout retorna cuánto se espera la decisión del usuario sobre una subida
+func (c Config) UploadPromptTimeout() time.Duration {
+	if c.UploadPromptTimeoutSeconds > 0 {
+		return time.Duration(c.UploadPromptTimeoutSeconds) * time.Second
+	}
+	return defaultUploadPromptTimeout
+}
+
+// IncomingPromptTimeout retorna cuánto se espera la decisión del usuario sobre una transferencia entrante
+func (c Config) IncomingPromptTimeout() time.Duration {
+	if c.IncomingPromptTimeoutSeconds > 0 {
+		return time.Duration(c.IncomingPromptTimeoutSeconds) * time.Second
+	}
+	return defaultIncomingPromptTimeout
+}
+
+// transferIdleTimeout retorna cuánto puede estar una transferencia sin recibir chunks
+func (c Config) transferIdleTimeout() time.Duration {
+	if c.TransferIdleTimeoutSeconds > 0 {
+		return time.Duration(c.TransferIdleTimeoutSeconds) * time.Second
+	}
+	return defaultTransferIdleTimeout
+}
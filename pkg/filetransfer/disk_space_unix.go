@@ -0,0 +1,23 @@
+//go:build !windows
+
+package filetransfer
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// freeDiskSpace retorna los bytes disponibles para el usuario en el sistema de archivos de dir
+func freeDiskSpace(dir string) (uint64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
+
+// isCrossDevice indica si un rename falló porque origen y destino están en discos distintos
+func isCrossDevice(err error) bool {
+	return errors.Is(err, unix.EXDEV)
+}
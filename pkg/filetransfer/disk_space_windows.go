@@ -0,0 +1,28 @@
+//go:build windows
+
+package filetransfer
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// freeDiskSpace retorna los bytes disponibles para el usuario en el volumen de dir
+func freeDiskSpace(dir string) (uint64, error) {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var available uint64
+	if err := windows.GetDiskFreeSpaceEx(path, &available, nil, nil); err != nil {
+		return 0, err
+	}
+	return available, nil
+}
+
+// isCrossDevice indica si un rename falló porque origen y destino están en volúmenes distintos
+func isCrossDevice(err error) bool {
+	return errors.Is(err, windows.ERROR_NOT_SAME_DEVICE)
+}
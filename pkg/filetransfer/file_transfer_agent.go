@@ -1,13 +1,13 @@
 package filetransfer
 
 import (
-	"crypto/md5"
 	"encoding/base64"
 	"fmt"
 	"hash"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"EscritorioRemoto-Cliente/pkg/api"
@@ -15,15 +15,77 @@ import (
 
 // FileTransferAgent maneja la recepción de archivos desde el servidor
 type FileTransferAgent struct {
-	// Archivos en progreso de recepción
+	// Archivos en progreso de recepción. El mutex protege solo los registros y la
+	// configuración; cada transferencia procesa sus chunks en su propio worker.
 	activeTransfers map[string]*FileTransfer
 	mutex           sync.RWMutex
 
 	// Directorio base para recibir archivos
 	downloadDir string
 
+	// Configuración de recepción (nombres, colisiones, subcarpetas permitidas)
+	config Config
+
+	// Política de espacio, tamaño y tipos de archivo derivada de config
+	policy *TransferPolicy
+
+	// Escáner de cuarentena (nil = los archivos se publican sin escanear)
+	scanner Scanner
+
+	// Transferencias completas que se están escaneando: ID → ruta final reservada
+	scanning map[string]string
+
+	// Búsqueda periódica de transferencias sin actividad (se detiene con Close)
+	stopReaper chan struct{}
+	reaperDone chan struct{}
+	stopOnce   sync.Once
+
+	// Transferencias interrumpidas (desconexión o reinicio) que se pueden reanudar
+	suspended map[string]*transferManifest
+
+	// Solicitudes que esperan la decisión del usuario
+	incoming map[string]*pendingIncoming
+
+	// Indica si una sesión es de un administrador de confianza (nil = ninguna lo es)
+	isTrustedSession func(sessionID string) bool
+
+	// Callback para preguntar al usuario por una transferencia entrante
+	onIncomingTransfer func(incoming IncomingTransfer)
+
 	// Callback para notificar al app sobre el estado de transferencia
-	onTransferCompleted func(transferID, fileName, filePath string, success bool, errorMsg string)
+	onTransferCompleted func(result TransferResult)
+
+	// Callback para pedir al servidor los chunks que faltan de una transferencia reanudada
+	onTransferResumed func(resume api.FileTransferResume)
+
+	// Callback para pedir el reenvío de un chunk que no pasó la verificación
+	onChunkRetransmit func(retransmit api.FileChunkRetransmit)
+
+	// Callback de progreso por entrada en transferencias de directorio
+	onArchiveEntry func(entry ArchiveEntryProgress)
+}
+
+// TransferResult describe el resultado final de una transferencia
+type TransferResult struct {
+	TransferID   string
+	SessionID    string
+	FileName     string
+	FilePath     string // Vacío si la transferencia falló
+	SizeBytes    int64
+	Checksum     string // Hex del hash del contenido recibido
+	ChecksumAlgo string
+	Duration     time.Duration
+	Success      bool
+	ErrorMessage string
+	ReasonCode   ReasonCode // Solo en rechazos de la política o del escaneo
+
+	// Resultado del escaneo; QuarantinePath solo si el archivo quedó retenido en cuarentena
+	ScanStatus     string
+	QuarantinePath string
+
+	// Transferencias de directorio: FilePath es la carpeta extraída
+	IsDirectory bool
+	Entries     int
 }
 
 // FileTransfer representa una transferencia de archivo en progreso
@@ -36,16 +98,71 @@ type FileTransfer struct {
 	DestinationPath string
 
 	// Estado actual
-	ReceivedChunks map[int][]byte
-	ChunksReceived int
-	StartTime      time.Time
+	StartTime time.Time
+
+	// Chunks escritos y última actividad (UnixNano); se leen desde otros goroutines
+	chunksReceived atomic.Int64
+	lastActivity   atomic.Int64
+	lastDequeue    atomic.Int64 // Cuándo tomó el worker el último chunk de la cola
+
+	// Sin chunks durante idleTimeout la transferencia se da por abandonada
+	idleTimeout time.Duration
+
+	// Worker de la transferencia: recibe los chunks por queue y cierra done al terminar
+	queue      chan api.FileChunk
+	stopSignal chan struct{}
+	done       chan struct{}
+	stopOnce   sync.Once
+	stopKind   transferStop
+	stopCode   ReasonCode
+	stopReason string
+	finished   bool // Completada o fallida; solo la usa el worker
+
+	// Estado guardado al suspender, para reanudar más tarde
+	suspendedManifest *transferManifest
+	resumed           bool // Reabierta desde un manifiesto: el worker pone al día el hash al arrancar
+
+	// Chunks recibidos (un bit por índice; la memoria no depende del tamaño del archivo)
+	received *ChunkBitmap
+
+	// Tamaño de todos los chunks salvo el último; 0 hasta conocerlo
+	chunkSize    int64
+	expectedSize int64 // 0 si el servidor no lo informa
+
+	// SHA-256 esperado del archivo completo (vacío si el servidor no lo informa)
+	expectedChecksum string
+
+	// Transferencias de directorio: el .part es un tar que se extrae según llega
+	archiveFormat ArchiveFormat
+	extractor     *archiveExtractor
+
+	// Política vigente y motivo del rechazo si la transferencia la incumple
+	policy     *TransferPolicy
+	reasonCode ReasonCode
+
+	// Reenvíos solicitados por chunk
+	retransmits map[int]int
+
+	lastChunkSize int64
+
+	// Último chunk recibido antes de conocer chunkSize (no se puede ubicar aún)
+	pendingLastChunk []byte
+
+	// Hash del prefijo contiguo de chunks; su estado se persiste en el manifiesto
+	hasher       hash.Hash
+	hashedChunks int
+
+	// Persistencia del manifiesto
+	chunksSinceSave  int
+	lastManifestSave time.Time
 
-	// Archivo de escritura
+	// Archivo de escritura: los datos van a stagingPath + ".part" hasta completar
 	outputFile     *os.File
 	outputFilePath string
 
-	// Verificación de integridad
-	hashWriter hash.Hash
+	// Base de los archivos de trabajo (.part, manifiesto y extracción): outputFilePath,
+	// o su carpeta en la cuarentena si hay escáner
+	stagingPath string
 }
 
 // NewFileTransferAgent crea un nuevo agente de transferencia de archivos
@@ -55,67 +172,265 @@ func NewFileTransferAgent(downloadDir string) *FileTransferAgent {
 		fmt.Printf("Warning: Could not create download directory %s: %v\n", downloadDir, err)
 	}
 
-	return &FileTransferAgent{
+	fta := &FileTransferAgent{
 		activeTransfers: make(map[string]*FileTransfer),
+		scanning:        make(map[string]string),
+		incoming:        make(map[string]*pendingIncoming),
 		downloadDir:     downloadDir,
+		config:          DefaultConfig(),
+		policy:          NewTransferPolicy(DefaultConfig()),
+		stopReaper:      make(chan struct{}),
+		reaperDone:      make(chan struct{}),
 	}
+
+	// Recuperar transferencias interrumpidas en una ejecución anterior
+	// (las de subcarpetas se buscan al recibir la configuración)
+	fta.suspended = loadManifests(downloadDir, nil, fta.quarantineRoot())
+	if len(fta.suspended) > 0 {
+		fmt.Printf("📁 FILE TRANSFER: %d interrupted transfer(s) can be resumed\n", len(fta.suspended))
+	}
+
+	go fta.reaperLoop()
+	return fta
 }
 
 // SetTransferCompletedCallback establece el callback para transferencias completadas
-func (fta *FileTransferAgent) SetTransferCompletedCallback(callback func(transferID, fileName, filePath string, success bool, errorMsg string)) {
+func (fta *FileTransferAgent) SetTransferCompletedCallback(callback func(result TransferResult)) {
 	fta.onTransferCompleted = callback
 }
 
-// HandleFileTransferRequest procesa una nueva solicitud de transferencia de archivo
+// SetConfig reemplaza la configuración de recepción tras validarla
+func (fta *FileTransferAgent) SetConfig(config Config) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	scanner, err := NewScannerFromConfig(config.Scan)
+	if err != nil {
+		return err
+	}
+
+	fta.mutex.Lock()
+	defer fta.mutex.Unlock()
+	fta.config = config
+	fta.policy = NewTransferPolicy(config)
+	fta.scanner = scanner
+
+	// Las transferencias a subcarpetas permitidas (o en otra cuarentena) también pueden reanudarse
+	found := 0
+	for id, manifest := range loadManifests(fta.downloadDir, config.AllowedSubdirectories, fta.quarantineRoot()) {
+		if _, exists := fta.suspended[id]; exists {
+			continue
+		}
+		if _, exists := fta.activeTransfers[id]; exists {
+			continue
+		}
+		if _, exists := fta.scanning[id]; exists {
+			continue
+		}
+		fta.suspended[id] = manifest
+		found++
+	}
+	if found > 0 {
+		fmt.Printf("📁 FILE TRANSFER: %d more interrupted transfer(s) can be resumed\n", found)
+	}
+	return nil
+}
+
+// SetChunkRetransmitCallback establece el callback que pide al servidor reenviar un chunk
+func (fta *FileTransferAgent) SetChunkRetransmitCallback(callback func(retransmit api.FileChunkRetransmit)) {
+	fta.onChunkRetransmit = callback
+}
+
+// SetArchiveEntryCallback establece el callback de progreso por entrada de las transferencias de directorio
+func (fta *FileTransferAgent) SetArchiveEntryCallback(callback func(entry ArchiveEntryProgress)) {
+	fta.onArchiveEntry = callback
+}
+
+// SetTransferResumedCallback establece el callback que envía al servidor los chunks faltantes
+func (fta *FileTransferAgent) SetTransferResumedCallback(callback func(resume api.FileTransferResume)) {
+	fta.onTransferResumed = callback
+}
+
+// HandleFileTransferRequest procesa una nueva solicitud de transferencia de archivo.
+// Si requiere confirmación del usuario, queda pendiente (sin crear ningún archivo)
+// hasta AcceptIncoming o DeclineIncoming.
 func (fta *FileTransferAgent) HandleFileTransferRequest(request api.FileTransferRequest) error {
+	incoming, err := fta.handleRequest(request)
+	if err == nil && incoming != nil && fta.onIncomingTransfer != nil {
+		fta.onIncomingTransfer(*incoming)
+	}
+	return err
+}
+
+// handleRequest reanuda, inicia o deja pendiente de confirmación una transferencia.
+// Retorna la transferencia pendiente si hay que preguntar al usuario.
+func (fta *FileTransferAgent) handleRequest(request api.FileTransferRequest) (*IncomingTransfer, error) {
 	fta.mutex.Lock()
 	defer fta.mutex.Unlock()
 
 	// Verificar si ya existe una transferencia activa con este ID
 	if _, exists := fta.activeTransfers[request.TransferID]; exists {
-		return fmt.Errorf("transfer %s already in progress", request.TransferID)
+		return nil, fmt.Errorf("transfer %s already in progress", request.TransferID)
+	}
+	if _, exists := fta.scanning[request.TransferID]; exists {
+		return nil, fmt.Errorf("transfer %s is being scanned", request.TransferID)
+	}
+	if _, exists := fta.incoming[request.TransferID]; exists {
+		// El servidor la reenvió (p. ej. tras reconectar): se sigue esperando al usuario
+		fmt.Printf("❓ Transfer %s is still awaiting confirmation\n", request.TransferID)
+		return nil, nil
+	}
+
+	if request.TotalChunks <= 0 {
+		return nil, fmt.Errorf("invalid total chunks: %d", request.TotalChunks)
+	}
+
+	archiveFormat, err := parseArchiveFormat(request.ArchiveFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	// El servidor reenvió una transferencia interrumpida: continuar donde quedó
+	if manifest, exists := fta.suspended[request.TransferID]; exists {
+		if manifest.TotalChunks != request.TotalChunks {
+			fmt.Printf("⚠️ Transfer %s changed from %d to %d chunks, restarting\n",
+				request.TransferID, manifest.TotalChunks, request.TotalChunks)
+		} else {
+			// Ya fue aceptada antes de interrumpirse: no se vuelve a preguntar
+			err := fta.resumeTransfer(manifest)
+			if err == nil {
+				return nil, nil
+			}
+			fmt.Printf("⚠️ Could not resume transfer %s, restarting: %v\n", request.TransferID, err)
+		}
+		delete(fta.suspended, request.TransferID)
+		removeStagingFiles(manifest.stagingPath, manifest.OutputPath)
+	}
+
+	if fta.requiresConfirmation(request.SessionID) {
+		return fta.holdIncoming(request, archiveFormat)
+	}
+	return nil, fta.startTransfer(request, archiveFormat)
+}
+
+// prepareRequest resuelve el nombre y la carpeta de destino de una solicitud y
+// comprueba la política (requiere fta.mutex)
+func (fta *FileTransferAgent) prepareRequest(request api.FileTransferRequest, archiveFormat ArchiveFormat) (string, string, error) {
+	// El nombre viene del servidor: nunca se usa tal cual en una ruta
+	fileName, err := SanitizeFileName(request.FileName)
+	if err != nil {
+		return "", "", err
+	}
+	if archiveFormat != ArchiveNone {
+		fileName = archiveDirName(fileName)
+	}
+	if _, err := parseFileChecksum(request.FileChecksum); err != nil {
+		return "", "", err
 	}
 
-	// Usar el directorio de descarga directamente (ya incluye RemoteDesk)
-	destDir := fta.downloadDir
+	// DestinationPath solo se respeta si es una subcarpeta permitida (el directorio ya incluye RemoteDesk)
+	destDir, err := resolveDestinationDir(fta.downloadDir, request.DestinationPath, fta.config.AllowedSubdirectories)
+	if err != nil {
+		fmt.Printf("⚠️ Ignoring destination path for transfer %s: %v\n", request.TransferID, err)
+		destDir = fta.downloadDir
+	}
 	
 	// Crear el directorio de destino si no existe
 	if err := os.MkdirAll(destDir, 0755); err != nil {
-		return fmt.Errorf("failed to create destination directory %s: %v", destDir, err)
+		return "", "", fmt.Errorf("failed to create destination directory %s: %v", destDir, err)
 	}
 
 	// Verificar que el directorio se creó correctamente
 	if _, err := os.Stat(destDir); os.IsNotExist(err) {
-		return fmt.Errorf("destination directory was not created: %s", destDir)
+		return "", "", fmt.Errorf("destination directory was not created: %s", destDir)
+	}
+
+	// Un enlace simbólico no debe sacar la subcarpeta fuera del directorio de descarga
+	if err := ensureWithinRoot(fta.downloadDir, destDir); err != nil {
+		return "", "", err
 	}
 
-	// Construir ruta completa del archivo
-	outputFilePath := filepath.Join(destDir, request.FileName)
+	// Comprobar espacio libre, tamaño máximo y extensión antes de crear nada
+	if err := fta.policy.CheckRequest(destDir, fileName, requestSize(request), archiveFormat != ArchiveNone); err != nil {
+		return "", "", err
+	}
+
+	return destDir, fileName, nil
+}
 
-	// Crear el archivo de salida
-	outputFile, err := os.Create(outputFilePath)
+// startTransfer crea el archivo parcial y arranca el worker de una transferencia (requiere fta.mutex)
+func (fta *FileTransferAgent) startTransfer(request api.FileTransferRequest, archiveFormat ArchiveFormat) error {
+	destDir, fileName, err := fta.prepareRequest(request, archiveFormat)
 	if err != nil {
+		return err
+	}
+	expectedChecksum, err := parseFileChecksum(request.FileChecksum)
+	if err != nil {
+		return err
+	}
+
+	// Construir ruta completa del archivo según la política de colisión
+	outputFilePath, err := resolveCollision(destDir, fileName, fta.config.CollisionPolicy, fta.isOutputPathInUse)
+	if err != nil {
+		return err
+	}
+	if filepath.Base(outputFilePath) != request.FileName {
+		fmt.Printf("📁 FILE TRANSFER: %q will be saved as %q\n", request.FileName, filepath.Base(outputFilePath))
+	}
+
+	stagingPath, err := fta.stagingPathFor(request.TransferID, outputFilePath)
+	if err != nil {
+		return err
+	}
+
+	// Crear el archivo parcial; se renombra al nombre final al completar.
+	// Un .part huérfano se descarta y O_EXCL evita seguir un enlace simbólico.
+	partPath := partPathFor(stagingPath)
+	os.Remove(partPath)
+	outputFile, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		removeStagingFiles(stagingPath, outputFilePath)
 		return fmt.Errorf("failed to create output file %s: %v", outputFilePath, err)
 	}
 
 	// Crear nueva transferencia
 	transfer := &FileTransfer{
-		TransferID:      request.TransferID,
-		SessionID:       request.SessionID,
-		FileName:        request.FileName,
-		FileSizeMB:      request.FileSizeMB,
-		TotalChunks:     request.TotalChunks,
-		DestinationPath: request.DestinationPath,
-		ReceivedChunks:  make(map[int][]byte),
-		ChunksReceived:  0,
-		StartTime:       time.Now(),
-		outputFile:      outputFile,
-		outputFilePath:  outputFilePath,
-		hashWriter:      md5.New(),
-	}
-
-	// Registrar transferencia activa
+		TransferID:       request.TransferID,
+		SessionID:        request.SessionID,
+		FileName:         filepath.Base(outputFilePath),
+		FileSizeMB:       request.FileSizeMB,
+		TotalChunks:      request.TotalChunks,
+		DestinationPath:  request.DestinationPath,
+		StartTime:        time.Now(),
+		idleTimeout:      fta.config.transferIdleTimeout(),
+		received:         NewChunkBitmap(request.TotalChunks),
+		chunkSize:        int64(request.ChunkSize),
+		expectedSize:     request.FileSizeBytes,
+		expectedChecksum: expectedChecksum,
+		archiveFormat:    archiveFormat,
+		policy:           fta.policy,
+		retransmits:      make(map[int]int),
+		hasher:           newTransferHash(),
+		outputFile:       outputFile,
+		outputFilePath:   outputFilePath,
+		stagingPath:      stagingPath,
+	}
+
+	if err := fta.attachExtractor(transfer); err != nil {
+		transfer.closeFiles()
+		removeStagingFiles(stagingPath, outputFilePath)
+		return err
+	}
+
+	if err := transfer.saveManifest(); err != nil {
+		fmt.Printf("⚠️ Could not save manifest for transfer %s: %v\n", transfer.TransferID, err)
+	}
+
+	// Registrar transferencia activa y arrancar su worker
+	transfer.touch()
 	fta.activeTransfers[request.TransferID] = transfer
+	fta.startWorker(transfer)
 
 	fmt.Printf("📁 FILE TRANSFER: Started receiving file %s (%.2f MB, %d chunks)\n",
 		request.FileName, request.FileSizeMB, request.TotalChunks)
@@ -124,69 +439,161 @@ func (fta *FileTransferAgent) HandleFileTransferRequest(request api.FileTransfer
 	return nil
 }
 
-// HandleFileChunk procesa un chunk de archivo recibido
+// HandleFileChunk entrega un chunk al worker de su transferencia. Solo se valida
+// lo necesario para encolarlo; el resto del procesamiento ocurre en el worker.
 func (fta *FileTransferAgent) HandleFileChunk(chunk api.FileChunk) error {
-	fta.mutex.Lock()
-	defer fta.mutex.Unlock()
-
 	// Buscar transferencia activa
+	fta.mutex.RLock()
 	transfer, exists := fta.activeTransfers[chunk.TransferID]
+	_, awaiting := fta.incoming[chunk.TransferID]
+	fta.mutex.RUnlock()
+	if awaiting {
+		// Se pedirá de nuevo al aceptar
+		return fmt.Errorf("transfer %s is awaiting confirmation, chunk %d discarded", chunk.TransferID, chunk.ChunkIndex)
+	}
 	if !exists {
 		return fmt.Errorf("no active transfer found for ID: %s", chunk.TransferID)
 	}
+	transfer.touch()
+
+	if chunk.ChunkIndex < 0 || chunk.ChunkIndex >= transfer.TotalChunks {
+		return fmt.Errorf("chunk index %d out of range for transfer %s (%d chunks)",
+			chunk.ChunkIndex, chunk.TransferID, transfer.TotalChunks)
+	}
 
-	// Verificar que el chunk no haya sido recibido previamente
-	if _, alreadyReceived := transfer.ReceivedChunks[chunk.ChunkIndex]; alreadyReceived {
-		fmt.Printf("⚠️ Duplicate chunk %d for transfer %s, ignoring\n", chunk.ChunkIndex, chunk.TransferID)
+	// Nunca se espera al worker: este goroutine también lee los comandos de entrada,
+	// los pings y el resto de transferencias
+	select {
+	case <-transfer.done:
+		return fmt.Errorf("transfer %s is no longer active", chunk.TransferID)
+	default:
+	}
+	select {
+	case transfer.queue <- chunk:
 		return nil
+	default:
+		return fta.dropChunk(transfer, chunk.ChunkIndex)
+	}
+}
+
+// dropChunk descarta un chunk que no cabe en la cola y lo pide de nuevo. Si el worker
+// lleva más que el timeout de inactividad sin tomar chunks, la transferencia falla.
+func (fta *FileTransferAgent) dropChunk(transfer *FileTransfer, index int) error {
+	if stalled := transfer.workerIdleFor(time.Now()); transfer.idleTimeout > 0 && stalled >= transfer.idleTimeout {
+		reason := fmt.Sprintf("receive stalled: no chunk written for %v", stalled.Round(time.Second))
+		if transfer.stop(stopFail, ReasonReceiveStalled, reason) {
+			fmt.Printf("❌ Transfer %s failed: %s\n", transfer.TransferID, reason)
+		}
+		return fmt.Errorf("transfer %s %s", transfer.TransferID, reason)
+	}
+
+	// Si se pierde la conexión, la reanudación por rangos faltantes también lo recupera
+	if fta.onChunkRetransmit != nil {
+		fta.onChunkRetransmit(api.FileChunkRetransmit{
+			TransferID: transfer.TransferID,
+			SessionID:  transfer.SessionID,
+			ChunkIndex: index,
+			Reason:     queueFullReason,
+		})
+	}
+	return fmt.Errorf("receive queue of transfer %s is full, chunk %d dropped and requested again",
+		transfer.TransferID, index)
+}
+
+// requestRetransmit pide al servidor reenviar un chunk, o aborta si se agotaron los intentos
+func (fta *FileTransferAgent) requestRetransmit(transfer *FileTransfer, index int, reason string) error {
+	transfer.retransmits[index]++
+	attempt := transfer.retransmits[index]
+
+	if attempt > maxChunkRetransmits {
+		errorMsg := fmt.Sprintf("chunk %d failed verification %d times: %s", index, attempt, reason)
+		fta.cleanupTransfer(transfer, errorMsg)
+		return fmt.Errorf("%s", errorMsg)
+	}
+
+	fmt.Printf("🔁 Chunk %d of transfer %s failed verification (attempt %d/%d): %s\n",
+		index, transfer.TransferID, attempt, maxChunkRetransmits, reason)
+
+	if fta.onChunkRetransmit != nil {
+		fta.onChunkRetransmit(api.FileChunkRetransmit{
+			TransferID: transfer.TransferID,
+			SessionID:  transfer.SessionID,
+			ChunkIndex: index,
+			Reason:     reason,
+			Attempt:    attempt,
+		})
+	}
+
+	return fmt.Errorf("chunk %d failed verification, retransmission requested: %s", index, reason)
+}
+
+// decodeChunkData obtiene los bytes reales de un chunk
+func decodeChunkData(chunk api.FileChunk) ([]byte, error) {
+	if len(chunk.ChunkData) == 0 {
+		return nil, fmt.Errorf("received empty chunk data for chunk %d", chunk.ChunkIndex)
 	}
 
 	// 🔧 DECODIFICAR BASE64: El servidor envía datos codificados en base64
-	var actualChunkData []byte
-	if len(chunk.ChunkData) > 0 {
-		// Si ChunkData es []byte, podríamos estar recibiendo base64 como bytes
-		// Intentar decodificar de base64 string primero
-		chunkDataStr := string(chunk.ChunkData)
-		decodedData, err := base64.StdEncoding.DecodeString(chunkDataStr)
-		if err != nil {
-			// Si la decodificación base64 falla, usar los datos tal como están
-			fmt.Printf("⚠️ Base64 decode failed for chunk %d, using raw data: %v\n", chunk.ChunkIndex, err)
-			actualChunkData = chunk.ChunkData
-		} else {
-			actualChunkData = decodedData
-			fmt.Printf("✅ Decoded base64 chunk %d: %d bytes -> %d bytes\n",
-				chunk.ChunkIndex, len(chunk.ChunkData), len(actualChunkData))
+	decodedData, err := base64.StdEncoding.DecodeString(string(chunk.ChunkData))
+	if err != nil {
+		// Si la decodificación base64 falla, usar los datos tal como están
+		fmt.Printf("⚠️ Base64 decode failed for chunk %d, using raw data: %v\n", chunk.ChunkIndex, err)
+		return chunk.ChunkData, nil
+	}
+
+	return decodedData, nil
+}
+
+// writeChunk escribe un chunk en su offset (índice × tamaño de chunk) y lo marca como recibido
+func (t *FileTransfer) writeChunk(index int, data []byte) error {
+	isLast := index == t.TotalChunks-1
+
+	if t.chunkSize == 0 {
+		if isLast && t.TotalChunks > 1 {
+			// El último chunk puede ser más corto: esperar a otro chunk para conocer el tamaño
+			t.pendingLastChunk = data
+			return nil
 		}
-	} else {
-		return fmt.Errorf("received empty chunk data for chunk %d", chunk.ChunkIndex)
+		t.chunkSize = int64(len(data))
 	}
 
-	// Escribir chunk decodificado al archivo
-	if _, err := transfer.outputFile.Write(actualChunkData); err != nil {
-		// Error escribiendo, limpiar transferencia
-		fta.cleanupTransfer(transfer, fmt.Sprintf("failed to write chunk: %v", err))
-		return err
+	if !isLast && int64(len(data)) != t.chunkSize {
+		return fmt.Errorf("chunk %d has %d bytes, expected %d", index, len(data), t.chunkSize)
+	}
+	if isLast && int64(len(data)) > t.chunkSize {
+		return fmt.Errorf("last chunk has %d bytes, more than chunk size %d", len(data), t.chunkSize)
 	}
 
-	// Actualizar hash para verificación de integridad usando datos decodificados
-	transfer.hashWriter.Write(actualChunkData)
+	// El servidor puede no informar el tamaño (o informarlo mal): limitar por offset
+	if err := t.policy.CheckSize(int64(index)*t.chunkSize + int64(len(data))); err != nil {
+		return err
+	}
 
-	// Registrar chunk recibido usando datos decodificados
-	transfer.ReceivedChunks[chunk.ChunkIndex] = actualChunkData
-	transfer.ChunksReceived++
+	if _, err := t.outputFile.WriteAt(data, int64(index)*t.chunkSize); err != nil {
+		return err
+	}
+	if isLast {
+		t.lastChunkSize = int64(len(data))
+	}
+	t.received.Set(index)
+	t.chunksReceived.Store(int64(t.received.Count()))
 
-	fmt.Printf("📦 Received chunk %d/%d for file %s (decoded: %d bytes)\n",
-		chunk.ChunkIndex+1, chunk.TotalChunks, transfer.FileName, len(actualChunkData))
+	if err := t.advanceHash(index, data); err != nil {
+		return err
+	}
+	t.maybeSaveManifest()
 
-	// Verificar si es el último chunk o si hemos recibido todos
-	if chunk.IsLastChunk || transfer.ChunksReceived >= transfer.TotalChunks {
-		return fta.completeTransfer(transfer)
+	// Ubicar el último chunk que llegó antes de conocer el tamaño
+	if t.pendingLastChunk != nil {
+		pending := t.pendingLastChunk
+		t.pendingLastChunk = nil
+		return t.writeChunk(t.TotalChunks-1, pending)
 	}
 
 	return nil
 }
 
-// completeTransfer finaliza una transferencia de archivo
+// completeTransfer finaliza una transferencia de archivo (desde su worker)
 func (fta *FileTransferAgent) completeTransfer(transfer *FileTransfer) error {
 	// Cerrar archivo
 	if err := transfer.outputFile.Close(); err != nil {
@@ -195,7 +602,8 @@ func (fta *FileTransferAgent) completeTransfer(transfer *FileTransfer) error {
 	}
 
 	// Verificar que el archivo realmente existe y tiene el tamaño esperado
-	fileInfo, err := os.Stat(transfer.outputFilePath)
+	partPath := partPathFor(transfer.stagingPath)
+	fileInfo, err := os.Stat(partPath)
 	if err != nil {
 		fta.cleanupTransfer(transfer, fmt.Sprintf("failed to stat completed file: %v", err))
 		return err
@@ -207,10 +615,68 @@ func (fta *FileTransferAgent) completeTransfer(transfer *FileTransfer) error {
 		return fmt.Errorf("file was saved but is empty")
 	}
 
-	// Verificar integridad del archivo (opcional para MVP)
-	fileChecksum := fmt.Sprintf("%x", transfer.hashWriter.Sum(nil))
+	if transfer.expectedSize > 0 && fileInfo.Size() != transfer.expectedSize {
+		errorMsg := fmt.Sprintf("file size mismatch: got %d bytes, expected %d", fileInfo.Size(), transfer.expectedSize)
+		fta.cleanupTransfer(transfer, errorMsg)
+		return fmt.Errorf("%s", errorMsg)
+	}
+
+	// Verificar integridad del archivo (el hash avanzó con el prefijo contiguo de chunks)
+	if transfer.hashedChunks != transfer.TotalChunks {
+		errorMsg := fmt.Sprintf("hash covers %d of %d chunks", transfer.hashedChunks, transfer.TotalChunks)
+		fta.cleanupTransfer(transfer, errorMsg)
+		return fmt.Errorf("%s", errorMsg)
+	}
+	fileChecksum := fmt.Sprintf("%x", transfer.hasher.Sum(nil))
+
+	if transfer.expectedChecksum != "" && fileChecksum != transfer.expectedChecksum {
+		errorMsg := fmt.Sprintf("file checksum mismatch: got %s, expected %s", fileChecksum, transfer.expectedChecksum)
+		fta.cleanupTransfer(transfer, errorMsg)
+		return fmt.Errorf("%s", errorMsg)
+	}
 
 	duration := time.Since(transfer.StartTime)
+	result := TransferResult{
+		TransferID:   transfer.TransferID,
+		SessionID:    transfer.SessionID,
+		FileName:     transfer.FileName,
+		SizeBytes:    fileInfo.Size(),
+		Checksum:     fileChecksum,
+		ChecksumAlgo: ChecksumAlgorithm,
+		Duration:     duration,
+		Success:      true,
+		IsDirectory:  transfer.archiveFormat != ArchiveNone,
+	}
+
+	fta.mutex.RLock()
+	scanner := fta.scanner
+	collision := fta.config.CollisionPolicy
+	fta.mutex.RUnlock()
+
+	// Con escáner configurado el resultado pasa por cuarentena y se publica solo si está limpio
+	if scanner != nil {
+		return fta.quarantineTransfer(transfer, result, scanner, collision)
+	}
+
+	// Publicar el archivo (o el directorio extraído) con su nombre final y descartar el manifiesto
+	outputPath := transfer.outputFilePath
+	entries, err := transfer.publish(func(source string) error {
+		var err error
+		outputPath, err = fta.moveToOutput(source, transfer.outputFilePath, collision)
+		return err
+	})
+	if err != nil {
+		transfer.reasonCode = ReasonCodeOf(err)
+		fta.cleanupTransfer(transfer, fmt.Sprintf("failed to publish completed transfer: %v", err))
+		return err
+	}
+	removeStagingFiles(transfer.stagingPath, transfer.outputFilePath)
+
+	// Si el nombre apareció mientras se recibía, la política de colisión eligió otro
+	transfer.outputFilePath = outputPath
+	transfer.FileName = filepath.Base(outputPath)
+	result.FileName = transfer.FileName
+
 	actualFileSize := float64(fileInfo.Size()) / (1024 * 1024) // MB
 
 	fmt.Printf("✅ File transfer completed: %s (%.2f MB) in %v\n",
@@ -226,40 +692,172 @@ func (fta *FileTransferAgent) completeTransfer(transfer *FileTransfer) error {
 		fmt.Printf("⚠️ Warning: Parent directory check failed: %v\n", err)
 	}
 
+	// Limpiar transferencia completada antes de notificar, para que el servidor
+	// pueda reutilizar su ID en cuanto reciba el acknowledgement
+	transfer.finished = true
+	fta.removeActive(transfer)
+
 	// Notificar al app sobre transferencia completada
 	if fta.onTransferCompleted != nil {
-		fta.onTransferCompleted(transfer.TransferID, transfer.FileName, transfer.outputFilePath, true, "")
+		result.FilePath = transfer.outputFilePath
+		result.Entries = entries
+		result.ScanStatus = ScanSkipped
+		fta.onTransferCompleted(result)
 	}
 
-	// Limpiar transferencia completada
-	delete(fta.activeTransfers, transfer.TransferID)
-
 	return nil
 }
 
-// cleanupTransfer limpia una transferencia fallida
+// requestedSize retorna el tamaño anunciado por el servidor: en bytes si lo informó,
+// si no el aproximado en MB
+func (t *FileTransfer) requestedSize() int64 {
+	if t.expectedSize > 0 {
+		return t.expectedSize
+	}
+	return int64(t.FileSizeMB * 1024 * 1024)
+}
+
+// cleanupTransfer limpia una transferencia fallida (desde su worker)
 func (fta *FileTransferAgent) cleanupTransfer(transfer *FileTransfer, errorMsg string) {
 	fmt.Printf("❌ File transfer failed for %s: %s\n", transfer.FileName, errorMsg)
 
-	// Cerrar archivo si está abierto
-	if transfer.outputFile != nil {
-		transfer.outputFile.Close()
-	}
+	// Detener la extracción y cerrar el archivo si está abierto
+	transfer.closeFiles()
 
-	// Intentar eliminar archivo parcial
-	if transfer.outputFilePath != "" {
-		if err := os.Remove(transfer.outputFilePath); err != nil {
-			fmt.Printf("Warning: Could not remove partial file %s: %v\n", transfer.outputFilePath, err)
-		}
+	// Eliminar archivo parcial, manifiesto y extracción: la transferencia no se podrá reanudar
+	if transfer.stagingPath != "" {
+		removeStagingFiles(transfer.stagingPath, transfer.outputFilePath)
 	}
 
+	// Eliminar transferencia activa
+	transfer.finished = true
+	fta.removeActive(transfer)
+
 	// Notificar al app sobre transferencia fallida
 	if fta.onTransferCompleted != nil {
-		fta.onTransferCompleted(transfer.TransferID, transfer.FileName, "", false, errorMsg)
+		fta.onTransferCompleted(TransferResult{
+			TransferID:   transfer.TransferID,
+			SessionID:    transfer.SessionID,
+			FileName:     transfer.FileName,
+			SizeBytes:    transfer.requestedSize(),
+			Duration:     time.Since(transfer.StartTime),
+			Success:      false,
+			ErrorMessage: errorMsg,
+			ReasonCode:   transfer.reasonCode,
+		})
 	}
+}
 
-	// Eliminar transferencia activa
-	delete(fta.activeTransfers, transfer.TransferID)
+// SuspendTransfers guarda el estado de las transferencias activas y libera sus archivos
+// (al perder la conexión); se reanudan con ResumeTransfers
+func (fta *FileTransferAgent) SuspendTransfers() {
+	fta.mutex.RLock()
+	transfers := make([]*FileTransfer, 0, len(fta.activeTransfers))
+	for _, transfer := range fta.activeTransfers {
+		transfers = append(transfers, transfer)
+	}
+	fta.mutex.RUnlock()
+
+	// Los workers guardan su manifiesto y cierran sus archivos; se espera sin el mutex
+	// porque un worker que está completando o fallando lo necesita para terminar
+	for _, transfer := range transfers {
+		transfer.stop(stopSuspend, "", "")
+		<-transfer.done
+	}
+
+	fta.mutex.Lock()
+	defer fta.mutex.Unlock()
+
+	for _, transfer := range transfers {
+		// Las que terminaron mientras tanto no tienen nada que reanudar
+		if transfer.suspendedManifest == nil {
+			continue
+		}
+		if fta.activeTransfers[transfer.TransferID] == transfer {
+			delete(fta.activeTransfers, transfer.TransferID)
+		}
+		fta.suspended[transfer.TransferID] = transfer.suspendedManifest
+
+		fmt.Printf("⏸️ Transfer %s suspended at %d/%d chunks\n",
+			transfer.TransferID, transfer.ChunksReceived(), transfer.TotalChunks)
+	}
+}
+
+// ResumeTransfers reabre las transferencias suspendidas y pide al servidor los chunks faltantes
+func (fta *FileTransferAgent) ResumeTransfers() {
+	fta.mutex.Lock()
+	defer fta.mutex.Unlock()
+
+	for id, manifest := range fta.suspended {
+		if err := fta.resumeTransfer(manifest); err != nil {
+			fmt.Printf("⚠️ Could not resume transfer %s, discarding: %v\n", id, err)
+			delete(fta.suspended, id)
+			removeStagingFiles(manifest.stagingPath, manifest.OutputPath)
+		}
+	}
+}
+
+// isOutputPathInUse indica si otra transferencia activa, suspendida o en escaneo usa path (requiere fta.mutex)
+func (fta *FileTransferAgent) isOutputPathInUse(path string) bool {
+	for _, outputPath := range fta.scanning {
+		if outputPath == path {
+			return true
+		}
+	}
+	for _, transfer := range fta.activeTransfers {
+		if transfer.outputFilePath == path {
+			return true
+		}
+	}
+	for _, manifest := range fta.suspended {
+		if manifest.OutputPath == path {
+			return true
+		}
+	}
+	return false
+}
+
+// resumeTransfer reactiva una transferencia suspendida (requiere fta.mutex). Solo
+// la registra y arranca su worker: releer el .part puede tardar y se hace fuera del mutex
+func (fta *FileTransferAgent) resumeTransfer(manifest *transferManifest) error {
+	transfer, err := transferFromManifest(manifest)
+	if err != nil {
+		return err
+	}
+	transfer.policy = fta.policy
+	transfer.idleTimeout = fta.config.transferIdleTimeout()
+	transfer.resumed = true
+	transfer.touch()
+
+	delete(fta.suspended, manifest.TransferID)
+	fta.activeTransfers[manifest.TransferID] = transfer
+
+	fmt.Printf("▶️ Resuming transfer %s: %d/%d chunks already received\n",
+		transfer.TransferID, transfer.ChunksReceived(), transfer.TotalChunks)
+
+	fta.startWorker(transfer)
+	return nil
+}
+
+// catchUpResumed pone al día el hash (y la extracción) de una transferencia reanudada
+// con los chunks contiguos ya en disco y pide al servidor los que faltan (desde su worker)
+func (fta *FileTransferAgent) catchUpResumed(t *FileTransfer) error {
+	err := fta.attachExtractor(t)
+	if err == nil {
+		err = t.catchUpHash()
+	}
+	if err != nil {
+		return err
+	}
+
+	// El reaper no debe contar la puesta al día como un worker detenido
+	t.lastDequeue.Store(time.Now().UnixNano())
+
+	// Si ya estaban todos los chunks, el worker la completa sin pedir nada
+	if !t.received.Complete() && fta.onTransferResumed != nil {
+		fta.onTransferResumed(t.resumeMessage())
+	}
+	return nil
 }
 
 // GetActiveTransfers retorna información sobre transferencias activas
@@ -270,7 +868,7 @@ func (fta *FileTransferAgent) GetActiveTransfers() map[string]map[string]interfa
 	result := make(map[string]map[string]interface{})
 
 	for id, transfer := range fta.activeTransfers {
-		progress := float64(transfer.ChunksReceived) / float64(transfer.TotalChunks) * 100
+		progress := float64(transfer.ChunksReceived()) / float64(transfer.TotalChunks) * 100
 
 		result[id] = map[string]interface{}{
 			"transfer_id":     transfer.TransferID,
@@ -278,7 +876,7 @@ func (fta *FileTransferAgent) GetActiveTransfers() map[string]map[string]interfa
 			"file_name":       transfer.FileName,
 			"file_size_mb":    transfer.FileSizeMB,
 			"total_chunks":    transfer.TotalChunks,
-			"chunks_received": transfer.ChunksReceived,
+			"chunks_received": transfer.ChunksReceived(),
 			"progress":        progress,
 			"start_time":      transfer.StartTime,
 			"destination":     transfer.outputFilePath,
@@ -0,0 +1,133 @@
+package filetransfer
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+const (
+	benchChunkSize = 256 * 1024
+	benchChunks    = 32 // 8 MB por transferencia
+)
+
+// BenchmarkConcurrentTransfers recibe varias transferencias a la vez entregando sus chunks
+// intercalados desde un solo goroutine, como hace el lector del WebSocket. Con un worker
+// por transferencia el throughput (MB/s) debe crecer con el número de transferencias.
+func BenchmarkConcurrentTransfers(b *testing.B) {
+	for _, transfers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("transfers=%d", transfers), func(b *testing.B) {
+			benchmarkTransfers(b, transfers)
+		})
+	}
+}
+
+func benchmarkTransfers(b *testing.B, transfers int) {
+	// Los chunks llegan en base64 y con su SHA-256, como los envía el servidor
+	raw := make([]byte, benchChunkSize)
+	rand.Read(raw)
+	encoded := []byte(base64.StdEncoding.EncodeToString(raw))
+	chunkSum := sha256.Sum256(raw)
+	chunkChecksum := hex.EncodeToString(chunkSum[:])
+
+	fileHash := sha256.New()
+	for i := 0; i < benchChunks; i++ {
+		fileHash.Write(raw)
+	}
+	fileChecksum := hex.EncodeToString(fileHash.Sum(nil))
+
+	// El agente registra cada chunk por stdout: se silencia durante la medición
+	stdout := os.Stdout
+	if devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+		os.Stdout = devNull
+		defer func() {
+			os.Stdout = stdout
+			devNull.Close()
+		}()
+	}
+
+	agent := NewFileTransferAgent(b.TempDir())
+	defer agent.Close()
+
+	config := DefaultConfig()
+	config.CollisionPolicy = CollisionOverwrite
+	config.MinFreeSpaceMB = 0
+	config.IncomingAutoAccept = IncomingAutoAcceptAlways
+	if err := agent.SetConfig(config); err != nil {
+		b.Fatal(err)
+	}
+
+	results := make(chan TransferResult, transfers)
+	agent.SetTransferCompletedCallback(func(result TransferResult) {
+		results <- result
+	})
+
+	// Los chunks descartados con la cola llena se reenvían, como haría el servidor
+	var resend []api.FileChunkRetransmit
+	agent.SetChunkRetransmitCallback(func(retransmit api.FileChunkRetransmit) {
+		resend = append(resend, retransmit)
+	})
+	deliver := func(transferID string, index int) {
+		requested := len(resend)
+		err := agent.HandleFileChunk(api.FileChunk{
+			TransferID:    transferID,
+			SessionID:     "bench",
+			ChunkIndex:    index,
+			TotalChunks:   benchChunks,
+			ChunkData:     encoded,
+			IsLastChunk:   index == benchChunks-1,
+			ChunkChecksum: chunkChecksum,
+		})
+		if err != nil && len(resend) == requested {
+			b.Fatal(err)
+		}
+	}
+
+	b.SetBytes(int64(transfers * benchChunks * benchChunkSize))
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		for t := 0; t < transfers; t++ {
+			err := agent.HandleFileTransferRequest(api.FileTransferRequest{
+				TransferID:    fmt.Sprintf("bench-%d-%d", n, t),
+				SessionID:     "bench",
+				FileName:      fmt.Sprintf("bench-%d.bin", t),
+				FileSizeBytes: benchChunks * benchChunkSize,
+				TotalChunks:   benchChunks,
+				ChunkSize:     benchChunkSize,
+				FileChecksum:  fileChecksum,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		for index := 0; index < benchChunks; index++ {
+			for t := 0; t < transfers; t++ {
+				deliver(fmt.Sprintf("bench-%d-%d", n, t), index)
+			}
+		}
+		for len(resend) > 0 {
+			// Un reenvío tarda al menos un viaje de ida y vuelta
+			time.Sleep(time.Millisecond)
+			pending := resend
+			resend = nil
+			for _, retransmit := range pending {
+				deliver(retransmit.TransferID, retransmit.ChunkIndex)
+			}
+		}
+
+		for t := 0; t < transfers; t++ {
+			if result := <-results; !result.Success {
+				b.Fatalf("transfer %s failed: %s", result.TransferID, result.ErrorMessage)
+			}
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package filetransfer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+func TestFileTransferAgentAssemblesOutOfOrder(t *testing.T) {
+	const (
+		chunkSize   = 1000
+		totalChunks = 5
+		fileSize    = (totalChunks-1)*chunkSize + 300 // Último chunk más corto
+	)
+
+	content := make([]byte, fileSize)
+	rand.Read(content)
+	fileSum := sha256.Sum256(content)
+	fileChecksum := hex.EncodeToString(fileSum[:])
+
+	// Orden inverso, con el chunk 2 repetido
+	order := []int{4, 3, 2, 2, 1, 0}
+
+	tests := []struct {
+		name      string
+		chunkSize int // 0: el servidor no informa el tamaño de chunk
+	}{
+		{name: "announced chunk size", chunkSize: chunkSize},
+		{name: "chunk size from the data", chunkSize: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := NewFileTransferAgent(t.TempDir())
+			defer agent.Close()
+
+			config := DefaultConfig()
+			config.MinFreeSpaceMB = 0
+			config.IncomingAutoAccept = IncomingAutoAcceptAlways
+			if err := agent.SetConfig(config); err != nil {
+				t.Fatal(err)
+			}
+
+			results := make(chan TransferResult, 1)
+			agent.SetTransferCompletedCallback(func(result TransferResult) {
+				results <- result
+			})
+
+			err := agent.HandleFileTransferRequest(api.FileTransferRequest{
+				TransferID:    "out-of-order",
+				SessionID:     "test",
+				FileName:      "out-of-order.bin",
+				FileSizeBytes: fileSize,
+				TotalChunks:   totalChunks,
+				ChunkSize:     tt.chunkSize,
+				FileChecksum:  fileChecksum,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, index := range order {
+				data := content[index*chunkSize : min(fileSize, (index+1)*chunkSize)]
+				chunkSum := sha256.Sum256(data)
+				err := agent.HandleFileChunk(api.FileChunk{
+					TransferID:    "out-of-order",
+					SessionID:     "test",
+					ChunkIndex:    index,
+					TotalChunks:   totalChunks,
+					ChunkData:     []byte(base64.StdEncoding.EncodeToString(data)),
+					IsLastChunk:   index == totalChunks-1,
+					ChunkChecksum: hex.EncodeToString(chunkSum[:]),
+				})
+				if err != nil {
+					t.Fatalf("chunk %d: %v", index, err)
+				}
+			}
+
+			var result TransferResult
+			select {
+			case result = <-results:
+			case <-time.After(5 * time.Second):
+				t.Fatal("transfer did not complete")
+			}
+
+			if !result.Success {
+				t.Fatalf("transfer failed: %s", result.ErrorMessage)
+			}
+			if result.Checksum != fileChecksum {
+				t.Errorf("checksum = %s, want %s", result.Checksum, fileChecksum)
+			}
+
+			got, err := os.ReadFile(result.FilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("assembled file differs from the original (%d bytes, want %d)", len(got), len(content))
+			}
+			if sum := sha256.Sum256(got); sum != fileSum {
+				t.Errorf("SHA-256 of the assembled file = %x, want %x", sum, fileSum)
+			}
+		})
+	}
+}
+
+// newTestTransferAgent crea un agente que acepta sin preguntar, con la cuarentena en
+// un directorio temporal; modify ajusta la configuración antes de aplicarla
+func newTestTransferAgent(t *testing.T, downloadDir string, modify func(config *Config)) *FileTransferAgent {
+	t.Helper()
+
+	agent := NewFileTransferAgent(downloadDir)
+	t.Cleanup(agent.Close)
+
+	config := DefaultConfig()
+	config.MinFreeSpaceMB = 0
+	config.IncomingAutoAccept = IncomingAutoAcceptAlways
+	config.QuarantineDir = filepath.Join(filepath.Dir(downloadDir), "quarantine")
+	if modify != nil {
+		modify(&config)
+	}
+	if err := agent.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	return agent
+}
+
+// testFileChunk construye el chunk index de content como lo envía el servidor
+func testFileChunk(transferID string, content []byte, chunkSize, index int) api.FileChunk {
+	totalChunks := (len(content) + chunkSize - 1) / chunkSize
+	data := content[index*chunkSize : min(len(content), (index+1)*chunkSize)]
+	sum := sha256.Sum256(data)
+
+	return api.FileChunk{
+		TransferID:    transferID,
+		SessionID:     "test",
+		ChunkIndex:    index,
+		TotalChunks:   totalChunks,
+		ChunkData:     []byte(base64.StdEncoding.EncodeToString(data)),
+		IsLastChunk:   index == totalChunks-1,
+		ChunkChecksum: hex.EncodeToString(sum[:]),
+	}
+}
+
+// waitTransferResult espera el resultado de una transferencia
+func waitTransferResult(t *testing.T, results <-chan TransferResult) TransferResult {
+	t.Helper()
+	select {
+	case result := <-results:
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatal("transfer did not finish")
+		return TransferResult{}
+	}
+}
+
+// waitChunksReceived espera a que el worker haya escrito count chunks de la transferencia
+func waitChunksReceived(t *testing.T, agent *FileTransferAgent, transferID string, count int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		agent.mutex.RLock()
+		transfer := agent.activeTransfers[transferID]
+		agent.mutex.RUnlock()
+		if transfer != nil && transfer.ChunksReceived() >= count {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("transfer %s did not receive %d chunks", transferID, count)
+}
+
+func TestFileTransferKeepsFileCreatedDuringTransfer(t *testing.T) {
+	content := make([]byte, 2500)
+	rand.Read(content)
+
+	tests := []struct {
+		name       string
+		policy     CollisionPolicy
+		wantName   string // Vacío: la transferencia falla
+		wantReason ReasonCode
+	}{
+		{name: "rename", policy: CollisionRename, wantName: "notes (1).bin"},
+		{name: "reject", policy: CollisionReject, wantReason: ReasonFileExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			downloadDir := filepath.Join(t.TempDir(), "downloads")
+			agent := newTestTransferAgent(t, downloadDir, func(config *Config) {
+				config.CollisionPolicy = tt.policy
+			})
+			results := make(chan TransferResult, 1)
+			agent.SetTransferCompletedCallback(func(result TransferResult) { results <- result })
+
+			if err := agent.HandleFileTransferRequest(api.FileTransferRequest{
+				TransferID:    "appeared",
+				SessionID:     "test",
+				FileName:      "notes.bin",
+				FileSizeBytes: int64(len(content)),
+				TotalChunks:   3,
+				ChunkSize:     1000,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			// El usuario guarda un archivo con el mismo nombre antes del último chunk
+			userFile := filepath.Join(downloadDir, "notes.bin")
+			if err := os.WriteFile(userFile, []byte("user"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			for index := 0; index < 3; index++ {
+				if err := agent.HandleFileChunk(testFileChunk("appeared", content, 1000, index)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result := waitTransferResult(t, results)
+			if result.ReasonCode != tt.wantReason {
+				t.Errorf("reason = %q, want %q", result.ReasonCode, tt.wantReason)
+			}
+			if tt.wantName != "" {
+				if !result.Success || result.FileName != tt.wantName || result.FilePath != filepath.Join(downloadDir, tt.wantName) {
+					t.Errorf("result = %+v, want the file saved as %s", result, tt.wantName)
+				}
+				if got, err := os.ReadFile(result.FilePath); err != nil || !bytes.Equal(got, content) {
+					t.Errorf("published file is missing or differs: %v", err)
+				}
+			} else if result.Success {
+				t.Errorf("result = %+v, want a failure", result)
+			}
+
+			if got, err := os.ReadFile(userFile); err != nil || string(got) != "user" {
+				t.Errorf("user's file = %q, %v, want it untouched", got, err)
+			}
+		})
+	}
+}
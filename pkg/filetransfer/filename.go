@@ -0,0 +1,208 @@
+package filetransfer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxFileNameBytes es el límite habitual de un nombre de archivo en NTFS, ext4 y APFS
+const maxFileNameBytes = 255
+
+// ReasonFileExists rechaza una transferencia cuyo destino ya existe con CollisionReject
+const ReasonFileExists ReasonCode = "file_exists"
+
+// windowsReservedNames no se pueden usar como nombre de archivo en Windows, con o sin extensión
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeFileName convierte un nombre recibido del servidor en un nombre de archivo
+// seguro: sin directorios, separadores, caracteres de control ni nombres reservados
+func SanitizeFileName(name string) (string, error) {
+	// Quedarse solo con el último elemento, con separadores de cualquier sistema
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r == utf8.RuneError, unicode.IsControl(r):
+			continue
+		case strings.ContainsRune(`<>:"|?*`, r):
+			b.WriteRune('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	// Windows ignora puntos y espacios finales; un nombre solo de puntos no es un archivo
+	clean := strings.TrimRight(strings.TrimSpace(b.String()), ". ")
+	if clean == "" {
+		return "", fmt.Errorf("invalid file name: %q", name)
+	}
+
+	base := clean
+	if i := strings.IndexByte(base, '.'); i >= 0 {
+		base = base[:i]
+	}
+	if windowsReservedNames[strings.ToUpper(strings.TrimSpace(base))] {
+		clean = "_" + clean
+	}
+
+	return truncateFileName(clean, maxFileNameBytes), nil
+}
+
+// truncateFileName recorta un nombre a maxBytes conservando la extensión
+func truncateFileName(name string, maxBytes int) string {
+	if len(name) <= maxBytes {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	if len(ext) > maxBytes/2 {
+		ext = ""
+	}
+	base := strings.TrimSuffix(name, ext)
+
+	limit := maxBytes - len(ext)
+	for len(base) > limit {
+		// Recortar por runas para no dejar UTF-8 inválido
+		_, size := utf8.DecodeLastRuneInString(base)
+		base = base[:len(base)-size]
+	}
+	return base + ext
+}
+
+// resolveDestinationDir valida DestinationPath contra las subcarpetas permitidas.
+// Solo se admiten rutas relativas bajo el directorio de descarga que estén en la
+// lista (o dentro de una de sus entradas); en otro caso retorna error.
+func resolveDestinationDir(root, destinationPath string, allowed []string) (string, error) {
+	destinationPath = strings.TrimSpace(destinationPath)
+	if destinationPath == "" {
+		return root, nil
+	}
+
+	relative, err := cleanRelativePath(destinationPath)
+	if err != nil {
+		return "", err
+	}
+
+	for _, entry := range allowed {
+		allowedPath, err := cleanRelativePath(entry)
+		if err != nil {
+			continue
+		}
+		if relative == allowedPath || strings.HasPrefix(relative, allowedPath+string(filepath.Separator)) {
+			return filepath.Join(root, relative), nil
+		}
+	}
+
+	return "", fmt.Errorf("destination %q is not an allowed subdirectory", destinationPath)
+}
+
+// ensureWithinRoot comprueba, resolviendo enlaces simbólicos, que dir está dentro de root
+func ensureWithinRoot(root, dir string) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+
+	if !isWithin(realRoot, realDir) {
+		return fmt.Errorf("destination %s is outside the download directory", dir)
+	}
+	return nil
+}
+
+// isWithin indica si path es root o está dentro de root; ambas rutas deben estar ya resueltas
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// cleanRelativePath normaliza una ruta relativa saneando cada componente.
+// Rechaza rutas absolutas, unidades de Windows y cualquier "..".
+func cleanRelativePath(path string) (string, error) {
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "/") || strings.HasPrefix(path, `\`) || filepath.VolumeName(path) != "" {
+		return "", fmt.Errorf("destination %q must be relative to the download directory", path)
+	}
+
+	var parts []string
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == "." {
+			continue
+		}
+		if part == ".." {
+			return "", fmt.Errorf("destination %q escapes the download directory", path)
+		}
+
+		clean, err := SanitizeFileName(part)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, clean)
+	}
+
+	if len(parts) == 0 {
+		return "", fmt.Errorf("empty destination path")
+	}
+	return filepath.Join(parts...), nil
+}
+
+// resolveCollision decide la ruta final de un archivo según la política de colisión.
+// inUse indica rutas reservadas por otras transferencias en curso.
+func resolveCollision(dir, name string, policy CollisionPolicy, inUse func(path string) bool) (string, error) {
+	path := filepath.Join(dir, name)
+	if !pathTaken(path, inUse) {
+		return path, nil
+	}
+
+	switch policy {
+	case CollisionOverwrite:
+		// El archivo existente se reemplaza al completar (rename atómico del .part)
+		if inUse(path) {
+			return "", fmt.Errorf("another transfer is already writing %s", name)
+		}
+		return path, nil
+
+	case CollisionReject:
+		return "", policyError(ReasonFileExists, "file %s already exists", name)
+
+	default:
+		ext := filepath.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		for i := 1; i < 1000; i++ {
+			suffix := fmt.Sprintf(" (%d)", i)
+			candidate := filepath.Join(dir, truncateFileName(base, maxFileNameBytes-len(suffix)-len(ext))+suffix+ext)
+			if !pathTaken(candidate, inUse) {
+				return candidate, nil
+			}
+		}
+		return "", fmt.Errorf("too many files named %s", name)
+	}
+}
+
+// pathTaken indica si ya existe el archivo, su .part o una transferencia que lo usa
+func pathTaken(path string, inUse func(path string) bool) bool {
+	if inUse(path) {
+		return true
+	}
+	for _, candidate := range []string{path, partPathFor(path), extractingPathFor(path)} {
+		if _, err := os.Lstat(candidate); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package filetransfer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", input: "report.pdf", want: "report.pdf"},
+		{name: "parent traversal", input: "../../etc/passwd", want: "passwd"},
+		{name: "windows traversal", input: `..\..\Windows\win.ini`, want: "win.ini"},
+		{name: "absolute unix", input: "/etc/shadow", want: "shadow"},
+		{name: "absolute windows", input: `C:\Users\admin\notes.txt`, want: "notes.txt"},
+		{name: "drive relative", input: "C:evil.txt", want: "C_evil.txt"},
+		{name: "unc path", input: `\\server\share\doc.txt`, want: "doc.txt"},
+		{name: "only dots", input: "..", wantErr: true},
+		{name: "trailing separator", input: "folder/", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+		{name: "reserved", input: "CON", want: "_CON"},
+		{name: "reserved lower case", input: "con.txt", want: "_con.txt"},
+		{name: "reserved double extension", input: "LPT1.tar.gz", want: "_LPT1.tar.gz"},
+		{name: "reserved after traversal", input: "../NUL", want: "_NUL"},
+		{name: "reserved prefix only", input: "CONSOLE.txt", want: "CONSOLE.txt"},
+		{name: "windows forbidden characters", input: `a<b>c:d"e|f?g*.txt`, want: "a_b_c_d_e_f_g_.txt"},
+		{name: "control characters", input: "file\x00name\n.txt", want: "filename.txt"},
+		{name: "trailing dots and spaces", input: "name. . ", want: "name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SanitizeFileName(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SanitizeFileName(%q) = %q, want error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SanitizeFileName(%q) error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("SanitizeFileName(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeFileNameTruncates(t *testing.T) {
+	for _, input := range []string{
+		strings.Repeat("a", 300) + ".txt",
+		strings.Repeat("é", 200) + ".txt",
+	} {
+		got, err := SanitizeFileName(input)
+		if err != nil {
+			t.Fatalf("SanitizeFileName error: %v", err)
+		}
+		if len(got) > maxFileNameBytes || !utf8.ValidString(got) || !strings.HasSuffix(got, ".txt") {
+			t.Errorf("SanitizeFileName(%d bytes) = %q (%d bytes)", len(input), got, len(got))
+		}
+	}
+}
+
+func TestResolveDestinationDir(t *testing.T) {
+	root := filepath.Join("downloads", "root")
+	allowed := []string{"reports", "shared/team"}
+
+	tests := []struct {
+		name        string
+		destination string
+		want        string
+		wantErr     bool
+	}{
+		{name: "default", destination: "", want: root},
+		{name: "allowed", destination: "reports", want: filepath.Join(root, "reports")},
+		{name: "inside allowed", destination: "reports/2024", want: filepath.Join(root, "reports", "2024")},
+		{name: "windows separators", destination: `shared\team\q1`, want: filepath.Join(root, "shared", "team", "q1")},
+		{name: "current dir components", destination: "./reports/.", want: filepath.Join(root, "reports")},
+		{name: "not allowed", destination: "other", wantErr: true},
+		{name: "parent of allowed", destination: "shared", wantErr: true},
+		{name: "allowed prefix", destination: "reportsX", wantErr: true},
+		{name: "escape through allowed", destination: "reports/../secret", wantErr: true},
+		{name: "leading parent", destination: "../reports", wantErr: true},
+		{name: "absolute unix", destination: "/reports", wantErr: true},
+		{name: "absolute windows", destination: `\reports`, wantErr: true},
+		{name: "drive", destination: `C:\reports`, wantErr: true},
+		{name: "reserved component", destination: "reports/CON", want: filepath.Join(root, "reports", "_CON")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveDestinationDir(root, tt.destination, allowed)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("resolveDestinationDir(%q) = %q, want error", tt.destination, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveDestinationDir(%q) error: %v", tt.destination, err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveDestinationDir(%q) = %q, want %q", tt.destination, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureWithinRootSymlink(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.Mkdir(filepath.Join(root, "reports"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("symlinks not available: %v", err)
+	}
+
+	if err := ensureWithinRoot(root, filepath.Join(root, "reports")); err != nil {
+		t.Errorf("real subdirectory rejected: %v", err)
+	}
+	if err := ensureWithinRoot(root, filepath.Join(root, "escape")); err == nil {
+		t.Error("symlink to a directory outside the root was accepted")
+	}
+}
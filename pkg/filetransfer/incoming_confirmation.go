@@ -0,0 +1,160 @@
+package filetransfer
+
+import (
+	"fmt"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// IncomingAutoAccept decide qué transferencias entrantes se aceptan sin preguntar al usuario
+type IncomingAutoAccept string
+
+const (
+	IncomingAutoAcceptNever   IncomingAutoAccept = "never"   // Siempre se pregunta
+	IncomingAutoAcceptTrusted IncomingAutoAccept = "trusted" // Sin preguntar en sesiones de confianza
+	IncomingAutoAcceptAlways  IncomingAutoAccept = "always"  // Nunca se pregunta
+)
+
+// ReasonDeclined es el código de una transferencia rechazada por el usuario o por timeout
+const ReasonDeclined ReasonCode = "declined"
+
+// IncomingTransfer describe una transferencia que espera la decisión del usuario
+type IncomingTransfer struct {
+	TransferID  string
+	SessionID   string
+	FileName    string // Nombre ya saneado; puede cambiar por colisión al aceptar
+	SizeBytes   int64
+	TotalChunks int
+	IsDirectory bool
+	ReceivedAt  time.Time
+}
+
+// pendingIncoming es una solicitud retenida hasta que el usuario decide
+type pendingIncoming struct {
+	request       api.FileTransferRequest
+	archiveFormat ArchiveFormat
+	info          IncomingTransfer
+}
+
+// SetIncomingTransferCallback establece el callback que pregunta al usuario por una transferencia
+func (fta *FileTransferAgent) SetIncomingTransferCallback(callback func(incoming IncomingTransfer)) {
+	fta.onIncomingTransfer = callback
+}
+
+// SetTrustedSessionCheck establece cómo saber si una sesión es de confianza (para "trusted")
+func (fta *FileTransferAgent) SetTrustedSessionCheck(check func(sessionID string) bool) {
+	fta.mutex.Lock()
+	defer fta.mutex.Unlock()
+	fta.isTrustedSession = check
+}
+
+// IncomingPromptTimeout retorna cuánto se espera la decisión del usuario
+func (fta *FileTransferAgent) IncomingPromptTimeout() time.Duration {
+	fta.mutex.RLock()
+	defer fta.mutex.RUnlock()
+	return fta.config.IncomingPromptTimeout()
+}
+
+// requiresConfirmation indica si hay que preguntar al usuario (requiere fta.mutex)
+func (fta *FileTransferAgent) requiresConfirmation(sessionID string) bool {
+	switch fta.config.IncomingAutoAccept {
+	case IncomingAutoAcceptAlways:
+		return false
+	case IncomingAutoAcceptTrusted:
+		return fta.isTrustedSession == nil || !fta.isTrustedSession(sessionID)
+	default:
+		return true
+	}
+}
+
+// holdIncoming comprueba la solicitud y la deja pendiente de confirmación. Se rechaza
+// antes de preguntar si la política no la permitiría (requiere fta.mutex).
+func (fta *FileTransferAgent) holdIncoming(request api.FileTransferRequest, archiveFormat ArchiveFormat) (*IncomingTransfer, error) {
+	_, fileName, err := fta.prepareRequest(request, archiveFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := &pendingIncoming{
+		request:       request,
+		archiveFormat: archiveFormat,
+		info: IncomingTransfer{
+			TransferID:  request.TransferID,
+			SessionID:   request.SessionID,
+			FileName:    fileName,
+			SizeBytes:   requestSize(request),
+			TotalChunks: request.TotalChunks,
+			IsDirectory: archiveFormat != ArchiveNone,
+			ReceivedAt:  time.Now(),
+		},
+	}
+	fta.incoming[request.TransferID] = pending
+
+	fmt.Printf("❓ FILE TRANSFER: %s (%.2f MB) awaiting user confirmation\n", fileName, request.FileSizeMB)
+	return &pending.info, nil
+}
+
+// AcceptIncoming inicia una transferencia pendiente y pide al servidor sus chunks
+// (los que llegaron mientras se esperaba la decisión se descartaron)
+func (fta *FileTransferAgent) AcceptIncoming(transferID string) (IncomingTransfer, error) {
+	fta.mutex.Lock()
+	pending, exists := fta.incoming[transferID]
+	if !exists {
+		fta.mutex.Unlock()
+		return IncomingTransfer{}, fmt.Errorf("transfer %s is not awaiting confirmation", transferID)
+	}
+	delete(fta.incoming, transferID)
+
+	// La política se vuelve a comprobar: el disco pudo llenarse mientras se esperaba
+	if err := fta.startTransfer(pending.request, pending.archiveFormat); err != nil {
+		fta.mutex.Unlock()
+		return pending.info, err
+	}
+	resume := fta.activeTransfers[transferID].resumeMessage()
+	fta.mutex.Unlock()
+
+	fmt.Printf("✅ FILE TRANSFER: %s accepted\n", pending.info.FileName)
+	if fta.onTransferResumed != nil {
+		fta.onTransferResumed(resume)
+	}
+	return pending.info, nil
+}
+
+// DeclineIncoming descarta una transferencia pendiente; el app informa el motivo al servidor
+func (fta *FileTransferAgent) DeclineIncoming(transferID string) (IncomingTransfer, error) {
+	fta.mutex.Lock()
+	defer fta.mutex.Unlock()
+
+	pending, exists := fta.incoming[transferID]
+	if !exists {
+		return IncomingTransfer{}, fmt.Errorf("transfer %s is not awaiting confirmation", transferID)
+	}
+	delete(fta.incoming, transferID)
+
+	fmt.Printf("🚫 FILE TRANSFER: %s declined\n", pending.info.FileName)
+	return pending.info, nil
+}
+
+// DiscardSessionIncoming descarta las transferencias pendientes de una sesión que terminó
+func (fta *FileTransferAgent) DiscardSessionIncoming(sessionID string) []IncomingTransfer {
+	fta.mutex.Lock()
+	defer fta.mutex.Unlock()
+
+	var discarded []IncomingTransfer
+	for id, pending := range fta.incoming {
+		if pending.info.SessionID == sessionID {
+			delete(fta.incoming, id)
+			discarded = append(discarded, pending.info)
+		}
+	}
+	return discarded
+}
+
+// requestSize retorna el tamaño anunciado de una solicitud en bytes
+func requestSize(request api.FileTransferRequest) int64 {
+	if request.FileSizeBytes > 0 {
+		return request.FileSizeBytes
+	}
+	return int64(request.FileSizeMB * 1024 * 1024)
+}
@@ -0,0 +1,210 @@
+package filetransfer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// incomingFixture es un agente que pregunta por cada transferencia entrante
+type incomingFixture struct {
+	agent       *FileTransferAgent
+	downloadDir string
+	prompts     chan IncomingTransfer
+	resumes     chan api.FileTransferResume
+	results     chan TransferResult
+}
+
+func newIncomingFixture(t *testing.T, autoAccept IncomingAutoAccept, trusted func(sessionID string) bool) incomingFixture {
+	t.Helper()
+
+	f := incomingFixture{
+		downloadDir: filepath.Join(t.TempDir(), "downloads"),
+		prompts:     make(chan IncomingTransfer, 4),
+		resumes:     make(chan api.FileTransferResume, 4),
+		results:     make(chan TransferResult, 4),
+	}
+	f.agent = newTestTransferAgent(t, f.downloadDir, func(config *Config) {
+		config.IncomingAutoAccept = autoAccept
+	})
+	if trusted != nil {
+		f.agent.SetTrustedSessionCheck(trusted)
+	}
+	f.agent.SetIncomingTransferCallback(func(incoming IncomingTransfer) { f.prompts <- incoming })
+	f.agent.SetTransferResumedCallback(func(resume api.FileTransferResume) { f.resumes <- resume })
+	f.agent.SetTransferCompletedCallback(func(result TransferResult) { f.results <- result })
+	return f
+}
+
+// request solicita transferID de sessionID con content en chunks de 1000 bytes
+func (f incomingFixture) request(t *testing.T, transferID, sessionID string, content []byte) {
+	t.Helper()
+	if err := f.agent.HandleFileTransferRequest(api.FileTransferRequest{
+		TransferID:    transferID,
+		SessionID:     sessionID,
+		FileName:      transferID + ".bin",
+		FileSizeBytes: int64(len(content)),
+		TotalChunks:   (len(content) + 999) / 1000,
+		ChunkSize:     1000,
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertNothingOnDisk comprueba que no se creó nada en descargas ni en la cuarentena
+func (f incomingFixture) assertNothingOnDisk(t *testing.T) {
+	t.Helper()
+	for _, dir := range []string{f.downloadDir, f.agent.GetQuarantineDirectory()} {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			t.Errorf("%s was created in %s before accepting", entry.Name(), dir)
+		}
+	}
+}
+
+func TestIncomingTransferWaitsForAcceptance(t *testing.T) {
+	content := make([]byte, 2500)
+	rand.Read(content)
+
+	f := newIncomingFixture(t, IncomingAutoAcceptNever, nil)
+	f.request(t, "held", "session-1", content)
+
+	select {
+	case prompt := <-f.prompts:
+		if prompt.TransferID != "held" || prompt.FileName != "held.bin" || prompt.SizeBytes != int64(len(content)) || prompt.TotalChunks != 3 {
+			t.Errorf("prompt = %+v", prompt)
+		}
+	default:
+		t.Fatal("the user was not asked")
+	}
+	f.assertNothingOnDisk(t)
+
+	// Los chunks que llegan mientras se espera se descartan
+	for index := 0; index < 2; index++ {
+		if err := f.agent.HandleFileChunk(testFileChunk("held", content, 1000, index)); err == nil {
+			t.Errorf("chunk %d was accepted while awaiting confirmation", index)
+		}
+	}
+	f.assertNothingOnDisk(t)
+	if len(f.agent.GetActiveTransfers()) != 0 {
+		t.Error("a transfer became active before accepting")
+	}
+
+	if _, err := f.agent.AcceptIncoming("held"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case resume := <-f.resumes:
+		want := []api.ChunkRange{{Start: 0, End: 2}}
+		if resume.TransferID != "held" || resume.ReceivedChunks != 0 || !reflect.DeepEqual(resume.MissingRanges, want) {
+			t.Errorf("resume = %+v, want every chunk requested again", resume)
+		}
+	default:
+		t.Fatal("accepting did not request the chunks")
+	}
+	if _, err := f.agent.AcceptIncoming("held"); err == nil {
+		t.Error("a transfer was accepted twice")
+	}
+
+	for index := 0; index < 3; index++ {
+		if err := f.agent.HandleFileChunk(testFileChunk("held", content, 1000, index)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result := waitTransferResult(t, f.results)
+	if !result.Success {
+		t.Fatalf("transfer failed: %s", result.ErrorMessage)
+	}
+	if got, err := os.ReadFile(result.FilePath); err != nil || !bytes.Equal(got, content) {
+		t.Errorf("received file is missing or differs: %v", err)
+	}
+}
+
+func TestIncomingTransferDecline(t *testing.T) {
+	content := make([]byte, 1500)
+	rand.Read(content)
+
+	f := newIncomingFixture(t, IncomingAutoAcceptNever, nil)
+	f.request(t, "unwanted", "session-1", content)
+
+	declined, err := f.agent.DeclineIncoming("unwanted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if declined.TransferID != "unwanted" || declined.SessionID != "session-1" {
+		t.Errorf("declined = %+v", declined)
+	}
+	if _, err := f.agent.AcceptIncoming("unwanted"); err == nil {
+		t.Error("a declined transfer was accepted")
+	}
+	if err := f.agent.HandleFileChunk(testFileChunk("unwanted", content, 1000, 0)); err == nil {
+		t.Error("a chunk of a declined transfer was accepted")
+	}
+	if len(f.resumes) != 0 || len(f.results) != 0 {
+		t.Error("declining requested chunks or reported a result")
+	}
+	f.assertNothingOnDisk(t)
+}
+
+func TestIncomingAutoAccept(t *testing.T) {
+	tests := []struct {
+		name       string
+		autoAccept IncomingAutoAccept
+		trusted    func(sessionID string) bool
+		wantPrompt bool
+	}{
+		{name: "never", autoAccept: IncomingAutoAcceptNever, trusted: func(string) bool { return true }, wantPrompt: true},
+		{name: "always", autoAccept: IncomingAutoAcceptAlways, wantPrompt: false},
+		{name: "trusted session", autoAccept: IncomingAutoAcceptTrusted, trusted: func(id string) bool { return id == "admin" }, wantPrompt: false},
+		{name: "untrusted session", autoAccept: IncomingAutoAcceptTrusted, trusted: func(id string) bool { return id == "other" }, wantPrompt: true},
+		{name: "trusted without a check", autoAccept: IncomingAutoAcceptTrusted, wantPrompt: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newIncomingFixture(t, tt.autoAccept, tt.trusted)
+			f.request(t, "auto", "admin", make([]byte, 1000))
+
+			prompted := len(f.prompts) == 1
+			active := len(f.agent.GetActiveTransfers()) == 1
+			if prompted != tt.wantPrompt || active == tt.wantPrompt {
+				t.Errorf("prompted = %v, active = %v, want prompt %v", prompted, active, tt.wantPrompt)
+			}
+		})
+	}
+}
+
+func TestDiscardSessionIncoming(t *testing.T) {
+	f := newIncomingFixture(t, IncomingAutoAcceptNever, nil)
+	f.request(t, "a-1", "session-a", make([]byte, 1000))
+	f.request(t, "a-2", "session-a", make([]byte, 1000))
+	f.request(t, "b-1", "session-b", make([]byte, 1000))
+
+	var discarded []string
+	for _, incoming := range f.agent.DiscardSessionIncoming("session-a") {
+		discarded = append(discarded, incoming.TransferID)
+	}
+	sort.Strings(discarded)
+	if want := []string{"a-1", "a-2"}; !reflect.DeepEqual(discarded, want) {
+		t.Errorf("discarded = %v, want %v", discarded, want)
+	}
+
+	if _, err := f.agent.AcceptIncoming("a-1"); err == nil {
+		t.Error("a discarded transfer was accepted")
+	}
+	if _, err := f.agent.AcceptIncoming("b-1"); err != nil {
+		t.Errorf("the other session's transfer was discarded: %v", err)
+	}
+	if got := f.agent.DiscardSessionIncoming("session-a"); len(got) != 0 {
+		t.Errorf("discarding again returned %v", got)
+	}
+}
@@ -0,0 +1,94 @@
+package filetransfer
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// maxChunkRetransmits es el número de reenvíos de un mismo chunk antes de abortar
+const maxChunkRetransmits = 3
+
+// ChecksumAlgorithm es el algoritmo del checksum de archivo completo
+const ChecksumAlgorithm = "sha256"
+
+// checksumSizes son los algoritmos aceptados y la longitud en hex de su digest
+var checksumSizes = map[string]int{
+	"sha256": sha256.Size * 2,
+	"md5":    md5.Size * 2,
+}
+
+// parseChecksum separa un checksum "algoritmo:hex" en algoritmo y digest en minúsculas.
+// Sin prefijo el algoritmo se deduce de la longitud; vacío devuelve "", "".
+func parseChecksum(checksum string) (string, string, error) {
+	checksum = strings.ToLower(strings.TrimSpace(checksum))
+	if checksum == "" {
+		return "", "", nil
+	}
+
+	algorithm, digest, prefixed := strings.Cut(checksum, ":")
+	if !prefixed {
+		digest = checksum
+		algorithm = ""
+		for name, size := range checksumSizes {
+			if len(digest) == size {
+				algorithm = name
+			}
+		}
+		if algorithm == "" {
+			return "", "", fmt.Errorf("unrecognized checksum %q", checksum)
+		}
+	}
+
+	size, ok := checksumSizes[algorithm]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+	}
+	if len(digest) != size {
+		return "", "", fmt.Errorf("%s checksum must have %d hex digits, got %d", algorithm, size, len(digest))
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", "", fmt.Errorf("invalid %s checksum %q", algorithm, digest)
+	}
+	return algorithm, digest, nil
+}
+
+// parseFileChecksum valida el checksum de archivo completo, que solo puede ser SHA-256
+func parseFileChecksum(checksum string) (string, error) {
+	algorithm, digest, err := parseChecksum(checksum)
+	if err != nil {
+		return "", fmt.Errorf("invalid file checksum: %v", err)
+	}
+	if algorithm != "" && algorithm != ChecksumAlgorithm {
+		return "", fmt.Errorf("unsupported file checksum algorithm %q, expected %s", algorithm, ChecksumAlgorithm)
+	}
+	return digest, nil
+}
+
+// verifyChunkChecksum compara los datos decodificados de un chunk con su checksum.
+// Se acepta SHA-256 o, por compatibilidad con servidores anteriores, MD5; vacío = sin verificar.
+func verifyChunkChecksum(expected string, data []byte) error {
+	algorithm, digest, err := parseChecksum(expected)
+	if err != nil {
+		return err
+	}
+
+	var actual string
+	switch algorithm {
+	case "":
+		return nil
+	case "sha256":
+		sum := sha256.Sum256(data)
+		actual = hex.EncodeToString(sum[:])
+	case "md5":
+		sum := md5.Sum(data)
+		actual = hex.EncodeToString(sum[:])
+	}
+
+	if actual != digest {
+		return fmt.Errorf("checksum mismatch: got %s, expected %s", actual, digest)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package filetransfer
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+func TestVerifyChunkChecksum(t *testing.T) {
+	data := []byte("chunk data")
+	sha := sha256.Sum256(data)
+	shaHex := hex.EncodeToString(sha[:])
+	md := md5.Sum(data)
+	mdHex := hex.EncodeToString(md[:])
+	otherSha := sha256.Sum256([]byte("other data"))
+
+	tests := []struct {
+		name     string
+		expected string
+		wantErr  string // "" = válido
+	}{
+		{name: "empty skips verification", expected: ""},
+		{name: "sha256 without prefix", expected: shaHex},
+		{name: "sha256 with prefix", expected: "sha256:" + shaHex},
+		{name: "uppercase and spaces", expected: "  SHA256:" + strings.ToUpper(shaHex) + " "},
+		{name: "md5 without prefix", expected: mdHex},
+		{name: "md5 with prefix", expected: "md5:" + mdHex},
+		{name: "sha256 mismatch", expected: hex.EncodeToString(otherSha[:]), wantErr: "checksum mismatch"},
+		{name: "md5 prefix on a sha256 digest", expected: "md5:" + shaHex, wantErr: "md5 checksum must have 32 hex digits"},
+		{name: "sha256 prefix on an md5 digest", expected: "sha256:" + mdHex, wantErr: "sha256 checksum must have 64 hex digits"},
+		{name: "unknown algorithm", expected: "sha1:" + shaHex, wantErr: `unsupported checksum algorithm "sha1"`},
+		{name: "empty algorithm", expected: ":" + shaHex, wantErr: `unsupported checksum algorithm ""`},
+		{name: "unknown length", expected: shaHex[:40], wantErr: "unrecognized checksum"},
+		{name: "not hex", expected: strings.Repeat("z", 64), wantErr: "invalid sha256 checksum"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyChunkChecksum(tt.expected, data)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyChunkChecksum(%q) = %v, want nil", tt.expected, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("verifyChunkChecksum(%q) = %v, want error containing %q", tt.expected, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFileChecksum(t *testing.T) {
+	sha := sha256.Sum256([]byte("file"))
+	shaHex := hex.EncodeToString(sha[:])
+	md := md5.Sum([]byte("file"))
+
+	tests := []struct {
+		name     string
+		checksum string
+		want     string
+		wantErr  bool
+	}{
+		{name: "empty", checksum: "", want: ""},
+		{name: "sha256", checksum: shaHex, want: shaHex},
+		{name: "sha256 with prefix", checksum: "SHA256:" + strings.ToUpper(shaHex), want: shaHex},
+		{name: "md5 is not accepted for the whole file", checksum: hex.EncodeToString(md[:]), wantErr: true},
+		{name: "md5 prefix on a sha256 digest", checksum: "md5:" + shaHex, wantErr: true},
+		{name: "unknown algorithm", checksum: "crc32:" + shaHex, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFileChecksum(tt.checksum)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFileChecksum(%q) error = %v, wantErr %v", tt.checksum, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseFileChecksum(%q) = %q, want %q", tt.checksum, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileTransferRejectsUnsupportedFileChecksum(t *testing.T) {
+	sha := sha256.Sum256([]byte("file"))
+	agent := newTestTransferAgent(t, t.TempDir(), nil)
+
+	err := agent.HandleFileTransferRequest(api.FileTransferRequest{
+		TransferID:    "bad-checksum",
+		SessionID:     "test",
+		FileName:      "bad-checksum.bin",
+		FileSizeBytes: 4,
+		TotalChunks:   1,
+		FileChecksum:  "md5:" + hex.EncodeToString(sha[:]),
+	})
+	if err == nil {
+		t.Fatal("request with an md5-prefixed SHA-256 digest was accepted")
+	}
+	if active := agent.GetActiveTransfers(); len(active) != 0 {
+		t.Errorf("active transfers = %d, want 0", len(active))
+	}
+}
+
+func TestFileTransferFailsAfterMaxRetransmits(t *testing.T) {
+	const chunkSize = 100
+	content := make([]byte, 2*chunkSize)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	sum := sha256.Sum256(content)
+
+	agent := newTestTransferAgent(t, t.TempDir(), nil)
+
+	results := make(chan TransferResult, 1)
+	agent.SetTransferCompletedCallback(func(result TransferResult) {
+		results <- result
+	})
+	retransmits := make(chan api.FileChunkRetransmit, maxChunkRetransmits+1)
+	agent.SetChunkRetransmitCallback(func(retransmit api.FileChunkRetransmit) {
+		retransmits <- retransmit
+	})
+
+	err := agent.HandleFileTransferRequest(api.FileTransferRequest{
+		TransferID:    "corrupt",
+		SessionID:     "test",
+		FileName:      "corrupt.bin",
+		FileSizeBytes: int64(len(content)),
+		TotalChunks:   2,
+		ChunkSize:     chunkSize,
+		FileChecksum:  hex.EncodeToString(sum[:]),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// El chunk 1 llega siempre con un checksum que no corresponde a sus datos
+	corrupt := testFileChunk("corrupt", content, chunkSize, 1)
+	corrupt.ChunkChecksum = testFileChunk("corrupt", content, chunkSize, 0).ChunkChecksum
+
+	for attempt := 1; attempt <= maxChunkRetransmits; attempt++ {
+		if err := agent.HandleFileChunk(corrupt); err != nil {
+			t.Fatalf("attempt %d: %v", attempt, err)
+		}
+		select {
+		case retransmit := <-retransmits:
+			if retransmit.ChunkIndex != 1 || retransmit.Attempt != attempt || retransmit.TransferID != "corrupt" {
+				t.Fatalf("retransmit = %+v, want chunk 1 attempt %d", retransmit, attempt)
+			}
+		case result := <-results:
+			t.Fatalf("transfer finished after %d attempts: %+v", attempt, result)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no retransmit requested for attempt %d", attempt)
+		}
+	}
+
+	// Un intento más agota los reenvíos: la transferencia falla sin pedir otro
+	if err := agent.HandleFileChunk(corrupt); err != nil {
+		t.Fatal(err)
+	}
+	result := waitTransferResult(t, results)
+	if result.Success {
+		t.Fatal("transfer succeeded with a corrupt chunk")
+	}
+	if !strings.Contains(result.ErrorMessage, "failed verification") {
+		t.Errorf("error = %q, want a verification failure", result.ErrorMessage)
+	}
+	select {
+	case retransmit := <-retransmits:
+		t.Errorf("unexpected retransmit after the limit: %+v", retransmit)
+	default:
+	}
+	if active := agent.GetActiveTransfers(); len(active) != 0 {
+		t.Errorf("active transfers = %d, want 0", len(active))
+	}
+}
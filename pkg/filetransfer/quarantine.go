@@ -0,0 +1,329 @@
+package filetransfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxPublishAttempts limita cuántos nombres se prueban si el destino aparece mientras se publica
+const maxPublishAttempts = 5
+
+// defaultQuarantineDir es la cuarentena si no se configura otra. Queda fuera del
+// directorio de descarga para que nadie abra un archivo que aún no se ha escaneado.
+func defaultQuarantineDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "escritorio-remoto-quarantine")
+	}
+	return filepath.Join(homeDir, ".escritorio-remoto", "quarantine")
+}
+
+// SetScanner reemplaza el escáner de cuarentena (nil = publicar sin escanear)
+func (fta *FileTransferAgent) SetScanner(scanner Scanner) {
+	fta.mutex.Lock()
+	defer fta.mutex.Unlock()
+	fta.scanner = scanner
+}
+
+// GetQuarantineDirectory retorna el directorio donde se escanean los archivos recibidos
+func (fta *FileTransferAgent) GetQuarantineDirectory() string {
+	fta.mutex.RLock()
+	defer fta.mutex.RUnlock()
+	return fta.quarantineRoot()
+}
+
+// quarantineRoot retorna el directorio de cuarentena configurado (requiere fta.mutex)
+func (fta *FileTransferAgent) quarantineRoot() string {
+	if fta.config.QuarantineDir != "" {
+		return fta.config.QuarantineDir
+	}
+	return defaultQuarantineDir()
+}
+
+// newQuarantineDir crea (vacía) la carpeta propia de una transferencia en la cuarentena
+// (requiere fta.mutex)
+func (fta *FileTransferAgent) newQuarantineDir(transferID string) (string, error) {
+	id, err := SanitizeFileName(transferID)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(fta.quarantineRoot(), id)
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// stagingPathFor retorna la base de los archivos de trabajo (.part, manifiesto y
+// extracción) de una transferencia. Con escáner van a su carpeta de la cuarentena:
+// hasta el escaneo nada de lo recibido debe estar en el directorio de descarga
+// (requiere fta.mutex)
+func (fta *FileTransferAgent) stagingPathFor(transferID, outputPath string) (string, error) {
+	if fta.scanner == nil {
+		return outputPath, nil
+	}
+
+	dir, err := fta.newQuarantineDir(transferID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+	return filepath.Join(dir, filepath.Base(outputPath)), nil
+}
+
+// quarantinePathFor retorna dónde se escanea una transferencia, en una carpeta propia
+// de modo que el archivo conserva su nombre (y extensión) durante el escaneo
+func (fta *FileTransferAgent) quarantinePathFor(transfer *FileTransfer) (string, error) {
+	// Se recibió directamente en la cuarentena: se escanea en la misma carpeta
+	if transfer.stagingPath != transfer.outputFilePath {
+		return transfer.stagingPath, nil
+	}
+
+	fta.mutex.RLock()
+	dir, err := fta.newQuarantineDir(transfer.TransferID)
+	fta.mutex.RUnlock()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, transfer.FileName), nil
+}
+
+// quarantineTransfer mueve una transferencia verificada a la cuarentena y la escanea
+// en segundo plano; el resultado se notifica al terminar el escaneo
+func (fta *FileTransferAgent) quarantineTransfer(transfer *FileTransfer, result TransferResult, scanner Scanner, collision CollisionPolicy) error {
+	quarantinePath, err := fta.quarantinePathFor(transfer)
+	if err == nil {
+		result.Entries, err = transfer.publish(func(source string) error {
+			return movePath(source, quarantinePath, false)
+		})
+	}
+	if err != nil {
+		transfer.reasonCode = ReasonCodeOf(err)
+		fta.cleanupTransfer(transfer, fmt.Sprintf("failed to move completed transfer to quarantine: %v", err))
+		if quarantinePath != "" {
+			os.RemoveAll(filepath.Dir(quarantinePath))
+		}
+		return err
+	}
+	removeTransferFiles(transfer.stagingPath)
+
+	// La ruta final queda reservada hasta que termine el escaneo
+	transfer.finished = true
+	fta.mutex.Lock()
+	delete(fta.activeTransfers, transfer.TransferID)
+	fta.scanning[transfer.TransferID] = transfer.outputFilePath
+	fta.mutex.Unlock()
+
+	fmt.Printf("🛡️ FILE TRANSFER: %s quarantined for scanning in %s\n", transfer.FileName, quarantinePath)
+
+	go fta.scanQuarantined(scanner, result, quarantinePath, transfer.outputFilePath, collision)
+	return nil
+}
+
+// scanQuarantined escanea un resultado en cuarentena y lo publica solo si está limpio.
+// Si el escáner detecta algo o falla, el archivo se queda en cuarentena.
+func (fta *FileTransferAgent) scanQuarantined(scanner Scanner, result TransferResult, quarantinePath, outputPath string, collision CollisionPolicy) {
+	scanStart := time.Now()
+	verdict, err := scanner.Scan(context.Background(), quarantinePath)
+	result.Duration += time.Since(scanStart)
+
+	switch {
+	case err != nil:
+		result.ScanStatus = ScanFailed
+		result.ReasonCode = ReasonScanFailed
+		result.ErrorMessage = fmt.Sprintf("scan failed: %v", err)
+
+	case !verdict.Clean:
+		result.ScanStatus = ScanInfected
+		result.ReasonCode = ReasonInfected
+		result.ErrorMessage = fmt.Sprintf("threat detected by %s: %s", verdict.Scanner, verdict.Threat)
+
+	default:
+		result.ScanStatus = ScanClean
+		finalPath, err := fta.moveToOutput(quarantinePath, outputPath, collision)
+		if err != nil {
+			result.ReasonCode = ReasonCodeOf(err)
+			result.ErrorMessage = fmt.Sprintf("failed to release file from quarantine: %v", err)
+			break
+		}
+		os.Remove(filepath.Dir(quarantinePath))
+		result.FilePath = finalPath
+		result.FileName = filepath.Base(finalPath)
+	}
+
+	// La ruta final deja de estar reservada una vez movido (o retenido) el archivo
+	fta.mutex.Lock()
+	delete(fta.scanning, result.TransferID)
+	fta.mutex.Unlock()
+
+	if result.FilePath == "" {
+		result.Success = false
+		result.QuarantinePath = quarantinePath
+		fmt.Printf("🦠 FILE TRANSFER: %s kept in quarantine: %s\n", result.FileName, result.ErrorMessage)
+	} else {
+		fmt.Printf("✅ FILE TRANSFER: %s scanned clean by %s, saved to %s\n", result.FileName, verdict.Scanner, result.FilePath)
+	}
+
+	if fta.onTransferCompleted != nil {
+		fta.onTransferCompleted(result)
+	}
+}
+
+// movePath mueve un archivo o carpeta a dest. Con overwrite se elimina antes lo que
+// ocupe dest, salvo entre archivos, donde el rename ya reemplaza de forma atómica.
+// Sin overwrite nunca se reemplaza dest: si ya existe se retorna un error fs.ErrExist.
+// Si dest está en otro disco (p. ej. al salir de la cuarentena) se copia y se borra el origen.
+func movePath(source, dest string, overwrite bool) error {
+	sourceInfo, err := os.Lstat(source)
+	if err != nil {
+		return err
+	}
+
+	if overwrite {
+		if destInfo, err := os.Lstat(dest); err == nil && (sourceInfo.IsDir() || destInfo.IsDir()) {
+			if err := os.RemoveAll(dest); err != nil {
+				return err
+			}
+		}
+		err = os.Rename(source, dest)
+	} else {
+		err = renameNoClobber(source, dest, sourceInfo.IsDir())
+	}
+	if err != nil && isCrossDevice(err) {
+		return copyAcrossDevices(source, dest, sourceInfo.IsDir(), overwrite)
+	}
+	return err
+}
+
+// renameNoClobber renombra source a dest sin reemplazar lo que exista en dest. Un archivo
+// se enlaza y después se elimina el origen, así la comprobación es atómica; una carpeta
+// (o un sistema de archivos sin enlaces duros) se comprueba con Lstat antes del rename.
+func renameNoClobber(source, dest string, isDir bool) error {
+	if !isDir {
+		err := os.Link(source, dest)
+		if err == nil {
+			return os.Remove(source)
+		}
+		if errors.Is(err, fs.ErrExist) || isCrossDevice(err) {
+			return err
+		}
+	}
+
+	if _, err := os.Lstat(dest); err == nil {
+		return &fs.PathError{Op: "move", Path: dest, Err: fs.ErrExist}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.Rename(source, dest)
+}
+
+// moveToOutput publica el resultado completo en su ruta final y retorna la ruta usada.
+// La ruta se eligió al iniciar la transferencia y desde entonces (p. ej. durante un
+// escaneo largo) puede haber aparecido un archivo con ese nombre: salvo con
+// CollisionOverwrite no se reemplaza, sino que se vuelve a aplicar la política.
+func (fta *FileTransferAgent) moveToOutput(source, outputPath string, policy CollisionPolicy) (string, error) {
+	dest := outputPath
+	for attempt := 1; ; attempt++ {
+		err := movePath(source, dest, policy == CollisionOverwrite)
+		if err == nil {
+			return dest, nil
+		}
+		if !errors.Is(err, fs.ErrExist) || attempt == maxPublishAttempts {
+			return "", err
+		}
+
+		fta.mutex.RLock()
+		dest, err = resolveCollision(filepath.Dir(outputPath), filepath.Base(outputPath), policy, fta.isOutputPathInUse)
+		fta.mutex.RUnlock()
+		if err != nil {
+			return "", err
+		}
+		fmt.Printf("📁 FILE TRANSFER: %s appeared while receiving, saving as %q\n",
+			filepath.Base(outputPath), filepath.Base(dest))
+	}
+}
+
+// copyAcrossDevices copia source junto a dest y lo renombra a dest, de modo que
+// dest nunca queda a medio copiar (sin overwrite, tampoco reemplazado); después elimina source
+func copyAcrossDevices(source, dest string, isDir, overwrite bool) error {
+	tmp := dest + ".moving"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+
+	err := copyTree(source, tmp)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if overwrite {
+		err = os.Rename(tmp, dest)
+	} else {
+		err = renameNoClobber(tmp, dest, isDir)
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	return os.RemoveAll(source)
+}
+
+// copyTree copia un archivo o un directorio con sus archivos regulares y subcarpetas
+func copyTree(source, dest string) error {
+	return filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case entry.Type().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			// La extracción nunca crea enlaces ni dispositivos
+			return nil
+		}
+	})
+}
+
+// copyFile copia un archivo regular y lo sincroniza antes de cerrarlo
+func copyFile(source, dest string, mode os.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,52 @@
+package filetransfer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// RevealInFileManager abre el explorador de archivos del sistema en la carpeta que
+// contiene path, seleccionándolo cuando el sistema lo permite. Si path ya no existe
+// se abre la carpeta que lo contenía.
+func RevealInFileManager(path string) error {
+	if path == "" {
+		return fmt.Errorf("no path to reveal")
+	}
+
+	_, err := os.Stat(path)
+	exists := err == nil
+	dir := filepath.Dir(path)
+	if !exists {
+		if _, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("folder no longer exists: %s", dir)
+		}
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		if exists {
+			cmd = exec.Command("explorer", "/select,", path)
+		} else {
+			cmd = exec.Command("explorer", dir)
+		}
+	case "darwin":
+		if exists {
+			cmd = exec.Command("open", "-R", path)
+		} else {
+			cmd = exec.Command("open", dir)
+		}
+	default:
+		cmd = exec.Command("xdg-open", dir)
+	}
+
+	// El explorador sigue abierto tras lanzarlo: no se espera a que termine
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open file manager: %w", err)
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,284 @@
+package filetransfer
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// Códigos de rechazo del escaneo en cuarentena
+	ReasonInfected   ReasonCode = "infected"
+	ReasonScanFailed ReasonCode = "scan_failed"
+
+	defaultScanTimeout = 2 * time.Minute
+	scanWaitDelay      = 5 * time.Second
+	maxThreatLength    = 200
+)
+
+// Estado del escaneo de un archivo recibido
+const (
+	ScanClean    = "clean"
+	ScanInfected = "infected"
+	ScanFailed   = "failed"
+	ScanSkipped  = "skipped" // No hay escáner configurado
+)
+
+// ScanResult es el veredicto de un escáner sobre un archivo o carpeta
+type ScanResult struct {
+	Clean   bool
+	Threat  string // Descripción de lo detectado si no está limpio
+	Scanner string
+}
+
+// Scanner analiza un archivo o carpeta en cuarentena. Un error significa que no se
+// pudo analizar, no que esté infectado.
+type Scanner interface {
+	Scan(ctx context.Context, path string) (ScanResult, error)
+}
+
+// ScanConfig configura los escáneres que se aplican en cuarentena
+type ScanConfig struct {
+	// Comando y argumentos; "{path}" se reemplaza por la ruta (si no aparece, se añade al final)
+	Command []string `json:"command"`
+
+	// Códigos de salida que indican amenaza (clamscan usa 1); cualquier otro distinto de 0 es error
+	InfectedExitCodes []int `json:"infected_exit_codes"`
+
+	TimeoutSeconds int `json:"timeout_seconds"`
+
+	// Archivo con un SHA-256 por línea (opcionalmente seguido de un nombre)
+	HashBlocklistFile string `json:"hash_blocklist_file"`
+}
+
+// NewScannerFromConfig crea los escáneres configurados; retorna nil si no hay ninguno
+func NewScannerFromConfig(config ScanConfig) (Scanner, error) {
+	var scanners ScannerChain
+
+	if config.HashBlocklistFile != "" {
+		blocklist, err := LoadHashBlocklist(config.HashBlocklistFile)
+		if err != nil {
+			return nil, err
+		}
+		scanners = append(scanners, blocklist)
+	}
+
+	if len(config.Command) > 0 {
+		timeout := defaultScanTimeout
+		if config.TimeoutSeconds > 0 {
+			timeout = time.Duration(config.TimeoutSeconds) * time.Second
+		}
+		infectedCodes := config.InfectedExitCodes
+		if len(infectedCodes) == 0 {
+			infectedCodes = []int{1}
+		}
+		scanners = append(scanners, &CommandScanner{
+			Command:           config.Command,
+			InfectedExitCodes: infectedCodes,
+			Timeout:           timeout,
+		})
+	}
+
+	switch len(scanners) {
+	case 0:
+		return nil, nil
+	case 1:
+		return scanners[0], nil
+	default:
+		return scanners, nil
+	}
+}
+
+// ScannerChain aplica varios escáneres en orden; el primero que detecta algo decide
+type ScannerChain []Scanner
+
+func (c ScannerChain) Scan(ctx context.Context, path string) (ScanResult, error) {
+	var names []string
+	for _, scanner := range c {
+		result, err := scanner.Scan(ctx, path)
+		if err != nil {
+			return ScanResult{}, err
+		}
+		if !result.Clean {
+			return result, nil
+		}
+		names = append(names, result.Scanner)
+	}
+	return ScanResult{Clean: true, Scanner: strings.Join(names, "+")}, nil
+}
+
+// CommandScanner ejecuta un antivirus externo como clamscan
+type CommandScanner struct {
+	Command           []string
+	InfectedExitCodes []int
+	Timeout           time.Duration
+}
+
+func (s *CommandScanner) Scan(ctx context.Context, path string) (ScanResult, error) {
+	name := filepath.Base(s.Command[0])
+
+	args := make([]string, 0, len(s.Command))
+	replaced := false
+	for _, arg := range s.Command[1:] {
+		if strings.Contains(arg, "{path}") {
+			arg = strings.ReplaceAll(arg, "{path}", path)
+			replaced = true
+		}
+		args = append(args, arg)
+	}
+	if !replaced {
+		args = append(args, path)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.Command[0], args...)
+	// Un proceso hijo que herede la salida no debe retener el escaneo tras el timeout
+	cmd.WaitDelay = scanWaitDelay
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return ScanResult{Clean: true, Scanner: name}, nil
+	}
+	if ctx.Err() != nil {
+		return ScanResult{}, fmt.Errorf("%s timed out after %v", name, s.Timeout)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		for _, code := range s.InfectedExitCodes {
+			if exitErr.ExitCode() == code {
+				return ScanResult{Threat: summarizeScanOutput(output), Scanner: name}, nil
+			}
+		}
+	}
+	if summary := summarizeScanOutput(output); summary != "" {
+		return ScanResult{}, fmt.Errorf("%s failed: %v: %s", name, err, summary)
+	}
+	return ScanResult{}, fmt.Errorf("%s failed: %v", name, err)
+}
+
+// summarizeScanOutput extrae la línea relevante de la salida de un antivirus
+func summarizeScanOutput(output []byte) string {
+	var last string
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		// clamscan: "<ruta>: <firma> FOUND"
+		if strings.HasSuffix(line, "FOUND") {
+			return truncateThreat(line)
+		}
+		last = line
+	}
+	return truncateThreat(last)
+}
+
+// truncateThreat limita el texto que se envía al servidor y al log
+func truncateThreat(threat string) string {
+	if len(threat) > maxThreatLength {
+		return threat[:maxThreatLength] + "..."
+	}
+	return threat
+}
+
+// HashBlocklist rechaza archivos cuyo SHA-256 está en una lista conocida
+type HashBlocklist struct {
+	hashes map[string]string // hash → nombre de la amenaza
+}
+
+// LoadHashBlocklist lee una lista de SHA-256; ignora líneas vacías y comentarios (#)
+func LoadHashBlocklist(path string) (*HashBlocklist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open hash blocklist: %w", err)
+	}
+	defer file.Close()
+
+	blocklist := &HashBlocklist{hashes: make(map[string]string)}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		hash := strings.ToLower(fields[0])
+		if len(hash) != sha256.Size*2 {
+			return nil, fmt.Errorf("invalid SHA-256 at line %d of %s", line, path)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("invalid SHA-256 at line %d of %s", line, path)
+		}
+
+		threat := strings.Join(fields[1:], " ")
+		if threat == "" {
+			threat = "blocklisted hash " + hash
+		}
+		blocklist.hashes[hash] = threat
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read hash blocklist: %w", err)
+	}
+
+	return blocklist, nil
+}
+
+// Scan calcula el SHA-256 del archivo, o de cada archivo de una carpeta
+func (b *HashBlocklist) Scan(ctx context.Context, path string) (ScanResult, error) {
+	result := ScanResult{Clean: true, Scanner: "hash_blocklist"}
+
+	err := filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		hash, err := hashFile(filePath)
+		if err != nil {
+			return err
+		}
+		if threat, blocked := b.hashes[hash]; blocked {
+			result.Clean = false
+			result.Threat = fmt.Sprintf("%s: %s", filepath.Base(filePath), threat)
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return ScanResult{}, err
+	}
+
+	return result, nil
+}
+
+// hashFile retorna el SHA-256 (hex) de un archivo
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,435 @@
+package filetransfer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// requireShell salta el test si no hay un sh con el que simular un antivirus
+func requireShell(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+}
+
+func TestCommandScanner(t *testing.T) {
+	requireShell(t)
+
+	tests := []struct {
+		name       string
+		script     string
+		wantClean  bool
+		wantThreat string
+		wantErr    string
+	}{
+		{name: "exit 0 is clean", script: "exit 0", wantClean: true},
+		{name: "infected exit code", script: `echo "$0: Eicar-Signature FOUND"; exit 1`, wantThreat: "Eicar-Signature FOUND"},
+		{name: "second infected exit code", script: "echo detected; exit 3", wantThreat: "detected"},
+		{name: "other exit code is an error", script: "echo 'cannot open database' >&2; exit 2", wantErr: "cannot open database"},
+		{name: "killed is an error", script: "kill -9 $$", wantErr: "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file.bin")
+			scanner := &CommandScanner{
+				Command:           []string{"sh", "-c", tt.script},
+				InfectedExitCodes: []int{1, 3},
+				Timeout:           5 * time.Second,
+			}
+
+			result, err := scanner.Scan(context.Background(), path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Scan() = %+v, %v; want error containing %q", result, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan() error: %v", err)
+			}
+			if result.Clean != tt.wantClean || !strings.Contains(result.Threat, tt.wantThreat) || result.Scanner != "sh" {
+				t.Errorf("Scan() = %+v, want clean %v with threat %q", result, tt.wantClean, tt.wantThreat)
+			}
+		})
+	}
+}
+
+func TestCommandScannerPathPlaceholder(t *testing.T) {
+	requireShell(t)
+	path := filepath.Join(t.TempDir(), "file with spaces.bin")
+
+	// La ruta sustituye a {path} en lugar de añadirse al final
+	scanner := &CommandScanner{
+		Command:           []string{"sh", "-c", `[ "$1" = "--file={path}" ] && [ $# -eq 1 ] || exit 1`, "sh", "--file={path}"},
+		InfectedExitCodes: []int{1},
+		Timeout:           5 * time.Second,
+	}
+	if result, err := scanner.Scan(context.Background(), path); err != nil || !result.Clean {
+		t.Errorf("Scan() = %+v, %v; the path was not substituted", result, err)
+	}
+}
+
+func TestCommandScannerTimeout(t *testing.T) {
+	requireShell(t)
+
+	scanner := &CommandScanner{
+		Command:           []string{"sh", "-c", "exec sleep 30"},
+		InfectedExitCodes: []int{1},
+		Timeout:           100 * time.Millisecond,
+	}
+
+	start := time.Now()
+	result, err := scanner.Scan(context.Background(), t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Scan() = %+v, %v; want a timeout error", result, err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Scan() took %v after a 100ms timeout", elapsed)
+	}
+}
+
+func TestLoadHashBlocklist(t *testing.T) {
+	hashA := strings.Repeat("a", 64)
+	hashB := strings.Repeat("B", 64)
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:    "hashes with and without names",
+			content: fmt.Sprintf("# known bad files\n\n%s EICAR test file\n   %s\n", hashA, hashB),
+			want: map[string]string{
+				hashA:                  "EICAR test file",
+				strings.ToLower(hashB): "blocklisted hash " + strings.ToLower(hashB),
+			},
+		},
+		{name: "only comments", content: "# nothing yet\n#" + hashA + "\n", want: map[string]string{}},
+		{name: "short hash", content: hashA + "\n" + hashA[:63] + "\n", wantErr: "line 2"},
+		{name: "not hex", content: strings.Repeat("z", 64) + "\n", wantErr: "line 1"},
+		{name: "md5 instead of sha256", content: strings.Repeat("0", 32) + " old\n", wantErr: "line 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "blocklist.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			blocklist, err := LoadHashBlocklist(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadHashBlocklist() error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(blocklist.hashes) != len(tt.want) {
+				t.Errorf("loaded %d hashes, want %d", len(blocklist.hashes), len(tt.want))
+			}
+			for hash, threat := range tt.want {
+				if blocklist.hashes[hash] != threat {
+					t.Errorf("hash %s… = %q, want %q", hash[:8], blocklist.hashes[hash], threat)
+				}
+			}
+		})
+	}
+
+	if _, err := LoadHashBlocklist(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("a missing blocklist was accepted")
+	}
+}
+
+func TestHashBlocklistScan(t *testing.T) {
+	dir := t.TempDir()
+	bad := []byte("known bad content")
+	sum := sha256.Sum256(bad)
+
+	blocklist := &HashBlocklist{hashes: map[string]string{hex.EncodeToString(sum[:]): "Test.Threat"}}
+
+	clean := filepath.Join(dir, "clean")
+	os.MkdirAll(filepath.Join(clean, "sub"), 0700)
+	os.WriteFile(filepath.Join(clean, "sub", "ok.txt"), []byte("fine"), 0600)
+
+	infected := filepath.Join(dir, "infected")
+	os.MkdirAll(filepath.Join(infected, "sub"), 0700)
+	os.WriteFile(filepath.Join(infected, "ok.txt"), []byte("fine"), 0600)
+	os.WriteFile(filepath.Join(infected, "sub", "bad.bin"), bad, 0600)
+
+	if result, err := blocklist.Scan(context.Background(), clean); err != nil || !result.Clean {
+		t.Errorf("clean dir: Scan() = %+v, %v", result, err)
+	}
+	if result, err := blocklist.Scan(context.Background(), filepath.Join(infected, "sub", "bad.bin")); err != nil || result.Clean || result.Threat != "bad.bin: Test.Threat" {
+		t.Errorf("blocklisted file: Scan() = %+v, %v", result, err)
+	}
+	if result, err := blocklist.Scan(context.Background(), infected); err != nil || result.Clean {
+		t.Errorf("dir with a blocklisted file: Scan() = %+v, %v", result, err)
+	}
+}
+
+func TestQuarantineKeepsFlaggedFiles(t *testing.T) {
+	requireShell(t)
+
+	content := make([]byte, 2500)
+	rand.Read(content)
+	sum := sha256.Sum256(content)
+	fileChecksum := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name       string
+		scan       func(t *testing.T) ScanConfig
+		wantStatus string
+		wantReason ReasonCode
+	}{
+		{
+			name:       "clean file is published",
+			scan:       func(t *testing.T) ScanConfig { return ScanConfig{Command: []string{"sh", "-c", "exit 0"}} },
+			wantStatus: ScanClean,
+		},
+		{
+			name: "infected by the antivirus",
+			scan: func(t *testing.T) ScanConfig {
+				return ScanConfig{Command: []string{"sh", "-c", "echo 'Win.Test FOUND'; exit 1"}}
+			},
+			wantStatus: ScanInfected,
+			wantReason: ReasonInfected,
+		},
+		{
+			name: "blocklisted hash",
+			scan: func(t *testing.T) ScanConfig {
+				path := filepath.Join(t.TempDir(), "blocklist.txt")
+				os.WriteFile(path, []byte(fileChecksum+" Test.Blocklisted\n"), 0600)
+				return ScanConfig{HashBlocklistFile: path, Command: []string{"sh", "-c", "exit 0"}}
+			},
+			wantStatus: ScanInfected,
+			wantReason: ReasonInfected,
+		},
+		{
+			name:       "scanner error",
+			scan:       func(t *testing.T) ScanConfig { return ScanConfig{Command: []string{"sh", "-c", "exit 2"}} },
+			wantStatus: ScanFailed,
+			wantReason: ReasonScanFailed,
+		},
+		{
+			name: "scanner timeout",
+			scan: func(t *testing.T) ScanConfig {
+				return ScanConfig{Command: []string{"sh", "-c", "exec sleep 30"}, TimeoutSeconds: 1}
+			},
+			wantStatus: ScanFailed,
+			wantReason: ReasonScanFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			downloadDir := filepath.Join(t.TempDir(), "downloads")
+			agent := newTestTransferAgent(t, downloadDir, func(config *Config) {
+				config.Scan = tt.scan(t)
+			})
+			results := make(chan TransferResult, 1)
+			agent.SetTransferCompletedCallback(func(result TransferResult) { results <- result })
+
+			if err := agent.HandleFileTransferRequest(api.FileTransferRequest{
+				TransferID:    "scan-1",
+				SessionID:     "test",
+				FileName:      "payload.bin",
+				FileSizeBytes: int64(len(content)),
+				TotalChunks:   3,
+				ChunkSize:     1000,
+				FileChecksum:  fileChecksum,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			for index := 0; index < 3; index++ {
+				if err := agent.HandleFileChunk(testFileChunk("scan-1", content, 1000, index)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result := waitTransferResult(t, results)
+			if result.ScanStatus != tt.wantStatus || result.ReasonCode != tt.wantReason {
+				t.Fatalf("result = %+v, want scan %s with reason %q", result, tt.wantStatus, tt.wantReason)
+			}
+
+			published := filepath.Join(downloadDir, "payload.bin")
+			if tt.wantStatus == ScanClean {
+				if !result.Success || result.FilePath != published || result.QuarantinePath != "" {
+					t.Errorf("result = %+v, want the file published to %s", result, published)
+				}
+				if got, err := os.ReadFile(published); err != nil || string(got) != string(content) {
+					t.Errorf("published file is missing or differs: %v", err)
+				}
+				return
+			}
+
+			if result.Success || result.FilePath != "" {
+				t.Errorf("result = %+v, want a failure without a file path", result)
+			}
+			if got, err := os.ReadFile(result.QuarantinePath); err != nil || string(got) != string(content) {
+				t.Errorf("quarantined file %q is missing or differs: %v", result.QuarantinePath, err)
+			}
+			if !isWithin(agent.quarantineRoot(), result.QuarantinePath) {
+				t.Errorf("quarantine path %q is outside the quarantine", result.QuarantinePath)
+			}
+			entries, err := os.ReadDir(downloadDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range entries {
+				t.Errorf("%s appeared in the download directory", entry.Name())
+			}
+		})
+	}
+}
+
+// blockingScanner retiene cada escaneo hasta que se cierra release
+type blockingScanner struct {
+	started chan string
+	release chan struct{}
+}
+
+func (s *blockingScanner) Scan(ctx context.Context, path string) (ScanResult, error) {
+	s.started <- path
+	<-s.release
+	return ScanResult{Clean: true, Scanner: "test"}, nil
+}
+
+func TestQuarantineKeepsFileCreatedDuringScan(t *testing.T) {
+	content := []byte("received from the server")
+	userContent := []byte("saved by the user during the scan")
+
+	tests := []struct {
+		name        string
+		policy      CollisionPolicy
+		wantPath    string // Vacío: queda retenido en cuarentena
+		wantReason  ReasonCode
+		wantReplace bool
+	}{
+		{name: "rename picks a free name", policy: CollisionRename, wantPath: "report (1).txt"},
+		{name: "reject keeps it in quarantine", policy: CollisionReject, wantReason: ReasonFileExists},
+		{name: "overwrite replaces the file", policy: CollisionOverwrite, wantPath: "report.txt", wantReplace: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			downloadDir := filepath.Join(t.TempDir(), "downloads")
+			agent := newTestTransferAgent(t, downloadDir, func(config *Config) {
+				config.CollisionPolicy = tt.policy
+			})
+			scanner := &blockingScanner{started: make(chan string, 1), release: make(chan struct{})}
+			agent.SetScanner(scanner)
+			results := make(chan TransferResult, 1)
+			agent.SetTransferCompletedCallback(func(result TransferResult) { results <- result })
+
+			if err := agent.HandleFileTransferRequest(api.FileTransferRequest{
+				TransferID:    "held-1",
+				SessionID:     "test",
+				FileName:      "report.txt",
+				FileSizeBytes: int64(len(content)),
+				TotalChunks:   1,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if err := agent.HandleFileChunk(testFileChunk("held-1", content, len(content), 0)); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case <-scanner.started:
+			case <-time.After(5 * time.Second):
+				t.Fatal("scan did not start")
+			}
+			userFile := filepath.Join(downloadDir, "report.txt")
+			if err := os.WriteFile(userFile, userContent, 0644); err != nil {
+				t.Fatal(err)
+			}
+			close(scanner.release)
+
+			result := waitTransferResult(t, results)
+			if result.ReasonCode != tt.wantReason {
+				t.Errorf("reason = %q, want %q", result.ReasonCode, tt.wantReason)
+			}
+
+			if tt.wantPath == "" {
+				if result.Success || result.FilePath != "" {
+					t.Errorf("result = %+v, want the file held in quarantine", result)
+				}
+				if got, err := os.ReadFile(result.QuarantinePath); err != nil || string(got) != string(content) {
+					t.Errorf("quarantined file %q is missing or differs: %v", result.QuarantinePath, err)
+				}
+			} else {
+				wantPath := filepath.Join(downloadDir, tt.wantPath)
+				if !result.Success || result.FilePath != wantPath || result.FileName != tt.wantPath {
+					t.Errorf("result = %+v, want the file published to %s", result, wantPath)
+				}
+				if got, err := os.ReadFile(wantPath); err != nil || string(got) != string(content) {
+					t.Errorf("published file is missing or differs: %v", err)
+				}
+			}
+
+			got, err := os.ReadFile(userFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if replaced := string(got) == string(content); replaced != tt.wantReplace {
+				t.Errorf("user's file replaced = %v, want %v", replaced, tt.wantReplace)
+			}
+		})
+	}
+}
+
+func TestMovePathDoesNotReplace(t *testing.T) {
+	for _, isDir := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dir=%v", isDir), func(t *testing.T) {
+			dir := t.TempDir()
+			source := filepath.Join(dir, "source")
+			dest := filepath.Join(dir, "dest")
+			if isDir {
+				os.Mkdir(source, 0700)
+				os.Mkdir(dest, 0700)
+			} else {
+				os.WriteFile(source, []byte("new"), 0600)
+				os.WriteFile(dest, []byte("old"), 0600)
+			}
+
+			if err := movePath(source, dest, false); !errors.Is(err, fs.ErrExist) {
+				t.Fatalf("movePath() = %v, want fs.ErrExist", err)
+			}
+			if _, err := os.Lstat(source); err != nil {
+				t.Errorf("source was removed: %v", err)
+			}
+			if !isDir {
+				if got, _ := os.ReadFile(dest); string(got) != "old" {
+					t.Errorf("dest = %q, want it untouched", got)
+				}
+			}
+
+			if err := movePath(source, dest, true); err != nil {
+				t.Fatalf("movePath() with overwrite = %v", err)
+			}
+			if _, err := os.Lstat(source); !os.IsNotExist(err) {
+				t.Errorf("source still exists after moving: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package filetransfer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dirección de una transferencia en el historial
+const (
+	HistoryReceived = "received" // Del servidor a este equipo
+	HistorySent     = "sent"     // Subida de este equipo al servidor
+)
+
+// Resultado de una transferencia en el historial
+const (
+	OutcomeCompleted   = "completed"
+	OutcomeFailed      = "failed"
+	OutcomeQuarantined = "quarantined" // Retenido por el escáner
+	OutcomeCancelled   = "cancelled"
+	OutcomeDeclined    = "declined" // Rechazado antes de recibirlo
+)
+
+// DefaultHistoryEntries es el número de entradas que se conservan; las más antiguas se descartan
+const DefaultHistoryEntries = 1000
+
+// HistoryEntry es una transferencia terminada, con éxito o sin él
+type HistoryEntry struct {
+	ID             string    `json:"id"`
+	TransferID     string    `json:"transfer_id"`
+	Direction      string    `json:"direction"`
+	FileName       string    `json:"file_name"`
+	SizeBytes      int64     `json:"size_bytes"`
+	SessionID      string    `json:"session_id"`
+	RequestedBy    string    `json:"requested_by,omitempty"`
+	FilePath       string    `json:"file_path,omitempty"` // Ruta final; vacía si no se publicó
+	QuarantinePath string    `json:"quarantine_path,omitempty"`
+	IsDirectory    bool      `json:"is_directory,omitempty"`
+	Checksum       string    `json:"checksum,omitempty"`
+	DurationMs     int64     `json:"duration_ms"`
+	Outcome        string    `json:"outcome"`
+	Error          string    `json:"error,omitempty"`
+	ReasonCode     string    `json:"reason_code,omitempty"`
+	FinishedAt     time.Time `json:"finished_at"`
+}
+
+// HistoryEntryFromResult crea la entrada de una transferencia recibida
+func HistoryEntryFromResult(result TransferResult) HistoryEntry {
+	outcome := OutcomeCompleted
+	switch {
+	case result.Success:
+	case result.ReasonCode == ReasonInfected || result.ReasonCode == ReasonScanFailed:
+		outcome = OutcomeQuarantined
+	case result.ReasonCode == ReasonSessionEnded:
+		outcome = OutcomeCancelled
+	default:
+		outcome = OutcomeFailed
+	}
+
+	return HistoryEntry{
+		TransferID:     result.TransferID,
+		Direction:      HistoryReceived,
+		FileName:       result.FileName,
+		SizeBytes:      result.SizeBytes,
+		SessionID:      result.SessionID,
+		FilePath:       result.FilePath,
+		QuarantinePath: result.QuarantinePath,
+		IsDirectory:    result.IsDirectory,
+		Checksum:       result.Checksum,
+		DurationMs:     result.Duration.Milliseconds(),
+		Outcome:        outcome,
+		Error:          result.ErrorMessage,
+		ReasonCode:     string(result.ReasonCode),
+	}
+}
+
+// HistoryEntryFromUpload crea la entrada de una subida al servidor
+func HistoryEntryFromUpload(result UploadResult) HistoryEntry {
+	outcome := OutcomeCompleted
+	switch {
+	case result.Success:
+	case result.Cancelled:
+		outcome = OutcomeCancelled
+	default:
+		outcome = OutcomeFailed
+	}
+
+	return HistoryEntry{
+		TransferID:  result.TransferID,
+		Direction:   HistorySent,
+		FileName:    result.FileName,
+		SizeBytes:   result.SizeBytes,
+		SessionID:   result.SessionID,
+		RequestedBy: result.RequestedBy,
+		FilePath:    result.FilePath,
+		Checksum:    result.Checksum,
+		DurationMs:  result.Duration.Milliseconds(),
+		Outcome:     outcome,
+		Error:       result.ErrorMessage,
+	}
+}
+
+// HistoryQuery filtra el historial; los campos vacíos no filtran
+type HistoryQuery struct {
+	Search    string // Texto en el nombre, la ruta o el ID (sin distinguir mayúsculas)
+	Direction string
+	Outcome   string
+	SessionID string
+	Since     time.Time
+	Until     time.Time
+	Offset    int
+	Limit     int // 0 = todas
+}
+
+// History guarda las transferencias terminadas en un archivo JSON
+type History struct {
+	path       string
+	maxEntries int
+	entries    []HistoryEntry // De la más antigua a la más reciente
+	mutex      sync.Mutex
+}
+
+// OpenHistory carga el historial de path; si no existe empieza vacío. Un archivo
+// ilegible se conserva como path.corrupt y se empieza un historial nuevo.
+func OpenHistory(path string, maxEntries int) (*History, error) {
+	if maxEntries <= 0 {
+		maxEntries = DefaultHistoryEntries
+	}
+	h := &History{path: path, maxEntries: maxEntries}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return h, nil
+		}
+		return h, fmt.Errorf("failed to read transfer history: %w", err)
+	}
+	if err := json.Unmarshal(data, &h.entries); err != nil {
+		h.entries = nil
+		os.Rename(path, path+".corrupt")
+		return h, fmt.Errorf("invalid transfer history %s: %w", path, err)
+	}
+	return h, nil
+}
+
+// Add registra una transferencia terminada y retorna la entrada con su ID
+func (h *History) Add(entry HistoryEntry) (HistoryEntry, error) {
+	if entry.ID == "" {
+		entry.ID = newHistoryID()
+	}
+	if entry.FinishedAt.IsZero() {
+		entry.FinishedAt = time.Now()
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.entries = append(h.entries, entry)
+	if excess := len(h.entries) - h.maxEntries; excess > 0 {
+		h.entries = append([]HistoryEntry(nil), h.entries[excess:]...)
+	}
+	return entry, h.save()
+}
+
+// Query retorna las entradas que cumplen q, de la más reciente a la más antigua,
+// y el total de coincidencias antes de aplicar Offset y Limit
+func (h *History) Query(q HistoryQuery) ([]HistoryEntry, int) {
+	search := strings.ToLower(strings.TrimSpace(q.Search))
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	matches := make([]HistoryEntry, 0)
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		entry := h.entries[i]
+		switch {
+		case q.Direction != "" && entry.Direction != q.Direction,
+			q.Outcome != "" && entry.Outcome != q.Outcome,
+			q.SessionID != "" && entry.SessionID != q.SessionID,
+			!q.Since.IsZero() && entry.FinishedAt.Before(q.Since),
+			!q.Until.IsZero() && !entry.FinishedAt.Before(q.Until),
+			search != "" && !entry.matches(search):
+			continue
+		}
+		matches = append(matches, entry)
+	}
+
+	total := len(matches)
+	if q.Offset > 0 {
+		if q.Offset >= total {
+			return []HistoryEntry{}, total
+		}
+		matches = matches[q.Offset:]
+	}
+	if q.Limit > 0 && len(matches) > q.Limit {
+		matches = matches[:q.Limit]
+	}
+	return matches, total
+}
+
+// Get retorna una entrada por su ID
+func (h *History) Get(id string) (HistoryEntry, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, entry := range h.entries {
+		if entry.ID == id {
+			return entry, true
+		}
+	}
+	return HistoryEntry{}, false
+}
+
+// Delete elimina las entradas indicadas (no los archivos) y retorna cuántas se eliminaron
+func (h *History) Delete(ids ...string) (int, error) {
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	kept := h.entries[:0]
+	for _, entry := range h.entries {
+		if !remove[entry.ID] {
+			kept = append(kept, entry)
+		}
+	}
+	deleted := len(h.entries) - len(kept)
+	h.entries = kept
+
+	if deleted == 0 {
+		return 0, nil
+	}
+	return deleted, h.save()
+}
+
+// Clear elimina todas las entradas
+func (h *History) Clear() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.entries = nil
+	return h.save()
+}
+
+// matches indica si la entrada contiene el texto buscado (ya en minúsculas)
+func (e HistoryEntry) matches(search string) bool {
+	for _, field := range []string{e.FileName, e.FilePath, e.TransferID, e.RequestedBy} {
+		if strings.Contains(strings.ToLower(field), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// save escribe el historial de forma atómica; requiere el mutex tomado
+func (h *History) save() error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(h.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := h.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write transfer history: %w", err)
+	}
+	if err := os.Rename(tmpPath, h.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write transfer history: %w", err)
+	}
+	return nil
+}
+
+// newHistoryID genera un identificador aleatorio para una entrada
+func newHistoryID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,255 @@
+package filetransfer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// historyIDs retorna los IDs de las entradas en orden
+func historyIDs(entries []HistoryEntry) []string {
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	return ids
+}
+
+func TestHistoryPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "transfers.json")
+
+	history, err := OpenHistory(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, total := history.Query(HistoryQuery{}); len(entries) != 0 || total != 0 {
+		t.Fatalf("new history has %d entries", total)
+	}
+
+	added, err := history.Add(HistoryEntry{TransferID: "t-1", Direction: HistoryReceived, FileName: "a.txt", Outcome: OutcomeCompleted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.ID == "" || added.FinishedAt.IsZero() {
+		t.Errorf("Add() = %+v, want an ID and a finish time", added)
+	}
+
+	reopened, err := OpenHistory(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := reopened.Get(added.ID)
+	if !ok {
+		t.Fatal("entry was not persisted")
+	}
+	if got.TransferID != "t-1" || got.FileName != "a.txt" || !got.FinishedAt.Equal(added.FinishedAt) {
+		t.Errorf("reopened entry = %+v, want %+v", got, added)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file was left behind: %v", err)
+	}
+}
+
+func TestHistoryQuery(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	history, err := OpenHistory(filepath.Join(t.TempDir(), "transfers.json"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, entry := range []HistoryEntry{
+		{ID: "1", TransferID: "t-1", Direction: HistoryReceived, FileName: "Report.pdf", SessionID: "s-a", Outcome: OutcomeCompleted},
+		{ID: "2", TransferID: "t-2", Direction: HistorySent, FileName: "app.log", FilePath: "/var/log/App/app.log", SessionID: "s-a", RequestedBy: "alice", Outcome: OutcomeCompleted},
+		{ID: "3", TransferID: "t-3", Direction: HistoryReceived, FileName: "setup.exe", SessionID: "s-b", Outcome: OutcomeQuarantined},
+		{ID: "4", TransferID: "t-4", Direction: HistoryReceived, FileName: "photo.jpg", SessionID: "s-b", Outcome: OutcomeFailed},
+		{ID: "5", TransferID: "t-5", Direction: HistorySent, FileName: "notes.txt", SessionID: "s-a", RequestedBy: "bob", Outcome: OutcomeCancelled},
+	} {
+		entry.FinishedAt = base.Add(time.Duration(i) * time.Hour)
+		if _, err := history.Add(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     HistoryQuery
+		wantIDs   []string
+		wantTotal int
+	}{
+		{name: "all, newest first", query: HistoryQuery{}, wantIDs: []string{"5", "4", "3", "2", "1"}, wantTotal: 5},
+		{name: "direction", query: HistoryQuery{Direction: HistorySent}, wantIDs: []string{"5", "2"}, wantTotal: 2},
+		{name: "outcome", query: HistoryQuery{Outcome: OutcomeCompleted}, wantIDs: []string{"2", "1"}, wantTotal: 2},
+		{name: "session", query: HistoryQuery{SessionID: "s-b"}, wantIDs: []string{"4", "3"}, wantTotal: 2},
+		{name: "search in the name ignores case", query: HistoryQuery{Search: "REPORT"}, wantIDs: []string{"1"}, wantTotal: 1},
+		{name: "search in the path", query: HistoryQuery{Search: "/app/"}, wantIDs: []string{"2"}, wantTotal: 1},
+		{name: "search by transfer ID", query: HistoryQuery{Search: " t-3 "}, wantIDs: []string{"3"}, wantTotal: 1},
+		{name: "search by requester", query: HistoryQuery{Search: "bob"}, wantIDs: []string{"5"}, wantTotal: 1},
+		{name: "since is inclusive", query: HistoryQuery{Since: base.Add(3 * time.Hour)}, wantIDs: []string{"5", "4"}, wantTotal: 2},
+		{name: "until is exclusive", query: HistoryQuery{Until: base.Add(2 * time.Hour)}, wantIDs: []string{"2", "1"}, wantTotal: 2},
+		{name: "since and until", query: HistoryQuery{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)}, wantIDs: []string{"3", "2"}, wantTotal: 2},
+		{name: "combined filters", query: HistoryQuery{Direction: HistoryReceived, SessionID: "s-a"}, wantIDs: []string{"1"}, wantTotal: 1},
+		{name: "limit", query: HistoryQuery{Limit: 2}, wantIDs: []string{"5", "4"}, wantTotal: 5},
+		{name: "offset and limit", query: HistoryQuery{Offset: 2, Limit: 2}, wantIDs: []string{"3", "2"}, wantTotal: 5},
+		{name: "offset past the end", query: HistoryQuery{Offset: 5}, wantIDs: []string{}, wantTotal: 5},
+		{name: "no matches", query: HistoryQuery{Search: "missing"}, wantIDs: []string{}, wantTotal: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, total := history.Query(tt.query)
+			if got := historyIDs(entries); !reflect.DeepEqual(got, tt.wantIDs) || total != tt.wantTotal {
+				t.Errorf("Query() = %v (total %d), want %v (total %d)", got, total, tt.wantIDs, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestHistoryDeleteAndClear(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transfers.json")
+	history, err := OpenHistory(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		if _, err := history.Add(HistoryEntry{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if deleted, err := history.Delete("1", "3", "missing"); err != nil || deleted != 2 {
+		t.Fatalf("Delete() = %d, %v, want 2", deleted, err)
+	}
+	if deleted, err := history.Delete("missing"); err != nil || deleted != 0 {
+		t.Errorf("Delete() of an unknown ID = %d, %v, want 0", deleted, err)
+	}
+
+	reopened, err := OpenHistory(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := reopened.Query(HistoryQuery{}); !reflect.DeepEqual(historyIDs(entries), []string{"2"}) {
+		t.Errorf("after Delete() = %v, want [2]", historyIDs(entries))
+	}
+
+	if err := reopened.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err = OpenHistory(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, total := reopened.Query(HistoryQuery{}); total != 0 {
+		t.Errorf("after Clear() the history has %d entries", total)
+	}
+}
+
+func TestHistoryTrimsOldestEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transfers.json")
+	history, err := OpenHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		if _, err := history.Add(HistoryEntry{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"5", "4", "3"}
+	if entries, total := history.Query(HistoryQuery{}); !reflect.DeepEqual(historyIDs(entries), want) || total != 3 {
+		t.Errorf("Query() = %v (total %d), want %v", historyIDs(entries), total, want)
+	}
+
+	reopened, err := OpenHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := reopened.Query(HistoryQuery{}); !reflect.DeepEqual(historyIDs(entries), want) {
+		t.Errorf("persisted entries = %v, want %v", historyIDs(entries), want)
+	}
+}
+
+func TestHistoryCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transfers.json")
+	corrupt := []byte(`[{"id": "1", "file_name": `)
+	if err := os.WriteFile(path, corrupt, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := OpenHistory(path, 0)
+	if err == nil {
+		t.Error("a corrupt history was accepted")
+	}
+	if history == nil {
+		t.Fatal("OpenHistory() returned no history to continue with")
+	}
+	if _, total := history.Query(HistoryQuery{}); total != 0 {
+		t.Errorf("corrupt history loaded %d entries", total)
+	}
+	if got, err := os.ReadFile(path + ".corrupt"); err != nil || string(got) != string(corrupt) {
+		t.Errorf("corrupt file was not kept aside: %v", err)
+	}
+
+	// El historial nuevo se guarda sin tocar la copia del corrupto
+	if _, err := history.Add(HistoryEntry{ID: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenHistory(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := reopened.Query(HistoryQuery{}); !reflect.DeepEqual(historyIDs(entries), []string{"new"}) {
+		t.Errorf("entries = %v, want [new]", historyIDs(entries))
+	}
+	if got, _ := os.ReadFile(path + ".corrupt"); string(got) != string(corrupt) {
+		t.Error("the corrupt copy changed")
+	}
+}
+
+func TestHistoryEntryFromFailedTransfer(t *testing.T) {
+	tests := []struct {
+		name    string
+		request api.FileTransferRequest
+		want    int64
+	}{
+		{
+			name:    "size in bytes",
+			request: api.FileTransferRequest{FileSizeBytes: 2500, FileSizeMB: 1},
+			want:    2500,
+		},
+		{
+			name:    "size in MB only",
+			request: api.FileTransferRequest{FileSizeMB: 1.5},
+			want:    1572864,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := newTestTransferAgent(t, filepath.Join(t.TempDir(), "downloads"), nil)
+			results := make(chan TransferResult, 1)
+			agent.SetTransferCompletedCallback(func(result TransferResult) { results <- result })
+
+			request := tt.request
+			request.TransferID = "failed"
+			request.SessionID = "session-1"
+			request.FileName = "failed.bin"
+			request.TotalChunks = 3
+			request.ChunkSize = 1000
+			if err := agent.HandleFileTransferRequest(request); err != nil {
+				t.Fatal(err)
+			}
+
+			// Se abandona sin recibir ningún chunk
+			agent.reapStaleTransfers(time.Now().Add(time.Hour))
+			entry := HistoryEntryFromResult(waitTransferResult(t, results))
+			if entry.Outcome != OutcomeFailed || entry.SizeBytes != tt.want {
+				t.Errorf("entry = %+v, want a failure of %d bytes", entry, tt.want)
+			}
+		})
+	}
+}
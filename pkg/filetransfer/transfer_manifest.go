@@ -0,0 +1,415 @@
+package filetransfer
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+const (
+	// Sufijos de los archivos de una transferencia en curso
+	partSuffix     = ".part"
+	manifestSuffix = ".part.json"
+
+	manifestVersion = 1
+
+	// El manifiesto se guarda como máximo cada intervalo o cada N chunks
+	manifestSaveInterval = 1 * time.Second
+	manifestSaveChunks   = 32
+)
+
+// transferManifest es el estado persistido de una transferencia junto al archivo .part.
+// Solo declara chunks que ya están sincronizados en disco, así que tras un corte
+// como mucho se vuelven a pedir algunos chunks que ya estaban escritos.
+type transferManifest struct {
+	Version         int       `json:"version"`
+	TransferID      string    `json:"transfer_id"`
+	SessionID       string    `json:"session_id"`
+	FileName        string    `json:"file_name"`
+	FileSizeMB      float64   `json:"file_size_mb"`
+	DestinationPath string    `json:"destination_path,omitempty"`
+	OutputPath      string    `json:"output_path"` // Ruta final; sin escáner el .part está al lado
+	TotalChunks     int       `json:"total_chunks"`
+	ChunkSize       int64     `json:"chunk_size"`
+	LastChunkSize   int64     `json:"last_chunk_size,omitempty"`
+	ExpectedSize    int64     `json:"expected_size,omitempty"`
+	ExpectedHash    string    `json:"expected_hash,omitempty"`
+	ArchiveFormat   string    `json:"archive_format,omitempty"`
+	ReceivedBits    []uint64  `json:"received_bits"`
+	HashedChunks    int       `json:"hashed_chunks"` // Prefijo contiguo ya incluido en HashState
+	HashState       []byte    `json:"hash_state,omitempty"`
+	StartTime       time.Time `json:"start_time"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	// Base del .part y del manifiesto; se deduce de dónde se encontró el manifiesto
+	stagingPath string
+}
+
+// partPathFor retorna la ruta del archivo parcial de una transferencia
+func partPathFor(stagingPath string) string {
+	return stagingPath + partSuffix
+}
+
+// manifestPathFor retorna la ruta del manifiesto de una transferencia
+func manifestPathFor(stagingPath string) string {
+	return stagingPath + manifestSuffix
+}
+
+// newTransferHash crea el hash usado para verificar el archivo completo
+func newTransferHash() hash.Hash {
+	return sha256.New()
+}
+
+// manifest captura el estado actual de la transferencia
+func (t *FileTransfer) manifest() (*transferManifest, error) {
+	marshaler, ok := t.hasher.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("hash state cannot be persisted")
+	}
+	hashState, err := marshaler.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	// La extracción no se puede persistir: al reanudar se vuelve a extraer (y a
+	// calcular el hash) desde el inicio del .part
+	hashedChunks := t.hashedChunks
+	if t.archiveFormat != ArchiveNone {
+		hashState, hashedChunks = nil, 0
+	}
+
+	return &transferManifest{
+		Version:         manifestVersion,
+		TransferID:      t.TransferID,
+		SessionID:       t.SessionID,
+		FileName:        t.FileName,
+		FileSizeMB:      t.FileSizeMB,
+		DestinationPath: t.DestinationPath,
+		OutputPath:      t.outputFilePath,
+		TotalChunks:     t.TotalChunks,
+		ChunkSize:       t.chunkSize,
+		LastChunkSize:   t.lastChunkSize,
+		ExpectedSize:    t.expectedSize,
+		ExpectedHash:    t.expectedChecksum,
+		ArchiveFormat:   string(t.archiveFormat),
+		ReceivedBits:    t.received.Words(),
+		HashedChunks:    hashedChunks,
+		HashState:       hashState,
+		StartTime:       t.StartTime,
+		UpdatedAt:       time.Now(),
+		stagingPath:     t.stagingPath,
+	}, nil
+}
+
+// saveManifest sincroniza el .part y guarda el manifiesto de forma atómica
+func (t *FileTransfer) saveManifest() error {
+	// Los datos deben estar en disco antes de que el manifiesto los declare recibidos
+	if err := t.outputFile.Sync(); err != nil {
+		return fmt.Errorf("failed to sync partial file: %w", err)
+	}
+
+	manifest, err := t.manifest()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	path := manifestPathFor(t.stagingPath)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write transfer manifest: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write transfer manifest: %w", err)
+	}
+
+	t.chunksSinceSave = 0
+	t.lastManifestSave = time.Now()
+	return nil
+}
+
+// maybeSaveManifest guarda el manifiesto si toca según intervalo o número de chunks
+func (t *FileTransfer) maybeSaveManifest() {
+	t.chunksSinceSave++
+	if t.chunksSinceSave < manifestSaveChunks && time.Since(t.lastManifestSave) < manifestSaveInterval {
+		return
+	}
+
+	if err := t.saveManifest(); err != nil {
+		// No es fatal: solo reduce lo que se puede reanudar
+		fmt.Printf("⚠️ Could not save manifest for transfer %s: %v\n", t.TransferID, err)
+	}
+}
+
+// advanceHash extiende el hash con el prefijo contiguo de chunks recibidos.
+// El chunk recién escrito se usa directamente; los siguientes que ya estaban
+// en disco (llegaron antes de tiempo) se leen del .part.
+func (t *FileTransfer) advanceHash(index int, data []byte) error {
+	if index == t.hashedChunks {
+		if err := t.consumeInOrder(data); err != nil {
+			return err
+		}
+	}
+	return t.catchUpHash()
+}
+
+// consumeInOrder procesa el siguiente chunk del prefijo contiguo: lo añade al hash
+// y, en transferencias de directorio, lo entrega al extractor
+func (t *FileTransfer) consumeInOrder(data []byte) error {
+	t.hasher.Write(data)
+	t.hashedChunks++
+
+	if t.extractor != nil {
+		if _, err := t.extractor.Write(data); err != nil {
+			return fmt.Errorf("extraction failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// catchUpHash lee del .part los chunks contiguos que aún no están en el hash
+func (t *FileTransfer) catchUpHash() error {
+	var buf []byte
+	for t.hashedChunks < t.TotalChunks && t.received.Has(t.hashedChunks) {
+		length := t.chunkLength(t.hashedChunks)
+		if int64(cap(buf)) < length {
+			buf = make([]byte, length)
+		}
+		chunk := buf[:length]
+
+		n, err := t.outputFile.ReadAt(chunk, int64(t.hashedChunks)*t.chunkSize)
+		if n < len(chunk) {
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("failed to read chunk %d for hashing: %w", t.hashedChunks, err)
+		}
+
+		if err := t.consumeInOrder(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkLength retorna el tamaño de un chunk ya recibido
+func (t *FileTransfer) chunkLength(index int) int64 {
+	if index == t.TotalChunks-1 {
+		return t.lastChunkSize
+	}
+	return t.chunkSize
+}
+
+// resumeMessage construye la solicitud de chunks faltantes para el servidor
+func (t *FileTransfer) resumeMessage() api.FileTransferResume {
+	return api.FileTransferResume{
+		TransferID:     t.TransferID,
+		SessionID:      t.SessionID,
+		TotalChunks:    t.TotalChunks,
+		ReceivedChunks: t.received.Count(),
+		MissingRanges:  t.received.MissingRanges(),
+	}
+}
+
+// transferFromManifest reabre el .part de una transferencia interrumpida
+func transferFromManifest(manifest *transferManifest) (*FileTransfer, error) {
+	if manifest.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+
+	received, err := ChunkBitmapFromWords(manifest.TotalChunks, manifest.ReceivedBits)
+	if err != nil {
+		return nil, err
+	}
+
+	archiveFormat, err := parseArchiveFormat(manifest.ArchiveFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher := newTransferHash()
+	hashedChunks := manifest.HashedChunks
+	if unmarshaler, ok := hasher.(encoding.BinaryUnmarshaler); !ok || unmarshaler.UnmarshalBinary(manifest.HashState) != nil {
+		// Sin estado válido se recalcula el hash desde el inicio del .part
+		// (el worker lo pone al día con catchUpHash al arrancar)
+		hasher = newTransferHash()
+		hashedChunks = 0
+	}
+
+	outputFile, err := os.OpenFile(partPathFor(manifest.stagingPath), os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reopen partial file: %w", err)
+	}
+
+	transfer := &FileTransfer{
+		TransferID:       manifest.TransferID,
+		SessionID:        manifest.SessionID,
+		FileName:         manifest.FileName,
+		FileSizeMB:       manifest.FileSizeMB,
+		TotalChunks:      manifest.TotalChunks,
+		DestinationPath:  manifest.DestinationPath,
+		StartTime:        manifest.StartTime,
+		received:         received,
+		chunkSize:        manifest.ChunkSize,
+		lastChunkSize:    manifest.LastChunkSize,
+		expectedSize:     manifest.ExpectedSize,
+		expectedChecksum: manifest.ExpectedHash,
+		archiveFormat:    archiveFormat,
+		retransmits:      make(map[int]int),
+		hasher:           hasher,
+		hashedChunks:     hashedChunks,
+		lastManifestSave: time.Now(),
+		outputFile:       outputFile,
+		outputFilePath:   manifest.OutputPath,
+		stagingPath:      manifest.stagingPath,
+	}
+	transfer.chunksReceived.Store(int64(received.Count()))
+
+	return transfer, nil
+}
+
+// loadManifests busca transferencias interrumpidas en el directorio de descarga, en
+// las subcarpetas permitidas (incluidas sus subcarpetas, que también son destinos
+// válidos) y en las carpetas de la cuarentena de las recibidas con escáner
+func loadManifests(dir string, subdirs []string, quarantineDir string) map[string]*transferManifest {
+	manifests := make(map[string]*transferManifest)
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+manifestSuffix))
+	if err != nil {
+		return manifests
+	}
+	for _, subdir := range subdirs {
+		relative, err := cleanRelativePath(subdir)
+		if err != nil {
+			continue
+		}
+		paths = append(paths, findManifests(filepath.Join(dir, relative))...)
+	}
+
+	for _, path := range paths {
+		manifest := readManifest(path)
+		if manifest == nil {
+			continue
+		}
+
+		// El manifiesto se identifica por su ruta, no por lo que diga su contenido
+		manifest.OutputPath = strings.TrimSuffix(path, manifestSuffix)
+		manifest.stagingPath = manifest.OutputPath
+		manifests[manifest.TransferID] = manifest
+	}
+
+	// En la cuarentena el destino sale del manifiesto: solo se acepta dentro del directorio de descarga
+	staged, _ := filepath.Glob(filepath.Join(quarantineDir, "*", "*"+manifestSuffix))
+	for _, path := range staged {
+		manifest := readManifest(path)
+		if manifest == nil {
+			continue
+		}
+		if !isOutputPathWithin(dir, manifest.OutputPath) {
+			fmt.Printf("⚠️ Ignoring transfer manifest %s: destination %q is outside the download directory\n",
+				path, manifest.OutputPath)
+			continue
+		}
+
+		manifest.stagingPath = strings.TrimSuffix(path, manifestSuffix)
+		manifests[manifest.TransferID] = manifest
+	}
+
+	return manifests
+}
+
+// readManifest lee un manifiesto; nil si no es válido
+func readManifest(path string) *transferManifest {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var manifest transferManifest
+	if err := json.Unmarshal(data, &manifest); err != nil || manifest.TransferID == "" {
+		fmt.Printf("⚠️ Ignoring invalid transfer manifest %s: %v\n", path, err)
+		return nil
+	}
+	return &manifest
+}
+
+// isOutputPathWithin comprueba que una ruta final leída de un manifiesto es absoluta y
+// está bajo el directorio de descarga
+func isOutputPathWithin(dir, outputPath string) bool {
+	if outputPath == "" || !filepath.IsAbs(outputPath) {
+		return false
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	clean := filepath.Clean(outputPath)
+	return clean != root && isWithin(root, clean)
+}
+
+// findManifests recorre dir buscando manifiestos, sin entrar en extracciones parciales
+// (su contenido viene del remitente)
+func findManifests(dir string) []string {
+	var paths []string
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Subcarpeta inexistente o ilegible: se omite
+			return nil
+		}
+		if entry.IsDir() {
+			if strings.HasSuffix(entry.Name(), extractingSuffix) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), manifestSuffix) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths
+}
+
+// removeTransferFiles elimina el .part, el manifiesto y la extracción parcial de una transferencia
+func removeTransferFiles(stagingPath string) {
+	for _, path := range []string{partPathFor(stagingPath), manifestPathFor(stagingPath), extractingPathFor(stagingPath)} {
+		if err := os.RemoveAll(path); err != nil {
+			fmt.Printf("Warning: Could not remove %s: %v\n", path, err)
+		}
+	}
+}
+
+// removeStagingFiles elimina los archivos de trabajo y, si estaban en la cuarentena,
+// su carpeta cuando queda vacía
+func removeStagingFiles(stagingPath, outputPath string) {
+	removeTransferFiles(stagingPath)
+	if stagingPath != outputPath {
+		os.Remove(filepath.Dir(stagingPath))
+	}
+}
+
+// closeFiles detiene la extracción y cierra el .part de una transferencia
+func (t *FileTransfer) closeFiles() {
+	if t.extractor != nil {
+		t.extractor.abort()
+		t.extractor = nil
+	}
+	if t.outputFile != nil {
+		t.outputFile.Close()
+	}
+}
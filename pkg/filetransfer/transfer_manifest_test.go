@@ -0,0 +1,245 @@
+package filetransfer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+func TestFileTransferResumesAfterRestart(t *testing.T) {
+	const (
+		chunkSize   = 1000
+		totalChunks = 8
+	)
+
+	content := make([]byte, (totalChunks-1)*chunkSize+500)
+	rand.Read(content)
+	fileSum := sha256.Sum256(content)
+	fileChecksum := hex.EncodeToString(fileSum[:])
+	request := api.FileTransferRequest{
+		TransferID:    "resume-1",
+		SessionID:     "test",
+		FileName:      "resume.bin",
+		FileSizeBytes: int64(len(content)),
+		TotalChunks:   totalChunks,
+		ChunkSize:     chunkSize,
+		FileChecksum:  fileChecksum,
+	}
+
+	downloadDir := filepath.Join(t.TempDir(), "downloads")
+
+	// Primera ejecución: llegan algunos chunks desordenados y se corta la conexión
+	first := newTestTransferAgent(t, downloadDir, nil)
+	if err := first.HandleFileTransferRequest(request); err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []int{3, 0, 5, 1} {
+		if err := first.HandleFileChunk(testFileChunk(request.TransferID, content, chunkSize, index)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitChunksReceived(t, first, request.TransferID, 4)
+	first.SuspendTransfers()
+	first.Close()
+
+	// Segunda ejecución sobre el mismo directorio
+	second := newTestTransferAgent(t, downloadDir, nil)
+	manifest := second.suspended[request.TransferID]
+	if manifest == nil {
+		t.Fatalf("interrupted transfer was not found; suspended = %v", second.suspended)
+	}
+	// Solo el prefijo 0-1 está en el hash; 3 y 5 esperan en el .part
+	if manifest.HashedChunks != 2 || len(manifest.HashState) == 0 {
+		t.Errorf("manifest hashed %d chunks with %d bytes of state, want 2 chunks", manifest.HashedChunks, len(manifest.HashState))
+	}
+
+	restored, err := transferFromManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMissing := []api.ChunkRange{{Start: 2, End: 2}, {Start: 4, End: 4}, {Start: 6, End: 7}}
+	resume := restored.resumeMessage()
+	if !reflect.DeepEqual(resume.MissingRanges, wantMissing) || resume.ReceivedChunks != 4 || resume.TotalChunks != totalChunks {
+		t.Errorf("resume message = %+v, want missing %v", resume, wantMissing)
+	}
+	if restored.hashedChunks != 2 {
+		t.Errorf("restored transfer hashed %d chunks, want the persisted 2", restored.hashedChunks)
+	}
+	restored.closeFiles()
+
+	resumed := make(chan api.FileTransferResume, 1)
+	second.SetTransferResumedCallback(func(resume api.FileTransferResume) { resumed <- resume })
+	results := make(chan TransferResult, 1)
+	second.SetTransferCompletedCallback(func(result TransferResult) { results <- result })
+
+	// El servidor reenvía la solicitud al reconectar
+	if err := second.HandleFileTransferRequest(request); err != nil {
+		t.Fatal(err)
+	}
+	if resume := <-resumed; !reflect.DeepEqual(resume.MissingRanges, wantMissing) {
+		t.Errorf("resume sent to the server = %+v, want missing %v", resume, wantMissing)
+	}
+
+	for _, r := range wantMissing {
+		for index := r.End; index >= r.Start; index-- {
+			if err := second.HandleFileChunk(testFileChunk(request.TransferID, content, chunkSize, index)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	result := waitTransferResult(t, results)
+	if !result.Success {
+		t.Fatalf("resumed transfer failed: %s", result.ErrorMessage)
+	}
+	if result.Checksum != fileChecksum {
+		t.Errorf("checksum = %s, want %s", result.Checksum, fileChecksum)
+	}
+	got, err := os.ReadFile(result.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("resumed file differs from the original")
+	}
+
+	for _, leftover := range []string{partPathFor(result.FilePath), manifestPathFor(result.FilePath)} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("%s was left behind", leftover)
+		}
+	}
+}
+
+func TestLoadManifestsChecksQuarantineOutputPath(t *testing.T) {
+	root := t.TempDir()
+	downloadDir := filepath.Join(root, "downloads")
+	quarantineDir := filepath.Join(root, "quarantine")
+
+	tests := []struct {
+		name       string
+		outputPath string
+		wantLoaded bool
+	}{
+		{name: "inside the download dir", outputPath: filepath.Join(downloadDir, "report.pdf"), wantLoaded: true},
+		{name: "outside the download dir", outputPath: filepath.Join(root, "elsewhere", "report.pdf")},
+		{name: "escaping with dot-dot", outputPath: downloadDir + "/../elsewhere/report.pdf"},
+		{name: "sibling prefix dir", outputPath: downloadDir + "-x/report.pdf"},
+		{name: "the download dir itself", outputPath: downloadDir},
+		{name: "relative", outputPath: "report.pdf"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := "staged-" + string(rune('a'+i))
+			staging := filepath.Join(quarantineDir, id, "report.pdf")
+			if err := os.MkdirAll(filepath.Dir(staging), 0700); err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(transferManifest{
+				Version:      manifestVersion,
+				TransferID:   id,
+				OutputPath:   tt.outputPath,
+				TotalChunks:  1,
+				ReceivedBits: []uint64{0},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(manifestPathFor(staging), data, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(partPathFor(staging), nil, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			manifests := loadManifests(downloadDir, nil, quarantineDir)
+			manifest, loaded := manifests[id]
+			if loaded != tt.wantLoaded {
+				t.Fatalf("manifest with output %q loaded = %v, want %v", tt.outputPath, loaded, tt.wantLoaded)
+			}
+			if loaded && manifest.stagingPath != staging {
+				t.Errorf("staging path = %q, want %q", manifest.stagingPath, staging)
+			}
+
+			// El agente tampoco la ofrece para reanudar
+			agent := newTestTransferAgent(t, downloadDir, func(config *Config) {
+				config.QuarantineDir = quarantineDir
+			})
+			if _, suspended := agent.suspended[id]; suspended != tt.wantLoaded {
+				t.Errorf("agent suspended = %v, want %v", suspended, tt.wantLoaded)
+			}
+		})
+	}
+}
+
+func TestResumeTransfersCatchesUpOutsideTheLock(t *testing.T) {
+	content := make([]byte, 3000)
+	rand.Read(content)
+
+	agent := newTestTransferAgent(t, filepath.Join(t.TempDir(), "downloads"), nil)
+	results := make(chan TransferResult, 2)
+	agent.SetTransferCompletedCallback(func(result TransferResult) { results <- result })
+
+	startTestTransfer(t, agent, "suspended", "session-1", content)
+	agent.SuspendTransfers()
+	startTestTransfer(t, agent, "other", "session-1", content)
+
+	// El callback toma el mutex del agente y se queda bloqueado en el worker
+	resumed := make(chan api.FileTransferResume, 1)
+	release := make(chan struct{})
+	agent.SetTransferResumedCallback(func(resume api.FileTransferResume) {
+		agent.GetActiveTransfers()
+		resumed <- resume
+		<-release
+	})
+
+	returned := make(chan struct{})
+	go func() {
+		agent.ResumeTransfers()
+		close(returned)
+	}()
+
+	select {
+	case resume := <-resumed:
+		want := []api.ChunkRange{{Start: 1, End: 2}}
+		if resume.TransferID != "suspended" || !reflect.DeepEqual(resume.MissingRanges, want) {
+			t.Errorf("resume = %+v, want missing %v", resume, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the resume was not sent (callback called under the agent lock?)")
+	}
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ResumeTransfers waited for the catch-up")
+	}
+
+	// Mientras el worker reanudado sigue ocupado, otras transferencias reciben chunks
+	if err := agent.HandleFileChunk(testFileChunk("other", content, 1000, 1)); err != nil {
+		t.Fatal(err)
+	}
+	waitChunksReceived(t, agent, "other", 2)
+
+	close(release)
+	for _, index := range []int{1, 2} {
+		if err := agent.HandleFileChunk(testFileChunk("suspended", content, 1000, index)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result := waitTransferResult(t, results)
+	if !result.Success || result.TransferID != "suspended" {
+		t.Fatalf("result = %+v, want the resumed transfer to complete", result)
+	}
+	if got, err := os.ReadFile(result.FilePath); err != nil || !bytes.Equal(got, content) {
+		t.Errorf("resumed file is missing or differs: %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package filetransfer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLength es lo que mira http.DetectContentType para identificar el contenido
+const sniffLength = 512
+
+// ReasonCode identifica de forma estable por qué se rechazó una transferencia;
+// se envía en el acknowledgement para que el servidor no tenga que interpretar el texto
+type ReasonCode string
+
+const (
+	ReasonInsufficientSpace   ReasonCode = "insufficient_disk_space"
+	ReasonFileTooLarge        ReasonCode = "file_too_large"
+	ReasonExtensionBlocked    ReasonCode = "extension_blocked"
+	ReasonExtensionNotAllowed ReasonCode = "extension_not_allowed"
+	ReasonFileTypeBlocked     ReasonCode = "file_type_blocked"
+	ReasonFileTypeNotAllowed  ReasonCode = "file_type_not_allowed"
+)
+
+// PolicyError es un rechazo de la política de transferencias
+type PolicyError struct {
+	Code    ReasonCode
+	Message string
+}
+
+func (e *PolicyError) Error() string {
+	return e.Message
+}
+
+// ReasonCodeOf retorna el código de rechazo de un error, o "" si no lo tiene
+func ReasonCodeOf(err error) ReasonCode {
+	var policyErr *PolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.Code
+	}
+	return ""
+}
+
+// policyError crea un PolicyError con mensaje formateado
+func policyError(code ReasonCode, format string, args ...interface{}) error {
+	return &PolicyError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// TransferPolicy decide qué transferencias se aceptan: espacio libre, tamaño máximo
+// y tipos de archivo por extensión y por contenido
+type TransferPolicy struct {
+	maxBytes     int64 // 0 = sin límite
+	minFreeBytes int64
+
+	allowedExtensions map[string]bool
+	blockedExtensions map[string]bool
+	allowedTypes      []string
+	blockedTypes      []string
+
+	// Reemplazable para consultar el espacio libre de un directorio
+	freeSpace func(dir string) (uint64, error)
+}
+
+// NewTransferPolicy crea la política a partir de la configuración (ya validada)
+func NewTransferPolicy(config Config) *TransferPolicy {
+	return &TransferPolicy{
+		maxBytes:          int64(config.MaxFileSizeMB * 1024 * 1024),
+		minFreeBytes:      int64(config.MinFreeSpaceMB * 1024 * 1024),
+		allowedExtensions: extensionSet(config.AllowedExtensions),
+		blockedExtensions: extensionSet(config.BlockedExtensions),
+		allowedTypes:      normalizeMIMETypes(config.AllowedMIMETypes),
+		blockedTypes:      normalizeMIMETypes(config.BlockedMIMETypes),
+		freeSpace:         freeDiskSpace,
+	}
+}
+
+// CheckRequest valida una solicitud antes de crear ningún archivo. En transferencias de
+// directorio la extensión se comprueba por entrada, no sobre el nombre del paquete.
+func (p *TransferPolicy) CheckRequest(dir, fileName string, sizeBytes int64, isArchive bool) error {
+	if p.maxBytes > 0 && sizeBytes > p.maxBytes {
+		return policyError(ReasonFileTooLarge, "file is %s, the maximum allowed is %s",
+			formatBytes(sizeBytes), formatBytes(p.maxBytes))
+	}
+
+	if err := p.CheckFreeSpace(dir, sizeBytes); err != nil {
+		return err
+	}
+
+	if !isArchive {
+		return p.CheckExtension(fileName)
+	}
+	return nil
+}
+
+// CheckFreeSpace comprueba que caben size bytes dejando libre la reserva configurada.
+// Si no se puede consultar el espacio libre no se bloquea la transferencia.
+func (p *TransferPolicy) CheckFreeSpace(dir string, size int64) error {
+	free, err := p.freeSpace(dir)
+	if err != nil {
+		fmt.Printf("⚠️ Could not check free disk space in %s: %v\n", dir, err)
+		return nil
+	}
+
+	needed := uint64(size + p.minFreeBytes)
+	if free < needed {
+		return policyError(ReasonInsufficientSpace, "not enough disk space: %s needed, %s available",
+			formatBytes(int64(needed)), formatBytes(int64(free)))
+	}
+	return nil
+}
+
+// CheckSize comprueba el tamaño acumulado contra el máximo permitido
+func (p *TransferPolicy) CheckSize(size int64) error {
+	if p.maxBytes > 0 && size > p.maxBytes {
+		return policyError(ReasonFileTooLarge, "content exceeds the maximum allowed size of %s", formatBytes(p.maxBytes))
+	}
+	return nil
+}
+
+// CheckExtension aplica las listas de extensiones permitidas y bloqueadas
+func (p *TransferPolicy) CheckExtension(fileName string) error {
+	ext := strings.ToLower(filepath.Ext(fileName))
+
+	if p.blockedExtensions[ext] {
+		return policyError(ReasonExtensionBlocked, "files with extension %s are blocked", ext)
+	}
+	if len(p.allowedExtensions) > 0 && !p.allowedExtensions[ext] {
+		if ext == "" {
+			return policyError(ReasonExtensionNotAllowed, "files without extension are not allowed")
+		}
+		return policyError(ReasonExtensionNotAllowed, "files with extension %s are not allowed", ext)
+	}
+	return nil
+}
+
+// CheckContent identifica el tipo real por los primeros bytes y aplica las listas de tipos
+func (p *TransferPolicy) CheckContent(fileName string, head []byte) error {
+	if len(p.allowedTypes) == 0 && len(p.blockedTypes) == 0 {
+		return nil
+	}
+
+	mimeType := SniffMIMEType(head)
+	if matchesMIMEType(p.blockedTypes, mimeType) {
+		return policyError(ReasonFileTypeBlocked, "%s is %s, which is blocked", fileName, mimeType)
+	}
+	if len(p.allowedTypes) > 0 && !matchesMIMEType(p.allowedTypes, mimeType) {
+		return policyError(ReasonFileTypeNotAllowed, "%s is %s, which is not allowed", fileName, mimeType)
+	}
+	return nil
+}
+
+// executableSignatures reconoce ejecutables que http.DetectContentType reporta como octet-stream
+var executableSignatures = []struct {
+	magic    []byte
+	mimeType string
+}{
+	{[]byte("MZ"), "application/x-msdownload"},
+	{[]byte("\x7fELF"), "application/x-executable"},
+	{[]byte("\xcf\xfa\xed\xfe"), "application/x-mach-binary"},
+	{[]byte("\xce\xfa\xed\xfe"), "application/x-mach-binary"},
+	{[]byte("\xca\xfe\xba\xbe"), "application/x-mach-binary"},
+	{[]byte("#!"), "text/x-shellscript"},
+}
+
+// SniffMIMEType retorna el tipo MIME (sin parámetros) de un contenido por sus primeros bytes
+func SniffMIMEType(head []byte) string {
+	for _, signature := range executableSignatures {
+		if bytes.HasPrefix(head, signature.magic) {
+			return signature.mimeType
+		}
+	}
+
+	if len(head) > sniffLength {
+		head = head[:sniffLength]
+	}
+	mimeType := http.DetectContentType(head)
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return strings.TrimSpace(mimeType)
+}
+
+// matchesMIMEType compara con patrones exactos o de familia ("image/*")
+func matchesMIMEType(patterns []string, mimeType string) bool {
+	for _, pattern := range patterns {
+		if pattern == mimeType {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// extensionSet normaliza extensiones a minúsculas con punto inicial
+func extensionSet(extensions []string) map[string]bool {
+	set := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		set[ext] = true
+	}
+	return set
+}
+
+// normalizeMIMETypes pasa los patrones a minúsculas
+func normalizeMIMETypes(types []string) []string {
+	normalized := make([]string, 0, len(types))
+	for _, mimeType := range types {
+		if mimeType = strings.ToLower(strings.TrimSpace(mimeType)); mimeType != "" {
+			normalized = append(normalized, mimeType)
+		}
+	}
+	return normalized
+}
+
+// formatBytes muestra un tamaño en la unidad más legible
+func formatBytes(size int64) string {
+	switch {
+	case size >= 1024*1024*1024:
+		return fmt.Sprintf("%.1f GB", float64(size)/(1024*1024*1024))
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
@@ -0,0 +1,261 @@
+package filetransfer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// newTestPolicy crea una política con freeBytes libres en cualquier directorio
+// (freeErr simula que no se puede consultar)
+func newTestPolicy(config Config, freeBytes uint64, freeErr error) *TransferPolicy {
+	policy := NewTransferPolicy(config)
+	policy.freeSpace = func(dir string) (uint64, error) {
+		return freeBytes, freeErr
+	}
+	return policy
+}
+
+func TestTransferPolicyCheckRequest(t *testing.T) {
+	const mb = 1024 * 1024
+
+	tests := []struct {
+		name      string
+		config    Config
+		free      uint64
+		freeErr   error
+		fileName  string
+		size      int64
+		isArchive bool
+		want      ReasonCode
+		wantErr   bool
+	}{
+		{
+			name:     "fits",
+			config:   Config{MaxFileSizeMB: 10, MinFreeSpaceMB: 1},
+			free:     20 * mb,
+			fileName: "report.pdf",
+			size:     5 * mb,
+		},
+		{
+			name:     "larger than the maximum",
+			config:   Config{MaxFileSizeMB: 10},
+			free:     100 * mb,
+			fileName: "report.pdf",
+			size:     10*mb + 1,
+			want:     ReasonFileTooLarge,
+			wantErr:  true,
+		},
+		{
+			name:     "no maximum",
+			config:   Config{},
+			free:     100 * mb,
+			fileName: "report.pdf",
+			size:     50 * mb,
+		},
+		{
+			name:     "reserve would not stay free",
+			config:   Config{MinFreeSpaceMB: 10},
+			free:     14 * mb,
+			fileName: "report.pdf",
+			size:     5 * mb,
+			want:     ReasonInsufficientSpace,
+			wantErr:  true,
+		},
+		{
+			name:     "exactly the reserve left",
+			config:   Config{MinFreeSpaceMB: 10},
+			free:     15 * mb,
+			fileName: "report.pdf",
+			size:     5 * mb,
+		},
+		{
+			name:     "free space unknown does not block",
+			config:   Config{MinFreeSpaceMB: 10},
+			freeErr:  errors.New("statfs failed"),
+			fileName: "report.pdf",
+			size:     5 * mb,
+		},
+		{
+			name:     "blocked extension",
+			config:   Config{BlockedExtensions: []string{".exe"}},
+			free:     100 * mb,
+			fileName: "setup.exe",
+			size:     1,
+			want:     ReasonExtensionBlocked,
+			wantErr:  true,
+		},
+		{
+			name:      "archive name is not checked as an extension",
+			config:    Config{BlockedExtensions: []string{".exe"}},
+			free:      100 * mb,
+			fileName:  "tools.exe",
+			size:      1,
+			isArchive: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := newTestPolicy(tt.config, tt.free, tt.freeErr)
+			err := policy.CheckRequest(t.TempDir(), tt.fileName, tt.size, tt.isArchive)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckRequest() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := ReasonCodeOf(err); got != tt.want {
+				t.Errorf("reason = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransferPolicyCheckSize(t *testing.T) {
+	policy := newTestPolicy(Config{MaxFileSizeMB: 1}, 0, nil)
+	if err := policy.CheckSize(1024 * 1024); err != nil {
+		t.Errorf("CheckSize(max) = %v", err)
+	}
+	if err := policy.CheckSize(1024*1024 + 1); ReasonCodeOf(err) != ReasonFileTooLarge {
+		t.Errorf("CheckSize(max+1) = %v, want %s", err, ReasonFileTooLarge)
+	}
+	if err := newTestPolicy(Config{}, 0, nil).CheckSize(1 << 40); err != nil {
+		t.Errorf("CheckSize() without a maximum = %v", err)
+	}
+}
+
+func TestTransferPolicyCheckExtension(t *testing.T) {
+	tests := []struct {
+		name     string
+		allowed  []string
+		blocked  []string
+		fileName string
+		want     ReasonCode
+	}{
+		{name: "no lists", fileName: "anything.bin"},
+		{name: "blocked with dot", blocked: []string{".exe"}, fileName: "setup.exe", want: ReasonExtensionBlocked},
+		{name: "blocked without dot", blocked: []string{"exe"}, fileName: "setup.exe", want: ReasonExtensionBlocked},
+		{name: "blocked is case insensitive", blocked: []string{" .EXE "}, fileName: "Setup.Exe", want: ReasonExtensionBlocked},
+		{name: "not blocked", blocked: []string{".exe"}, fileName: "notes.txt"},
+		{name: "allowed with dot", allowed: []string{".pdf"}, fileName: "report.pdf"},
+		{name: "allowed without dot", allowed: []string{"pdf"}, fileName: "report.PDF"},
+		{name: "not in the allowed list", allowed: []string{"pdf"}, fileName: "report.docx", want: ReasonExtensionNotAllowed},
+		{name: "no extension with an allowed list", allowed: []string{"pdf"}, fileName: "README", want: ReasonExtensionNotAllowed},
+		{name: "blocked wins over allowed", allowed: []string{"exe"}, blocked: []string{"exe"}, fileName: "setup.exe", want: ReasonExtensionBlocked},
+		{name: "only the last extension counts", blocked: []string{"exe"}, fileName: "setup.exe.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := newTestPolicy(Config{AllowedExtensions: tt.allowed, BlockedExtensions: tt.blocked}, 0, nil)
+			err := policy.CheckExtension(tt.fileName)
+			if got := ReasonCodeOf(err); got != tt.want {
+				t.Errorf("CheckExtension(%q) = %v, want reason %q", tt.fileName, err, tt.want)
+			}
+			if (err != nil) != (tt.want != "") {
+				t.Errorf("CheckExtension(%q) = %v", tt.fileName, err)
+			}
+		})
+	}
+}
+
+func TestSniffMIMEType(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{name: "windows executable", head: []byte("MZ\x90\x00\x03\x00\x00\x00"), want: "application/x-msdownload"},
+		{name: "elf", head: []byte("\x7fELF\x02\x01\x01"), want: "application/x-executable"},
+		{name: "mach-o", head: []byte("\xcf\xfa\xed\xfe\x07\x00"), want: "application/x-mach-binary"},
+		{name: "shebang", head: []byte("#!/bin/sh\nrm -rf /\n"), want: "text/x-shellscript"},
+		{name: "png", head: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), want: "image/png"},
+		{name: "pdf", head: []byte("%PDF-1.7\n"), want: "application/pdf"},
+		{name: "plain text drops the charset", head: []byte("hello world\n"), want: "text/plain"},
+		{name: "unknown binary", head: []byte{0x00, 0x01, 0x02, 0x03}, want: "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SniffMIMEType(tt.head); got != tt.want {
+				t.Errorf("SniffMIMEType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransferPolicyCheckContent(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	exe := []byte("MZ\x90\x00\x03\x00\x00\x00")
+	text := []byte("hello world\n")
+
+	tests := []struct {
+		name    string
+		allowed []string
+		blocked []string
+		head    []byte
+		want    ReasonCode
+	}{
+		{name: "no lists", head: exe},
+		{name: "blocked exact type", blocked: []string{"application/x-msdownload"}, head: exe, want: ReasonFileTypeBlocked},
+		{name: "blocked family", blocked: []string{"image/*"}, head: png, want: ReasonFileTypeBlocked},
+		{name: "blocked is case insensitive", blocked: []string{" Application/X-MSDownload "}, head: exe, want: ReasonFileTypeBlocked},
+		{name: "not blocked", blocked: []string{"image/*"}, head: text},
+		{name: "allowed family", allowed: []string{"image/*"}, head: png},
+		{name: "allowed exact type", allowed: []string{"text/plain"}, head: text},
+		{name: "not in the allowed family", allowed: []string{"image/*"}, head: text, want: ReasonFileTypeNotAllowed},
+		{name: "family needs the slash", allowed: []string{"image*"}, head: png, want: ReasonFileTypeNotAllowed},
+		{name: "blocked wins over allowed", allowed: []string{"application/*"}, blocked: []string{"application/x-msdownload"}, head: exe, want: ReasonFileTypeBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := newTestPolicy(Config{AllowedMIMETypes: tt.allowed, BlockedMIMETypes: tt.blocked}, 0, nil)
+			err := policy.CheckContent("file", tt.head)
+			if got := ReasonCodeOf(err); got != tt.want {
+				t.Errorf("CheckContent() = %v, want reason %q", err, tt.want)
+			}
+			if (err != nil) != (tt.want != "") {
+				t.Errorf("CheckContent() = %v", err)
+			}
+		})
+	}
+}
+
+func TestFileTransferRejectsRenamedExecutable(t *testing.T) {
+	content := append([]byte("MZ\x90\x00\x03\x00\x00\x00"), make([]byte, 1500)...)
+
+	downloadDir := filepath.Join(t.TempDir(), "downloads")
+	agent := newTestTransferAgent(t, downloadDir, func(config *Config) {
+		config.BlockedExtensions = []string{".exe"}
+		config.BlockedMIMETypes = []string{"application/x-msdownload"}
+	})
+	results := make(chan TransferResult, 1)
+	agent.SetTransferCompletedCallback(func(result TransferResult) { results <- result })
+
+	// La extensión pasa la política; el primer chunk delata el ejecutable
+	if err := agent.HandleFileTransferRequest(api.FileTransferRequest{
+		TransferID:    "renamed-exe",
+		SessionID:     "test",
+		FileName:      "holiday.jpg",
+		FileSizeBytes: int64(len(content)),
+		TotalChunks:   2,
+		ChunkSize:     1000,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := agent.HandleFileChunk(testFileChunk("renamed-exe", content, 1000, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	result := waitTransferResult(t, results)
+	if result.Success || result.ReasonCode != ReasonFileTypeBlocked {
+		t.Errorf("result = %+v, want a failure with reason %s", result, ReasonFileTypeBlocked)
+	}
+	for _, path := range []string{filepath.Join(downloadDir, "holiday.jpg"), partPathFor(filepath.Join(downloadDir, "holiday.jpg"))} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was left behind: %v", path, err)
+		}
+	}
+}
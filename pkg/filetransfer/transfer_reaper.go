@@ -0,0 +1,87 @@
+package filetransfer
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// Códigos de cancelación de transferencias sin actividad o de sesiones terminadas
+	ReasonTimeout      ReasonCode = "transfer_timeout"
+	ReasonSessionEnded ReasonCode = "session_ended"
+
+	// reaperInterval es cada cuánto se buscan transferencias sin actividad
+	reaperInterval = 5 * time.Second
+)
+
+// Close detiene la búsqueda de transferencias sin actividad. Las transferencias en
+// curso no se tocan: se suspenden con SuspendTransfers.
+func (fta *FileTransferAgent) Close() {
+	fta.stopOnce.Do(func() {
+		close(fta.stopReaper)
+	})
+	<-fta.reaperDone
+}
+
+// reaperLoop revisa periódicamente las transferencias activas
+func (fta *FileTransferAgent) reaperLoop() {
+	defer close(fta.reaperDone)
+
+	ticker := time.NewTicker(reaperInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-fta.stopReaper:
+			return
+		case now := <-ticker.C:
+			fta.reapStaleTransfers(now)
+		}
+	}
+}
+
+// reapStaleTransfers falla las transferencias que llevan más que su timeout sin recibir
+// chunks; su worker libera los archivos
+func (fta *FileTransferAgent) reapStaleTransfers(now time.Time) {
+	fta.mutex.RLock()
+	var stale []*FileTransfer
+	for _, transfer := range fta.activeTransfers {
+		if transfer.idleTimeout > 0 && transfer.idleFor(now) >= transfer.idleTimeout {
+			stale = append(stale, transfer)
+		}
+	}
+	fta.mutex.RUnlock()
+
+	for _, transfer := range stale {
+		idle := transfer.idleFor(now).Round(time.Second)
+		if transfer.stop(stopFail, ReasonTimeout, fmt.Sprintf("transfer timed out: no data received for %v", idle)) {
+			fmt.Printf("⏱️ Transfer %s timed out after %v without data\n", transfer.TransferID, idle)
+		}
+	}
+}
+
+// CancelSessionTransfers cancela las transferencias en curso de una sesión que terminó.
+// Las suspendidas por una caída de conexión se conservan: al reanudarse, si el servidor
+// no continúa, el timeout de inactividad las cancela. Retorna cuántas se cancelaron.
+func (fta *FileTransferAgent) CancelSessionTransfers(sessionID, reason string) int {
+	if sessionID == "" {
+		return 0
+	}
+
+	fta.mutex.RLock()
+	var transfers []*FileTransfer
+	for _, transfer := range fta.activeTransfers {
+		if transfer.SessionID == sessionID {
+			transfers = append(transfers, transfer)
+		}
+	}
+	fta.mutex.RUnlock()
+
+	cancelled := 0
+	for _, transfer := range transfers {
+		if transfer.stop(stopFail, ReasonSessionEnded, fmt.Sprintf("session ended: %s", reason)) {
+			cancelled++
+		}
+	}
+	return cancelled
+}
@@ -0,0 +1,143 @@
+package filetransfer
+
+import (
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// startTestTransfer inicia transferID con un primer chunk escrito y retorna la transferencia
+func startTestTransfer(t *testing.T, agent *FileTransferAgent, transferID, sessionID string, content []byte) *FileTransfer {
+	t.Helper()
+
+	if err := agent.HandleFileTransferRequest(api.FileTransferRequest{
+		TransferID:    transferID,
+		SessionID:     sessionID,
+		FileName:      transferID + ".bin",
+		FileSizeBytes: int64(len(content)),
+		TotalChunks:   (len(content) + 999) / 1000,
+		ChunkSize:     1000,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := agent.HandleFileChunk(testFileChunk(transferID, content, 1000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	waitChunksReceived(t, agent, transferID, 1)
+
+	agent.mutex.RLock()
+	defer agent.mutex.RUnlock()
+	return agent.activeTransfers[transferID]
+}
+
+// assertTransferReleased comprueba que el worker terminó, cerró el .part y borró los
+// archivos de trabajo
+func assertTransferReleased(t *testing.T, agent *FileTransferAgent, transfer *FileTransfer) {
+	t.Helper()
+
+	select {
+	case <-transfer.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the transfer worker did not stop")
+	}
+	if _, err := transfer.outputFile.Stat(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("the .part handle is still open: %v", err)
+	}
+	for _, path := range []string{partPathFor(transfer.stagingPath), manifestPathFor(transfer.stagingPath)} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was left behind: %v", filepath.Base(path), err)
+		}
+	}
+	if _, exists := agent.GetActiveTransfers()[transfer.TransferID]; exists {
+		t.Error("the transfer is still active")
+	}
+}
+
+func TestReapStaleTransfers(t *testing.T) {
+	content := make([]byte, 3000)
+	rand.Read(content)
+
+	agent := newTestTransferAgent(t, filepath.Join(t.TempDir(), "downloads"), func(config *Config) {
+		config.TransferIdleTimeoutSeconds = 30
+	})
+	results := make(chan TransferResult, 1)
+	agent.SetTransferCompletedCallback(func(result TransferResult) { results <- result })
+
+	transfer := startTestTransfer(t, agent, "idle", "session-1", content)
+
+	// Antes del timeout no se toca
+	agent.reapStaleTransfers(time.Now().Add(20 * time.Second))
+	select {
+	case result := <-results:
+		t.Fatalf("transfer reaped before its timeout: %+v", result)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	agent.reapStaleTransfers(time.Now().Add(30 * time.Second))
+	result := waitTransferResult(t, results)
+	if result.Success || result.ReasonCode != ReasonTimeout || result.TransferID != "idle" {
+		t.Errorf("result = %+v, want a failure with reason %s", result, ReasonTimeout)
+	}
+	assertTransferReleased(t, agent, transfer)
+
+	// Un chunk tardío ya no encuentra la transferencia
+	if err := agent.HandleFileChunk(testFileChunk("idle", content, 1000, 1)); err == nil {
+		t.Error("a chunk was accepted after the transfer was reaped")
+	}
+}
+
+func TestCancelSessionTransfers(t *testing.T) {
+	content := make([]byte, 3000)
+	rand.Read(content)
+
+	agent := newTestTransferAgent(t, filepath.Join(t.TempDir(), "downloads"), nil)
+	results := make(chan TransferResult, 4)
+	agent.SetTransferCompletedCallback(func(result TransferResult) { results <- result })
+
+	// Una transferencia de la sesión suspendida por una caída de conexión
+	suspended := startTestTransfer(t, agent, "suspended", "session-a", content)
+	agent.SuspendTransfers()
+
+	ended := startTestTransfer(t, agent, "ended", "session-a", content)
+	other := startTestTransfer(t, agent, "other", "session-b", content)
+
+	if got := agent.CancelSessionTransfers("", "no session"); got != 0 {
+		t.Errorf("CancelSessionTransfers(\"\") = %d, want 0", got)
+	}
+	if got := agent.CancelSessionTransfers("session-a", "closed by the admin"); got != 1 {
+		t.Errorf("CancelSessionTransfers() = %d, want 1", got)
+	}
+
+	result := waitTransferResult(t, results)
+	if result.Success || result.ReasonCode != ReasonSessionEnded || result.TransferID != "ended" {
+		t.Errorf("result = %+v, want %q to fail with reason %s", result, "ended", ReasonSessionEnded)
+	}
+	assertTransferReleased(t, agent, ended)
+
+	if _, exists := agent.GetActiveTransfers()[other.TransferID]; !exists {
+		t.Error("the other session's transfer was cancelled")
+	}
+
+	agent.mutex.RLock()
+	_, stillSuspended := agent.suspended["suspended"]
+	agent.mutex.RUnlock()
+	if !stillSuspended {
+		t.Error("the suspended transfer was discarded")
+	}
+	for _, path := range []string{partPathFor(suspended.stagingPath), manifestPathFor(suspended.stagingPath)} {
+		if _, err := os.Lstat(path); err != nil {
+			t.Errorf("%s of the suspended transfer was removed: %v", filepath.Base(path), err)
+		}
+	}
+
+	select {
+	case result := <-results:
+		t.Errorf("unexpected result: %+v", result)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
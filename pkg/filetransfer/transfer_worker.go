@@ -0,0 +1,176 @@
+package filetransfer
+
+import (
+	"fmt"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+const (
+	// transferQueueSize limita los chunks pendientes de cada transferencia. Con la
+	// cola llena HandleFileChunk descarta el chunk y lo pide de nuevo al servidor.
+	transferQueueSize = 16
+
+	// queueFullReason es el motivo de los reenvíos pedidos por cola llena
+	queueFullReason = "receive queue full"
+
+	// ReasonReceiveStalled es el código de las transferencias cuyo worker no toma
+	// chunks durante el timeout de inactividad (por ejemplo, un disco bloqueado)
+	ReasonReceiveStalled ReasonCode = "receive_stalled"
+)
+
+// transferStop es lo que se pide al worker de una transferencia al detenerla
+type transferStop int
+
+const (
+	stopFail    transferStop = iota + 1 // Fallar y borrar lo recibido
+	stopSuspend                         // Guardar el manifiesto para reanudar
+)
+
+// startWorker arranca el goroutine que procesa los chunks de la transferencia
+func (fta *FileTransferAgent) startWorker(t *FileTransfer) {
+	t.queue = make(chan api.FileChunk, transferQueueSize)
+	t.stopSignal = make(chan struct{})
+	t.done = make(chan struct{})
+	t.lastDequeue.Store(time.Now().UnixNano())
+	go fta.runTransfer(t)
+}
+
+// runTransfer procesa los chunks de una transferencia en su propio goroutine.
+// Decodificación, verificación, escritura, hash y extracción ocurren aquí, fuera del
+// goroutine que lee el WebSocket y sin bloquear al resto de transferencias. Solo este
+// goroutine modifica el estado de recepción de t.
+func (fta *FileTransferAgent) runTransfer(t *FileTransfer) {
+	defer close(t.done)
+
+	if t.resumed {
+		if err := fta.catchUpResumed(t); err != nil {
+			fta.cleanupTransfer(t, fmt.Sprintf("failed to resume transfer: %v", err))
+			return
+		}
+	}
+
+	// Una transferencia reanudada puede tener ya todos sus chunks en disco
+	if t.received.Complete() {
+		fta.completeTransfer(t)
+		return
+	}
+
+	for !t.finished {
+		select {
+		case chunk := <-t.queue:
+			t.lastDequeue.Store(time.Now().UnixNano())
+			if err := fta.processChunk(t, chunk); err != nil {
+				fmt.Printf("⚠️ Chunk %d of transfer %s: %v\n", chunk.ChunkIndex, t.TransferID, err)
+			}
+		case <-t.stopSignal:
+			fta.stopTransfer(t)
+			return
+		}
+	}
+}
+
+// stop pide al worker que se detenga; retorna false si ya se había pedido antes
+func (t *FileTransfer) stop(kind transferStop, code ReasonCode, reason string) bool {
+	requested := false
+	t.stopOnce.Do(func() {
+		t.stopKind, t.stopCode, t.stopReason = kind, code, reason
+		close(t.stopSignal)
+		requested = true
+	})
+	return requested
+}
+
+// stopTransfer ejecuta en el worker la detención pedida con stop
+func (fta *FileTransferAgent) stopTransfer(t *FileTransfer) {
+	switch t.stopKind {
+	case stopSuspend:
+		if err := t.saveManifest(); err != nil {
+			fmt.Printf("⚠️ Could not save manifest for transfer %s: %v\n", t.TransferID, err)
+		}
+		manifest, err := t.manifest()
+		t.closeFiles()
+		if err == nil {
+			t.suspendedManifest = manifest
+		}
+
+	case stopFail:
+		t.reasonCode = t.stopCode
+		fta.cleanupTransfer(t, t.stopReason)
+	}
+}
+
+// processChunk verifica y escribe un chunk; completa la transferencia con el último
+func (fta *FileTransferAgent) processChunk(t *FileTransfer, chunk api.FileChunk) error {
+	// Verificar que el chunk no haya sido recibido previamente
+	if t.received.Has(chunk.ChunkIndex) {
+		fmt.Printf("⚠️ Duplicate chunk %d for transfer %s, ignoring\n", chunk.ChunkIndex, chunk.TransferID)
+		return nil
+	}
+
+	actualChunkData, err := decodeChunkData(chunk)
+	if err != nil {
+		return err
+	}
+
+	// Un chunk corrupto no se escribe: se pide de nuevo
+	if err := verifyChunkChecksum(chunk.ChunkChecksum, actualChunkData); err != nil {
+		return fta.requestRetransmit(t, chunk.ChunkIndex, err.Error())
+	}
+
+	// El tipo real se comprueba con el primer chunk; nada se publica hasta completar.
+	// En carpetas lo comprueba el extractor en cada entrada.
+	if chunk.ChunkIndex == 0 && t.archiveFormat == ArchiveNone {
+		if err := t.policy.CheckContent(t.FileName, actualChunkData); err != nil {
+			t.reasonCode = ReasonCodeOf(err)
+			fta.cleanupTransfer(t, err.Error())
+			return err
+		}
+	}
+
+	// Escribir chunk decodificado en su posición del archivo
+	if err := t.writeChunk(chunk.ChunkIndex, actualChunkData); err != nil {
+		t.reasonCode = ReasonCodeOf(err)
+		fta.cleanupTransfer(t, fmt.Sprintf("failed to write chunk %d: %v", chunk.ChunkIndex, err))
+		return err
+	}
+
+	fmt.Printf("📦 Received chunk %d/%d for file %s (decoded: %d bytes)\n",
+		chunk.ChunkIndex+1, t.TotalChunks, t.FileName, len(actualChunkData))
+
+	// La transferencia termina solo cuando están todos los índices, sin importar el orden
+	if t.received.Complete() {
+		return fta.completeTransfer(t)
+	}
+	return nil
+}
+
+// removeActive quita la transferencia del registro si sigue registrada
+func (fta *FileTransferAgent) removeActive(t *FileTransfer) {
+	fta.mutex.Lock()
+	defer fta.mutex.Unlock()
+	if fta.activeTransfers[t.TransferID] == t {
+		delete(fta.activeTransfers, t.TransferID)
+	}
+}
+
+// touch registra actividad de la transferencia (se lee desde el reaper)
+func (t *FileTransfer) touch() {
+	t.lastActivity.Store(time.Now().UnixNano())
+}
+
+// idleFor retorna cuánto tiempo lleva la transferencia sin recibir chunks
+func (t *FileTransfer) idleFor(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, t.lastActivity.Load()))
+}
+
+// workerIdleFor retorna cuánto tiempo lleva el worker sin tomar chunks de la cola
+func (t *FileTransfer) workerIdleFor(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, t.lastDequeue.Load()))
+}
+
+// ChunksReceived retorna cuántos chunks distintos se han escrito
+func (t *FileTransfer) ChunksReceived() int {
+	return int(t.chunksReceived.Load())
+}
@@ -0,0 +1,479 @@
+package filetransfer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// uploadProgressInterval limita la frecuencia de las notificaciones de progreso
+const uploadProgressInterval = 250 * time.Millisecond
+
+// ErrUploadNotFound indica que la subida no está pendiente ni en curso
+var ErrUploadNotFound = errors.New("upload not found")
+
+// UploadSender envía al servidor los mensajes de una subida (lo implementa api.APIClient)
+type UploadSender interface {
+	SendFileUploadResponse(response api.FileUploadResponse) error
+	SendFileUploadChunk(chunk api.FileChunk) error
+	SendFileUploadComplete(complete api.FileUploadComplete) error
+}
+
+// UploadInfo describe un archivo que el servidor pidió subir
+type UploadInfo struct {
+	TransferID  string
+	SessionID   string
+	RequestedBy string
+	FileName    string
+	FilePath    string // Ruta real, con enlaces simbólicos resueltos
+	SizeBytes   int64
+}
+
+// UploadProgress describe el avance de una subida en curso
+type UploadProgress struct {
+	TransferID  string
+	SessionID   string
+	FileName    string
+	BytesSent   int64
+	TotalBytes  int64
+	ChunksSent  int
+	TotalChunks int
+}
+
+// UploadResult describe el resultado final de una subida
+type UploadResult struct {
+	UploadInfo
+	BytesSent    int64
+	Checksum     string // Hex SHA-256 de lo enviado
+	Duration     time.Duration
+	Success      bool
+	Cancelled    bool
+	ErrorMessage string
+}
+
+// upload es una subida pendiente de consentimiento o en curso
+type upload struct {
+	info UploadInfo
+
+	cancel       chan struct{}
+	cancelOnce   sync.Once
+	cancelReason string
+}
+
+// UploadAgent envía al servidor archivos locales que este solicita.
+// Solo lee archivos dentro de Config.UploadDirectories y únicamente después
+// de que el usuario acepte cada solicitud (Accept).
+type UploadAgent struct {
+	config Config
+	sender UploadSender
+
+	// Subidas esperando la decisión del usuario y subidas en curso
+	pending map[string]*upload
+	active  map[string]*upload
+	mutex   sync.Mutex
+
+	// Callbacks para notificar al app
+	onProgress func(progress UploadProgress)
+	onFinished func(result UploadResult)
+}
+
+// NewUploadAgent crea un agente de subida sin directorios aprobados
+func NewUploadAgent() *UploadAgent {
+	return &UploadAgent{
+		config:  DefaultConfig(),
+		pending: make(map[string]*upload),
+		active:  make(map[string]*upload),
+	}
+}
+
+// SetConfig reemplaza la configuración tras validarla
+func (ua *UploadAgent) SetConfig(config Config) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	ua.mutex.Lock()
+	defer ua.mutex.Unlock()
+	ua.config = config
+	return nil
+}
+
+// SetSender establece el canal por el que se envían respuestas y chunks
+func (ua *UploadAgent) SetSender(sender UploadSender) {
+	ua.mutex.Lock()
+	defer ua.mutex.Unlock()
+	ua.sender = sender
+}
+
+// SetUploadProgressCallback establece el callback de progreso
+func (ua *UploadAgent) SetUploadProgressCallback(callback func(progress UploadProgress)) {
+	ua.onProgress = callback
+}
+
+// SetUploadFinishedCallback establece el callback de subidas terminadas (con o sin éxito)
+func (ua *UploadAgent) SetUploadFinishedCallback(callback func(result UploadResult)) {
+	ua.onFinished = callback
+}
+
+// HandleUploadRequest valida una solicitud y la deja pendiente de la decisión del
+// usuario. Si la ruta no está permitida se rechaza directamente ante el servidor.
+func (ua *UploadAgent) HandleUploadRequest(request api.FileUploadRequest) (UploadInfo, error) {
+	ua.mutex.Lock()
+	defer ua.mutex.Unlock()
+
+	if request.TransferID == "" {
+		return UploadInfo{}, fmt.Errorf("upload request without transfer ID")
+	}
+	if ua.pending[request.TransferID] != nil || ua.active[request.TransferID] != nil {
+		return UploadInfo{}, fmt.Errorf("upload %s already exists", request.TransferID)
+	}
+
+	realPath, fileInfo, err := resolveUploadPath(request.FilePath, ua.config.UploadDirectories)
+	if err != nil {
+		ua.sendResponse(api.FileUploadResponse{
+			TransferID: request.TransferID,
+			SessionID:  request.SessionID,
+			Reason:     err.Error(),
+		})
+		return UploadInfo{}, err
+	}
+
+	info := UploadInfo{
+		TransferID:  request.TransferID,
+		SessionID:   request.SessionID,
+		RequestedBy: request.RequestedBy,
+		FileName:    filepath.Base(realPath),
+		FilePath:    realPath,
+		SizeBytes:   fileInfo.Size(),
+	}
+	ua.pending[request.TransferID] = &upload{info: info, cancel: make(chan struct{})}
+
+	fmt.Printf("📤 UPLOAD: %s requested %s (%d bytes), waiting for user\n", request.RequestedBy, realPath, info.SizeBytes)
+	return info, nil
+}
+
+// PromptTimeout retorna cuánto se espera la decisión del usuario sobre una subida
+func (ua *UploadAgent) PromptTimeout() time.Duration {
+	ua.mutex.Lock()
+	defer ua.mutex.Unlock()
+	return ua.config.UploadPromptTimeout()
+}
+
+// Accept inicia el envío de una subida pendiente
+func (ua *UploadAgent) Accept(transferID string) (UploadInfo, error) {
+	ua.mutex.Lock()
+	defer ua.mutex.Unlock()
+
+	u, exists := ua.pending[transferID]
+	if !exists {
+		return UploadInfo{}, ErrUploadNotFound
+	}
+	delete(ua.pending, transferID)
+
+	// El archivo se vuelve a validar: pudo cambiar mientras el usuario decidía
+	file, size, err := openUploadFile(u.info.FilePath, ua.config.UploadDirectories)
+	if err != nil {
+		ua.sendResponse(api.FileUploadResponse{
+			TransferID: transferID,
+			SessionID:  u.info.SessionID,
+			Reason:     err.Error(),
+		})
+		return u.info, err
+	}
+	u.info.SizeBytes = size
+
+	chunkSize := ua.config.uploadChunkSize()
+	totalChunks := uploadChunkCount(size, chunkSize)
+
+	if err := ua.sendResponse(api.FileUploadResponse{
+		TransferID:    transferID,
+		SessionID:     u.info.SessionID,
+		Accepted:      true,
+		FileName:      u.info.FileName,
+		FileSizeBytes: size,
+		TotalChunks:   totalChunks,
+		ChunkSize:     chunkSize,
+	}); err != nil {
+		file.Close()
+		return u.info, fmt.Errorf("failed to send upload response: %w", err)
+	}
+
+	ua.active[transferID] = u
+	go ua.stream(u, file, ua.sender, chunkSize, totalChunks)
+
+	fmt.Printf("📤 UPLOAD: Sending %s (%d bytes, %d chunks)\n", u.info.FilePath, size, totalChunks)
+	return u.info, nil
+}
+
+// Decline rechaza una subida pendiente e informa al servidor del motivo
+func (ua *UploadAgent) Decline(transferID, reason string) (UploadInfo, error) {
+	ua.mutex.Lock()
+	defer ua.mutex.Unlock()
+
+	u, exists := ua.pending[transferID]
+	if !exists {
+		return UploadInfo{}, ErrUploadNotFound
+	}
+	delete(ua.pending, transferID)
+
+	fmt.Printf("📤 UPLOAD: %s declined: %s\n", transferID, reason)
+	return u.info, ua.sendResponse(api.FileUploadResponse{
+		TransferID: transferID,
+		SessionID:  u.info.SessionID,
+		Reason:     reason,
+	})
+}
+
+// Cancel detiene una subida en curso o descarta una pendiente sin responder al servidor.
+// Retorna ErrUploadNotFound si no existe.
+func (ua *UploadAgent) Cancel(transferID, reason string) error {
+	ua.mutex.Lock()
+	defer ua.mutex.Unlock()
+
+	if _, exists := ua.pending[transferID]; exists {
+		delete(ua.pending, transferID)
+		return nil
+	}
+
+	u, exists := ua.active[transferID]
+	if !exists {
+		return ErrUploadNotFound
+	}
+	u.stop(reason)
+	return nil
+}
+
+// CancelAll detiene todas las subidas y retorna las pendientes que se descartaron
+func (ua *UploadAgent) CancelAll(reason string) []string {
+	ua.mutex.Lock()
+	defer ua.mutex.Unlock()
+
+	var discarded []string
+	for transferID := range ua.pending {
+		discarded = append(discarded, transferID)
+	}
+	ua.pending = make(map[string]*upload)
+
+	for _, u := range ua.active {
+		u.stop(reason)
+	}
+
+	return discarded
+}
+
+// GetActiveUploads retorna el estado de las subidas en curso
+func (ua *UploadAgent) GetActiveUploads() []UploadInfo {
+	ua.mutex.Lock()
+	defer ua.mutex.Unlock()
+
+	uploads := make([]UploadInfo, 0, len(ua.active))
+	for _, u := range ua.active {
+		uploads = append(uploads, u.info)
+	}
+	return uploads
+}
+
+// stream lee el archivo por chunks y los envía con su checksum
+func (ua *UploadAgent) stream(u *upload, file *os.File, sender UploadSender, chunkSize, totalChunks int) {
+	defer file.Close()
+
+	startTime := time.Now()
+	hasher := sha256.New()
+	buffer := make([]byte, chunkSize)
+
+	var sent int64
+	var lastProgress time.Time
+
+	err := func() error {
+		for index := 0; index < totalChunks; index++ {
+			select {
+			case <-u.cancel:
+				return errUploadCancelled
+			default:
+			}
+
+			length := int64(chunkSize)
+			if remaining := u.info.SizeBytes - sent; remaining < length {
+				length = remaining
+			}
+
+			data := buffer[:length]
+			if _, err := io.ReadFull(file, data); err != nil {
+				return fmt.Errorf("failed to read %s: %w", u.info.FileName, err)
+			}
+			hasher.Write(data)
+			sum := sha256.Sum256(data)
+
+			// El buffer se reutiliza: el envío serializa el chunk antes de retornar
+			if err := sender.SendFileUploadChunk(api.FileChunk{
+				TransferID:    u.info.TransferID,
+				SessionID:     u.info.SessionID,
+				ChunkIndex:    index,
+				TotalChunks:   totalChunks,
+				ChunkData:     data,
+				IsLastChunk:   index == totalChunks-1,
+				ChunkChecksum: hex.EncodeToString(sum[:]),
+			}); err != nil {
+				return fmt.Errorf("failed to send chunk %d: %w", index, err)
+			}
+			sent += length
+
+			if ua.onProgress != nil && (index == totalChunks-1 || time.Since(lastProgress) >= uploadProgressInterval) {
+				lastProgress = time.Now()
+				ua.onProgress(UploadProgress{
+					TransferID:  u.info.TransferID,
+					SessionID:   u.info.SessionID,
+					FileName:    u.info.FileName,
+					BytesSent:   sent,
+					TotalBytes:  u.info.SizeBytes,
+					ChunksSent:  index + 1,
+					TotalChunks: totalChunks,
+				})
+			}
+		}
+		return nil
+	}()
+
+	result := UploadResult{
+		UploadInfo: u.info,
+		BytesSent:  sent,
+		Checksum:   hex.EncodeToString(hasher.Sum(nil)),
+		Duration:   time.Since(startTime),
+		Success:    err == nil,
+	}
+	if errors.Is(err, errUploadCancelled) {
+		result.Cancelled = true
+		result.ErrorMessage = u.cancelReason
+	} else if err != nil {
+		result.ErrorMessage = err.Error()
+	}
+
+	complete := api.FileUploadComplete{
+		TransferID:   u.info.TransferID,
+		SessionID:    u.info.SessionID,
+		Success:      result.Success,
+		Cancelled:    result.Cancelled,
+		ErrorMessage: result.ErrorMessage,
+		BytesSent:    sent,
+	}
+	if result.Success {
+		complete.FileChecksum = result.Checksum
+	}
+	if err := sender.SendFileUploadComplete(complete); err != nil {
+		fmt.Printf("⚠️ UPLOAD: Could not send completion for %s: %v\n", u.info.TransferID, err)
+	}
+
+	ua.mutex.Lock()
+	delete(ua.active, u.info.TransferID)
+	ua.mutex.Unlock()
+
+	if result.Success {
+		fmt.Printf("✅ UPLOAD: %s sent in %v (sha256 %s)\n", u.info.FileName, result.Duration, result.Checksum)
+	} else {
+		fmt.Printf("❌ UPLOAD: %s stopped after %d bytes: %s\n", u.info.FileName, sent, result.ErrorMessage)
+	}
+
+	if ua.onFinished != nil {
+		ua.onFinished(result)
+	}
+}
+
+// sendResponse envía la respuesta a una solicitud; requiere el mutex tomado
+func (ua *UploadAgent) sendResponse(response api.FileUploadResponse) error {
+	if ua.sender == nil {
+		return fmt.Errorf("no connection to send upload response")
+	}
+	return ua.sender.SendFileUploadResponse(response)
+}
+
+// errUploadCancelled marca una subida detenida por el usuario o el servidor
+var errUploadCancelled = errors.New("upload cancelled")
+
+// stop pide a la subida que se detenga tras el chunk actual
+func (u *upload) stop(reason string) {
+	u.cancelOnce.Do(func() {
+		u.cancelReason = reason
+		close(u.cancel)
+	})
+}
+
+// uploadChunkCount retorna el número de chunks de un archivo; uno vacío se envía como un chunk vacío
+func uploadChunkCount(size int64, chunkSize int) int {
+	if size == 0 {
+		return 1
+	}
+	return int((size + int64(chunkSize) - 1) / int64(chunkSize))
+}
+
+// resolveUploadPath resuelve la ruta pedida por el servidor y comprueba que es un
+// archivo regular dentro de alguno de los directorios aprobados
+func resolveUploadPath(path string, approvedDirs []string) (string, os.FileInfo, error) {
+	path = strings.TrimSpace(path)
+	if !filepath.IsAbs(path) {
+		return "", nil, fmt.Errorf("upload path %q must be absolute", path)
+	}
+	if len(approvedDirs) == 0 {
+		return "", nil, fmt.Errorf("uploads are disabled: no approved directories")
+	}
+
+	realPath, err := filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return "", nil, fmt.Errorf("file %s is not available: %w", path, err)
+	}
+
+	approved := false
+	for _, dir := range approvedDirs {
+		realDir, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		if isWithin(realDir, realPath) {
+			approved = true
+			break
+		}
+	}
+	if !approved {
+		return "", nil, fmt.Errorf("%s is not inside an approved upload directory", path)
+	}
+
+	fileInfo, err := os.Stat(realPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("file %s is not available: %w", path, err)
+	}
+	if !fileInfo.Mode().IsRegular() {
+		return "", nil, fmt.Errorf("%s is not a regular file", path)
+	}
+
+	return realPath, fileInfo, nil
+}
+
+// openUploadFile valida de nuevo la ruta y abre el archivo para lectura
+func openUploadFile(path string, approvedDirs []string) (*os.File, int64, error) {
+	realPath, _, err := resolveUploadPath(path, approvedDirs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	file, err := os.Open(realPath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open %s: %w", realPath, err)
+	}
+
+	// Comprobar sobre el descriptor abierto por si la ruta cambió tras validarla
+	fileInfo, err := file.Stat()
+	if err != nil || !fileInfo.Mode().IsRegular() {
+		file.Close()
+		return nil, 0, fmt.Errorf("%s is not a regular file", realPath)
+	}
+
+	return file, fileInfo.Size(), nil
+}
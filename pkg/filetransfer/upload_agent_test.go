@@ -0,0 +1,449 @@
+package filetransfer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// fakeUploadSender registra lo que el agente envía al servidor. Con gate, cada
+// chunk avisa por sent y espera en gate antes de retornar.
+type fakeUploadSender struct {
+	mutex     sync.Mutex
+	responses []api.FileUploadResponse
+	chunks    []api.FileChunk
+
+	sent     chan int
+	gate     chan struct{}
+	complete chan api.FileUploadComplete
+}
+
+func newFakeUploadSender() *fakeUploadSender {
+	return &fakeUploadSender{complete: make(chan api.FileUploadComplete, 1)}
+}
+
+func (s *fakeUploadSender) SendFileUploadResponse(response api.FileUploadResponse) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.responses = append(s.responses, response)
+	return nil
+}
+
+func (s *fakeUploadSender) SendFileUploadChunk(chunk api.FileChunk) error {
+	// El agente reutiliza el buffer: se copia como haría la serialización
+	chunk.ChunkData = append([]byte(nil), chunk.ChunkData...)
+
+	s.mutex.Lock()
+	s.chunks = append(s.chunks, chunk)
+	s.mutex.Unlock()
+
+	if s.gate != nil {
+		s.sent <- chunk.ChunkIndex
+		<-s.gate
+	}
+	return nil
+}
+
+func (s *fakeUploadSender) SendFileUploadComplete(complete api.FileUploadComplete) error {
+	s.complete <- complete
+	return nil
+}
+
+func (s *fakeUploadSender) lastResponse(t *testing.T) api.FileUploadResponse {
+	t.Helper()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.responses) == 0 {
+		t.Fatal("no upload response was sent")
+	}
+	return s.responses[len(s.responses)-1]
+}
+
+func (s *fakeUploadSender) waitComplete(t *testing.T) api.FileUploadComplete {
+	t.Helper()
+	select {
+	case complete := <-s.complete:
+		return complete
+	case <-time.After(5 * time.Second):
+		t.Fatal("upload did not complete")
+		return api.FileUploadComplete{}
+	}
+}
+
+// uploadFixture crea /approved con un archivo, un directorio hermano /approved-x y un
+// directorio fuera de ambos, todos bajo un directorio temporal sin enlaces simbólicos
+type uploadFixture struct {
+	approved, sibling, outside string
+}
+
+func newUploadFixture(t *testing.T) uploadFixture {
+	t.Helper()
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := uploadFixture{
+		approved: filepath.Join(root, "approved"),
+		sibling:  filepath.Join(root, "approved-x"),
+		outside:  filepath.Join(root, "outside"),
+	}
+	for _, dir := range []string{f.approved, filepath.Join(f.approved, "sub"), f.sibling, f.outside} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for path, content := range map[string]string{
+		filepath.Join(f.approved, "report.txt"):        "report",
+		filepath.Join(f.approved, "sub", "nested.txt"): "nested",
+		filepath.Join(f.sibling, "secret.txt"):         "sibling secret",
+		filepath.Join(f.outside, "secret.txt"):         "outside secret",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+// symlink crea un enlace o salta el test si el sistema no lo permite
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not available: %v", err)
+	}
+}
+
+func TestResolveUploadPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T, f uploadFixture)
+		path     func(f uploadFixture) string
+		wantPath func(f uploadFixture) string // Vacío: se espera error
+	}{
+		{
+			name:     "file in approved dir",
+			path:     func(f uploadFixture) string { return filepath.Join(f.approved, "report.txt") },
+			wantPath: func(f uploadFixture) string { return filepath.Join(f.approved, "report.txt") },
+		},
+		{
+			name:     "file in approved subdir",
+			path:     func(f uploadFixture) string { return filepath.Join(f.approved, "sub", "nested.txt") },
+			wantPath: func(f uploadFixture) string { return filepath.Join(f.approved, "sub", "nested.txt") },
+		},
+		{
+			name:     "dot-dot that stays inside",
+			path:     func(f uploadFixture) string { return f.approved + "/sub/../report.txt" },
+			wantPath: func(f uploadFixture) string { return filepath.Join(f.approved, "report.txt") },
+		},
+		{
+			name: "dot-dot escaping the approved dir",
+			path: func(f uploadFixture) string { return f.approved + "/../outside/secret.txt" },
+		},
+		{
+			name: "dot-dot into the sibling prefix dir",
+			path: func(f uploadFixture) string { return f.approved + "/../approved-x/secret.txt" },
+		},
+		{
+			name: "sibling dir sharing the prefix",
+			path: func(f uploadFixture) string { return filepath.Join(f.sibling, "secret.txt") },
+		},
+		{
+			name: "symlink pointing outside",
+			setup: func(t *testing.T, f uploadFixture) {
+				symlink(t, filepath.Join(f.outside, "secret.txt"), filepath.Join(f.approved, "link.txt"))
+			},
+			path: func(f uploadFixture) string { return filepath.Join(f.approved, "link.txt") },
+		},
+		{
+			name: "symlinked dir pointing outside",
+			setup: func(t *testing.T, f uploadFixture) {
+				symlink(t, f.outside, filepath.Join(f.approved, "linkdir"))
+			},
+			path: func(f uploadFixture) string { return filepath.Join(f.approved, "linkdir", "secret.txt") },
+		},
+		{
+			name: "symlink staying inside",
+			setup: func(t *testing.T, f uploadFixture) {
+				symlink(t, filepath.Join(f.approved, "report.txt"), filepath.Join(f.approved, "alias.txt"))
+			},
+			path:     func(f uploadFixture) string { return filepath.Join(f.approved, "alias.txt") },
+			wantPath: func(f uploadFixture) string { return filepath.Join(f.approved, "report.txt") },
+		},
+		{
+			name: "approved dir itself",
+			path: func(f uploadFixture) string { return f.approved },
+		},
+		{
+			name: "subdirectory",
+			path: func(f uploadFixture) string { return filepath.Join(f.approved, "sub") },
+		},
+		{
+			name:  "named pipe",
+			setup: func(t *testing.T, f uploadFixture) { mkfifo(t, filepath.Join(f.approved, "pipe")) },
+			path:  func(f uploadFixture) string { return filepath.Join(f.approved, "pipe") },
+		},
+		{
+			name: "missing file",
+			path: func(f uploadFixture) string { return filepath.Join(f.approved, "missing.txt") },
+		},
+		{
+			name: "relative path",
+			path: func(f uploadFixture) string { return "approved/report.txt" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newUploadFixture(t)
+			if tt.setup != nil {
+				tt.setup(t, f)
+			}
+
+			got, info, err := resolveUploadPath(tt.path(f), []string{f.approved})
+			if tt.wantPath == nil {
+				if err == nil {
+					t.Errorf("resolveUploadPath(%q) = %q, want error", tt.path(f), got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveUploadPath(%q) error: %v", tt.path(f), err)
+			}
+			if got != tt.wantPath(f) {
+				t.Errorf("resolveUploadPath(%q) = %q, want %q", tt.path(f), got, tt.wantPath(f))
+			}
+			if !info.Mode().IsRegular() {
+				t.Errorf("resolved %q is not a regular file", got)
+			}
+		})
+	}
+}
+
+func TestResolveUploadPathWithoutApprovedDirs(t *testing.T) {
+	f := newUploadFixture(t)
+	if _, _, err := resolveUploadPath(filepath.Join(f.approved, "report.txt"), nil); err == nil {
+		t.Error("upload allowed without approved directories")
+	}
+}
+
+// newTestUploadAgent crea un agente con el directorio aprobado de f y chunks de chunkSize bytes
+func newTestUploadAgent(t *testing.T, f uploadFixture, chunkSize int) (*UploadAgent, *fakeUploadSender) {
+	t.Helper()
+
+	agent := NewUploadAgent()
+	config := DefaultConfig()
+	config.UploadDirectories = []string{f.approved}
+	config.UploadChunkSize = chunkSize
+	if err := agent.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := newFakeUploadSender()
+	agent.SetSender(sender)
+	return agent, sender
+}
+
+func TestUploadAgentRejectsPathOutsideApprovedDirs(t *testing.T) {
+	f := newUploadFixture(t)
+	agent, sender := newTestUploadAgent(t, f, 0)
+
+	_, err := agent.HandleUploadRequest(api.FileUploadRequest{
+		TransferID: "up-1",
+		SessionID:  "session-1",
+		FilePath:   filepath.Join(f.sibling, "secret.txt"),
+	})
+	if err == nil {
+		t.Fatal("request outside the approved dirs was accepted")
+	}
+
+	response := sender.lastResponse(t)
+	if response.Accepted || response.Reason == "" || response.TransferID != "up-1" {
+		t.Errorf("response = %+v, want a rejection with a reason", response)
+	}
+	if _, err := agent.Accept("up-1"); err != ErrUploadNotFound {
+		t.Errorf("Accept() of a rejected upload error = %v, want ErrUploadNotFound", err)
+	}
+}
+
+func TestUploadAgentRevalidatesOnAccept(t *testing.T) {
+	tests := []struct {
+		name string
+		swap func(t *testing.T, f uploadFixture, path string)
+	}{
+		{
+			name: "replaced by a symlink to outside",
+			swap: func(t *testing.T, f uploadFixture, path string) {
+				os.Remove(path)
+				symlink(t, filepath.Join(f.outside, "secret.txt"), path)
+			},
+		},
+		{
+			name: "replaced by a directory",
+			swap: func(t *testing.T, f uploadFixture, path string) {
+				os.Remove(path)
+				if err := os.Mkdir(path, 0700); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "removed",
+			swap: func(t *testing.T, f uploadFixture, path string) { os.Remove(path) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newUploadFixture(t)
+			agent, sender := newTestUploadAgent(t, f, 0)
+			path := filepath.Join(f.approved, "report.txt")
+
+			if _, err := agent.HandleUploadRequest(api.FileUploadRequest{
+				TransferID: "up-1",
+				SessionID:  "session-1",
+				FilePath:   path,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.swap(t, f, path)
+
+			if _, err := agent.Accept("up-1"); err == nil {
+				t.Fatal("Accept() succeeded after the file was swapped")
+			}
+			if response := sender.lastResponse(t); response.Accepted {
+				t.Errorf("response = %+v, want a rejection", response)
+			}
+			sender.mutex.Lock()
+			defer sender.mutex.Unlock()
+			if len(sender.chunks) != 0 {
+				t.Errorf("%d chunks were sent", len(sender.chunks))
+			}
+		})
+	}
+}
+
+func TestUploadAgentSendsChunksWithChecksums(t *testing.T) {
+	const chunkSize = 1000
+
+	for _, size := range []int{0, 1, chunkSize, 3*chunkSize + 17} {
+		t.Run(fmt.Sprintf("%d bytes", size), func(t *testing.T) {
+			f := newUploadFixture(t)
+			agent, sender := newTestUploadAgent(t, f, chunkSize)
+
+			content := make([]byte, size)
+			rand.Read(content)
+			path := filepath.Join(f.approved, "data.bin")
+			if err := os.WriteFile(path, content, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			finished := make(chan UploadResult, 1)
+			agent.SetUploadFinishedCallback(func(result UploadResult) { finished <- result })
+
+			if _, err := agent.HandleUploadRequest(api.FileUploadRequest{
+				TransferID: "up-1",
+				SessionID:  "session-1",
+				FilePath:   path,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			info, err := agent.Accept("up-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response := sender.lastResponse(t)
+			wantChunks := uploadChunkCount(int64(size), chunkSize)
+			if !response.Accepted || response.FileSizeBytes != int64(size) || response.TotalChunks != wantChunks || response.ChunkSize != chunkSize {
+				t.Errorf("response = %+v, want accepted with %d bytes in %d chunks", response, size, wantChunks)
+			}
+
+			complete := sender.waitComplete(t)
+			sum := sha256.Sum256(content)
+			if !complete.Success || complete.Cancelled || complete.BytesSent != int64(size) {
+				t.Errorf("complete = %+v, want success with %d bytes", complete, size)
+			}
+			if complete.FileChecksum != hex.EncodeToString(sum[:]) {
+				t.Errorf("file checksum = %s, want %x", complete.FileChecksum, sum)
+			}
+
+			sender.mutex.Lock()
+			defer sender.mutex.Unlock()
+			if len(sender.chunks) != wantChunks {
+				t.Fatalf("sent %d chunks, want %d", len(sender.chunks), wantChunks)
+			}
+			var assembled []byte
+			for i, chunk := range sender.chunks {
+				chunkSum := sha256.Sum256(chunk.ChunkData)
+				if chunk.ChunkIndex != i || chunk.TotalChunks != wantChunks || chunk.IsLastChunk != (i == wantChunks-1) {
+					t.Errorf("chunk %d header = %+v", i, chunk)
+				}
+				if chunk.ChunkChecksum != hex.EncodeToString(chunkSum[:]) {
+					t.Errorf("chunk %d checksum = %s, want %x", i, chunk.ChunkChecksum, chunkSum)
+				}
+				if chunk.TransferID != info.TransferID || chunk.SessionID != "session-1" {
+					t.Errorf("chunk %d ids = %s/%s", i, chunk.TransferID, chunk.SessionID)
+				}
+				assembled = append(assembled, chunk.ChunkData...)
+			}
+			if !bytes.Equal(assembled, content) {
+				t.Error("sent chunks do not reassemble the file")
+			}
+
+			if result := <-finished; !result.Success || result.Checksum != complete.FileChecksum {
+				t.Errorf("finished = %+v, want success with checksum %s", result, complete.FileChecksum)
+			}
+		})
+	}
+}
+
+func TestUploadAgentCancelMidStream(t *testing.T) {
+	f := newUploadFixture(t)
+	agent, sender := newTestUploadAgent(t, f, 100)
+	sender.sent = make(chan int)
+	sender.gate = make(chan struct{})
+
+	path := filepath.Join(f.approved, "data.bin")
+	if err := os.WriteFile(path, make([]byte, 1000), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := agent.HandleUploadRequest(api.FileUploadRequest{TransferID: "up-1", SessionID: "session-1", FilePath: path}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := agent.Accept("up-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Cancelar mientras el primer chunk está en vuelo
+	<-sender.sent
+	if err := agent.Cancel("up-1", "cancelled by user"); err != nil {
+		t.Fatal(err)
+	}
+	close(sender.gate)
+
+	complete := sender.waitComplete(t)
+	if complete.Success || !complete.Cancelled || complete.ErrorMessage != "cancelled by user" {
+		t.Errorf("complete = %+v, want cancelled by user", complete)
+	}
+	if complete.BytesSent != 100 || complete.FileChecksum != "" {
+		t.Errorf("complete = %+v, want 100 bytes and no checksum", complete)
+	}
+
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+	if len(sender.chunks) != 1 {
+		t.Errorf("sent %d chunks after cancelling, want 1", len(sender.chunks))
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package filetransfer
+
+import (
+	"syscall"
+	"testing"
+)
+
+// mkfifo crea una tubería con nombre en path
+func mkfifo(t *testing.T, path string) {
+	t.Helper()
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,20 +1,33 @@
 package remotecontrol
 
 import (
+	"errors"
 	"fmt"
+	"image"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"EscritorioRemoto-Cliente/pkg/api"
 )
 
+// ErrViewOnly is returned for input commands received while the session is view-only
+var ErrViewOnly = errors.New("session is view-only: input control is disabled")
+
+// SessionOptions configures a remote control session at start time
+type SessionOptions struct {
+	ViewOnly      bool // Stream the screen but reject every InputCommand
+	ClipboardSync bool // Sync the clipboard both ways; can be toggled mid-session
+}
+
 // RemoteControlAgent coordinates screen capture and input simulation
 type RemoteControlAgent struct {
 	screenCapture   *ScreenCapture
 	inputSimulator  *InputSimulator
 	isActive        bool
 	activeSessionID string
+	viewOnly        bool
 	mutex           sync.RWMutex
 
 	// Configuration
@@ -22,14 +35,29 @@ type RemoteControlAgent struct {
 	jpegQuality  int           // JPEG compression quality (1-100)
 	captureDelay time.Duration // Delay between captures
 
+	// Delta encoding
+	tileConfig        TileEncoderConfig // Each session gets its own encoder with it
+	deltaEncoding     bool              // Send only changed tiles between keyframes
+	fullFrameCapture  bool              // Attach a local full JPEG to every frame (recording)
+	keyframeRequested atomic.Bool       // Set from other goroutines, consumed by captureLoop
+
+	// Adaptive streaming
+	congestion     *CongestionController
+	frameTransport FrameTransport
+	frameSender    *FrameSender
+
+	// Clipboard synchronization
+	clipboard *ClipboardSync
+
 	// Channels for coordination
 	stopCapture chan struct{}
+	captureDone chan struct{} // Closed when the session's capture loop has returned
 	frameOutput chan api.ScreenFrame
 }
 
 // NewRemoteControlAgent creates a new RemoteControlAgent
 func NewRemoteControlAgent() *RemoteControlAgent {
-	return &RemoteControlAgent{
+	a := &RemoteControlAgent{
 		screenCapture:  NewScreenCapture(),
 		inputSimulator: NewInputSimulator(),
 		isActive:       false,
@@ -37,11 +65,23 @@ func NewRemoteControlAgent() *RemoteControlAgent {
 		jpegQuality:    75,                             // 75% quality by default
 		captureDelay:   66 * time.Millisecond,          // ~15 FPS
 		frameOutput:    make(chan api.ScreenFrame, 10), // Buffer for 10 frames
+		tileConfig:     DefaultTileEncoderConfig(),
+		deltaEncoding:  true,
+		congestion:     NewCongestionController(DefaultCongestionConfig()),
+		clipboard:      NewClipboardSync(DefaultClipboardConfig()),
 	}
+
+	a.congestion.SetSettingsHandler(a.applyStreamSettings)
+	return a
 }
 
 // StartSession begins screen capture and prepares for input control
 func (a *RemoteControlAgent) StartSession(sessionID string) error {
+	return a.StartSessionWithOptions(sessionID, SessionOptions{})
+}
+
+// StartSessionWithOptions begins a session with the given options
+func (a *RemoteControlAgent) StartSessionWithOptions(sessionID string, options SessionOptions) error {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
@@ -49,14 +89,39 @@ func (a *RemoteControlAgent) StartSession(sessionID string) error {
 		return fmt.Errorf("session already active: %s", a.activeSessionID)
 	}
 
-	log.Printf("🎬 Starting remote control session: %s", sessionID)
+	log.Printf("🎬 Starting remote control session: %s (view-only: %v)", sessionID, options.ViewOnly)
 
 	a.activeSessionID = sessionID
 	a.isActive = true
+	a.viewOnly = options.ViewOnly
 	a.stopCapture = make(chan struct{})
+	a.captureDone = make(chan struct{})
+	a.frameOutput = make(chan api.ScreenFrame, 10)
+
+	// A new viewer always starts from a keyframe; a fresh encoder also keeps a
+	// capture loop that is still stopping away from this session's reference frame
+	encoder := NewTileEncoder(a.tileConfig)
+
+	// Map input to the display being streamed
+	a.inputSimulator.SetDisplayBounds(a.screenCapture.CurrentBounds())
+
+	// Start at the configured bounds and adapt from there
+	a.congestion.Reset()
+	config := a.congestion.Config()
+	a.frameRate = config.MaxFPS
+	a.jpegQuality = config.MaxQuality
+	a.captureDelay = time.Second / time.Duration(config.MaxFPS)
+
+	if a.frameTransport != nil {
+		a.frameSender = NewFrameSender(a.frameTransport, a.congestion, a.RequestKeyframe)
+		a.frameSender.Start()
+	}
+
+	// Start screen capture in goroutine; the loop keeps its own session ID, channels
+	// and encoder so a quick stop/start cannot hand it the next session's
+	go a.captureLoop(sessionID, encoder, a.stopCapture, a.captureDone, a.frameOutput)
 
-	// Start screen capture in goroutine
-	go a.captureLoop()
+	a.clipboard.Start(sessionID, options.ClipboardSync)
 
 	log.Printf("✅ Remote control session started successfully: %s", sessionID)
 	return nil
@@ -65,9 +130,9 @@ func (a *RemoteControlAgent) StartSession(sessionID string) error {
 // StopSession ends screen capture and input control
 func (a *RemoteControlAgent) StopSession() error {
 	a.mutex.Lock()
-	defer a.mutex.Unlock()
 
 	if !a.isActive {
+		a.mutex.Unlock()
 		return fmt.Errorf("no active session")
 	}
 
@@ -75,9 +140,27 @@ func (a *RemoteControlAgent) StopSession() error {
 
 	// Signal capture loop to stop
 	close(a.stopCapture)
+	captureDone := a.captureDone
 
 	a.isActive = false
 	a.activeSessionID = ""
+	a.viewOnly = false
+
+	// A session that ends mid-drag or mid-combination must not leave a button or key held
+	a.inputSimulator.ReleaseAll()
+
+	sender := a.frameSender
+	a.frameSender = nil
+	a.mutex.Unlock()
+
+	// Stopped outside the lock: an in-flight write reports back into the agent
+	if sender != nil {
+		sender.Stop()
+	}
+	a.clipboard.Stop()
+
+	// The loop takes the agent lock for its settings, so it is awaited outside it
+	<-captureDone
 
 	log.Printf("✅ Remote control session stopped successfully")
 	return nil
@@ -111,6 +194,10 @@ func (a *RemoteControlAgent) ProcessInputCommand(command api.InputCommand) error
 			command.SessionID, a.activeSessionID)
 	}
 
+	if a.viewOnly {
+		return ErrViewOnly
+	}
+
 	log.Printf("🎮 Processing input command: type=%s, action=%s", command.EventType, command.Action)
 
 	switch command.EventType {
@@ -123,56 +210,319 @@ func (a *RemoteControlAgent) ProcessInputCommand(command api.InputCommand) error
 	}
 }
 
-// GetFrameOutput returns the channel for receiving captured frames
+// SetViewOnly revokes (true) or grants (false) input control in the active session
+func (a *RemoteControlAgent) SetViewOnly(viewOnly bool) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.isActive {
+		return fmt.Errorf("no active session")
+	}
+
+	if a.viewOnly != viewOnly {
+		log.Printf("👁️ Session %s view-only: %v", a.activeSessionID, viewOnly)
+	}
+	a.viewOnly = viewOnly
+
+	// Revoking control releases whatever the admin was holding
+	if viewOnly {
+		a.inputSimulator.ReleaseAll()
+	}
+	return nil
+}
+
+// IsViewOnly returns whether input control is disabled in the active session
+func (a *RemoteControlAgent) IsViewOnly() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.viewOnly
+}
+
+// GetFrameOutput returns the channel for receiving the current session's
+// frames. Each session gets its own channel, closed when its capture loop stops.
 func (a *RemoteControlAgent) GetFrameOutput() <-chan api.ScreenFrame {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
 	return a.frameOutput
 }
 
-// SetFrameRate sets the capture frame rate (FPS)
+// SetFrameRate sets the capture frame rate (FPS). With adaptive streaming
+// enabled it is the upper bound the controller works under.
 func (a *RemoteControlAgent) SetFrameRate(fps int) {
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
-
 	if fps < 1 {
 		fps = 1
 	} else if fps > 30 {
 		fps = 30
 	}
 
-	a.frameRate = fps
-	a.captureDelay = time.Duration(1000/fps) * time.Millisecond
+	config := a.congestion.Config()
+	config.MaxFPS = fps
+	if config.MinFPS > fps {
+		config.MinFPS = fps
+	}
+	if err := a.congestion.SetConfig(config); err != nil {
+		log.Printf("❌ Cannot set frame rate: %v", err)
+		return
+	}
 
-	log.Printf("📹 Frame rate set to %d FPS (delay: %v)", fps, a.captureDelay)
+	log.Printf("📹 Frame rate set to %d FPS", fps)
 }
 
-// SetJPEGQuality sets the JPEG compression quality (1-100)
+// SetJPEGQuality sets the JPEG compression quality (1-100). With adaptive
+// streaming enabled it is the upper bound the controller works under.
 func (a *RemoteControlAgent) SetJPEGQuality(quality int) {
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
-
 	if quality < 1 {
 		quality = 1
 	} else if quality > 100 {
 		quality = 100
 	}
 
-	a.jpegQuality = quality
+	config := a.congestion.Config()
+	config.MaxQuality = quality
+	if config.MinQuality > quality {
+		config.MinQuality = quality
+	}
+	if err := a.congestion.SetConfig(config); err != nil {
+		log.Printf("❌ Cannot set JPEG quality: %v", err)
+		return
+	}
+
 	log.Printf("🖼️ JPEG quality set to %d%%", quality)
 }
 
-// captureLoop runs the screen capture loop
-func (a *RemoteControlAgent) captureLoop() {
+// SetCongestionConfig replaces the adaptive streaming bounds and thresholds after validating them
+func (a *RemoteControlAgent) SetCongestionConfig(config CongestionConfig) error {
+	return a.congestion.SetConfig(config)
+}
+
+// GetStreamStats returns the current adaptive streaming measurements
+func (a *RemoteControlAgent) GetStreamStats() CongestionStats {
+	return a.congestion.Stats()
+}
+
+// SetFrameTransport sets where captured frames are sent; used from the next session on
+func (a *RemoteControlAgent) SetFrameTransport(transport FrameTransport) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.frameTransport = transport
+}
+
+// SetClipboardTransport sets where local clipboard changes are sent; used from the next session on
+func (a *RemoteControlAgent) SetClipboardTransport(transport ClipboardTransport) {
+	a.clipboard.SetTransport(transport)
+}
+
+// SetClipboardSentCallback sets a callback run after each local clipboard change is sent
+func (a *RemoteControlAgent) SetClipboardSentCallback(callback func(update api.ClipboardUpdate)) {
+	a.clipboard.SetSentCallback(callback)
+}
+
+// SetClipboardConfig replaces the clipboard limits after validating them
+func (a *RemoteControlAgent) SetClipboardConfig(config ClipboardConfig) error {
+	return a.clipboard.SetConfig(config)
+}
+
+// GetClipboardConfig returns the clipboard limits
+func (a *RemoteControlAgent) GetClipboardConfig() ClipboardConfig {
+	return a.clipboard.Config()
+}
+
+// SetClipboardSyncEnabled turns clipboard sync on or off in the active session
+func (a *RemoteControlAgent) SetClipboardSyncEnabled(enabled bool) error {
+	return a.clipboard.SetEnabled(enabled)
+}
+
+// IsClipboardSyncEnabled returns whether clipboard sync is on in the active session
+func (a *RemoteControlAgent) IsClipboardSyncEnabled() bool {
+	return a.clipboard.IsEnabled()
+}
+
+// GetClipboardSyncState describes the clipboard sync for the admin
+func (a *RemoteControlAgent) GetClipboardSyncState() api.ClipboardSyncState {
+	return a.clipboard.State()
+}
+
+// ApplyClipboardUpdate writes clipboard content sent by the admin. Writing the
+// clipboard is input, so it is rejected while the session is view-only.
+func (a *RemoteControlAgent) ApplyClipboardUpdate(update api.ClipboardUpdate) error {
+	a.mutex.RLock()
+	active, sessionID, viewOnly := a.isActive, a.activeSessionID, a.viewOnly
+	a.mutex.RUnlock()
+
+	if !active {
+		return fmt.Errorf("no active session")
+	}
+
+	if update.SessionID != sessionID {
+		return fmt.Errorf("clipboard session ID %s does not match active session %s",
+			update.SessionID, sessionID)
+	}
+
+	if viewOnly {
+		return ErrViewOnly
+	}
+
+	return a.clipboard.ApplyRemote(update)
+}
+
+// SendFrame queues a frame on the session's latest-frame-wins sender
+func (a *RemoteControlAgent) SendFrame(frame api.ScreenFrame) error {
+	a.mutex.RLock()
+	sender := a.frameSender
+	a.mutex.RUnlock()
+
+	if sender == nil {
+		return fmt.Errorf("no frame transport for the active session")
+	}
+
+	sender.Enqueue(frame)
+	return nil
+}
+
+// applyStreamSettings applies FPS and quality chosen by the congestion controller
+func (a *RemoteControlAgent) applyStreamSettings(fps, quality int) {
+	if fps < 1 {
+		fps = 1
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.frameRate = fps
+	a.jpegQuality = quality
+	a.captureDelay = time.Second / time.Duration(fps)
+}
+
+// ListDisplays returns the client's displays with their desktop bounds
+func (a *RemoteControlAgent) ListDisplays() []DisplayInfo {
+	return a.screenCapture.ListDisplays()
+}
+
+// GetSelectedDisplay returns the streamed display index, or VirtualDesktop
+func (a *RemoteControlAgent) GetSelectedDisplay() int {
+	return a.screenCapture.GetDisplay()
+}
+
+// GetCaptureBounds returns the desktop rectangle being streamed
+func (a *RemoteControlAgent) GetCaptureBounds() image.Rectangle {
+	return a.screenCapture.CurrentBounds()
+}
+
+// SelectDisplay switches the streamed display, mid-session if needed.
+// Use VirtualDesktop to stream the union of all displays.
+func (a *RemoteControlAgent) SelectDisplay(displayNum int) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if err := a.screenCapture.SetDisplay(displayNum); err != nil {
+		return err
+	}
+
+	// Input coordinates follow the new display; the viewer needs a full frame
+	a.inputSimulator.SetDisplayBounds(a.screenCapture.CurrentBounds())
+	a.keyframeRequested.Store(true)
+
+	return nil
+}
+
+// SetKeyReleaseTimeout sets how long keys may stay held without input before
+// they are released as stuck; 0 disables the timeout
+func (a *RemoteControlAgent) SetKeyReleaseTimeout(timeout time.Duration) {
+	a.inputSimulator.SetKeyReleaseTimeout(timeout)
+}
+
+// SetDeltaEncoding enables or disables tile-based delta frames
+func (a *RemoteControlAgent) SetDeltaEncoding(enabled bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.deltaEncoding = enabled
+	a.keyframeRequested.Store(true)
+	log.Printf("🧩 Delta encoding enabled: %v", enabled)
+}
+
+// SetFullFrameCapture makes every frame carry a full local JPEG in
+// FullFrameData, needed by the video recorder when delta encoding is on
+func (a *RemoteControlAgent) SetFullFrameCapture(enabled bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.fullFrameCapture = enabled
+}
+
+// RequestKeyframe forces the next captured frame to be a full keyframe
+func (a *RemoteControlAgent) RequestKeyframe() {
+	a.keyframeRequested.Store(true)
+}
+
+// encodeFrame turns a capture into a ScreenFrame. It returns nil when the
+// screen did not change and no keyframe is due.
+func (a *RemoteControlAgent) encodeFrame(encoder *TileEncoder, frame *image.RGBA, quality int, delta, fullFrame bool) (*api.ScreenFrame, error) {
+	screenFrame := &api.ScreenFrame{
+		Width:   frame.Bounds().Dx(),
+		Height:  frame.Bounds().Dy(),
+		Quality: quality,
+	}
+
+	if !delta {
+		frameData, err := a.screenCapture.CompressToJPEG(frame, quality)
+		if err != nil {
+			return nil, err
+		}
+		screenFrame.Format = api.FrameFormatJPEG
+		screenFrame.IsKeyframe = true
+		screenFrame.FrameData = frameData
+		screenFrame.FullFrameData = frameData
+		return screenFrame, nil
+	}
+
+	if a.keyframeRequested.Swap(false) {
+		encoder.RequestKeyframe()
+	}
+
+	encoded, err := encoder.Encode(frame, quality)
+	if err != nil || encoded == nil {
+		return nil, err
+	}
+
+	screenFrame.Format = encoded.Format
+	screenFrame.IsKeyframe = encoded.IsKeyframe
+	screenFrame.FrameData = encoded.FrameData
+	screenFrame.Tiles = encoded.Tiles
+
+	if encoded.IsKeyframe {
+		screenFrame.FullFrameData = encoded.FrameData
+	} else if fullFrame {
+		screenFrame.FullFrameData, err = a.screenCapture.CompressToJPEG(frame, quality)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return screenFrame, nil
+}
+
+// captureLoop runs the screen capture loop for sessionID until stop is closed,
+// then closes frames so the session's reader ends with it and done for StopSession
+func (a *RemoteControlAgent) captureLoop(sessionID string, encoder *TileEncoder, stop <-chan struct{}, done chan<- struct{}, frames chan<- api.ScreenFrame) {
+	defer close(done)
+	defer close(frames)
+
+	a.mutex.RLock()
+	currentDelay := a.captureDelay
+	frameRate, jpegQuality := a.frameRate, a.jpegQuality
+	a.mutex.RUnlock()
+
 	log.Printf("📸 Starting screen capture loop (FPS: %d, Quality: %d%%)",
-		a.frameRate, a.jpegQuality)
+		frameRate, jpegQuality)
 
-	ticker := time.NewTicker(a.captureDelay)
+	ticker := time.NewTicker(currentDelay)
 	defer ticker.Stop()
 
 	sequenceNum := int64(0)
 
 	for {
 		select {
-		case <-a.stopCapture:
+		case <-stop:
 			log.Printf("🔚 Screen capture loop stopped")
 			return
 
@@ -184,32 +534,46 @@ func (a *RemoteControlAgent) captureLoop() {
 				continue
 			}
 
-			// Compress to JPEG
-			frameData, err := a.screenCapture.CompressToJPEG(frame, a.jpegQuality)
+			a.mutex.RLock()
+			quality := a.jpegQuality
+			delta := a.deltaEncoding
+			fullFrame := a.fullFrameCapture
+			delay := a.captureDelay
+			a.mutex.RUnlock()
+
+			// Follow frame rate changes made by the congestion controller
+			if delay != currentDelay {
+				currentDelay = delay
+				ticker.Reset(currentDelay)
+			}
+
+			// Encode the full frame or only the tiles that changed
+			screenFrame, err := a.encodeFrame(encoder, frame, quality, delta, fullFrame)
 			if err != nil {
 				log.Printf("❌ Error compressing frame: %v", err)
 				continue
 			}
-
-			// Create frame message
-			screenFrame := api.ScreenFrame{
-				SessionID:   a.activeSessionID,
-				Timestamp:   time.Now().Unix(),
-				Width:       frame.Bounds().Dx(),
-				Height:      frame.Bounds().Dy(),
-				Format:      "jpeg",
-				Quality:     a.jpegQuality,
-				FrameData:   frameData,
-				SequenceNum: sequenceNum,
+			if screenFrame == nil {
+				// Nothing changed since the previous frame
+				continue
 			}
 
+			screenFrame.SessionID = sessionID
+			screenFrame.Timestamp = time.Now().Unix()
+			screenFrame.SequenceNum = sequenceNum
+
 			// Send to output channel (non-blocking)
 			select {
-			case a.frameOutput <- screenFrame:
+			case frames <- *screenFrame:
 				sequenceNum++
 			default:
-				// Channel is full, skip this frame
+				// Channel is full, skip this frame; a dropped delta leaves the
+				// viewer out of sync, so the next frame must be a keyframe
 				log.Printf("⚠️ Frame output channel full, skipping frame %d", sequenceNum)
+				a.congestion.RecordDrop()
+				if !screenFrame.IsKeyframe {
+					a.keyframeRequested.Store(true)
+				}
 			}
 		}
 	}
@@ -217,22 +581,41 @@ func (a *RemoteControlAgent) captureLoop() {
 
 // GetCapabilities returns the capabilities of this agent
 func (a *RemoteControlAgent) GetCapabilities() map[string]interface{} {
+	// Written by the frame sender and by session start
+	a.mutex.RLock()
+	deltaEncoding := a.deltaEncoding
+	frameRate, jpegQuality := a.frameRate, a.jpegQuality
+	a.mutex.RUnlock()
+
 	return map[string]interface{}{
 		"screen_capture": map[string]interface{}{
 			"max_fps":           30,
 			"min_fps":           1,
-			"supported_formats": []string{"jpeg"},
+			"supported_formats": []string{api.FrameFormatJPEG, api.FrameFormatJPEGTiles},
 			"compression":       true,
+			"delta_encoding":    deltaEncoding,
+			"tile_size":         a.tileConfig.TileSize,
+		},
+		"displays": map[string]interface{}{
+			"count":           a.screenCapture.GetAvailableDisplays(),
+			"selected":        a.screenCapture.GetDisplay(),
+			"virtual_desktop": true,
 		},
 		"input_control": map[string]interface{}{
-			"mouse":    true,
-			"keyboard": true,
-			"scroll":   true,
+			"mouse":             true,
+			"keyboard":          true,
+			"scroll":            true,
+			"horizontal_scroll": true,
+			"drag":              true,
+			"double_click":      true,
+			"view_only":         a.IsViewOnly(),
 		},
+		"clipboard": a.clipboardMap(),
 		"current_settings": map[string]interface{}{
-			"fps":          a.frameRate,
-			"jpeg_quality": a.jpegQuality,
+			"fps":          frameRate,
+			"jpeg_quality": jpegQuality,
 		},
+		"adaptive_streaming": a.streamStatsMap(),
 	}
 }
 
@@ -245,3 +628,38 @@ func (a *RemoteControlAgent) TestScreenCapture() error {
 func (a *RemoteControlAgent) TestInputSimulation() error {
 	return a.inputSimulator.TestInput()
 }
+
+// clipboardMap returns the clipboard sync state for GetCapabilities
+func (a *RemoteControlAgent) clipboardMap() map[string]interface{} {
+	state := a.clipboard.State()
+
+	return map[string]interface{}{
+		"enabled":         state.Enabled,
+		"formats":         clipboardFormats(state.AllowImages),
+		"max_text_bytes":  state.MaxTextBytes,
+		"max_image_bytes": state.MaxImageBytes,
+	}
+}
+
+// clipboardFormats lists the clipboard formats that are synced
+func clipboardFormats(allowImages bool) []string {
+	if allowImages {
+		return []string{api.ClipboardFormatText, api.ClipboardFormatPNG}
+	}
+	return []string{api.ClipboardFormatText}
+}
+
+// streamStatsMap returns the adaptive streaming state for GetCapabilities
+func (a *RemoteControlAgent) streamStatsMap() map[string]interface{} {
+	config := a.congestion.Config()
+	stats := a.congestion.Stats()
+
+	return map[string]interface{}{
+		"enabled":           config.Enabled,
+		"fps_range":         []int{config.MinFPS, config.MaxFPS},
+		"quality_range":     []int{config.MinQuality, config.MaxQuality},
+		"avg_write_latency": stats.AvgWriteLatency.Milliseconds(),
+		"drop_rate":         stats.DropRate,
+		"max_queue_depth":   stats.MaxQueueDepth,
+	}
+}
@@ -0,0 +1,262 @@
+package remotecontrol
+
+import (
+	"errors"
+	"image"
+	"reflect"
+	"testing"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// newTestAgent returns an agent in session "session-1" that injects into a
+// fakeInput and writes to a fakeClipboard. Only the session state is set: no
+// capture loop runs, so the tests do not need a display.
+func newTestAgent(t *testing.T, viewOnly bool) (*RemoteControlAgent, *fakeInput, *fakeClipboard) {
+	t.Helper()
+
+	agent := NewRemoteControlAgent()
+	input := &fakeInput{}
+	agent.inputSimulator.input = input
+
+	clipboard := &fakeClipboard{}
+	agent.clipboard.backend = clipboard
+	agent.clipboard.Start("session-1", true)
+	t.Cleanup(agent.clipboard.Stop)
+
+	agent.isActive = true
+	agent.activeSessionID = "session-1"
+	agent.viewOnly = viewOnly
+	return agent, input, clipboard
+}
+
+// inSession tags command with the test agent's session
+func inSession(command api.InputCommand) api.InputCommand {
+	command.SessionID = "session-1"
+	return command
+}
+
+func TestAgentViewOnlyRejectsInput(t *testing.T) {
+	clipboardUpdate := api.ClipboardUpdate{SessionID: "session-1", Format: api.ClipboardFormatText, Text: "pasted"}
+
+	tests := []struct {
+		name     string
+		viewOnly bool
+		wantErr  error
+	}{
+		{name: "control", viewOnly: false, wantErr: nil},
+		{name: "view-only", viewOnly: true, wantErr: ErrViewOnly},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, input, clipboard := newTestAgent(t, tt.viewOnly)
+
+			for _, command := range []api.InputCommand{
+				mouseCommand("move", 10, 20, ""),
+				mouseCommand("click", 10, 20, "left"),
+				keyCommand("keydown", "a"),
+			} {
+				if err := agent.ProcessInputCommand(inSession(command)); !errors.Is(err, tt.wantErr) {
+					t.Errorf("ProcessInputCommand(%s %s) = %v, want %v", command.EventType, command.Action, err, tt.wantErr)
+				}
+			}
+			if err := agent.ApplyClipboardUpdate(clipboardUpdate); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ApplyClipboardUpdate() = %v, want %v", err, tt.wantErr)
+			}
+
+			injected := len(input.take()) > 0
+			text, _ := clipboard.ReadText()
+			if injected == tt.viewOnly || (text == "pasted") == tt.viewOnly {
+				t.Errorf("input injected = %v, clipboard = %q, view-only %v", injected, text, tt.viewOnly)
+			}
+		})
+	}
+}
+
+func TestAgentChecksSessionBeforeViewOnly(t *testing.T) {
+	agent, input, _ := newTestAgent(t, true)
+
+	command := mouseCommand("click", 10, 20, "left")
+	command.SessionID = "other"
+	if err := agent.ProcessInputCommand(command); err == nil || errors.Is(err, ErrViewOnly) {
+		t.Errorf("ProcessInputCommand() from another session = %v, want a session mismatch", err)
+	}
+	if err := agent.ApplyClipboardUpdate(api.ClipboardUpdate{SessionID: "other", Format: api.ClipboardFormatText, Text: "x"}); err == nil || errors.Is(err, ErrViewOnly) {
+		t.Errorf("ApplyClipboardUpdate() from another session = %v, want a session mismatch", err)
+	}
+	if events := input.take(); len(events) != 0 {
+		t.Errorf("injected %v", events)
+	}
+}
+
+func TestAgentSetViewOnlyReleasesHeldInput(t *testing.T) {
+	agent, input, _ := newTestAgent(t, false)
+
+	for _, command := range []api.InputCommand{
+		keyCommand("keydown", "ctrl"),
+		keyCommand("keydown", "a", "ctrl"),
+		mouseCommand("down", 10, 20, "left"),
+	} {
+		if err := agent.ProcessInputCommand(inSession(command)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	input.take()
+
+	if err := agent.SetViewOnly(true); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"mouseup left", "keyup a", "keyup ctrl"}
+	if got := input.take(); !reflect.DeepEqual(sortedKeys(got), sortedKeys(want)) {
+		t.Errorf("released %v, want %v", got, want)
+	}
+	if held, pressed := agent.inputSimulator.HeldKeys(), agent.inputSimulator.PressedButtons(); len(held) != 0 || len(pressed) != 0 {
+		t.Errorf("still held: keys %v, buttons %v", held, pressed)
+	}
+	if !agent.IsViewOnly() {
+		t.Error("IsViewOnly() = false after SetViewOnly(true)")
+	}
+
+	if err := agent.ProcessInputCommand(inSession(keyCommand("keyup", "a"))); !errors.Is(err, ErrViewOnly) {
+		t.Errorf("ProcessInputCommand() = %v, want %v", err, ErrViewOnly)
+	}
+
+	// Granting control back does not replay anything
+	if err := agent.SetViewOnly(false); err != nil {
+		t.Fatal(err)
+	}
+	if events := input.take(); len(events) != 0 {
+		t.Errorf("SetViewOnly(false) injected %v", events)
+	}
+	if err := agent.ProcessInputCommand(inSession(keyCommand("keydown", "b"))); err != nil {
+		t.Errorf("ProcessInputCommand() after granting control = %v", err)
+	}
+}
+
+func TestAgentSetViewOnlyWithoutSession(t *testing.T) {
+	agent := NewRemoteControlAgent()
+	if err := agent.SetViewOnly(true); err == nil {
+		t.Error("SetViewOnly() without a session succeeded")
+	}
+	if agent.IsViewOnly() {
+		t.Error("IsViewOnly() = true without a session")
+	}
+}
+
+func TestAgentCapabilitiesAdvertiseViewOnly(t *testing.T) {
+	for _, viewOnly := range []bool{false, true} {
+		agent, _, _ := newTestAgent(t, viewOnly)
+
+		inputControl, ok := agent.GetCapabilities()["input_control"].(map[string]interface{})
+		if !ok {
+			t.Fatal("capabilities have no input_control")
+		}
+		if got := inputControl["view_only"]; got != viewOnly {
+			t.Errorf("view_only = %v, want %v", got, viewOnly)
+		}
+	}
+
+	// A toggle mid-session is advertised too
+	agent, _, _ := newTestAgent(t, false)
+	if err := agent.SetViewOnly(true); err != nil {
+		t.Fatal(err)
+	}
+	if got := agent.GetCapabilities()["input_control"].(map[string]interface{})["view_only"]; got != true {
+		t.Errorf("view_only after SetViewOnly(true) = %v, want true", got)
+	}
+}
+
+// useTestDisplays makes the agent see displays instead of the real ones
+func useTestDisplays(agent *RemoteControlAgent, displays ...image.Rectangle) {
+	agent.screenCapture.numDisplays = func() int { return len(displays) }
+	agent.screenCapture.displayBounds = func(displayIndex int) image.Rectangle {
+		if displayIndex < 0 || displayIndex >= len(displays) {
+			return image.Rectangle{}
+		}
+		return displays[displayIndex]
+	}
+}
+
+func TestAgentSelectDisplay(t *testing.T) {
+	virtualDesktop := image.Rect(-1280, -200, 4480, 1440)
+
+	tests := []struct {
+		name       string
+		display    int
+		wantBounds image.Rectangle
+		wantMove   string // Where a click at (30, 40) in the stream lands
+	}{
+		{name: "primary", display: 0, wantBounds: primaryDisplay, wantMove: "move 30,40"},
+		{name: "negative origin", display: 1, wantBounds: leftDisplay, wantMove: "move -1250,-160"},
+		{name: "right of the primary", display: 2, wantBounds: rightDisplay, wantMove: "move 1950,40"},
+		{name: "virtual desktop", display: VirtualDesktop, wantBounds: virtualDesktop, wantMove: "move -1250,-160"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, input, _ := newTestAgent(t, false)
+			useTestDisplays(agent, primaryDisplay, leftDisplay, rightDisplay)
+
+			if err := agent.SelectDisplay(tt.display); err != nil {
+				t.Fatal(err)
+			}
+			if got := agent.GetSelectedDisplay(); got != tt.display {
+				t.Errorf("GetSelectedDisplay() = %d, want %d", got, tt.display)
+			}
+			if got := agent.GetCaptureBounds(); got != tt.wantBounds {
+				t.Errorf("GetCaptureBounds() = %v, want %v", got, tt.wantBounds)
+			}
+			if got := agent.inputSimulator.displayBounds; got != tt.wantBounds {
+				t.Errorf("input bounds = %v, want %v", got, tt.wantBounds)
+			}
+			if !agent.keyframeRequested.Load() {
+				t.Error("switching displays did not force a keyframe")
+			}
+
+			if err := agent.ProcessInputCommand(inSession(mouseCommand("click", 30, 40, "left"))); err != nil {
+				t.Fatal(err)
+			}
+			if events := input.take(); len(events) == 0 || events[0] != tt.wantMove {
+				t.Errorf("events = %q, want %q first", events, tt.wantMove)
+			}
+		})
+	}
+}
+
+func TestAgentSelectDisplayRejectsUnknownDisplay(t *testing.T) {
+	agent, _, _ := newTestAgent(t, false)
+	useTestDisplays(agent, primaryDisplay, leftDisplay)
+	if err := agent.SelectDisplay(1); err != nil {
+		t.Fatal(err)
+	}
+	agent.keyframeRequested.Store(false)
+
+	for _, display := range []int{2, -2} {
+		if err := agent.SelectDisplay(display); err == nil {
+			t.Errorf("SelectDisplay(%d) succeeded", display)
+		}
+	}
+	if got := agent.GetSelectedDisplay(); got != 1 {
+		t.Errorf("GetSelectedDisplay() = %d, want 1", got)
+	}
+	if got := agent.inputSimulator.displayBounds; got != leftDisplay {
+		t.Errorf("input bounds = %v, want %v", got, leftDisplay)
+	}
+	if agent.keyframeRequested.Load() {
+		t.Error("a rejected switch forced a keyframe")
+	}
+}
+
+func TestAgentListDisplays(t *testing.T) {
+	agent := NewRemoteControlAgent()
+	useTestDisplays(agent, primaryDisplay, leftDisplay)
+
+	want := []DisplayInfo{
+		{Index: 0, X: 0, Y: 0, Width: 1920, Height: 1080, Primary: true},
+		{Index: 1, X: -1280, Y: -200, Width: 1280, Height: 1024},
+	}
+	if got := agent.ListDisplays(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ListDisplays() = %+v, want %+v", got, want)
+	}
+}
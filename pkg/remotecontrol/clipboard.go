@@ -0,0 +1,523 @@
+package remotecontrol
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"log"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// Errors returned for clipboard updates the client refuses to apply
+var (
+	ErrClipboardDisabled = errors.New("clipboard sync is disabled for this session")
+	ErrClipboardTooLarge = errors.New("clipboard content exceeds the size limit")
+	ErrClipboardFormat   = errors.New("unsupported clipboard format")
+)
+
+// clipboardImagePollInterval limits how often the image clipboard is read; on
+// some platforms reading it means launching an external process
+const clipboardImagePollInterval = 2 * time.Second
+
+// ClipboardConfig is the persisted clipboard sync configuration
+type ClipboardConfig struct {
+	EnabledByDefault bool `json:"enabled_by_default"` // Sessions start with sync on
+	AllowImages      bool `json:"allow_images"`       // Also sync PNG images
+	MaxTextBytes     int  `json:"max_text_bytes"`
+	MaxImageBytes    int  `json:"max_image_bytes"`
+	PollIntervalMs   int  `json:"poll_interval_ms"` // How often the local clipboard is checked
+}
+
+// DefaultClipboardConfig syncs text up to 1 MB and no images
+func DefaultClipboardConfig() ClipboardConfig {
+	return ClipboardConfig{
+		EnabledByDefault: true,
+		AllowImages:      false,
+		MaxTextBytes:     1024 * 1024,
+		MaxImageBytes:    5 * 1024 * 1024,
+		PollIntervalMs:   500,
+	}
+}
+
+// LoadClipboardConfig reads the configuration from a JSON file; a missing file
+// yields the default configuration
+func LoadClipboardConfig(path string) (ClipboardConfig, error) {
+	config := DefaultClipboardConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return config, fmt.Errorf("failed to read clipboard config: %w", err)
+	}
+
+	loaded := config
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return config, fmt.Errorf("invalid clipboard config %s: %w", path, err)
+	}
+	if err := loaded.validate(); err != nil {
+		return config, fmt.Errorf("invalid clipboard config %s: %w", path, err)
+	}
+
+	return loaded, nil
+}
+
+// pollInterval returns the local clipboard polling interval
+func (c ClipboardConfig) pollInterval() time.Duration {
+	return time.Duration(c.PollIntervalMs) * time.Millisecond
+}
+
+// validate checks the limits are usable
+func (c ClipboardConfig) validate() error {
+	if c.MaxTextBytes <= 0 {
+		return fmt.Errorf("max_text_bytes must be positive")
+	}
+	if c.AllowImages && c.MaxImageBytes <= 0 {
+		return fmt.Errorf("max_image_bytes must be positive when allow_images is set")
+	}
+	if c.PollIntervalMs < 100 {
+		return fmt.Errorf("poll_interval_ms must be at least 100")
+	}
+	return nil
+}
+
+// ClipboardTransport sends local clipboard changes to the server (implemented by api.APIClient)
+type ClipboardTransport interface {
+	SendClipboardUpdate(update api.ClipboardUpdate) error
+}
+
+// clipboardBackend reads and writes the system clipboard
+type clipboardBackend interface {
+	ReadText() (string, error)
+	WriteText(text string) error
+	ReadImage() ([]byte, error) // PNG; nil when the clipboard holds no image
+	WriteImage(data []byte) error
+}
+
+// ClipboardSync keeps the local clipboard and the admin's in sync during a
+// session. Changes are detected by hashing the clipboard: the hash of content
+// written from the remote side is remembered so it is not sent back.
+type ClipboardSync struct {
+	backend   clipboardBackend
+	transport ClipboardTransport
+	onSent    func(update api.ClipboardUpdate)
+
+	mutex     sync.Mutex
+	config    ClipboardConfig
+	sessionID string
+	enabled   bool
+	primed    bool                 // lastHash reflects the clipboard as it was when sync was enabled
+	lastHash  string               // Content last sent, received or present when sync was enabled
+	applying  bool                 // A remote update is being written
+	pending   *api.ClipboardUpdate // Latest remote update received while applying
+	applied   uint64               // Counts remote updates so a read that raced one is discarded
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewClipboardSync creates a clipboard sync bound to the system clipboard
+func NewClipboardSync(config ClipboardConfig) *ClipboardSync {
+	return &ClipboardSync{
+		backend: systemClipboard{},
+		config:  config,
+	}
+}
+
+// Config returns a copy of the current configuration
+func (c *ClipboardSync) Config() ClipboardConfig {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.config
+}
+
+// SetConfig replaces the configuration after validating it; the poll interval
+// applies from the next session on
+func (c *ClipboardSync) SetConfig(config ClipboardConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.config = config
+	return nil
+}
+
+// SetTransport sets where local clipboard changes are sent; used from the next session on
+func (c *ClipboardSync) SetTransport(transport ClipboardTransport) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.transport = transport
+}
+
+// SetSentCallback sets a callback run after each local change is sent
+func (c *ClipboardSync) SetSentCallback(callback func(update api.ClipboardUpdate)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.onSent = callback
+}
+
+// Start begins watching the local clipboard for a session. Content already on
+// the clipboard is never sent; only changes made while sync is enabled are.
+func (c *ClipboardSync) Start(sessionID string, enabled bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.stop != nil {
+		return
+	}
+
+	c.sessionID = sessionID
+	c.enabled = enabled
+	c.primed = false
+	c.lastHash = ""
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+
+	go c.pollLoop(c.stop, c.done, c.config.pollInterval())
+
+	log.Printf("📋 Clipboard sync ready for session %s (enabled: %v, images: %v)", sessionID, enabled, c.config.AllowImages)
+}
+
+// Stop ends clipboard sync for the current session
+func (c *ClipboardSync) Stop() {
+	c.mutex.Lock()
+	stop, done := c.stop, c.done
+	c.stop, c.done = nil, nil
+	c.sessionID = ""
+	c.enabled = false
+	c.lastHash = ""
+	c.pending = nil
+	c.mutex.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// SetEnabled turns sync on or off for the current session
+func (c *ClipboardSync) SetEnabled(enabled bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.stop == nil {
+		return fmt.Errorf("no active session")
+	}
+
+	if c.enabled != enabled {
+		log.Printf("📋 Clipboard sync for session %s enabled: %v", c.sessionID, enabled)
+		// Whatever was copied while sync was off stays local
+		c.primed = false
+	}
+	c.enabled = enabled
+	return nil
+}
+
+// IsEnabled returns whether sync is on for the current session
+func (c *ClipboardSync) IsEnabled() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stop != nil && c.enabled
+}
+
+// State describes the sync for the admin
+func (c *ClipboardSync) State() api.ClipboardSyncState {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return api.ClipboardSyncState{
+		SessionID:     c.sessionID,
+		Enabled:       c.stop != nil && c.enabled,
+		AllowImages:   c.config.AllowImages,
+		MaxTextBytes:  c.config.MaxTextBytes,
+		MaxImageBytes: c.config.MaxImageBytes,
+	}
+}
+
+// ApplyRemote writes content sent by the admin to the local clipboard. An update
+// that arrives while another is being written is queued and written right after
+// it; if several arrive, only the latest is kept.
+func (c *ClipboardSync) ApplyRemote(update api.ClipboardUpdate) error {
+	c.mutex.Lock()
+	config := c.config
+	switch {
+	case c.stop == nil || update.SessionID != c.sessionID:
+		c.mutex.Unlock()
+		return fmt.Errorf("clipboard update for inactive session %s", update.SessionID)
+	case !c.enabled:
+		c.mutex.Unlock()
+		return ErrClipboardDisabled
+	}
+	c.mutex.Unlock()
+
+	var content []byte
+	switch update.Format {
+	case api.ClipboardFormatText:
+		if len(update.Text) > config.MaxTextBytes {
+			return fmt.Errorf("%w: %d bytes (max %d)", ErrClipboardTooLarge, len(update.Text), config.MaxTextBytes)
+		}
+		if !utf8.ValidString(update.Text) {
+			return fmt.Errorf("clipboard text is not valid UTF-8")
+		}
+		content = []byte(update.Text)
+	case api.ClipboardFormatPNG:
+		if !config.AllowImages {
+			return fmt.Errorf("%w: images are not allowed", ErrClipboardFormat)
+		}
+		if len(update.Data) > config.MaxImageBytes {
+			return fmt.Errorf("%w: %d bytes (max %d)", ErrClipboardTooLarge, len(update.Data), config.MaxImageBytes)
+		}
+		if _, err := png.DecodeConfig(bytes.NewReader(update.Data)); err != nil {
+			return fmt.Errorf("clipboard image is not a valid PNG: %w", err)
+		}
+		content = update.Data
+	default:
+		return fmt.Errorf("%w: %q", ErrClipboardFormat, update.Format)
+	}
+
+	if update.Hash != "" && update.Hash != contentHash(content) {
+		return fmt.Errorf("clipboard content does not match its hash")
+	}
+
+	hash := clipboardHash(update.Format, content)
+	c.mutex.Lock()
+	if c.applying {
+		// Another update is being written: the newest one is written right after it
+		queued := update
+		c.pending = &queued
+		c.mutex.Unlock()
+		log.Printf("📋 Remote clipboard (%s, %d bytes) queued behind the one being applied", update.Format, len(content))
+		return nil
+	}
+	if hash == c.lastHash {
+		c.mutex.Unlock()
+		return nil
+	}
+	c.applying = true
+	c.applied++
+	c.mutex.Unlock()
+
+	err := c.writeRemote(update)
+
+	// Write whatever arrived meanwhile; only the latest pending update is kept
+	for {
+		c.mutex.Lock()
+		next := c.pending
+		c.pending = nil
+		if next != nil && (c.stop == nil || next.SessionID != c.sessionID || !c.enabled) {
+			next = nil
+		}
+		if next == nil {
+			c.applying = false
+			c.applied++
+			c.mutex.Unlock()
+			break
+		}
+		c.applied++
+		c.mutex.Unlock()
+
+		if queuedErr := c.writeRemote(*next); queuedErr != nil {
+			log.Printf("❌ Failed to apply queued remote clipboard: %v", queuedErr)
+		}
+	}
+
+	return err
+}
+
+// writeRemote writes one validated remote update to the clipboard and records
+// its hash (requires c.applying)
+func (c *ClipboardSync) writeRemote(update api.ClipboardUpdate) error {
+	content := clipboardContent(update)
+	hash := clipboardHash(update.Format, content)
+
+	c.mutex.Lock()
+	unchanged := hash == c.lastHash
+	c.mutex.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	var err error
+	if update.Format == api.ClipboardFormatText {
+		err = c.backend.WriteText(update.Text)
+	} else {
+		err = c.backend.WriteImage(update.Data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write clipboard: %w", err)
+	}
+
+	// The system may hand the content back in another form (line endings, a
+	// re-encoded PNG): remember what it reads back so it is not echoed
+	if readBack, readErr := c.readLocal(update.Format == api.ClipboardFormatPNG); readErr == nil && readBack != nil {
+		hash = clipboardHash(readBack.Format, clipboardContent(*readBack))
+	}
+
+	c.mutex.Lock()
+	c.lastHash = hash
+	c.mutex.Unlock()
+
+	log.Printf("📋 Applied remote clipboard (%s, %d bytes)", update.Format, len(content))
+	return nil
+}
+
+// pollLoop sends local clipboard changes until stop is closed
+func (c *ClipboardSync) pollLoop(stop, done chan struct{}, interval time.Duration) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastImageCheck time.Time
+	readFailed := false
+
+	for {
+		select {
+		case <-stop:
+			log.Printf("🔚 Clipboard sync stopped")
+			return
+
+		case <-ticker.C:
+			c.mutex.Lock()
+			enabled, applying, applied := c.enabled, c.applying, c.applied
+			config, transport, onSent := c.config, c.transport, c.onSent
+			sessionID := c.sessionID
+			c.mutex.Unlock()
+
+			if !enabled || applying || transport == nil {
+				continue
+			}
+
+			checkImage := config.AllowImages && time.Since(lastImageCheck) >= clipboardImagePollInterval
+			if checkImage {
+				lastImageCheck = time.Now()
+			}
+
+			update, err := c.readLocal(checkImage)
+			if err != nil {
+				// Logged once: a clipboard owned by another app fails on every tick
+				if !readFailed {
+					log.Printf("⚠️ Failed to read local clipboard: %v", err)
+					readFailed = true
+				}
+				continue
+			}
+			readFailed = false
+			if update == nil && config.AllowImages && !checkImage {
+				// Only text was read: an image may still be on the clipboard
+				continue
+			}
+
+			// An empty clipboard is a baseline too, so the first copy after it is sent
+			var content []byte
+			hash := ""
+			if update != nil {
+				content = clipboardContent(*update)
+				hash = clipboardHash(update.Format, content)
+			}
+
+			c.mutex.Lock()
+			if c.stop != stop || !c.enabled || c.applying || c.applied != applied {
+				// A remote update landed while reading; the next tick sees its result
+				c.mutex.Unlock()
+				continue
+			}
+			// The first read after enabling is the baseline and is never sent
+			changed := c.primed && hash != c.lastHash
+			c.primed = true
+			c.lastHash = hash
+			c.mutex.Unlock()
+
+			if !changed || update == nil {
+				continue
+			}
+
+			if limit := config.limitFor(update.Format); update.SizeBytes > limit {
+				log.Printf("⚠️ Local clipboard (%s, %d bytes) exceeds the %d byte limit, not sent",
+					update.Format, update.SizeBytes, limit)
+				continue
+			}
+
+			update.SessionID = sessionID
+			update.Hash = contentHash(content)
+			if err := transport.SendClipboardUpdate(*update); err != nil {
+				log.Printf("❌ Failed to send clipboard update: %v", err)
+				continue
+			}
+
+			log.Printf("📋 Sent local clipboard (%s, %d bytes)", update.Format, update.SizeBytes)
+			if onSent != nil {
+				onSent(*update)
+			}
+		}
+	}
+}
+
+// readLocal returns the current clipboard content, preferring text. It returns
+// nil when the clipboard is empty (or only holds an image and checkImage is false).
+func (c *ClipboardSync) readLocal(checkImage bool) (*api.ClipboardUpdate, error) {
+	text, err := c.backend.ReadText()
+	if err != nil {
+		return nil, err
+	}
+	if text != "" {
+		return &api.ClipboardUpdate{
+			Format:    api.ClipboardFormatText,
+			Text:      text,
+			SizeBytes: len(text),
+		}, nil
+	}
+
+	if !checkImage {
+		return nil, nil
+	}
+
+	data, err := c.backend.ReadImage()
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+	return &api.ClipboardUpdate{
+		Format:    api.ClipboardFormatPNG,
+		Data:      data,
+		SizeBytes: len(data),
+	}, nil
+}
+
+// clipboardContent returns the raw bytes of an update
+func clipboardContent(update api.ClipboardUpdate) []byte {
+	if update.Format == api.ClipboardFormatPNG {
+		return update.Data
+	}
+	return []byte(update.Text)
+}
+
+// limitFor returns the size limit of a clipboard format
+func (c ClipboardConfig) limitFor(format string) int {
+	if format == api.ClipboardFormatPNG {
+		return c.MaxImageBytes
+	}
+	return c.MaxTextBytes
+}
+
+// clipboardHash identifies clipboard content for change detection
+func clipboardHash(format string, content []byte) string {
+	return format + ":" + contentHash(content)
+}
+
+// contentHash returns the hex SHA-256 of content
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
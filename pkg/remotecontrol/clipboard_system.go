@@ -0,0 +1,135 @@
+package remotecontrol
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/go-vgo/robotgo"
+)
+
+// systemClipboard uses robotgo for text and platform tools for PNG images:
+// PowerShell on Windows, osascript on macOS, wl-clipboard or xclip on Linux
+type systemClipboard struct{}
+
+// macPNGData matches the «data PNGf...» literal osascript prints for clipboard images
+var macPNGData = regexp.MustCompile(`«data PNGf([0-9A-Fa-f]*)»`)
+
+// ReadText returns the clipboard text, or "" when it holds none
+func (systemClipboard) ReadText() (string, error) {
+	return robotgo.ReadAll()
+}
+
+// WriteText replaces the clipboard with text
+func (systemClipboard) WriteText(text string) error {
+	return robotgo.WriteAll(text)
+}
+
+// ReadImage returns the clipboard image as PNG, or nil when it holds none
+func (systemClipboard) ReadImage() ([]byte, error) {
+	switch runtime.GOOS {
+	case "windows":
+		out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-STA", "-Command",
+			"Add-Type -AssemblyName System.Windows.Forms,System.Drawing; "+
+				"$img = [System.Windows.Forms.Clipboard]::GetImage(); "+
+				"if ($img) { $ms = New-Object System.IO.MemoryStream; "+
+				"$img.Save($ms, [System.Drawing.Imaging.ImageFormat]::Png); "+
+				"[Convert]::ToBase64String($ms.ToArray()) }").Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read clipboard image: %w", err)
+		}
+		encoded := strings.TrimSpace(string(out))
+		if encoded == "" {
+			return nil, nil
+		}
+		return base64.StdEncoding.DecodeString(encoded)
+
+	case "darwin":
+		// Fails when the clipboard holds no image
+		out, err := exec.Command("osascript", "-e", "the clipboard as «class PNGf»").Output()
+		if err != nil {
+			return nil, nil
+		}
+		match := macPNGData.FindSubmatch(out)
+		if match == nil {
+			return nil, nil
+		}
+		return hex.DecodeString(string(match[1]))
+
+	default:
+		var cmd *exec.Cmd
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			cmd = exec.Command("wl-paste", "--no-newline", "--type", "image/png")
+		} else {
+			cmd = exec.Command("xclip", "-selection", "clipboard", "-target", "image/png", "-out")
+		}
+		out, err := cmd.Output()
+		if err != nil {
+			if _, ok := err.(*exec.ExitError); ok {
+				// The clipboard holds no PNG
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to read clipboard image: %w", err)
+		}
+		return out, nil
+	}
+}
+
+// WriteImage replaces the clipboard with a PNG image
+func (systemClipboard) WriteImage(data []byte) error {
+	switch runtime.GOOS {
+	case "windows", "darwin":
+		// Both tools load the image from a file
+		tmpFile, err := os.CreateTemp("", "clipboard-*.png")
+		if err != nil {
+			return err
+		}
+		path := tmpFile.Name()
+		defer os.Remove(path)
+
+		if _, err := tmpFile.Write(data); err != nil {
+			tmpFile.Close()
+			return err
+		}
+		if err := tmpFile.Close(); err != nil {
+			return err
+		}
+
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-STA", "-Command",
+				"Add-Type -AssemblyName System.Windows.Forms,System.Drawing; "+
+					"$img = [System.Drawing.Image]::FromFile('"+strings.ReplaceAll(path, "'", "''")+"'); "+
+					"[System.Windows.Forms.Clipboard]::SetImage($img); $img.Dispose()")
+		} else {
+			cmd = exec.Command("osascript", "-e",
+				fmt.Sprintf("set the clipboard to (read (POSIX file %q) as «class PNGf»)", filepath.ToSlash(path)))
+		}
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to write clipboard image: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+
+	default:
+		var cmd *exec.Cmd
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			cmd = exec.Command("wl-copy", "--type", "image/png")
+		} else {
+			cmd = exec.Command("xclip", "-selection", "clipboard", "-target", "image/png", "-in")
+		}
+		// Output is not captured: both tools leave a child serving the clipboard
+		// that would keep the pipes open
+		cmd.Stdin = bytes.NewReader(data)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to write clipboard image: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,232 @@
+package remotecontrol
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// blockingClipboard holds the first write until release is closed
+type blockingClipboard struct {
+	mutex   sync.Mutex
+	text    string
+	writes  []string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingClipboard) ReadText() (string, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.text, nil
+}
+
+func (b *blockingClipboard) WriteText(text string) error {
+	b.mutex.Lock()
+	first := len(b.writes) == 0
+	b.writes = append(b.writes, text)
+	b.mutex.Unlock()
+
+	if first {
+		close(b.started)
+		<-b.release
+	}
+
+	b.mutex.Lock()
+	b.text = text
+	b.mutex.Unlock()
+	return nil
+}
+
+func (b *blockingClipboard) ReadImage() ([]byte, error)   { return nil, nil }
+func (b *blockingClipboard) WriteImage(data []byte) error { return nil }
+
+func TestClipboardApplyRemoteKeepsLatestUpdate(t *testing.T) {
+	backend := &blockingClipboard{started: make(chan struct{}), release: make(chan struct{})}
+	config := DefaultClipboardConfig()
+	config.PollIntervalMs = 60 * 1000 // Keep the poll loop out of the way
+	clipboard := NewClipboardSync(config)
+	clipboard.backend = backend
+	clipboard.Start("session-1", true)
+	defer clipboard.Stop()
+
+	update := func(text string) api.ClipboardUpdate {
+		return api.ClipboardUpdate{SessionID: "session-1", Format: api.ClipboardFormatText, Text: text}
+	}
+
+	firstDone := make(chan error, 1)
+	go func() { firstDone <- clipboard.ApplyRemote(update("first")) }()
+
+	select {
+	case <-backend.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("first update was not written")
+	}
+
+	// Both arrive while the first is being written; only the latest must survive
+	for _, text := range []string{"second", "third"} {
+		if err := clipboard.ApplyRemote(update(text)); err != nil {
+			t.Fatalf("ApplyRemote(%q): %v", text, err)
+		}
+	}
+	close(backend.release)
+
+	if err := <-firstDone; err != nil {
+		t.Fatalf("ApplyRemote(first): %v", err)
+	}
+
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+	if backend.text != "third" {
+		t.Errorf("clipboard = %q, want %q (writes: %q)", backend.text, "third", backend.writes)
+	}
+	if len(backend.writes) != 2 {
+		t.Errorf("writes = %q, want the first and the latest only", backend.writes)
+	}
+}
+
+// fakeClipboard is an in-memory clipboard; like Windows it hands text back
+// with CRLF line endings
+type fakeClipboard struct {
+	mutex sync.Mutex
+	text  string
+}
+
+func (f *fakeClipboard) ReadText() (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.text, nil
+}
+
+func (f *fakeClipboard) WriteText(text string) error {
+	f.set(strings.ReplaceAll(text, "\n", "\r\n"))
+	return nil
+}
+
+func (f *fakeClipboard) set(text string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.text = text
+}
+
+func (f *fakeClipboard) ReadImage() ([]byte, error)   { return nil, nil }
+func (f *fakeClipboard) WriteImage(data []byte) error { return nil }
+
+// fakeClipboardTransport records the updates sent to the server
+type fakeClipboardTransport struct {
+	sent chan api.ClipboardUpdate
+}
+
+func (f *fakeClipboardTransport) SendClipboardUpdate(update api.ClipboardUpdate) error {
+	f.sent <- update
+	return nil
+}
+
+// newTestClipboardSync starts a fast-polling sync over a fake clipboard holding initial
+func newTestClipboardSync(t *testing.T, initial string, modify func(config *ClipboardConfig)) (*ClipboardSync, *fakeClipboard, *fakeClipboardTransport) {
+	t.Helper()
+
+	backend := &fakeClipboard{text: initial}
+	transport := &fakeClipboardTransport{sent: make(chan api.ClipboardUpdate, 16)}
+
+	config := DefaultClipboardConfig()
+	config.PollIntervalMs = 5
+	if modify != nil {
+		modify(&config)
+	}
+	clipboard := NewClipboardSync(config)
+	clipboard.backend = backend
+	clipboard.SetTransport(transport)
+	clipboard.Start("session-1", true)
+	t.Cleanup(clipboard.Stop)
+
+	waitClipboardBaseline(t, clipboard)
+	return clipboard, backend, transport
+}
+
+// waitClipboardBaseline waits until the poll loop has read the clipboard as it is now
+func waitClipboardBaseline(t *testing.T, clipboard *ClipboardSync) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		clipboard.mutex.Lock()
+		primed := clipboard.primed
+		clipboard.mutex.Unlock()
+		if primed {
+			// A few more ticks so any change already on the clipboard is seen
+			time.Sleep(50 * time.Millisecond)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("the clipboard baseline was not read")
+}
+
+// expectNextSent copies text locally and checks it is the next update sent, so
+// nothing else was sent before it
+func expectNextSent(t *testing.T, backend *fakeClipboard, transport *fakeClipboardTransport, text string) {
+	t.Helper()
+	backend.set(text)
+
+	select {
+	case update := <-transport.sent:
+		if update.Text != text || update.SessionID != "session-1" || update.Hash != contentHash([]byte(text)) {
+			t.Errorf("sent %+v, want %q", update, text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%q was not sent", text)
+	}
+}
+
+func TestClipboardDoesNotEchoRemoteUpdates(t *testing.T) {
+	clipboard, backend, transport := newTestClipboardSync(t, "", nil)
+
+	for _, text := range []string{"from the admin", "line one\nline two"} {
+		if err := clipboard.ApplyRemote(api.ClipboardUpdate{SessionID: "session-1", Format: api.ClipboardFormatText, Text: text}); err != nil {
+			t.Fatalf("ApplyRemote(%q): %v", text, err)
+		}
+	}
+	if got, _ := backend.ReadText(); got != "line one\r\nline two" {
+		t.Fatalf("clipboard = %q, want the CRLF form", got)
+	}
+
+	// Let the poll loop read the applied content several times
+	time.Sleep(100 * time.Millisecond)
+	expectNextSent(t, backend, transport, "copied locally")
+}
+
+func TestClipboardSkipsContentPresentWhenEnabled(t *testing.T) {
+	clipboard, backend, transport := newTestClipboardSync(t, "copied before the session", nil)
+
+	// Content copied while sync is off stays local, also after turning it back on
+	if err := clipboard.SetEnabled(false); err != nil {
+		t.Fatal(err)
+	}
+	backend.set("copied while sync was off")
+	time.Sleep(50 * time.Millisecond)
+	if err := clipboard.SetEnabled(true); err != nil {
+		t.Fatal(err)
+	}
+	waitClipboardBaseline(t, clipboard)
+
+	expectNextSent(t, backend, transport, "copied after enabling")
+}
+
+func TestClipboardSkipsOversizedContent(t *testing.T) {
+	_, backend, transport := newTestClipboardSync(t, "", func(config *ClipboardConfig) {
+		config.MaxTextBytes = 16
+	})
+
+	backend.set(strings.Repeat("x", 17))
+	time.Sleep(50 * time.Millisecond)
+
+	expectNextSent(t, backend, transport, "fits the limit")
+}
+
+func TestClipboardSendsFirstCopyAfterEmptyClipboard(t *testing.T) {
+	_, backend, transport := newTestClipboardSync(t, "", nil)
+	expectNextSent(t, backend, transport, "first copy")
+}
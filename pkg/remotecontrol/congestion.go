@@ -0,0 +1,257 @@
+package remotecontrol
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// CongestionConfig defines the bounds and thresholds of the adaptive stream controller
+type CongestionConfig struct {
+	Enabled        bool
+	MinFPS         int
+	MaxFPS         int
+	MinQuality     int
+	MaxQuality     int
+	TargetLatency  time.Duration // Write latency considered healthy
+	MaxDropRate    float64       // Fraction of dropped frames considered congested
+	AdjustInterval time.Duration // Minimum time between adjustments
+}
+
+// validate checks that the bounds can drive the capture ticker and the JPEG encoder
+func (c CongestionConfig) validate() error {
+	if c.MaxFPS < 1 {
+		return fmt.Errorf("max_fps must be at least 1")
+	}
+	if c.MinFPS < 1 || c.MinFPS > c.MaxFPS {
+		return fmt.Errorf("min_fps must be between 1 and max_fps")
+	}
+	if c.MaxQuality < 1 || c.MaxQuality > 100 {
+		return fmt.Errorf("max_quality must be between 1 and 100")
+	}
+	if c.MinQuality < 1 || c.MinQuality > c.MaxQuality {
+		return fmt.Errorf("min_quality must be between 1 and max_quality")
+	}
+	return nil
+}
+
+// DefaultCongestionConfig returns the default adaptive streaming settings
+func DefaultCongestionConfig() CongestionConfig {
+	return CongestionConfig{
+		Enabled:        true,
+		MinFPS:         2,
+		MaxFPS:         15,
+		MinQuality:     30,
+		MaxQuality:     75,
+		TargetLatency:  100 * time.Millisecond,
+		MaxDropRate:    0.05,
+		AdjustInterval: 1 * time.Second,
+	}
+}
+
+// CongestionStats is a snapshot of the controller measurements
+type CongestionStats struct {
+	FPS             int
+	Quality         int
+	AvgWriteLatency time.Duration
+	MaxQueueDepth   int
+	DropRate        float64
+}
+
+// CongestionSettingsHandler is called when the controller changes FPS or quality
+type CongestionSettingsHandler func(fps, quality int)
+
+// CongestionController adapts frame rate and JPEG quality to the measured
+// write latency, queue depth and drop rate (AIMD: back off multiplicatively
+// under congestion, recover additively when the link is clear)
+type CongestionController struct {
+	mutex   sync.Mutex
+	config  CongestionConfig
+	fps     int
+	quality int
+
+	// Measurements for the current window
+	latencySum    time.Duration
+	writes        int
+	drops         int
+	maxQueueDepth int
+	windowStart   time.Time
+
+	lastStats CongestionStats
+	onChange  CongestionSettingsHandler
+}
+
+// NewCongestionController creates a controller starting at the upper bounds
+func NewCongestionController(config CongestionConfig) *CongestionController {
+	return &CongestionController{
+		config:      config,
+		fps:         config.MaxFPS,
+		quality:     config.MaxQuality,
+		windowStart: time.Now(),
+	}
+}
+
+// SetSettingsHandler sets the callback for FPS/quality changes
+func (c *CongestionController) SetSettingsHandler(handler CongestionSettingsHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.onChange = handler
+}
+
+// SetConfig replaces the controller bounds after validating them, clamping the current settings
+func (c *CongestionController) SetConfig(config CongestionConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	c.config = config
+	c.fps = clampInt(c.fps, config.MinFPS, config.MaxFPS)
+	c.quality = clampInt(c.quality, config.MinQuality, config.MaxQuality)
+	if !config.Enabled {
+		// Without adaptation the upper bounds are the fixed settings
+		c.fps = config.MaxFPS
+		c.quality = config.MaxQuality
+	}
+	fps, quality, handler := c.fps, c.quality, c.onChange
+	c.mutex.Unlock()
+
+	if handler != nil {
+		handler(fps, quality)
+	}
+	return nil
+}
+
+// Config returns the current controller configuration
+func (c *CongestionController) Config() CongestionConfig {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.config
+}
+
+// Reset restarts the measurements and returns to the upper bounds
+func (c *CongestionController) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.fps = c.config.MaxFPS
+	c.quality = c.config.MaxQuality
+	c.resetWindow()
+	c.lastStats = CongestionStats{}
+}
+
+// RecordWrite registers a completed frame write and its latency
+func (c *CongestionController) RecordWrite(latency time.Duration) {
+	c.mutex.Lock()
+	c.latencySum += latency
+	c.writes++
+	c.mutex.Unlock()
+
+	c.maybeAdjust()
+}
+
+// RecordDrop registers a frame that was discarded before being written
+func (c *CongestionController) RecordDrop() {
+	c.mutex.Lock()
+	c.drops++
+	c.mutex.Unlock()
+
+	c.maybeAdjust()
+}
+
+// RecordQueueDepth registers the send queue depth observed when enqueuing
+func (c *CongestionController) RecordQueueDepth(depth int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if depth > c.maxQueueDepth {
+		c.maxQueueDepth = depth
+	}
+}
+
+// Stats returns the measurements of the last completed window
+func (c *CongestionController) Stats() CongestionStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stats := c.lastStats
+	stats.FPS = c.fps
+	stats.Quality = c.quality
+	return stats
+}
+
+// maybeAdjust closes the measurement window and adapts settings if due
+func (c *CongestionController) maybeAdjust() {
+	c.mutex.Lock()
+
+	if !c.config.Enabled || time.Since(c.windowStart) < c.config.AdjustInterval {
+		c.mutex.Unlock()
+		return
+	}
+
+	stats := CongestionStats{MaxQueueDepth: c.maxQueueDepth}
+	if c.writes > 0 {
+		stats.AvgWriteLatency = c.latencySum / time.Duration(c.writes)
+	}
+	if total := c.writes + c.drops; total > 0 {
+		stats.DropRate = float64(c.drops) / float64(total)
+	}
+	c.lastStats = stats
+	c.resetWindow()
+
+	oldFPS, oldQuality := c.fps, c.quality
+
+	congested := stats.DropRate > c.config.MaxDropRate || stats.AvgWriteLatency > c.config.TargetLatency
+	clear := stats.DropRate == 0 && stats.AvgWriteLatency < c.config.TargetLatency/2 && stats.MaxQueueDepth <= 1
+
+	switch {
+	case congested:
+		// Sacrifice image quality first, then frame rate
+		if c.quality > c.config.MinQuality {
+			c.quality = clampInt(c.quality*3/4, c.config.MinQuality, c.config.MaxQuality)
+		} else {
+			c.fps = clampInt(c.fps*3/4, c.config.MinFPS, c.config.MaxFPS)
+		}
+	case clear:
+		// Recover responsiveness first, then image quality
+		if c.fps < c.config.MaxFPS {
+			c.fps = clampInt(c.fps+1, c.config.MinFPS, c.config.MaxFPS)
+		} else {
+			c.quality = clampInt(c.quality+5, c.config.MinQuality, c.config.MaxQuality)
+		}
+	}
+
+	fps, quality, handler := c.fps, c.quality, c.onChange
+	c.mutex.Unlock()
+
+	if fps == oldFPS && quality == oldQuality {
+		return
+	}
+
+	log.Printf("📶 Stream adapted: FPS %d→%d, quality %d→%d (latency %v, drops %.0f%%, queue %d)",
+		oldFPS, fps, oldQuality, quality, stats.AvgWriteLatency, stats.DropRate*100, stats.MaxQueueDepth)
+
+	if handler != nil {
+		handler(fps, quality)
+	}
+}
+
+// resetWindow starts a new measurement window (requires c.mutex)
+func (c *CongestionController) resetWindow() {
+	c.latencySum = 0
+	c.writes = 0
+	c.drops = 0
+	c.maxQueueDepth = 0
+	c.windowStart = time.Now()
+}
+
+func clampInt(value, min, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
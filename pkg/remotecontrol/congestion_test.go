@@ -0,0 +1,198 @@
+package remotecontrol
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCongestionControllerRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(config *CongestionConfig)
+	}{
+		{"zero max fps", func(c *CongestionConfig) { c.MaxFPS = 0; c.MinFPS = 0 }},
+		{"min fps above max", func(c *CongestionConfig) { c.MinFPS = c.MaxFPS + 1 }},
+		{"zero min quality", func(c *CongestionConfig) { c.MinQuality = 0 }},
+		{"negative max quality", func(c *CongestionConfig) { c.MaxQuality = -1 }},
+		{"max quality above 100", func(c *CongestionConfig) { c.MaxQuality = 101 }},
+		{"min quality above max", func(c *CongestionConfig) { c.MinQuality = c.MaxQuality + 1 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := NewCongestionController(DefaultCongestionConfig())
+			config := DefaultCongestionConfig()
+			tt.modify(&config)
+
+			if err := controller.SetConfig(config); err == nil {
+				t.Fatalf("config %+v was accepted", config)
+			}
+			if got := controller.Config(); got != DefaultCongestionConfig() {
+				t.Errorf("rejected config replaced the current one: %+v", got)
+			}
+		})
+	}
+}
+
+// testCongestionController creates a controller that adjusts on every sample
+func testCongestionController() (*CongestionController, *[][2]int) {
+	config := DefaultCongestionConfig()
+	config.AdjustInterval = 0
+
+	controller := NewCongestionController(config)
+	changes := &[][2]int{}
+	controller.SetSettingsHandler(func(fps, quality int) {
+		*changes = append(*changes, [2]int{fps, quality})
+	})
+	return controller, changes
+}
+
+func TestCongestionControllerBacksOffQualityThenFPS(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample func(c *CongestionController)
+	}{
+		{name: "slow writes", sample: func(c *CongestionController) { c.RecordWrite(300 * time.Millisecond) }},
+		{name: "dropped frames", sample: func(c *CongestionController) { c.RecordDrop() }},
+	}
+
+	// Quality falls by a quarter down to its minimum before FPS is touched
+	want := [][2]int{
+		{15, 56}, {15, 42}, {15, 31}, {15, 30},
+		{11, 30}, {8, 30}, {6, 30}, {4, 30}, {3, 30}, {2, 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller, changes := testCongestionController()
+
+			for i := 0; i < len(want)+3; i++ {
+				tt.sample(controller)
+			}
+
+			if !reflect.DeepEqual(*changes, want) {
+				t.Errorf("settings = %v, want %v", *changes, want)
+			}
+			if stats := controller.Stats(); stats.FPS != 2 || stats.Quality != 30 {
+				t.Errorf("stats = %+v, want the lower bounds", stats)
+			}
+		})
+	}
+}
+
+func TestCongestionControllerRecoversFPSThenQuality(t *testing.T) {
+	controller, changes := testCongestionController()
+
+	// Down to the lower bounds first
+	for i := 0; i < 20; i++ {
+		controller.RecordDrop()
+	}
+	*changes = nil
+
+	for i := 0; i < 40; i++ {
+		controller.RecordQueueDepth(1)
+		controller.RecordWrite(time.Millisecond)
+	}
+
+	if len(*changes) == 0 {
+		t.Fatal("a clear link did not recover")
+	}
+	// FPS goes back to the maximum one step at a time before quality moves
+	for i, change := range *changes {
+		fps, quality := change[0], change[1]
+		if i < 13 {
+			if fps != 3+i || quality != 30 {
+				t.Errorf("step %d = %v, want FPS %d at quality 30", i, change, 3+i)
+			}
+		} else if fps != 15 {
+			t.Errorf("step %d = %v, quality rose before FPS reached 15", i, change)
+		}
+	}
+	if stats := controller.Stats(); stats.FPS != 15 || stats.Quality != 75 {
+		t.Errorf("stats = %+v, want the upper bounds", stats)
+	}
+}
+
+func TestCongestionControllerHoldsOnAmbiguousSamples(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample func(c *CongestionController)
+	}{
+		{name: "queue building up", sample: func(c *CongestionController) {
+			c.RecordQueueDepth(2)
+			c.RecordWrite(time.Millisecond)
+		}},
+		{name: "latency near the target", sample: func(c *CongestionController) {
+			c.RecordWrite(80 * time.Millisecond)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller, changes := testCongestionController()
+			controller.RecordDrop()
+			*changes = nil
+
+			for i := 0; i < 10; i++ {
+				tt.sample(controller)
+			}
+
+			if len(*changes) != 0 {
+				t.Errorf("settings changed to %v, want them held", *changes)
+			}
+		})
+	}
+}
+
+func TestCongestionControllerReportsWindowStats(t *testing.T) {
+	config := DefaultCongestionConfig()
+	config.AdjustInterval = time.Hour
+	controller := NewCongestionController(config)
+
+	controller.RecordQueueDepth(3)
+	controller.RecordWrite(10 * time.Millisecond)
+	controller.RecordWrite(30 * time.Millisecond)
+	controller.RecordDrop()
+	controller.RecordDrop()
+
+	// The window is still open: nothing is reported or changed yet
+	if stats := controller.Stats(); stats != (CongestionStats{FPS: 15, Quality: 75}) {
+		t.Fatalf("stats before the window closes = %+v", stats)
+	}
+
+	controller.mutex.Lock()
+	controller.windowStart = time.Now().Add(-2 * time.Hour)
+	controller.mutex.Unlock()
+	controller.maybeAdjust()
+
+	want := CongestionStats{
+		FPS:             15,
+		Quality:         56,
+		AvgWriteLatency: 20 * time.Millisecond,
+		MaxQueueDepth:   3,
+		DropRate:        0.5,
+	}
+	if stats := controller.Stats(); stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestCongestionControllerDisabled(t *testing.T) {
+	controller, changes := testCongestionController()
+	config := controller.Config()
+	config.Enabled = false
+	if err := controller.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	*changes = nil
+
+	for i := 0; i < 10; i++ {
+		controller.RecordDrop()
+		controller.RecordWrite(time.Second)
+	}
+
+	if len(*changes) != 0 {
+		t.Errorf("disabled controller changed settings: %v", *changes)
+	}
+}
@@ -0,0 +1,335 @@
+package remotecontrol
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// ConsentAction is the outcome of evaluating a remote control request
+type ConsentAction string
+
+const (
+	ConsentAccept ConsentAction = "accept" // Accept without asking the user
+	ConsentReject ConsentAction = "reject" // Reject without asking the user
+	ConsentPrompt ConsentAction = "prompt" // Ask the user, with a timeout
+)
+
+// ViewOnlyMode decides which sessions start without input control
+type ViewOnlyMode string
+
+const (
+	ViewOnlyNever     ViewOnlyMode = "never"     // Full control unless the user picks view-only
+	ViewOnlyUntrusted ViewOnlyMode = "untrusted" // View-only by default for non-trusted admins
+	ViewOnlyAlways    ViewOnlyMode = "always"    // Every session is view-only; the user cannot grant control
+)
+
+// ConsentDecision is the policy verdict for one request
+type ConsentDecision struct {
+	Action         ConsentAction
+	Reason         string
+	PromptTimeout  time.Duration // Only for ConsentPrompt
+	TimeoutAction  ConsentAction // Applied when the prompt times out
+	ViewOnly       bool          // Default mode for the session
+	ViewOnlyLocked bool          // The user cannot grant input control
+	Trusted        bool          // The admin is in the trusted lists
+}
+
+// BusinessHours restricts when remote control may be requested
+type BusinessHours struct {
+	Days     []string `json:"days"`               // "mon".."sun"; empty = every day
+	Start    string   `json:"start"`              // "HH:MM"
+	End      string   `json:"end"`                // "HH:MM", exclusive; before Start = ends the next day
+	Location string   `json:"location,omitempty"` // IANA zone; empty = local time
+}
+
+// ConsentPolicyConfig is the persisted consent policy
+type ConsentPolicyConfig struct {
+	TrustedAdminIDs       []string       `json:"trusted_admin_ids"`
+	TrustedAdminUsernames []string       `json:"trusted_admin_usernames"`
+	AutoAcceptTrusted     bool           `json:"auto_accept_trusted"`
+	BusinessHours         *BusinessHours `json:"business_hours,omitempty"` // nil = any time
+	TrustedBypassHours    bool           `json:"trusted_bypass_hours"`     // Trusted admins ignore business hours
+	PromptTimeoutSeconds  int            `json:"prompt_timeout_seconds"`
+	TimeoutAction         ConsentAction  `json:"timeout_action"` // "reject" (default) or "accept"
+	ViewOnly              ViewOnlyMode   `json:"view_only"`      // "never" (default), "untrusted" or "always"
+}
+
+// DefaultConsentPolicyConfig always prompts and rejects after 30 seconds
+func DefaultConsentPolicyConfig() ConsentPolicyConfig {
+	return ConsentPolicyConfig{
+		AutoAcceptTrusted:    true,
+		PromptTimeoutSeconds: 30,
+		TimeoutAction:        ConsentReject,
+	}
+}
+
+// ConsentPolicy evaluates incoming remote control requests before the user sees them
+type ConsentPolicy struct {
+	config ConsentPolicyConfig
+	mutex  sync.RWMutex
+}
+
+// NewConsentPolicy creates a policy from a configuration
+func NewConsentPolicy(config ConsentPolicyConfig) (*ConsentPolicy, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return &ConsentPolicy{config: config}, nil
+}
+
+// LoadConsentPolicy reads the policy from a JSON file; a missing file yields the default policy
+func LoadConsentPolicy(path string) (*ConsentPolicy, error) {
+	config := DefaultConsentPolicyConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewConsentPolicy(config)
+		}
+		return nil, fmt.Errorf("failed to read consent policy: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid consent policy %s: %w", path, err)
+	}
+
+	return NewConsentPolicy(config)
+}
+
+// Config returns a copy of the current configuration
+func (p *ConsentPolicy) Config() ConsentPolicyConfig {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.config
+}
+
+// SetConfig replaces the configuration after validating it
+func (p *ConsentPolicy) SetConfig(config ConsentPolicyConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.config = config
+	return nil
+}
+
+// Evaluate decides what to do with a request at the given time
+func (p *ConsentPolicy) Evaluate(request api.RemoteControlRequest, now time.Time) ConsentDecision {
+	p.mutex.RLock()
+	config := p.config
+	p.mutex.RUnlock()
+
+	trusted := config.isTrusted(request)
+
+	if config.BusinessHours != nil && !(trusted && config.TrustedBypassHours) {
+		if !config.BusinessHours.contains(now) {
+			return ConsentDecision{
+				Action:  ConsentReject,
+				Reason:  "Fuera del horario laboral",
+				Trusted: trusted,
+			}
+		}
+	}
+
+	viewOnly := config.ViewOnly == ViewOnlyAlways || (config.ViewOnly == ViewOnlyUntrusted && !trusted)
+	viewOnlyLocked := config.ViewOnly == ViewOnlyAlways
+
+	if trusted && config.AutoAcceptTrusted {
+		return ConsentDecision{
+			Action:         ConsentAccept,
+			Reason:         "Administrador de confianza",
+			ViewOnly:       viewOnly,
+			ViewOnlyLocked: viewOnlyLocked,
+			Trusted:        true,
+		}
+	}
+
+	timeoutAction := config.TimeoutAction
+	if timeoutAction == "" {
+		timeoutAction = ConsentReject
+	}
+
+	timeoutSeconds := config.PromptTimeoutSeconds
+	if timeoutSeconds == 0 {
+		timeoutSeconds = DefaultConsentPolicyConfig().PromptTimeoutSeconds
+	}
+
+	return ConsentDecision{
+		Action:         ConsentPrompt,
+		PromptTimeout:  time.Duration(timeoutSeconds) * time.Second,
+		TimeoutAction:  timeoutAction,
+		ViewOnly:       viewOnly,
+		ViewOnlyLocked: viewOnlyLocked,
+		Trusted:        trusted,
+	}
+}
+
+// isTrusted checks the request admin against the trusted lists
+func (c ConsentPolicyConfig) isTrusted(request api.RemoteControlRequest) bool {
+	for _, id := range c.TrustedAdminIDs {
+		if id != "" && id == request.AdminUserID {
+			return true
+		}
+	}
+	for _, username := range c.TrustedAdminUsernames {
+		if username != "" && strings.EqualFold(username, request.AdminUsername) {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the configuration for values that cannot be evaluated
+func (c ConsentPolicyConfig) validate() error {
+	if c.PromptTimeoutSeconds < 0 {
+		return fmt.Errorf("prompt_timeout_seconds cannot be negative")
+	}
+
+	switch c.TimeoutAction {
+	case "", ConsentReject, ConsentAccept:
+	default:
+		return fmt.Errorf("invalid timeout_action: %s", c.TimeoutAction)
+	}
+
+	switch c.ViewOnly {
+	case "", ViewOnlyNever, ViewOnlyUntrusted, ViewOnlyAlways:
+	default:
+		return fmt.Errorf("invalid view_only: %s", c.ViewOnly)
+	}
+
+	if c.BusinessHours != nil {
+		if _, err := c.BusinessHours.window(time.Now(), 0); err != nil {
+			return err
+		}
+		for _, day := range c.BusinessHours.Days {
+			if _, ok := weekdays[strings.ToLower(day)]; !ok {
+				return fmt.Errorf("invalid business day: %s", day)
+			}
+		}
+	}
+
+	return nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// contains checks whether now falls inside business hours. An overnight window
+// belongs to the day it starts on, so the one that began yesterday is checked too.
+func (b *BusinessHours) contains(now time.Time) bool {
+	for _, dayOffset := range []int{0, -1} {
+		window, err := b.window(now, dayOffset)
+		if err != nil {
+			return false
+		}
+		if !b.isBusinessDay(window[0].Weekday()) {
+			continue
+		}
+		if !now.Before(window[0]) && now.Before(window[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBusinessDay checks whether a window may start on day
+func (b *BusinessHours) isBusinessDay(day time.Weekday) bool {
+	if len(b.Days) == 0 {
+		return true
+	}
+	for _, name := range b.Days {
+		if weekdays[strings.ToLower(name)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// window parses Start/End into the window starting dayOffset days from now, in the
+// configured zone. An End before Start wraps past midnight into the following day.
+func (b *BusinessHours) window(now time.Time, dayOffset int) ([2]time.Time, error) {
+	loc := time.Local
+	if b.Location != "" {
+		var err error
+		if loc, err = time.LoadLocation(b.Location); err != nil {
+			return [2]time.Time{}, fmt.Errorf("invalid business hours location: %w", err)
+		}
+	}
+	now = now.In(loc)
+
+	var window [2]time.Time
+	for i, value := range []string{b.Start, b.End} {
+		parsed, err := time.Parse("15:04", value)
+		if err != nil {
+			return window, fmt.Errorf("invalid business hours time %q: %w", value, err)
+		}
+		window[i] = time.Date(now.Year(), now.Month(), now.Day()+dayOffset, parsed.Hour(), parsed.Minute(), 0, 0, loc)
+	}
+
+	if window[0].Equal(window[1]) {
+		return window, fmt.Errorf("business hours start %s must differ from end %s", b.Start, b.End)
+	}
+	if window[1].Before(window[0]) {
+		// Overnight shift, e.g. 22:00-06:00
+		window[1] = time.Date(window[1].Year(), window[1].Month(), window[1].Day()+1,
+			window[1].Hour(), window[1].Minute(), 0, 0, loc)
+	}
+	return window, nil
+}
+
+// ConsentPrompts tracks requests waiting for the user and applies the
+// timeout action when nobody answers in time
+type ConsentPrompts struct {
+	pending map[string]*time.Timer
+	mutex   sync.Mutex
+}
+
+// NewConsentPrompts creates an empty prompt tracker
+func NewConsentPrompts() *ConsentPrompts {
+	return &ConsentPrompts{
+		pending: make(map[string]*time.Timer),
+	}
+}
+
+// Begin starts waiting for a decision on sessionID; onTimeout runs if
+// Resolve is not called within timeout
+func (cp *ConsentPrompts) Begin(sessionID string, timeout time.Duration, onTimeout func()) {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+
+	if timer, exists := cp.pending[sessionID]; exists {
+		timer.Stop()
+	}
+
+	cp.pending[sessionID] = time.AfterFunc(timeout, func() {
+		if cp.Resolve(sessionID) {
+			onTimeout()
+		}
+	})
+}
+
+// Resolve marks sessionID as decided. It returns false if the request was
+// not pending, e.g. because the timeout already fired.
+func (cp *ConsentPrompts) Resolve(sessionID string) bool {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+
+	timer, exists := cp.pending[sessionID]
+	if !exists {
+		return false
+	}
+
+	timer.Stop()
+	delete(cp.pending, sessionID)
+	return true
+}
@@ -0,0 +1,91 @@
+package remotecontrol
+
+import (
+	"testing"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+func TestConsentPolicyBusinessHours(t *testing.T) {
+	overnight := &BusinessHours{Start: "22:00", End: "06:00"}
+	fridayNights := &BusinessHours{Days: []string{"fri"}, Start: "22:00", End: "06:00"}
+	tokyoOffice := &BusinessHours{Start: "09:00", End: "17:00", Location: "Asia/Tokyo"}
+	office := &BusinessHours{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00"}
+
+	// 2024-01-01 is a Monday
+	local := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.January, day, hour, minute, 0, 0, time.Local)
+	}
+	// A zone that differs from both the local zone and Asia/Tokyo
+	bogota := time.FixedZone("UTC-5", -5*60*60)
+
+	tests := []struct {
+		name    string
+		hours   *BusinessHours
+		bypass  bool
+		trusted bool
+		now     time.Time
+		inside  bool
+	}{
+		{name: "overnight before midnight", hours: overnight, now: local(1, 23, 0), inside: true},
+		{name: "overnight after midnight", hours: overnight, now: local(2, 5, 59), inside: true},
+		{name: "overnight end is exclusive", hours: overnight, now: local(2, 6, 0), inside: false},
+		{name: "overnight start is inclusive", hours: overnight, now: local(1, 22, 0), inside: true},
+		{name: "overnight gap", hours: overnight, now: local(1, 12, 0), inside: false},
+
+		{name: "wrap starts on listed day", hours: fridayNights, now: local(5, 23, 0), inside: true},
+		{name: "wrap ends on unlisted day", hours: fridayNights, now: local(6, 5, 0), inside: true},
+		{name: "wrap starting on unlisted day", hours: fridayNights, now: local(6, 23, 0), inside: false},
+		{name: "morning after unlisted day", hours: fridayNights, now: local(5, 5, 0), inside: false},
+
+		// 10:00 and 18:00 in Tokyo (UTC+9)
+		{name: "location inside", hours: tokyoOffice, now: time.Date(2023, time.December, 31, 20, 0, 0, 0, bogota), inside: true},
+		{name: "location outside", hours: tokyoOffice, now: time.Date(2024, time.January, 1, 4, 0, 0, 0, bogota), inside: false},
+
+		{name: "trusted bypass", hours: office, bypass: true, trusted: true, now: local(6, 12, 0), inside: true},
+		{name: "trusted without bypass", hours: office, trusted: true, now: local(6, 12, 0), inside: false},
+		{name: "bypass is only for trusted", hours: office, bypass: true, now: local(6, 12, 0), inside: false},
+		{name: "bypass inside hours", hours: office, bypass: true, now: local(1, 12, 0), inside: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConsentPolicyConfig()
+			config.TrustedAdminIDs = []string{"admin-trusted"}
+			config.BusinessHours = tt.hours
+			config.TrustedBypassHours = tt.bypass
+
+			policy, err := NewConsentPolicy(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			request := api.RemoteControlRequest{AdminUserID: "admin-other"}
+			if tt.trusted {
+				request.AdminUserID = "admin-trusted"
+			}
+
+			decision := policy.Evaluate(request, tt.now)
+			if inside := decision.Action != ConsentReject; inside != tt.inside {
+				t.Errorf("at %s: action %s (%s), want inside=%v",
+					tt.now.Format("Mon 15:04 MST"), decision.Action, decision.Reason, tt.inside)
+			}
+		})
+	}
+}
+
+func TestConsentPolicyRejectsInvalidHours(t *testing.T) {
+	for _, hours := range []*BusinessHours{
+		{Start: "09:00", End: "09:00"},
+		{Start: "9am", End: "17:00"},
+		{Start: "09:00", End: "17:00", Days: []string{"monday"}},
+		{Start: "09:00", End: "17:00", Location: "Mars/Olympus"},
+	} {
+		config := DefaultConsentPolicyConfig()
+		config.BusinessHours = hours
+		if _, err := NewConsentPolicy(config); err == nil {
+			t.Errorf("business hours %+v were accepted", *hours)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package remotecontrol
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// FrameTransport sends screen frames to the server (implemented by api.APIClient)
+type FrameTransport interface {
+	SendScreenFrame(frame api.ScreenFrame) error
+}
+
+// FrameSender delivers frames from a single goroutine through a one-slot,
+// latest-frame-wins queue: a frame that has not been written yet is replaced
+// by a newer one instead of piling up behind a slow link
+type FrameSender struct {
+	transport  FrameTransport
+	controller *CongestionController
+
+	mutex     sync.Mutex
+	pending   *api.ScreenFrame
+	resyncing bool // A frame was lost; deltas are useless until the next keyframe
+	onResync  func()
+
+	notify chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewFrameSender creates a sender; onResync is called whenever a keyframe is needed
+func NewFrameSender(transport FrameTransport, controller *CongestionController, onResync func()) *FrameSender {
+	return &FrameSender{
+		transport:  transport,
+		controller: controller,
+		onResync:   onResync,
+		notify:     make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start launches the send loop
+func (s *FrameSender) Start() {
+	go s.sendLoop()
+}
+
+// Stop ends the send loop and discards any pending frame
+func (s *FrameSender) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// Enqueue queues a frame for sending, replacing any frame still waiting
+func (s *FrameSender) Enqueue(frame api.ScreenFrame) {
+	s.mutex.Lock()
+
+	depth := 0
+	if s.pending != nil {
+		depth = 1
+	}
+	s.controller.RecordQueueDepth(depth + 1)
+
+	if s.pending != nil {
+		// The link is behind: the older frame is superseded
+		s.pending = nil
+		s.controller.RecordDrop()
+		s.requestResync()
+	}
+
+	if s.resyncing && !frame.IsKeyframe {
+		// A delta on top of a lost frame would corrupt the viewer's image
+		s.mutex.Unlock()
+		s.controller.RecordDrop()
+		return
+	}
+	if frame.IsKeyframe {
+		s.resyncing = false
+	}
+
+	s.pending = &frame
+	s.mutex.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// sendLoop writes queued frames one at a time and reports latency
+func (s *FrameSender) sendLoop() {
+	defer close(s.done)
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.notify:
+		}
+
+		s.mutex.Lock()
+		frame := s.pending
+		s.pending = nil
+		s.mutex.Unlock()
+
+		if frame == nil {
+			continue
+		}
+
+		start := time.Now()
+		if err := s.transport.SendScreenFrame(*frame); err != nil {
+			log.Printf("❌ Error sending screen frame %d: %v", frame.SequenceNum, err)
+			s.controller.RecordDrop()
+
+			s.mutex.Lock()
+			s.requestResync()
+			s.mutex.Unlock()
+			continue
+		}
+		s.controller.RecordWrite(time.Since(start))
+	}
+}
+
+// requestResync asks the capture side for a keyframe (requires s.mutex)
+func (s *FrameSender) requestResync() {
+	s.resyncing = true
+	if s.onResync != nil {
+		s.onResync()
+	}
+}
@@ -0,0 +1,171 @@
+package remotecontrol
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// blockingTransport holds every write until release is closed, reporting each
+// write as it starts and as it completes
+type blockingTransport struct {
+	started chan int64
+	release chan struct{}
+	once    sync.Once
+	sent    chan int64
+}
+
+func newBlockingTransport() *blockingTransport {
+	return &blockingTransport{
+		started: make(chan int64, 16),
+		release: make(chan struct{}),
+		sent:    make(chan int64, 16),
+	}
+}
+
+// open lets every pending and future write complete
+func (t *blockingTransport) open() {
+	t.once.Do(func() { close(t.release) })
+}
+
+func (t *blockingTransport) SendScreenFrame(frame api.ScreenFrame) error {
+	t.started <- frame.SequenceNum
+	<-t.release
+	t.sent <- frame.SequenceNum
+	return nil
+}
+
+// startBlockedSender starts a sender whose first keyframe is stuck in the transport
+func startBlockedSender(t *testing.T) (*FrameSender, *blockingTransport, *atomic.Int32) {
+	t.Helper()
+
+	transport := newBlockingTransport()
+	resyncs := &atomic.Int32{}
+	sender := NewFrameSender(transport, NewCongestionController(DefaultCongestionConfig()), func() {
+		resyncs.Add(1)
+	})
+	sender.Start()
+	t.Cleanup(sender.Stop)
+	t.Cleanup(transport.open) // Runs first, so Stop never waits on a held write
+
+	sender.Enqueue(api.ScreenFrame{SequenceNum: 0, IsKeyframe: true})
+	select {
+	case <-transport.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("first frame was not written")
+	}
+	return sender, transport, resyncs
+}
+
+// collectSent releases the transport and returns the frames written after the first
+func collectSent(t *testing.T, transport *blockingTransport, want int) []int64 {
+	t.Helper()
+	transport.open()
+
+	var sent []int64
+	for len(sent) < want+1 {
+		select {
+		case seq := <-transport.sent:
+			sent = append(sent, seq)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("sent %v, want %d more frames", sent, want+1-len(sent))
+		}
+	}
+
+	select {
+	case seq := <-transport.sent:
+		t.Errorf("unexpected frame %d sent", seq)
+	case <-time.After(50 * time.Millisecond):
+	}
+	return sent[1:]
+}
+
+func TestFrameSenderLatestFrameWins(t *testing.T) {
+	sender, transport, _ := startBlockedSender(t)
+
+	// While the link is busy only the newest keyframe survives
+	for seq := int64(1); seq <= 3; seq++ {
+		sender.Enqueue(api.ScreenFrame{SequenceNum: seq, IsKeyframe: true})
+	}
+
+	if sent := collectSent(t, transport, 1); !reflect.DeepEqual(sent, []int64{3}) {
+		t.Errorf("sent %v, want [3]", sent)
+	}
+}
+
+func TestFrameSenderDropsDeltasUntilKeyframe(t *testing.T) {
+	tests := []struct {
+		name        string
+		frames      []api.ScreenFrame
+		want        []int64
+		wantResyncs bool
+	}{
+		{
+			name:   "one pending delta is kept",
+			frames: []api.ScreenFrame{{SequenceNum: 1}},
+			want:   []int64{1},
+		},
+		{
+			name:        "superseded delta drops the following deltas",
+			frames:      []api.ScreenFrame{{SequenceNum: 1}, {SequenceNum: 2}, {SequenceNum: 3}},
+			want:        []int64{},
+			wantResyncs: true,
+		},
+		{
+			name: "keyframe ends the resync",
+			frames: []api.ScreenFrame{
+				{SequenceNum: 1}, {SequenceNum: 2}, {SequenceNum: 3},
+				{SequenceNum: 4, IsKeyframe: true},
+			},
+			want:        []int64{4},
+			wantResyncs: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, transport, resyncs := startBlockedSender(t)
+
+			for _, frame := range tt.frames {
+				sender.Enqueue(frame)
+			}
+
+			if sent := collectSent(t, transport, len(tt.want)); !reflect.DeepEqual(sent, tt.want) {
+				t.Errorf("sent %v, want %v", sent, tt.want)
+			}
+			if got := resyncs.Load() > 0; got != tt.wantResyncs {
+				t.Errorf("keyframe requested = %v, want %v", got, tt.wantResyncs)
+			}
+		})
+	}
+}
+
+func TestFrameSenderDeltasFlowAfterResync(t *testing.T) {
+	sender, transport, _ := startBlockedSender(t)
+
+	sender.Enqueue(api.ScreenFrame{SequenceNum: 1})
+	sender.Enqueue(api.ScreenFrame{SequenceNum: 2})
+	sender.Enqueue(api.ScreenFrame{SequenceNum: 3, IsKeyframe: true})
+	transport.open()
+
+	for _, want := range []int64{0, 3} {
+		if seq := <-transport.sent; seq != want {
+			t.Fatalf("sent %d, want %d", seq, want)
+		}
+	}
+
+	// Once the keyframe is out, deltas are sent again
+	sender.Enqueue(api.ScreenFrame{SequenceNum: 4})
+	select {
+	case seq := <-transport.sent:
+		if seq != 4 {
+			t.Errorf("sent %d, want 4", seq)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("delta after the keyframe was not sent")
+	}
+}
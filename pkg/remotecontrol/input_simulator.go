@@ -3,24 +3,91 @@ package remotecontrol
 import (
 	"encoding/json"
 	"fmt"
+	"image"
 	"log"
 	"strings"
+	"sync"
+	"time"
 
 	"EscritorioRemoto-Cliente/pkg/api"
 
 	"github.com/go-vgo/robotgo"
 )
 
+// inputBackend injects input into the system; robotgo outside of tests
+type inputBackend interface {
+	MoveMouse(x, y int)
+	MousePos() (int, int)
+	MouseDown(button string) error
+	MouseUp(button string) error
+	Click(button string, double bool)
+	Scroll(deltaX, deltaY int) error
+	KeyDown(key string) error
+	KeyUp(key string) error
+	TypeStr(text string)
+	ScreenSize() (int, int)
+	ActiveWindowTitle() string
+}
+
+// robotgoInput is the inputBackend backed by robotgo
+type robotgoInput struct{}
+
+func (robotgoInput) MoveMouse(x, y int)               { robotgo.MoveMouse(x, y) }
+func (robotgoInput) MousePos() (int, int)             { return robotgo.GetMousePos() }
+func (robotgoInput) MouseDown(button string) error    { return robotgo.MouseDown(button) }
+func (robotgoInput) MouseUp(button string) error      { return robotgo.MouseUp(button) }
+func (robotgoInput) Click(button string, double bool) { robotgo.Click(button, double) }
+func (robotgoInput) Scroll(deltaX, deltaY int) error  { return scrollWheel(deltaX, deltaY) }
+func (robotgoInput) KeyDown(key string) error         { return robotgo.KeyDown(key) }
+func (robotgoInput) KeyUp(key string) error           { return robotgo.KeyUp(key) }
+func (robotgoInput) TypeStr(text string)              { robotgo.TypeStr(text) }
+func (robotgoInput) ScreenSize() (int, int)           { return robotgo.GetScreenSize() }
+func (robotgoInput) ActiveWindowTitle() string        { return robotgo.GetTitle() }
+
 // InputSimulator handles mouse and keyboard input simulation
 type InputSimulator struct {
 	// Configuration
 	enableSafety bool // Enable safety checks to prevent dangerous commands
+
+	// Where input is injected (robotgoInput except in tests)
+	input inputBackend
+
+	// Desktop rectangle of the streamed display; incoming coordinates are
+	// relative to its origin. Empty means the primary screen at (0, 0).
+	displayBounds image.Rectangle
+	boundsMutex   sync.RWMutex
+
+	// Mouse buttons pressed by a "down" and not yet released (robotgo names)
+	pressedButtons map[string]bool
+	buttonsMutex   sync.Mutex
+
+	// Keys and modifiers held by "keydown" and not yet released (robotgo
+	// names). Implied modifiers went down only because a payload listed them
+	// and follow the modifiers reported by the next keyboard event.
+	heldKeys          map[string]bool
+	impliedModifiers  map[string]bool
+	keyReleaseTimeout time.Duration // Held keys are released after this long without input
+	keyReleaseTimer   *time.Timer
+	lastInput         time.Time
+	keysMutex         sync.Mutex
 }
 
+// maxScrollDelta caps the scroll steps of a single command when safety is on
+const maxScrollDelta = 50
+
+// DefaultKeyReleaseTimeout is how long keys may stay held without any input
+// before they are considered stuck and released
+const DefaultKeyReleaseTimeout = 10 * time.Second
+
 // NewInputSimulator creates a new InputSimulator instance
 func NewInputSimulator() *InputSimulator {
 	return &InputSimulator{
-		enableSafety: true, // Enable safety by default
+		enableSafety:      true, // Enable safety by default
+		input:             robotgoInput{},
+		pressedButtons:    make(map[string]bool),
+		heldKeys:          make(map[string]bool),
+		impliedModifiers:  make(map[string]bool),
+		keyReleaseTimeout: DefaultKeyReleaseTimeout,
 	}
 }
 
@@ -34,13 +101,30 @@ func (is *InputSimulator) ProcessMouseCommand(command api.InputCommand) error {
 		return fmt.Errorf("invalid mouse payload: %w", err)
 	}
 
+	// Mouse input also keeps held keys alive (e.g. shift+click selections)
+	is.noteInput()
+
+	// Translate frame coordinates to desktop coordinates
+	x, y := is.toDesktop(payload.X, payload.Y)
+
 	switch command.Action {
 	case "move":
-		return is.moveMouse(payload.X, payload.Y)
+		return is.moveMouse(x, y)
+	case "down":
+		return is.mouseDown(x, y, payload.Button)
+	case "up":
+		return is.mouseUp(x, y, payload.Button)
 	case "click":
-		return is.clickMouse(payload.X, payload.Y, payload.Button)
+		return is.clickMouse(x, y, payload.Button)
+	case "dblclick":
+		return is.doubleClickMouse(x, y, payload.Button)
 	case "scroll":
-		return is.scrollMouse(payload.X, payload.Y, payload.Delta)
+		deltaX, deltaY := payload.DeltaX, payload.DeltaY
+		if deltaX == 0 && deltaY == 0 {
+			// Older viewers only send a vertical delta
+			deltaY = payload.Delta
+		}
+		return is.scrollMouse(x, y, deltaX, deltaY)
 	default:
 		return fmt.Errorf("unknown mouse action: %s", command.Action)
 	}
@@ -75,7 +159,7 @@ func (is *InputSimulator) moveMouse(x, y int) error {
 		return fmt.Errorf("invalid coordinates: (%d, %d)", x, y)
 	}
 
-	robotgo.MoveMouse(x, y)
+	is.input.MoveMouse(x, y)
 	log.Printf("🖱️ Mouse moved to (%d, %d)", x, y)
 	return nil
 }
@@ -86,19 +170,19 @@ func (is *InputSimulator) clickMouse(x, y int, button string) error {
 	}
 
 	// Log screen dimensions for debugging
-	width, height := robotgo.GetScreenSize()
+	width, height := is.input.ScreenSize()
 	log.Printf("🖥️ Screen dimensions: %dx%d", width, height)
 	log.Printf("🎯 Target coordinates: (%d, %d)", x, y)
 
 	// Check current mouse position before moving
-	currentX, currentY := robotgo.GetMousePos()
+	currentX, currentY := is.input.MousePos()
 	log.Printf("🔍 Current mouse position: (%d, %d)", currentX, currentY)
 
 	// Move to position first
-	robotgo.MoveMouse(x, y)
+	is.input.MoveMouse(x, y)
 
 	// Verify mouse moved to correct position
-	newX, newY := robotgo.GetMousePos()
+	newX, newY := is.input.MousePos()
 	log.Printf("✅ Mouse moved to: (%d, %d)", newX, newY)
 
 	// Add small delay for system to register the movement
@@ -108,9 +192,12 @@ func (is *InputSimulator) clickMouse(x, y int, button string) error {
 	robotgoButton := is.convertButtonToRobotgo(button)
 	log.Printf("🔘 Using button: %s (robotgo: %s)", button, robotgoButton)
 
+	// The click's own release also ends any drag left open with this button
+	is.forgetButton(robotgoButton)
+
 	// Use single click method to avoid crashes
 	log.Printf("🖱️ Executing click...")
-	robotgo.Click(robotgoButton, false) // false = single click
+	is.input.Click(robotgoButton, false) // false = single click
 
 	// Add small delay after click
 	robotgo.MilliSleep(100)
@@ -118,7 +205,7 @@ func (is *InputSimulator) clickMouse(x, y int, button string) error {
 	log.Printf("🖱️ Mouse clicked at (%d, %d) with %s button - COMPLETED", x, y, button)
 
 	// Optional: Try to get window information at click position for debugging
-	if title := robotgo.GetTitle(); title != "" {
+	if title := is.input.ActiveWindowTitle(); title != "" {
 		log.Printf("🪟 Active window at click: '%s'", title)
 	} else {
 		log.Printf("⚠️ Could not get active window title - may indicate permission issues")
@@ -127,73 +214,370 @@ func (is *InputSimulator) clickMouse(x, y int, button string) error {
 	return nil
 }
 
-func (is *InputSimulator) scrollMouse(x, y int, delta int) error {
+func (is *InputSimulator) doubleClickMouse(x, y int, button string) error {
 	if is.enableSafety && !is.isValidCoordinates(x, y) {
 		return fmt.Errorf("invalid coordinates: (%d, %d)", x, y)
 	}
 
-	// Move to position first
-	robotgo.MoveMouse(x, y)
+	robotgoButton := is.convertButtonToRobotgo(button)
+	is.forgetButton(robotgoButton)
+
+	is.input.MoveMouse(x, y)
+	is.input.Click(robotgoButton, true) // true = double click
+
+	log.Printf("🖱️ Mouse double-clicked at (%d, %d) with %s button", x, y, robotgoButton)
+	return nil
+}
+
+// mouseDown presses a button and keeps it held (drag, text selection) until
+// the matching "up" or the end of the session
+func (is *InputSimulator) mouseDown(x, y int, button string) error {
+	if is.enableSafety && !is.isValidCoordinates(x, y) {
+		return fmt.Errorf("invalid coordinates: (%d, %d)", x, y)
+	}
+
+	robotgoButton := is.convertButtonToRobotgo(button)
+
+	is.buttonsMutex.Lock()
+	defer is.buttonsMutex.Unlock()
+
+	is.input.MoveMouse(x, y)
+
+	if is.pressedButtons[robotgoButton] {
+		// The previous "up" was lost; the button is still held
+		log.Printf("⚠️ Mouse %s button already down, ignoring repeated down", robotgoButton)
+		return nil
+	}
+
+	if err := is.input.MouseDown(robotgoButton); err != nil {
+		return fmt.Errorf("mouse down failed: %w", err)
+	}
+	is.pressedButtons[robotgoButton] = true
+
+	log.Printf("🖱️ Mouse %s button down at (%d, %d)", robotgoButton, x, y)
+	return nil
+}
+
+// mouseUp releases a button held by mouseDown
+func (is *InputSimulator) mouseUp(x, y int, button string) error {
+	robotgoButton := is.convertButtonToRobotgo(button)
+
+	is.buttonsMutex.Lock()
+	defer is.buttonsMutex.Unlock()
+
+	if !is.pressedButtons[robotgoButton] {
+		log.Printf("⚠️ Mouse %s button up without a down, ignored", robotgoButton)
+		return nil
+	}
 
-	// Determine scroll direction
-	direction := "up"
-	scrollAmount := delta
-	if delta < 0 {
-		direction = "down"
-		scrollAmount = -delta
+	// A drag may end outside the display: the button is released where the
+	// pointer is instead of leaving it held
+	if !is.enableSafety || is.isValidCoordinates(x, y) {
+		is.input.MoveMouse(x, y)
 	}
 
-	// Perform scroll
-	robotgo.Scroll(0, scrollAmount)
+	delete(is.pressedButtons, robotgoButton)
+	if err := is.input.MouseUp(robotgoButton); err != nil {
+		return fmt.Errorf("mouse up failed: %w", err)
+	}
+
+	log.Printf("🖱️ Mouse %s button up at (%d, %d)", robotgoButton, x, y)
+	return nil
+}
+
+// scrollMouse scrolls deltaY steps up (negative = down) and deltaX steps
+// right (negative = left)
+func (is *InputSimulator) scrollMouse(x, y int, deltaX, deltaY int) error {
+	if is.enableSafety && !is.isValidCoordinates(x, y) {
+		return fmt.Errorf("invalid coordinates: (%d, %d)", x, y)
+	}
+
+	if is.enableSafety {
+		deltaX = clampScroll(deltaX)
+		deltaY = clampScroll(deltaY)
+	}
+
+	if deltaX == 0 && deltaY == 0 {
+		return nil
+	}
+
+	// Move to position first
+	is.input.MoveMouse(x, y)
 
-	log.Printf("🖱️ Mouse scrolled %s by %d at (%d, %d)", direction, scrollAmount, x, y)
+	if err := is.input.Scroll(deltaX, deltaY); err != nil {
+		return fmt.Errorf("scroll failed: %w", err)
+	}
+
+	log.Printf("🖱️ Mouse scrolled (x: %d, y: %d) at (%d, %d)", deltaX, deltaY, x, y)
 	return nil
 }
 
+// ReleaseButtons releases every mouse button still held by a "down", so a
+// session that ends mid-drag does not leave a button pressed
+func (is *InputSimulator) ReleaseButtons() []string {
+	is.buttonsMutex.Lock()
+	defer is.buttonsMutex.Unlock()
+
+	released := make([]string, 0, len(is.pressedButtons))
+	for button := range is.pressedButtons {
+		if err := is.input.MouseUp(button); err != nil {
+			log.Printf("❌ Failed to release mouse %s button: %v", button, err)
+		}
+		released = append(released, button)
+	}
+	is.pressedButtons = make(map[string]bool)
+
+	if len(released) > 0 {
+		log.Printf("🖱️ Released held mouse buttons: %v", released)
+	}
+	return released
+}
+
+// PressedButtons returns the mouse buttons currently held by a "down"
+func (is *InputSimulator) PressedButtons() []string {
+	is.buttonsMutex.Lock()
+	defer is.buttonsMutex.Unlock()
+
+	buttons := make([]string, 0, len(is.pressedButtons))
+	for button := range is.pressedButtons {
+		buttons = append(buttons, button)
+	}
+	return buttons
+}
+
+// forgetButton stops tracking a button released by a click
+func (is *InputSimulator) forgetButton(robotgoButton string) {
+	is.buttonsMutex.Lock()
+	defer is.buttonsMutex.Unlock()
+	delete(is.pressedButtons, robotgoButton)
+}
+
+// clampScroll limits a scroll delta to maxScrollDelta steps in either direction
+func clampScroll(delta int) int {
+	if delta > maxScrollDelta {
+		return maxScrollDelta
+	}
+	if delta < -maxScrollDelta {
+		return -maxScrollDelta
+	}
+	return delta
+}
+
 // Keyboard operations
 
+// keyDown presses the key and its modifiers and keeps them held until the
+// matching keyup, so combinations behave like a real keyboard
 func (is *InputSimulator) keyDown(key string, modifiers []string) error {
 	if is.enableSafety && is.isDangerousKey(key) {
 		return fmt.Errorf("dangerous key blocked: %s", key)
 	}
 
 	// Convert key to robotgo format
-	robotgoKey := is.convertKeyToRobotgo(key)
-
-	// Handle modifiers
-	if len(modifiers) > 0 {
-		// Build key combination
-		keys := make([]string, 0, len(modifiers)+1)
-		for _, mod := range modifiers {
-			keys = append(keys, is.convertModifierToRobotgo(mod))
-		}
-		keys = append(keys, robotgoKey)
+	robotgoKey := is.robotgoKeyName(key)
 
-		robotgo.KeyTap(robotgoKey, keys[:len(keys)-1])
-	} else {
-		robotgo.KeyDown(robotgoKey)
+	is.keysMutex.Lock()
+	defer is.keysMutex.Unlock()
+
+	// Modifiers go down before the key
+	is.syncModifiers(modifiers, robotgoKey)
+
+	// A repeated down for a held key is auto-repeat and is sent again
+	if err := is.input.KeyDown(robotgoKey); err != nil {
+		is.touchKeys()
+		return fmt.Errorf("key down failed: %w", err)
 	}
+	is.heldKeys[robotgoKey] = true
+	delete(is.impliedModifiers, robotgoKey) // Its own keydown makes a modifier explicit
+	is.touchKeys()
 
 	log.Printf("⌨️ Key down: %s (modifiers: %v)", key, modifiers)
 	return nil
 }
 
+// keyUp releases the key, then any implied modifier the event no longer reports
 func (is *InputSimulator) keyUp(key string, modifiers []string) error {
 	// Convert key to robotgo format
-	robotgoKey := is.convertKeyToRobotgo(key)
+	robotgoKey := is.robotgoKeyName(key)
+
+	is.keysMutex.Lock()
+	defer is.keysMutex.Unlock()
 
-	robotgo.KeyUp(robotgoKey)
+	if !is.heldKeys[robotgoKey] {
+		// Released anyway: harmless if it was not down
+		log.Printf("⚠️ Key up without a tracked down: %s", key)
+	}
+	delete(is.heldKeys, robotgoKey)
+	delete(is.impliedModifiers, robotgoKey)
+	err := is.input.KeyUp(robotgoKey)
+
+	is.syncModifiers(modifiers, robotgoKey)
+	is.touchKeys()
+
+	if err != nil {
+		return fmt.Errorf("key up failed: %w", err)
+	}
 
 	log.Printf("⌨️ Key up: %s", key)
 	return nil
 }
 
+// syncModifiers makes the implied modifiers match those reported with a key
+// event: missing ones go down, implied ones no longer reported go up.
+// Modifiers held by their own keydown are left to their keyup (requires keysMutex).
+func (is *InputSimulator) syncModifiers(modifiers []string, robotgoKey string) {
+	reported := make(map[string]bool, len(modifiers))
+	for _, mod := range modifiers {
+		name := is.convertModifierToRobotgo(mod)
+		if !isModifierKey(name) {
+			continue
+		}
+		reported[name] = true
+		if name == robotgoKey {
+			// The event's own key is pressed or released by the caller
+			continue
+		}
+
+		if !is.heldKeys[name] {
+			if err := is.input.KeyDown(name); err != nil {
+				log.Printf("❌ Failed to press modifier %s: %v", name, err)
+				continue
+			}
+			is.heldKeys[name] = true
+			is.impliedModifiers[name] = true
+		}
+	}
+
+	for name := range is.impliedModifiers {
+		if reported[name] {
+			continue
+		}
+		if err := is.input.KeyUp(name); err != nil {
+			log.Printf("❌ Failed to release modifier %s: %v", name, err)
+		}
+		delete(is.heldKeys, name)
+		delete(is.impliedModifiers, name)
+	}
+}
+
+// ReleaseKeys releases every key and modifier still held, so a session that
+// ends mid-combination does not leave Ctrl or Shift stuck
+func (is *InputSimulator) ReleaseKeys() []string {
+	is.keysMutex.Lock()
+	defer is.keysMutex.Unlock()
+
+	released := is.releaseKeys()
+	if len(released) > 0 {
+		log.Printf("⌨️ Released held keys: %v", released)
+	}
+	return released
+}
+
+// ReleaseAll releases every key and mouse button still held
+func (is *InputSimulator) ReleaseAll() {
+	is.ReleaseKeys()
+	is.ReleaseButtons()
+}
+
+// HeldKeys returns the keys and modifiers currently held
+func (is *InputSimulator) HeldKeys() []string {
+	is.keysMutex.Lock()
+	defer is.keysMutex.Unlock()
+
+	keys := make([]string, 0, len(is.heldKeys))
+	for key := range is.heldKeys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// SetKeyReleaseTimeout sets how long keys may stay held without input; 0 disables it
+func (is *InputSimulator) SetKeyReleaseTimeout(timeout time.Duration) {
+	is.keysMutex.Lock()
+	defer is.keysMutex.Unlock()
+
+	is.keyReleaseTimeout = timeout
+	is.armKeyRelease()
+}
+
+// releaseKeys releases base keys first and modifiers last (requires keysMutex)
+func (is *InputSimulator) releaseKeys() []string {
+	released := make([]string, 0, len(is.heldKeys))
+	for _, modifiers := range []bool{false, true} {
+		for key := range is.heldKeys {
+			if isModifierKey(key) != modifiers {
+				continue
+			}
+			if err := is.input.KeyUp(key); err != nil {
+				log.Printf("❌ Failed to release key %s: %v", key, err)
+			}
+			released = append(released, key)
+		}
+	}
+
+	is.heldKeys = make(map[string]bool)
+	is.impliedModifiers = make(map[string]bool)
+	if is.keyReleaseTimer != nil {
+		is.keyReleaseTimer.Stop()
+	}
+	return released
+}
+
+// noteInput records input activity for the stuck-key timeout
+func (is *InputSimulator) noteInput() {
+	is.keysMutex.Lock()
+	defer is.keysMutex.Unlock()
+	is.touchKeys()
+}
+
+// touchKeys records input activity and re-arms the stuck-key timer (requires keysMutex)
+func (is *InputSimulator) touchKeys() {
+	is.lastInput = time.Now()
+	is.armKeyRelease()
+}
+
+// armKeyRelease schedules the stuck-key check while keys are held (requires keysMutex)
+func (is *InputSimulator) armKeyRelease() {
+	if len(is.heldKeys) == 0 || is.keyReleaseTimeout <= 0 {
+		if is.keyReleaseTimer != nil {
+			is.keyReleaseTimer.Stop()
+		}
+		return
+	}
+
+	if is.keyReleaseTimer == nil {
+		is.keyReleaseTimer = time.AfterFunc(is.keyReleaseTimeout, is.releaseIdleKeys)
+	} else {
+		is.keyReleaseTimer.Reset(is.keyReleaseTimeout)
+	}
+}
+
+// releaseIdleKeys releases held keys once no input arrived for keyReleaseTimeout
+func (is *InputSimulator) releaseIdleKeys() {
+	is.keysMutex.Lock()
+	defer is.keysMutex.Unlock()
+
+	if len(is.heldKeys) == 0 || is.keyReleaseTimeout <= 0 {
+		return
+	}
+
+	idle := time.Since(is.lastInput)
+	if idle < is.keyReleaseTimeout {
+		// Input arrived after the timer fired
+		is.keyReleaseTimer.Reset(is.keyReleaseTimeout - idle)
+		return
+	}
+
+	released := is.releaseKeys()
+	log.Printf("⌨️ Released keys held for %v without input: %v", idle.Round(time.Second), released)
+}
+
 func (is *InputSimulator) typeText(text string) error {
 	if is.enableSafety && len(text) > 1000 {
 		return fmt.Errorf("text too long: %d characters (max 1000)", len(text))
 	}
 
-	robotgo.TypeStr(text)
+	is.noteInput()
+	is.input.TypeStr(text)
 
 	log.Printf("⌨️ Typed text: %s", text)
 	return nil
@@ -234,12 +618,38 @@ func (is *InputSimulator) parseKeyboardPayload(payload map[string]interface{}) (
 }
 
 func (is *InputSimulator) isValidCoordinates(x, y int) bool {
+	is.boundsMutex.RLock()
+	bounds := is.displayBounds
+	is.boundsMutex.RUnlock()
+
+	if !bounds.Empty() {
+		return image.Pt(x, y).In(bounds)
+	}
+
 	// Get screen dimensions
-	width, height := robotgo.GetScreenSize()
+	width, height := is.input.ScreenSize()
 
 	return x >= 0 && x < width && y >= 0 && y < height
 }
 
+// toDesktop translates coordinates relative to the streamed display into
+// desktop coordinates
+func (is *InputSimulator) toDesktop(x, y int) (int, int) {
+	is.boundsMutex.RLock()
+	defer is.boundsMutex.RUnlock()
+
+	return x + is.displayBounds.Min.X, y + is.displayBounds.Min.Y
+}
+
+// SetDisplayBounds sets the desktop rectangle of the streamed display
+func (is *InputSimulator) SetDisplayBounds(bounds image.Rectangle) {
+	is.boundsMutex.Lock()
+	defer is.boundsMutex.Unlock()
+
+	is.displayBounds = bounds
+	log.Printf("🖥️ Input mapped to display bounds %v", bounds)
+}
+
 func (is *InputSimulator) convertButtonToRobotgo(button string) string {
 	switch strings.ToLower(button) {
 	case "left", "":
@@ -310,13 +720,32 @@ func (is *InputSimulator) convertModifierToRobotgo(modifier string) string {
 		return "alt"
 	case "shift":
 		return "shift"
-	case "meta", "cmd", "windows":
+	case "meta", "cmd", "windows", "os":
 		return "cmd"
 	default:
 		return modifier
 	}
 }
 
+// robotgoKeyName maps a key to robotgo; modifier keys get the same name they
+// have in a modifiers list so both are tracked as one key
+func (is *InputSimulator) robotgoKeyName(key string) string {
+	if name := is.convertModifierToRobotgo(key); isModifierKey(name) {
+		return name
+	}
+	return is.convertKeyToRobotgo(key)
+}
+
+// isModifierKey reports whether a robotgo key name is a modifier
+func isModifierKey(name string) bool {
+	switch name {
+	case "ctrl", "alt", "shift", "cmd":
+		return true
+	default:
+		return false
+	}
+}
+
 func (is *InputSimulator) isDangerousKey(key string) bool {
 	// List of potentially dangerous key combinations
 	dangerousKeys := []string{
@@ -341,7 +770,7 @@ func (is *InputSimulator) SetSafety(enabled bool) {
 
 // GetScreenInfo returns screen information
 func (is *InputSimulator) GetScreenInfo() map[string]interface{} {
-	width, height := robotgo.GetScreenSize()
+	width, height := is.input.ScreenSize()
 
 	return map[string]interface{}{
 		"width":  width,
@@ -354,7 +783,7 @@ func (is *InputSimulator) TestInput() error {
 	log.Printf("🧪 Testing input simulation...")
 
 	// Test mouse movement (move to center of screen)
-	width, height := robotgo.GetScreenSize()
+	width, height := is.input.ScreenSize()
 	centerX, centerY := width/2, height/2
 
 	log.Printf("🖥️ Screen size: %dx%d", width, height)
@@ -366,7 +795,7 @@ func (is *InputSimulator) TestInput() error {
 	}
 
 	// Verify mouse position
-	actualX, actualY := robotgo.GetMousePos()
+	actualX, actualY := is.input.MousePos()
 	log.Printf("✅ Mouse position after test move: (%d, %d)", actualX, actualY)
 
 	// Test click functionality
@@ -377,14 +806,14 @@ func (is *InputSimulator) TestInput() error {
 	}
 
 	// Test if we can detect if click was successful by checking if mouse is still at position
-	afterClickX, afterClickY := robotgo.GetMousePos()
+	afterClickX, afterClickY := is.input.MousePos()
 	log.Printf("🔍 Mouse position after click: (%d, %d)", afterClickX, afterClickY)
 
 	// Check if robotgo has admin privileges (Windows specific test)
 	log.Printf("🛡️ Testing system permissions...")
 
 	// Try to get active window title as a privilege test
-	if title := robotgo.GetTitle(); title != "" {
+	if title := is.input.ActiveWindowTitle(); title != "" {
 		log.Printf("✅ Can read active window title: '%s'", title)
 	} else {
 		log.Printf("⚠️ Cannot read active window title - may indicate permission issues")
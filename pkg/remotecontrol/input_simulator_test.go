@@ -0,0 +1,492 @@
+package remotecontrol
+
+import (
+	"fmt"
+	"image"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// fakeInput records the input injected instead of sending it to the system
+type fakeInput struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (f *fakeInput) record(format string, args ...interface{}) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.events = append(f.events, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeInput) MoveMouse(x, y int)            { f.record("move %d,%d", x, y) }
+func (f *fakeInput) MousePos() (int, int)          { return 0, 0 }
+func (f *fakeInput) MouseDown(button string) error { f.record("mousedown %s", button); return nil }
+func (f *fakeInput) MouseUp(button string) error   { f.record("mouseup %s", button); return nil }
+func (f *fakeInput) Click(button string, double bool) {
+	if double {
+		f.record("dblclick %s", button)
+	} else {
+		f.record("click %s", button)
+	}
+}
+func (f *fakeInput) Scroll(deltaX, deltaY int) error {
+	f.record("scroll %d,%d", deltaX, deltaY)
+	return nil
+}
+func (f *fakeInput) KeyDown(key string) error  { f.record("keydown %s", key); return nil }
+func (f *fakeInput) KeyUp(key string) error    { f.record("keyup %s", key); return nil }
+func (f *fakeInput) TypeStr(text string)       { f.record("type %s", text) }
+func (f *fakeInput) ScreenSize() (int, int)    { return 1920, 1080 }
+func (f *fakeInput) ActiveWindowTitle() string { return "" }
+
+// take returns the events recorded so far and clears them
+func (f *fakeInput) take() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	events := f.events
+	f.events = nil
+	return events
+}
+
+// newTestInputSimulator creates a simulator that injects into a fakeInput
+func newTestInputSimulator() (*InputSimulator, *fakeInput) {
+	input := &fakeInput{}
+	simulator := NewInputSimulator()
+	simulator.input = input
+	return simulator, input
+}
+
+// keyCommand builds a keyboard command as the viewer sends it
+func keyCommand(action, key string, modifiers ...string) api.InputCommand {
+	return api.InputCommand{
+		EventType: "keyboard",
+		Action:    action,
+		Payload:   map[string]interface{}{"key": key, "modifiers": modifiers},
+	}
+}
+
+// sortedKeys returns the held keys in a stable order
+func sortedKeys(keys []string) []string {
+	sort.Strings(keys)
+	return keys
+}
+
+func TestInputSimulatorModifierTracking(t *testing.T) {
+	tests := []struct {
+		name       string
+		commands   []api.InputCommand
+		wantEvents []string
+		wantHeld   []string
+	}{
+		{
+			name: "implied modifier goes down before the key",
+			commands: []api.InputCommand{
+				keyCommand("keydown", "a", "shift"),
+			},
+			wantEvents: []string{"keydown shift", "keydown a"},
+			wantHeld:   []string{"a", "shift"},
+		},
+		{
+			name: "implied modifier stays while reported",
+			commands: []api.InputCommand{
+				keyCommand("keydown", "a", "shift"),
+				keyCommand("keyup", "a", "shift"),
+				keyCommand("keydown", "b", "shift"),
+			},
+			wantEvents: []string{"keydown shift", "keydown a", "keyup a", "keydown b"},
+			wantHeld:   []string{"b", "shift"},
+		},
+		{
+			name: "implied modifier is released by the next event without it",
+			commands: []api.InputCommand{
+				keyCommand("keydown", "a", "ctrl"),
+				keyCommand("keyup", "a", "ctrl"),
+				keyCommand("keydown", "b"),
+			},
+			wantEvents: []string{"keydown ctrl", "keydown a", "keyup a", "keyup ctrl", "keydown b"},
+			wantHeld:   []string{"b"},
+		},
+		{
+			name: "keyup without the modifier releases it after the key",
+			commands: []api.InputCommand{
+				keyCommand("keydown", "a", "alt"),
+				keyCommand("keyup", "a"),
+			},
+			wantEvents: []string{"keydown alt", "keydown a", "keyup a", "keyup alt"},
+			wantHeld:   []string{},
+		},
+		{
+			name: "explicit modifier waits for its own keyup",
+			commands: []api.InputCommand{
+				keyCommand("keydown", "Shift", "shift"),
+				keyCommand("keydown", "a", "shift"),
+				keyCommand("keyup", "a"),
+			},
+			wantEvents: []string{"keydown shift", "keydown a", "keyup a"},
+			wantHeld:   []string{"shift"},
+		},
+		{
+			name: "modifier keydown makes an implied modifier explicit",
+			commands: []api.InputCommand{
+				keyCommand("keydown", "a", "ctrl"),
+				keyCommand("keydown", "Control", "ctrl"),
+				keyCommand("keyup", "a"),
+			},
+			wantEvents: []string{"keydown ctrl", "keydown a", "keydown ctrl", "keyup a"},
+			wantHeld:   []string{"ctrl"},
+		},
+		{
+			name: "meta aliases are one key",
+			commands: []api.InputCommand{
+				keyCommand("keydown", "Meta", "meta"),
+				keyCommand("keyup", "OS", "cmd"),
+			},
+			wantEvents: []string{"keydown cmd", "keyup cmd"},
+			wantHeld:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			simulator, input := newTestInputSimulator()
+			simulator.SetKeyReleaseTimeout(0)
+
+			for _, command := range tt.commands {
+				if err := simulator.ProcessKeyboardCommand(command); err != nil {
+					t.Fatalf("%s %v: %v", command.Action, command.Payload, err)
+				}
+			}
+
+			if got := input.take(); !reflect.DeepEqual(got, tt.wantEvents) {
+				t.Errorf("events = %q, want %q", got, tt.wantEvents)
+			}
+			if got := sortedKeys(simulator.HeldKeys()); !reflect.DeepEqual(got, tt.wantHeld) {
+				t.Errorf("held = %q, want %q", got, tt.wantHeld)
+			}
+		})
+	}
+}
+
+func TestInputSimulatorReleasesModifiersLast(t *testing.T) {
+	simulator, input := newTestInputSimulator()
+	simulator.SetKeyReleaseTimeout(0)
+
+	for _, command := range []api.InputCommand{
+		keyCommand("keydown", "Control", "ctrl"),
+		keyCommand("keydown", "a", "ctrl", "shift"),
+		keyCommand("keydown", "b", "ctrl", "shift"),
+	} {
+		if err := simulator.ProcessKeyboardCommand(command); err != nil {
+			t.Fatal(err)
+		}
+	}
+	input.take()
+
+	released := simulator.ReleaseKeys()
+	if got, want := sortedKeys(released), []string{"a", "b", "ctrl", "shift"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("released = %q, want %q", got, want)
+	}
+
+	events := input.take()
+	if len(events) != 4 {
+		t.Fatalf("events = %q, want four key ups", events)
+	}
+	if got := sortedKeys(events[:2]); !reflect.DeepEqual(got, []string{"keyup a", "keyup b"}) {
+		t.Errorf("first released = %q, want the regular keys", got)
+	}
+	if got := sortedKeys(events[2:]); !reflect.DeepEqual(got, []string{"keyup ctrl", "keyup shift"}) {
+		t.Errorf("last released = %q, want the modifiers", got)
+	}
+	if held := simulator.HeldKeys(); len(held) != 0 {
+		t.Errorf("still held after ReleaseKeys: %q", held)
+	}
+
+	// Nothing is held, so the next event has no implied modifier to release
+	if err := simulator.ProcessKeyboardCommand(keyCommand("keydown", "c")); err != nil {
+		t.Fatal(err)
+	}
+	if got := input.take(); !reflect.DeepEqual(got, []string{"keydown c"}) {
+		t.Errorf("events = %q, want only the new key", got)
+	}
+}
+
+func TestInputSimulatorReleasesIdleKeys(t *testing.T) {
+	simulator, input := newTestInputSimulator()
+	simulator.SetKeyReleaseTimeout(200 * time.Millisecond)
+
+	if err := simulator.ProcessKeyboardCommand(keyCommand("keydown", "a", "ctrl")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mouse input counts as activity and keeps the keys held
+	for i := 0; i < 4; i++ {
+		time.Sleep(40 * time.Millisecond)
+		if err := simulator.ProcessMouseCommand(api.InputCommand{
+			EventType: "mouse",
+			Action:    "move",
+			Payload:   map[string]interface{}{"x": 10, "y": 10},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := sortedKeys(simulator.HeldKeys()); !reflect.DeepEqual(got, []string{"a", "ctrl"}) {
+		t.Fatalf("held = %q, want the keys still held while input arrives", got)
+	}
+	input.take()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(simulator.HeldKeys()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("idle keys were not released")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := input.take(); !reflect.DeepEqual(got, []string{"keyup a", "keyup ctrl"}) {
+		t.Errorf("events = %q, want the key released before its modifier", got)
+	}
+}
+
+// mouseCommand builds a mouse command as the viewer sends it
+func mouseCommand(action string, x, y int, button string) api.InputCommand {
+	return api.InputCommand{
+		EventType: "mouse",
+		Action:    action,
+		Payload:   map[string]interface{}{"x": x, "y": y, "button": button},
+	}
+}
+
+func TestInputSimulatorButtonTracking(t *testing.T) {
+	tests := []struct {
+		name        string
+		commands    []api.InputCommand
+		wantEvents  []string
+		wantPressed []string
+	}{
+		{
+			name: "down and up",
+			commands: []api.InputCommand{
+				mouseCommand("down", 10, 20, "left"),
+				mouseCommand("up", 30, 40, "left"),
+			},
+			wantEvents:  []string{"move 10,20", "mousedown left", "move 30,40", "mouseup left"},
+			wantPressed: []string{},
+		},
+		{
+			name: "repeated down is not pressed again",
+			commands: []api.InputCommand{
+				mouseCommand("down", 10, 20, "left"),
+				mouseCommand("down", 30, 40, "left"),
+			},
+			wantEvents:  []string{"move 10,20", "mousedown left", "move 30,40"},
+			wantPressed: []string{"left"},
+		},
+		{
+			name: "up without a down is ignored",
+			commands: []api.InputCommand{
+				mouseCommand("up", 10, 20, "right"),
+			},
+			wantEvents:  nil,
+			wantPressed: []string{},
+		},
+		{
+			name: "buttons are tracked separately",
+			commands: []api.InputCommand{
+				mouseCommand("down", 10, 20, "middle"),
+				mouseCommand("down", 10, 20, "right"),
+				mouseCommand("up", 10, 20, "middle"),
+			},
+			wantEvents:  []string{"move 10,20", "mousedown center", "move 10,20", "mousedown right", "move 10,20", "mouseup center"},
+			wantPressed: []string{"right"},
+		},
+		{
+			name: "drag released outside the display",
+			commands: []api.InputCommand{
+				mouseCommand("down", 10, 20, "left"),
+				mouseCommand("up", 5000, 5000, "left"),
+			},
+			wantEvents:  []string{"move 10,20", "mousedown left", "mouseup left"},
+			wantPressed: []string{},
+		},
+		{
+			name: "double click ends a drag with the same button",
+			commands: []api.InputCommand{
+				mouseCommand("down", 10, 20, "left"),
+				mouseCommand("dblclick", 10, 20, "left"),
+			},
+			wantEvents:  []string{"move 10,20", "mousedown left", "move 10,20", "dblclick left"},
+			wantPressed: []string{},
+		},
+		{
+			name: "click ends a drag with the same button",
+			commands: []api.InputCommand{
+				mouseCommand("down", 10, 20, "right"),
+				mouseCommand("down", 10, 20, "left"),
+				mouseCommand("click", 10, 20, "left"),
+			},
+			wantEvents:  []string{"move 10,20", "mousedown right", "move 10,20", "mousedown left", "move 10,20", "click left"},
+			wantPressed: []string{"right"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			simulator, input := newTestInputSimulator()
+
+			for _, command := range tt.commands {
+				if err := simulator.ProcessMouseCommand(command); err != nil {
+					t.Fatalf("%s %v: %v", command.Action, command.Payload, err)
+				}
+			}
+
+			if got := input.take(); !reflect.DeepEqual(got, tt.wantEvents) {
+				t.Errorf("events = %q, want %q", got, tt.wantEvents)
+			}
+			if got := sortedKeys(simulator.PressedButtons()); !reflect.DeepEqual(got, tt.wantPressed) {
+				t.Errorf("pressed = %q, want %q", got, tt.wantPressed)
+			}
+		})
+	}
+}
+
+func TestInputSimulatorRejectsDownOutsideDisplay(t *testing.T) {
+	simulator, input := newTestInputSimulator()
+
+	if err := simulator.ProcessMouseCommand(mouseCommand("down", 1920, 10, "left")); err == nil {
+		t.Error("a down outside the display was accepted")
+	}
+	if events := input.take(); len(events) != 0 {
+		t.Errorf("events = %q, want none", events)
+	}
+	if pressed := simulator.PressedButtons(); len(pressed) != 0 {
+		t.Errorf("pressed = %q, want none", pressed)
+	}
+}
+
+func TestInputSimulatorReleaseButtons(t *testing.T) {
+	simulator, input := newTestInputSimulator()
+
+	for _, button := range []string{"left", "right"} {
+		if err := simulator.ProcessMouseCommand(mouseCommand("down", 10, 20, button)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	input.take()
+
+	// The session ends mid-drag
+	if got := sortedKeys(simulator.ReleaseButtons()); !reflect.DeepEqual(got, []string{"left", "right"}) {
+		t.Errorf("released = %q, want both buttons", got)
+	}
+	if got := sortedKeys(input.take()); !reflect.DeepEqual(got, []string{"mouseup left", "mouseup right"}) {
+		t.Errorf("events = %q, want both buttons released", got)
+	}
+	if pressed := simulator.PressedButtons(); len(pressed) != 0 {
+		t.Errorf("pressed = %q after ReleaseButtons", pressed)
+	}
+
+	if released := simulator.ReleaseButtons(); len(released) != 0 {
+		t.Errorf("second ReleaseButtons() = %q, want nothing", released)
+	}
+	if err := simulator.ProcessMouseCommand(mouseCommand("up", 10, 20, "left")); err != nil {
+		t.Fatal(err)
+	}
+	if events := input.take(); len(events) != 0 {
+		t.Errorf("a late up was injected: %q", events)
+	}
+}
+
+func TestInputSimulatorScroll(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload map[string]interface{}
+		want    []string
+	}{
+		{name: "vertical", payload: map[string]interface{}{"x": 5, "y": 5, "delta_y": -3}, want: []string{"move 5,5", "scroll 0,-3"}},
+		{name: "horizontal", payload: map[string]interface{}{"x": 5, "y": 5, "delta_x": 2}, want: []string{"move 5,5", "scroll 2,0"}},
+		{name: "legacy delta", payload: map[string]interface{}{"x": 5, "y": 5, "delta": 4}, want: []string{"move 5,5", "scroll 0,4"}},
+		{name: "clamped", payload: map[string]interface{}{"x": 5, "y": 5, "delta_x": -500, "delta_y": 500}, want: []string{"move 5,5", "scroll -50,50"}},
+		{name: "no delta", payload: map[string]interface{}{"x": 5, "y": 5}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			simulator, input := newTestInputSimulator()
+			if err := simulator.ProcessMouseCommand(api.InputCommand{EventType: "mouse", Action: "scroll", Payload: tt.payload}); err != nil {
+				t.Fatal(err)
+			}
+			if got := input.take(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Three displays: a primary at the origin, one to the left and above it (negative
+// origin) and one to the right
+var (
+	primaryDisplay = image.Rect(0, 0, 1920, 1080)
+	leftDisplay    = image.Rect(-1280, -200, 0, 824)
+	rightDisplay   = image.Rect(1920, 0, 4480, 1440)
+)
+
+func TestInputSimulatorToDesktop(t *testing.T) {
+	tests := []struct {
+		name       string
+		bounds     image.Rectangle
+		x, y       int
+		wantX      int
+		wantY      int
+		wantInside bool
+	}{
+		{name: "no bounds", x: 100, y: 200, wantX: 100, wantY: 200, wantInside: true},
+		{name: "primary", bounds: primaryDisplay, x: 100, y: 200, wantX: 100, wantY: 200, wantInside: true},
+		{name: "right display", bounds: rightDisplay, x: 100, y: 200, wantX: 2020, wantY: 200, wantInside: true},
+		{name: "right display corner", bounds: rightDisplay, x: 2559, y: 1439, wantX: 4479, wantY: 1439, wantInside: true},
+		{name: "past the right display", bounds: rightDisplay, x: 2560, y: 0, wantX: 4480, wantY: 0, wantInside: false},
+		{name: "negative origin", bounds: leftDisplay, x: 0, y: 0, wantX: -1280, wantY: -200, wantInside: true},
+		{name: "negative origin inside", bounds: leftDisplay, x: 1000, y: 500, wantX: -280, wantY: 300, wantInside: true},
+		{name: "negative origin into the primary", bounds: leftDisplay, x: 1280, y: 300, wantX: 0, wantY: 100, wantInside: false},
+		{name: "negative origin below", bounds: leftDisplay, x: 10, y: 1024, wantX: -1270, wantY: 824, wantInside: false},
+		{name: "virtual desktop", bounds: leftDisplay.Union(primaryDisplay).Union(rightDisplay), x: 1380, y: 300, wantX: 100, wantY: 100, wantInside: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			simulator, _ := newTestInputSimulator()
+			simulator.SetDisplayBounds(tt.bounds)
+
+			x, y := simulator.toDesktop(tt.x, tt.y)
+			if x != tt.wantX || y != tt.wantY {
+				t.Errorf("toDesktop(%d, %d) = (%d, %d), want (%d, %d)", tt.x, tt.y, x, y, tt.wantX, tt.wantY)
+			}
+			if got := simulator.isValidCoordinates(x, y); got != tt.wantInside {
+				t.Errorf("isValidCoordinates(%d, %d) = %v, want %v", x, y, got, tt.wantInside)
+			}
+		})
+	}
+}
+
+func TestInputSimulatorClicksOnStreamedDisplay(t *testing.T) {
+	simulator, input := newTestInputSimulator()
+	simulator.SetDisplayBounds(leftDisplay)
+
+	if err := simulator.ProcessMouseCommand(mouseCommand("dblclick", 30, 40, "left")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := input.take(), []string{"move -1250,-160", "dblclick left"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+
+	// A point past the streamed display must not land on its neighbour
+	if err := simulator.ProcessMouseCommand(mouseCommand("dblclick", 1290, 40, "left")); err == nil {
+		t.Error("a click outside the streamed display was accepted")
+	}
+	if events := input.take(); len(events) != 0 {
+		t.Errorf("injected %q", events)
+	}
+}
@@ -6,26 +6,47 @@ import (
 	"image"
 	"image/jpeg"
 	"log"
+	"sync"
 
 	"github.com/kbinani/screenshot"
 )
 
+// VirtualDesktop selects the union of all displays instead of a single one
+const VirtualDesktop = -1
+
+// DisplayInfo describes one display and its bounds in desktop coordinates
+type DisplayInfo struct {
+	Index   int
+	X       int
+	Y       int
+	Width   int
+	Height  int
+	Primary bool
+}
+
 // ScreenCapture handles screen capture functionality
 type ScreenCapture struct {
-	displayNum int // Display number for multi-monitor support
+	displayNum int // Display number for multi-monitor support, or VirtualDesktop
+	mutex      sync.RWMutex
+
+	// Display enumeration (the screenshot package except in tests)
+	numDisplays   func() int
+	displayBounds func(displayIndex int) image.Rectangle
 }
 
 // NewScreenCapture creates a new ScreenCapture instance
 func NewScreenCapture() *ScreenCapture {
 	return &ScreenCapture{
-		displayNum: 0, // Primary display by default
+		displayNum:    0, // Primary display by default
+		numDisplays:   screenshot.NumActiveDisplays,
+		displayBounds: screenshot.GetDisplayBounds,
 	}
 }
 
 // CaptureFrame captures the current screen as an image
 func (sc *ScreenCapture) CaptureFrame() (*image.RGBA, error) {
 	// Get the bounds of the display
-	bounds := screenshot.GetDisplayBounds(sc.displayNum)
+	bounds := sc.CurrentBounds()
 
 	// Capture the screen
 	img, err := screenshot.CaptureRect(bounds)
@@ -57,12 +78,61 @@ func (sc *ScreenCapture) CompressToJPEG(img *image.RGBA, quality int) ([]byte, e
 	return buf.Bytes(), nil
 }
 
+// CurrentBounds returns the desktop rectangle being captured
+func (sc *ScreenCapture) CurrentBounds() image.Rectangle {
+	sc.mutex.RLock()
+	displayNum := sc.displayNum
+	sc.mutex.RUnlock()
+
+	if displayNum == VirtualDesktop {
+		return sc.virtualDesktopBounds()
+	}
+	return sc.displayBounds(displayNum)
+}
+
+// GetDisplay returns the selected display number, or VirtualDesktop
+func (sc *ScreenCapture) GetDisplay() int {
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+	return sc.displayNum
+}
+
+// ListDisplays returns all active displays with their bounds
+func (sc *ScreenCapture) ListDisplays() []DisplayInfo {
+	numDisplays := sc.numDisplays()
+	displays := make([]DisplayInfo, 0, numDisplays)
+
+	for i := 0; i < numDisplays; i++ {
+		bounds := sc.displayBounds(i)
+		displays = append(displays, DisplayInfo{
+			Index:  i,
+			X:      bounds.Min.X,
+			Y:      bounds.Min.Y,
+			Width:  bounds.Dx(),
+			Height: bounds.Dy(),
+			// The primary display is the one anchored at the desktop origin
+			Primary: bounds.Min.X == 0 && bounds.Min.Y == 0,
+		})
+	}
+
+	return displays
+}
+
+// virtualDesktopBounds returns the union of all display bounds
+func (sc *ScreenCapture) virtualDesktopBounds() image.Rectangle {
+	var union image.Rectangle
+	for i := 0; i < sc.numDisplays(); i++ {
+		union = union.Union(sc.displayBounds(i))
+	}
+	return union
+}
+
 // GetScreenInfo returns information about the current screen
 func (sc *ScreenCapture) GetScreenInfo() map[string]interface{} {
-	bounds := screenshot.GetDisplayBounds(sc.displayNum)
+	bounds := sc.CurrentBounds()
 
 	return map[string]interface{}{
-		"display_num": sc.displayNum,
+		"display_num": sc.GetDisplay(),
 		"width":       bounds.Dx(),
 		"height":      bounds.Dy(),
 		"x":           bounds.Min.X,
@@ -72,21 +142,28 @@ func (sc *ScreenCapture) GetScreenInfo() map[string]interface{} {
 
 // GetAvailableDisplays returns the number of available displays
 func (sc *ScreenCapture) GetAvailableDisplays() int {
-	numDisplays := screenshot.NumActiveDisplays()
+	numDisplays := sc.numDisplays()
 	return numDisplays
 }
 
-// SetDisplay sets the display to capture from
+// SetDisplay sets the display to capture from; VirtualDesktop captures all displays
 func (sc *ScreenCapture) SetDisplay(displayNum int) error {
 	numDisplays := sc.GetAvailableDisplays()
 
-	if displayNum < 0 || displayNum >= numDisplays {
+	if displayNum != VirtualDesktop && (displayNum < 0 || displayNum >= numDisplays) {
 		return fmt.Errorf("invalid display number %d, available displays: 0-%d",
 			displayNum, numDisplays-1)
 	}
 
+	sc.mutex.Lock()
 	sc.displayNum = displayNum
-	log.Printf("📺 Display set to %d", displayNum)
+	sc.mutex.Unlock()
+
+	if displayNum == VirtualDesktop {
+		log.Printf("📺 Display set to virtual desktop (%d displays)", numDisplays)
+	} else {
+		log.Printf("📺 Display set to %d", displayNum)
+	}
 
 	return nil
 }
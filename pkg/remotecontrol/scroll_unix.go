@@ -0,0 +1,13 @@
+//go:build !windows
+
+package remotecontrol
+
+import "github.com/go-vgo/robotgo"
+
+// scrollWheel scrolls deltaY notches up (negative = down) and deltaX notches
+// right (negative = left)
+func scrollWheel(deltaX, deltaY int) error {
+	// robotgo scrolls left for positive x
+	robotgo.Scroll(-deltaX, deltaY)
+	return nil
+}
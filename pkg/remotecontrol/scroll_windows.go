@@ -0,0 +1,73 @@
+//go:build windows
+
+package remotecontrol
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// robotgo sends the horizontal amount as a vertical wheel event on Windows,
+// so both wheels are sent through SendInput directly
+var procSendInput = windows.NewLazySystemDLL("user32.dll").NewProc("SendInput")
+
+const (
+	inputMouse       = 0      // INPUT_MOUSE
+	mouseEventWheel  = 0x0800 // MOUSEEVENTF_WHEEL: positive scrolls up
+	mouseEventHWheel = 0x1000 // MOUSEEVENTF_HWHEEL: positive scrolls right
+	wheelDelta       = 120    // One notch
+)
+
+// mouseInput mirrors MOUSEINPUT
+type mouseInput struct {
+	dx, dy    int32
+	mouseData uint32
+	flags     uint32
+	time      uint32
+	extraInfo uintptr
+}
+
+// sendInput mirrors an INPUT holding a mouse event; the nested struct keeps
+// the union aligned as on the Win32 side
+type sendInput struct {
+	inputType uint32
+	mouse     mouseInput
+}
+
+// scrollWheel scrolls deltaY notches up (negative = down) and deltaX notches
+// right (negative = left)
+func scrollWheel(deltaX, deltaY int) error {
+	var inputs []sendInput
+	if deltaY != 0 {
+		inputs = append(inputs, wheelInput(mouseEventWheel, deltaY))
+	}
+	if deltaX != 0 {
+		inputs = append(inputs, wheelInput(mouseEventHWheel, deltaX))
+	}
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	sent, _, err := procSendInput.Call(
+		uintptr(len(inputs)),
+		uintptr(unsafe.Pointer(&inputs[0])),
+		unsafe.Sizeof(inputs[0]),
+	)
+	if int(sent) != len(inputs) {
+		return fmt.Errorf("SendInput sent %d of %d wheel events: %v", sent, len(inputs), err)
+	}
+	return nil
+}
+
+// wheelInput builds a wheel event of the given notches
+func wheelInput(flags uint32, notches int) sendInput {
+	return sendInput{
+		inputType: inputMouse,
+		mouse: mouseInput{
+			mouseData: uint32(int32(notches * wheelDelta)),
+			flags:     flags,
+		},
+	}
+}
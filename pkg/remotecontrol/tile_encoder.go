@@ -0,0 +1,187 @@
+package remotecontrol
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// TileEncoderConfig configures tile-based delta encoding
+type TileEncoderConfig struct {
+	TileSize         int           // Tile edge in pixels
+	KeyframeInterval time.Duration // Maximum time between full frames
+	// MaxDirtyRatio is the fraction of changed tiles above which a full
+	// keyframe is sent instead, since it compresses better than many tiles
+	MaxDirtyRatio float64
+}
+
+// DefaultTileEncoderConfig returns the default tile encoding settings
+func DefaultTileEncoderConfig() TileEncoderConfig {
+	return TileEncoderConfig{
+		TileSize:         64,
+		KeyframeInterval: 5 * time.Second,
+		MaxDirtyRatio:    0.5,
+	}
+}
+
+// EncodedFrame is the result of encoding one capture
+type EncodedFrame struct {
+	Format     string         // api.FrameFormatJPEG or api.FrameFormatJPEGTiles
+	IsKeyframe bool           // True when FrameData holds the full screen
+	FrameData  []byte         // Full JPEG (keyframes only)
+	Tiles      []api.TileData // Changed tiles (delta frames only)
+}
+
+// TileEncoder compares each capture with the previous one and encodes only
+// the tiles that changed. It is not safe for concurrent use.
+type TileEncoder struct {
+	config        TileEncoderConfig
+	previous      *image.RGBA
+	lastKeyframe  time.Time
+	forceKeyframe bool
+}
+
+// NewTileEncoder creates a new TileEncoder
+func NewTileEncoder(config TileEncoderConfig) *TileEncoder {
+	if config.TileSize <= 0 {
+		config.TileSize = DefaultTileEncoderConfig().TileSize
+	}
+	return &TileEncoder{
+		config:        config,
+		forceKeyframe: true,
+	}
+}
+
+// RequestKeyframe makes the next Encode call emit a full frame
+func (e *TileEncoder) RequestKeyframe() {
+	e.forceKeyframe = true
+}
+
+// Reset drops the reference frame so the next capture is a keyframe
+func (e *TileEncoder) Reset() {
+	e.previous = nil
+	e.forceKeyframe = true
+}
+
+// Encode encodes img against the previous capture. It returns nil when
+// nothing changed and no keyframe is due, so the frame can be skipped.
+func (e *TileEncoder) Encode(img *image.RGBA, quality int) (*EncodedFrame, error) {
+	if e.needsKeyframe(img) {
+		return e.encodeKeyframe(img, quality)
+	}
+
+	dirty, total := e.dirtyTiles(img)
+	if len(dirty) == 0 {
+		e.previous = img
+		return nil, nil
+	}
+
+	if e.config.MaxDirtyRatio > 0 && float64(len(dirty)) > float64(total)*e.config.MaxDirtyRatio {
+		return e.encodeKeyframe(img, quality)
+	}
+
+	tiles := make([]api.TileData, 0, len(dirty))
+	for _, rect := range dirty {
+		data, err := encodeJPEG(img.SubImage(rect), quality)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode tile at %d,%d: %w", rect.Min.X, rect.Min.Y, err)
+		}
+
+		origin := img.Bounds().Min
+		tiles = append(tiles, api.TileData{
+			X:      rect.Min.X - origin.X,
+			Y:      rect.Min.Y - origin.Y,
+			Width:  rect.Dx(),
+			Height: rect.Dy(),
+			Data:   data,
+		})
+	}
+
+	// Only now do the tiles count as sent; after a failure they stay dirty
+	e.previous = img
+
+	return &EncodedFrame{
+		Format: api.FrameFormatJPEGTiles,
+		Tiles:  tiles,
+	}, nil
+}
+
+// needsKeyframe decides whether the next frame must be sent in full
+func (e *TileEncoder) needsKeyframe(img *image.RGBA) bool {
+	if e.forceKeyframe || e.previous == nil {
+		return true
+	}
+	if !e.previous.Bounds().Eq(img.Bounds()) {
+		return true
+	}
+	return e.config.KeyframeInterval > 0 && time.Since(e.lastKeyframe) >= e.config.KeyframeInterval
+}
+
+// encodeKeyframe encodes the full image and makes it the new reference
+func (e *TileEncoder) encodeKeyframe(img *image.RGBA, quality int) (*EncodedFrame, error) {
+	data, err := encodeJPEG(img, quality)
+	if err != nil {
+		return nil, err
+	}
+
+	e.previous = img
+	e.lastKeyframe = time.Now()
+	e.forceKeyframe = false
+
+	return &EncodedFrame{
+		Format:     api.FrameFormatJPEG,
+		IsKeyframe: true,
+		FrameData:  data,
+	}, nil
+}
+
+// dirtyTiles returns the rectangles of the tiles that differ from the previous capture
+func (e *TileEncoder) dirtyTiles(img *image.RGBA) ([]image.Rectangle, int) {
+	bounds := img.Bounds()
+	size := e.config.TileSize
+
+	var dirty []image.Rectangle
+	total := 0
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += size {
+		for x := bounds.Min.X; x < bounds.Max.X; x += size {
+			rect := image.Rect(x, y, x+size, y+size).Intersect(bounds)
+			total++
+			if !tileEqual(e.previous, img, rect) {
+				dirty = append(dirty, rect)
+			}
+		}
+	}
+
+	return dirty, total
+}
+
+// tileEqual compares the pixels of rect row by row
+func tileEqual(a, b *image.RGBA, rect image.Rectangle) bool {
+	rowBytes := rect.Dx() * 4
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		offA := a.PixOffset(rect.Min.X, y)
+		offB := b.PixOffset(rect.Min.X, y)
+		if !bytes.Equal(a.Pix[offA:offA+rowBytes], b.Pix[offB:offB+rowBytes]) {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeJPEG encodes any image to JPEG with the given quality
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+	if quality < 1 || quality > 100 {
+		quality = 75
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode JPEG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
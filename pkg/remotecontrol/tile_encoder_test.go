@@ -0,0 +1,203 @@
+package remotecontrol
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"EscritorioRemoto-Cliente/pkg/api"
+)
+
+// testTileEncoder creates an encoder with 64px tiles and no periodic keyframes
+func testTileEncoder() *TileEncoder {
+	return NewTileEncoder(TileEncoderConfig{
+		TileSize:         64,
+		KeyframeInterval: time.Hour,
+		MaxDirtyRatio:    0.5,
+	})
+}
+
+// solidFrame returns a frame filled with one color
+func solidFrame(width, height int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// withPixels returns a copy of img with the given pixels set to c
+func withPixels(img *image.RGBA, c color.RGBA, points ...image.Point) *image.RGBA {
+	out := image.NewRGBA(img.Bounds())
+	copy(out.Pix, img.Pix)
+	for _, p := range points {
+		out.SetRGBA(p.X, p.Y, c)
+	}
+	return out
+}
+
+func TestTileEncoderEncode(t *testing.T) {
+	gray := color.RGBA{R: 128, G: 128, B: 128, A: 255}
+	red := color.RGBA{R: 255, A: 255}
+	base := solidFrame(256, 128, gray) // 4x2 tiles
+
+	tests := []struct {
+		name         string
+		prepare      func(e *TileEncoder)
+		next         *image.RGBA
+		wantNil      bool
+		wantKeyframe bool
+		wantTiles    []image.Rectangle
+	}{
+		{name: "unchanged frame", next: base, wantNil: true},
+		{
+			name:      "one changed pixel",
+			next:      withPixels(base, red, image.Pt(70, 10)),
+			wantTiles: []image.Rectangle{image.Rect(64, 0, 128, 64)},
+		},
+		{
+			name: "changed pixels in two tiles",
+			next: withPixels(base, red, image.Pt(0, 0), image.Pt(255, 127)),
+			wantTiles: []image.Rectangle{
+				image.Rect(0, 0, 64, 64),
+				image.Rect(192, 64, 256, 128),
+			},
+		},
+		{
+			name:      "exactly half the tiles dirty",
+			next:      withPixels(base, red, image.Pt(0, 0), image.Pt(64, 0), image.Pt(128, 0), image.Pt(192, 0)),
+			wantTiles: []image.Rectangle{image.Rect(0, 0, 64, 64), image.Rect(64, 0, 128, 64), image.Rect(128, 0, 192, 64), image.Rect(192, 0, 256, 64)},
+		},
+		{
+			name:         "more than half the tiles dirty",
+			next:         withPixels(base, red, image.Pt(0, 0), image.Pt(64, 0), image.Pt(128, 0), image.Pt(192, 0), image.Pt(0, 64)),
+			wantKeyframe: true,
+		},
+		{name: "size change", next: solidFrame(128, 128, gray), wantKeyframe: true},
+		{name: "reset", prepare: (*TileEncoder).Reset, next: base, wantKeyframe: true},
+		{name: "keyframe requested", prepare: (*TileEncoder).RequestKeyframe, next: base, wantKeyframe: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := testTileEncoder()
+
+			first, err := e.Encode(base, 75)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first == nil || !first.IsKeyframe {
+				t.Fatalf("first frame is not a keyframe: %+v", first)
+			}
+
+			if tt.prepare != nil {
+				tt.prepare(e)
+			}
+
+			got, err := e.Encode(tt.next, 75)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			switch {
+			case tt.wantNil:
+				if got != nil {
+					t.Errorf("Encode() = %+v, want nil", got)
+				}
+			case tt.wantKeyframe:
+				if got == nil || !got.IsKeyframe || got.Format != api.FrameFormatJPEG || len(got.FrameData) == 0 {
+					t.Errorf("Encode() = %+v, want a JPEG keyframe", got)
+				}
+			default:
+				if got == nil || got.IsKeyframe || got.Format != api.FrameFormatJPEGTiles {
+					t.Fatalf("Encode() = %+v, want a tile frame", got)
+				}
+				if len(got.Tiles) != len(tt.wantTiles) {
+					t.Fatalf("got %d tiles, want %d", len(got.Tiles), len(tt.wantTiles))
+				}
+				for i, tile := range got.Tiles {
+					rect := image.Rect(tile.X, tile.Y, tile.X+tile.Width, tile.Y+tile.Height)
+					if rect != tt.wantTiles[i] {
+						t.Errorf("tile %d = %v, want %v", i, rect, tt.wantTiles[i])
+					}
+					if len(tile.Data) == 0 {
+						t.Errorf("tile %d has no data", i)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTileEncoderTileOffsetsAreFrameRelative(t *testing.T) {
+	gray := color.RGBA{R: 128, G: 128, B: 128, A: 255}
+
+	// A capture of a secondary display keeps its desktop origin in the bounds
+	base := image.NewRGBA(image.Rect(1920, 0, 1920+128, 128))
+	for i := range base.Pix {
+		base.Pix[i] = gray.R
+	}
+
+	e := testTileEncoder()
+	if _, err := e.Encode(base, 75); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := e.Encode(withPixels(base, color.RGBA{A: 255}, image.Pt(1920+100, 100)), 75)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || len(got.Tiles) != 1 {
+		t.Fatalf("Encode() = %+v, want one tile", got)
+	}
+	if tile := got.Tiles[0]; tile.X != 64 || tile.Y != 64 || tile.Width != 64 || tile.Height != 64 {
+		t.Errorf("tile = %d,%d %dx%d, want 64,64 64x64", tile.X, tile.Y, tile.Width, tile.Height)
+	}
+}
+
+func TestTileEncoderEdgeTilesAreClipped(t *testing.T) {
+	gray := color.RGBA{R: 128, G: 128, B: 128, A: 255}
+	base := solidFrame(100, 100, gray)
+
+	e := testTileEncoder()
+	if _, err := e.Encode(base, 75); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := e.Encode(withPixels(base, color.RGBA{A: 255}, image.Pt(99, 99)), 75)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || len(got.Tiles) != 1 {
+		t.Fatalf("Encode() = %+v, want one tile", got)
+	}
+	if tile := got.Tiles[0]; tile.X != 64 || tile.Y != 64 || tile.Width != 36 || tile.Height != 36 {
+		t.Errorf("tile = %d,%d %dx%d, want 64,64 36x36", tile.X, tile.Y, tile.Width, tile.Height)
+	}
+}
+
+func TestTileEncoderKeepsTilesDirtyAfterEncodeError(t *testing.T) {
+	// JPEG cannot encode a tile 65536 pixels wide, so the delta frame fails
+	const width = 1 << 16
+	encoder := NewTileEncoder(TileEncoderConfig{TileSize: width, KeyframeInterval: time.Hour})
+	reference := image.NewRGBA(image.Rect(0, 0, width, 8))
+	encoder.previous = reference
+	encoder.lastKeyframe = time.Now()
+	encoder.forceKeyframe = false
+
+	next := withPixels(reference, color.RGBA{R: 255, A: 255}, image.Pt(10, 2))
+	if _, err := encoder.Encode(next, 75); err == nil {
+		t.Fatal("Encode() of an oversized tile succeeded")
+	}
+	if encoder.previous != reference {
+		t.Error("a frame whose tiles failed to encode became the reference")
+	}
+
+	// The failed tile is still sent with the next capture instead of being skipped
+	if frame, err := encoder.Encode(next, 75); frame == nil && err == nil {
+		t.Error("the tile that failed was treated as sent")
+	}
+}
@@ -0,0 +1,49 @@
+package session
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// PasswordEnvVar es la variable de entorno alternativa a --password-file
+const PasswordEnvVar = "ESCRITORIO_REMOTO_PASSWORD"
+
+// ResolvePassword obtiene la contraseña de auto-login sin exponerla en argv.
+// Prioridad: archivo indicado, variable de entorno. La variable se elimina del
+// entorno del proceso tras leerla para que no la hereden procesos hijos; el valor
+// que Go ya copió del entorno es un string y no se puede sobrescribir.
+func ResolvePassword(passwordFile string) ([]byte, error) {
+	if passwordFile != "" {
+		info, err := os.Stat(passwordFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password file: %w", err)
+		}
+		if info.Mode().Perm()&0077 != 0 {
+			fmt.Fprintf(os.Stderr, "⚠️ El archivo de contraseña %s es accesible por otros usuarios (permisos %o)\n", passwordFile, info.Mode().Perm())
+		}
+
+		data, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password file: %w", err)
+		}
+
+		// Solo se recorta el salto de línea final que añaden los editores. Se recorta
+		// el mismo buffer para no dejar copias que Wipe no alcance.
+		return bytes.TrimRight(data, "\r\n"), nil
+	}
+
+	if value, ok := os.LookupEnv(PasswordEnvVar); ok {
+		os.Unsetenv(PasswordEnvVar)
+		return []byte(value), nil
+	}
+
+	return nil, nil
+}
+
+// Wipe sobrescribe con ceros un buffer con datos sensibles
+func Wipe(buf []byte) {
+	for i := range buf {
+		buf[i] = 0
+	}
+}
@@ -0,0 +1,141 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// La clave maestra del EncryptedFileStore nunca se guarda junto a los archivos que
+// cifra. Se guarda en el almacén de claves del sistema: DPAPI en Windows, el Keychain
+// en macOS y Secret Service (secret-tool) en Linux. Así, una copia del directorio de
+// sesión (un respaldo, otro usuario con acceso de lectura, otro equipo) no basta para
+// descifrar el token.
+//
+// No protege frente a código que se ejecute como el mismo usuario con su sesión
+// abierta: ese código puede pedir la clave al almacén igual que el cliente. Sin
+// almacén disponible (p. ej. Linux sin secret-tool o sin sesión D-Bus) la clave se
+// guarda en el directorio de configuración del usuario, fuera del de sesión, y solo
+// protege frente a copias del directorio de sesión.
+
+const (
+	// Identificación de la clave en el Keychain y en Secret Service
+	keyringService = "escritorio-remoto"
+	keyringAccount = "secret-store-key"
+
+	masterKeySize = 32
+
+	// legacyKeyFile es la clave que versiones anteriores guardaban en el directorio de sesión
+	legacyKeyFile = "machine.key"
+)
+
+// Acceso al almacén de claves del sistema; los tests lo sustituyen por uno en memoria
+var (
+	loadFromKeystore = keystoreLoad
+	saveToKeystore   = keystoreSave
+)
+
+// errKeystoreUnavailable indica que el sistema no ofrece un almacén de claves utilizable
+var errKeystoreUnavailable = errors.New("system keystore unavailable")
+
+// loadOrCreateMasterKey obtiene la clave maestra del almacén de claves del sistema,
+// generándola en el primer uso. Una machine.key de versiones anteriores se traslada
+// al almacén y se elimina del directorio de sesión.
+func loadOrCreateMasterKey(sessionDir string) ([]byte, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate user config directory: %w", err)
+	}
+	keyDir := filepath.Join(configDir, "escritorio-remoto")
+	fallbackPath := filepath.Join(keyDir, "store.key")
+
+	// store.key solo se crea cuando el almacén no estaba disponible y no había secretos
+	// cifrados, así que si existe es la clave con la que se cifró la sesión actual,
+	// aunque el almacén vuelva a estar disponible con una clave anterior
+	if key, err := os.ReadFile(fallbackPath); err == nil {
+		return checkMasterKey(key)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read store key: %w", err)
+	}
+
+	key, err := loadFromKeystore(keyDir)
+	switch {
+	case err == nil:
+		return checkMasterKey(key)
+	case !errors.Is(err, ErrSecretNotFound) && !errors.Is(err, errKeystoreUnavailable):
+		// El almacén existe pero falló: no generar otra clave que deje ilegible la sesión
+		return nil, fmt.Errorf("failed to read key from system keystore: %w", err)
+	}
+	keystoreErr := err
+
+	legacyPath := filepath.Join(sessionDir, legacyKeyFile)
+	key, err = os.ReadFile(legacyPath)
+	legacy := err == nil && len(key) == masterKeySize
+	if !legacy {
+		// Sin ninguna clave, los secretos ya cifrados solo pueden haberse cifrado con una
+		// clave que ahora no está accesible (almacén bloqueado, sin sesión D-Bus): una
+		// clave nueva los dejaría ilegibles para siempre
+		if hasEncryptedSecrets(sessionDir) {
+			return nil, fmt.Errorf("no key for the encrypted secrets in %s (%v); remove the .enc files to start over", sessionDir, keystoreErr)
+		}
+		key = make([]byte, masterKeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate store key: %w", err)
+		}
+	}
+
+	if err := saveMasterKey(keyDir, fallbackPath, key); err != nil {
+		return nil, err
+	}
+	if legacy {
+		if err := os.Remove(legacyPath); err != nil {
+			log.Printf("⚠️ Could not remove legacy key %s: %v", legacyPath, err)
+		}
+	}
+	return key, nil
+}
+
+// saveMasterKey guarda la clave en el almacén del sistema o, sin él, en fallbackPath
+func saveMasterKey(keyDir, fallbackPath string, key []byte) error {
+	err := saveToKeystore(keyDir, key)
+	if err == nil {
+		return nil
+	}
+	log.Printf("⚠️ System keystore unavailable (%v); storing the session key in %s", err, fallbackPath)
+
+	if err := os.MkdirAll(keyDir, 0700); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+	if err := writeFileAtomic(fallbackPath, key, 0600); err != nil {
+		return fmt.Errorf("failed to store key: %w", err)
+	}
+	return nil
+}
+
+// hasEncryptedSecrets indica si el directorio de sesión contiene secretos cifrados
+func hasEncryptedSecrets(sessionDir string) bool {
+	matches, err := filepath.Glob(filepath.Join(sessionDir, "*.enc"))
+	return err == nil && len(matches) > 0
+}
+
+// checkMasterKey rechaza claves con un tamaño inesperado
+func checkMasterKey(key []byte) ([]byte, error) {
+	if len(key) != masterKeySize {
+		return nil, fmt.Errorf("stored key has %d bytes, expected %d", len(key), masterKeySize)
+	}
+	return key, nil
+}
+
+// decodeKeyringSecret interpreta la clave guardada como texto hexadecimal
+func decodeKeyringSecret(out []byte) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(string(out)))
+	if err != nil {
+		return nil, fmt.Errorf("stored key is not valid hex: %w", err)
+	}
+	return key, nil
+}
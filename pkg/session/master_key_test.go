@@ -0,0 +1,188 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeKeystore sustituye al almacén de claves del sistema durante un test
+type fakeKeystore struct {
+	key     []byte
+	loadErr error // Error de lectura forzado (almacén bloqueado, sin D-Bus...)
+	saveErr error
+	saves   int
+}
+
+// useFakeKeystore instala un almacén en memoria y un directorio de configuración
+// temporal, de modo que el test no toca el almacén ni la configuración del usuario
+func useFakeKeystore(t *testing.T) (*fakeKeystore, string) {
+	t.Helper()
+
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("HOME", configHome)
+	t.Setenv("APPDATA", configHome)
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeKeystore{}
+	prevLoad, prevSave := loadFromKeystore, saveToKeystore
+	loadFromKeystore = func(keyDir string) ([]byte, error) {
+		if fake.loadErr != nil {
+			return nil, fake.loadErr
+		}
+		if fake.key == nil {
+			return nil, ErrSecretNotFound
+		}
+		return append([]byte(nil), fake.key...), nil
+	}
+	saveToKeystore = func(keyDir string, key []byte) error {
+		if fake.saveErr != nil {
+			return fake.saveErr
+		}
+		fake.saves++
+		fake.key = append([]byte(nil), key...)
+		return nil
+	}
+	t.Cleanup(func() {
+		loadFromKeystore, saveToKeystore = prevLoad, prevSave
+	})
+
+	return fake, filepath.Join(configDir, "escritorio-remoto", "store.key")
+}
+
+func TestLoadOrCreateMasterKey(t *testing.T) {
+	existingKey := bytes.Repeat([]byte{7}, masterKeySize)
+	locked := errors.New("secret-tool: exit status 1: Cannot unlock collection")
+
+	tests := []struct {
+		name          string
+		keystoreKey   []byte
+		loadErr       error
+		saveErr       error
+		fallbackKey   []byte
+		legacyKey     []byte
+		encryptedFile bool
+
+		wantErr      bool
+		wantKey      []byte // nil: cualquier clave nueva
+		wantSaves    int
+		wantFallback bool
+	}{
+		{name: "first use stores a new key in the keystore", wantSaves: 1},
+		{name: "key from the keystore", keystoreKey: existingKey, encryptedFile: true, wantKey: existingKey},
+		{name: "locked keystore does not create a key", loadErr: locked, wantErr: true},
+		{name: "locked keystore with secrets does not create a key", loadErr: locked, encryptedFile: true, wantErr: true},
+		{name: "missing key with secrets does not create a key", encryptedFile: true, wantErr: true},
+		{name: "unavailable keystore with secrets does not create a key", loadErr: errKeystoreUnavailable, encryptedFile: true, wantErr: true},
+		{
+			name:    "unavailable keystore falls back to store.key",
+			loadErr: errKeystoreUnavailable, saveErr: errKeystoreUnavailable,
+			wantFallback: true,
+		},
+		{
+			name:        "store.key wins over an older keystore key",
+			keystoreKey: bytes.Repeat([]byte{1}, masterKeySize), fallbackKey: existingKey, encryptedFile: true,
+			wantKey: existingKey, wantFallback: true,
+		},
+		{
+			name:      "legacy machine.key moves to the keystore",
+			legacyKey: existingKey, encryptedFile: true,
+			wantKey: existingKey, wantSaves: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, fallbackPath := useFakeKeystore(t)
+			fake.key, fake.loadErr, fake.saveErr = tt.keystoreKey, tt.loadErr, tt.saveErr
+
+			sessionDir := t.TempDir()
+			if tt.fallbackKey != nil {
+				os.MkdirAll(filepath.Dir(fallbackPath), 0700)
+				if err := os.WriteFile(fallbackPath, tt.fallbackKey, 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.legacyKey != nil {
+				if err := os.WriteFile(filepath.Join(sessionDir, legacyKeyFile), tt.legacyKey, 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.encryptedFile {
+				if err := os.WriteFile(filepath.Join(sessionDir, "session.enc"), []byte("ERS1..."), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			key, err := loadOrCreateMasterKey(sessionDir)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("loadOrCreateMasterKey succeeded, want error")
+				}
+				if fake.saves != 0 {
+					t.Errorf("a new key was saved to the keystore")
+				}
+				if _, err := os.Stat(fallbackPath); !os.IsNotExist(err) {
+					t.Errorf("a new store.key was written")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadOrCreateMasterKey error: %v", err)
+			}
+
+			if len(key) != masterKeySize {
+				t.Fatalf("key has %d bytes", len(key))
+			}
+			if tt.wantKey != nil && !bytes.Equal(key, tt.wantKey) {
+				t.Errorf("key = %x, want %x", key, tt.wantKey)
+			}
+			if fake.saves != tt.wantSaves {
+				t.Errorf("keystore saves = %d, want %d", fake.saves, tt.wantSaves)
+			}
+			if _, err := os.Stat(fallbackPath); (err == nil) != tt.wantFallback {
+				t.Errorf("store.key exists = %v, want %v", err == nil, tt.wantFallback)
+			}
+			if tt.legacyKey != nil {
+				if _, err := os.Stat(filepath.Join(sessionDir, legacyKeyFile)); !os.IsNotExist(err) {
+					t.Errorf("legacy machine.key was not removed")
+				}
+			}
+
+			// La segunda carga devuelve la misma clave
+			again, err := loadOrCreateMasterKey(sessionDir)
+			if err != nil {
+				t.Fatalf("second load error: %v", err)
+			}
+			if !bytes.Equal(again, key) {
+				t.Errorf("second load returned a different key")
+			}
+		})
+	}
+}
+
+func TestLoadOrCreateMasterKeyReportsKeystoreError(t *testing.T) {
+	fake, _ := useFakeKeystore(t)
+	fake.loadErr = errors.New("Cannot unlock collection")
+
+	_, err := loadOrCreateMasterKey(t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "Cannot unlock collection") {
+		t.Errorf("error = %v, want the keystore error", err)
+	}
+}
+
+func TestLoadOrCreateMasterKeyRejectsWrongSize(t *testing.T) {
+	fake, _ := useFakeKeystore(t)
+	fake.key = []byte("short")
+
+	if _, err := loadOrCreateMasterKey(t.TempDir()); err == nil {
+		t.Error("a 5-byte key was accepted")
+	}
+}
@@ -0,0 +1,107 @@
+//go:build !windows
+
+package session
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// keystoreLoad lee la clave del Keychain (macOS) o de Secret Service (resto)
+func keystoreLoad(keyDir string) ([]byte, error) {
+	if runtime.GOOS != "darwin" && !sessionBusAvailable() {
+		return nil, fmt.Errorf("%w: no D-Bus session bus", errKeystoreUnavailable)
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", keyringService, "-a", keyringAccount, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", keyringService, "account", keyringAccount)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			// Herramienta no instalada
+			return nil, fmt.Errorf("%w: %v", errKeystoreUnavailable, err)
+		}
+		return nil, lookupExitError(runtime.GOOS, exitErr.ExitCode(), strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return decodeKeyringSecret(out)
+}
+
+// lookupExitError interpreta la salida con error de security o secret-tool al buscar
+// la clave. security sale con 44 si no existe. secret-tool sale con 1 tanto si no
+// existe como si el llavero está bloqueado o el servicio no responde; solo en el
+// primer caso no escribe nada en stderr.
+func lookupExitError(goos string, exitCode int, stderr string) error {
+	if goos == "darwin" {
+		if exitCode == 44 {
+			return ErrSecretNotFound
+		}
+		return fmt.Errorf("security: exit status %d: %s", exitCode, stderr)
+	}
+	if exitCode == 1 && stderr == "" {
+		return ErrSecretNotFound
+	}
+	return fmt.Errorf("secret-tool: exit status %d: %s", exitCode, stderr)
+}
+
+// sessionBusAvailable indica si hay un bus de sesión D-Bus al que secret-tool pueda
+// conectarse: el indicado en DBUS_SESSION_BUS_ADDRESS o el de systemd en XDG_RUNTIME_DIR
+func sessionBusAvailable() bool {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+		return true
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(runtimeDir, "bus"))
+	return err == nil
+}
+
+// keystoreSave guarda la clave en el Keychain (macOS) o en Secret Service (resto).
+// La clave se pasa por stdin para que no aparezca en la lista de procesos.
+func keystoreSave(keyDir string, key []byte) error {
+	secret := hex.EncodeToString(key)
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		// En modo interactivo security lee los comandos de stdin
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+			keyringService, keyringAccount, secret))
+	} else {
+		cmd = exec.Command("secret-tool", "store", "--label=Escritorio Remoto", "service", keyringService, "account", keyringAccount)
+		cmd.Stdin = strings.NewReader(secret)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%v: %s", err, msg)
+		}
+		return err
+	}
+
+	// security -i no falla por un comando con error: comprobar que quedó guardada
+	stored, err := keystoreLoad(keyDir)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(stored, key) {
+		return fmt.Errorf("stored key does not match")
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLookupExitError(t *testing.T) {
+	tests := []struct {
+		name         string
+		goos         string
+		exitCode     int
+		stderr       string
+		wantNotFound bool
+	}{
+		{name: "secret-tool not found", goos: "linux", exitCode: 1, wantNotFound: true},
+		{name: "secret-tool locked keyring", goos: "linux", exitCode: 1, stderr: "Cannot unlock collection"},
+		{name: "secret-tool no service", goos: "linux", exitCode: 1, stderr: "The name org.freedesktop.secrets was not provided"},
+		{name: "secret-tool other exit code", goos: "linux", exitCode: 2},
+		{name: "security not found", goos: "darwin", exitCode: 44, wantNotFound: true},
+		{name: "security locked keychain", goos: "darwin", exitCode: 36, stderr: "User interaction is not allowed."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := lookupExitError(tt.goos, tt.exitCode, tt.stderr)
+			if err == nil {
+				t.Fatal("lookupExitError returned nil")
+			}
+			if got := errors.Is(err, ErrSecretNotFound); got != tt.wantNotFound {
+				t.Errorf("errors.Is(%v, ErrSecretNotFound) = %v, want %v", err, got, tt.wantNotFound)
+			}
+			if errors.Is(err, errKeystoreUnavailable) {
+				t.Errorf("%v reported as an unavailable keystore", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+//go:build windows
+
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// En Windows la clave se cifra con DPAPI para el usuario actual: el archivo solo
+// puede descifrarse con la sesión de ese usuario en este equipo.
+
+// keystoreLoad lee y descifra con DPAPI la clave guardada en keyDir
+func keystoreLoad(keyDir string) ([]byte, error) {
+	blob, err := os.ReadFile(dpapiKeyPath(keyDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrSecretNotFound
+		}
+		return nil, err
+	}
+
+	in := dataBlob(blob)
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, fmt.Errorf("DPAPI decryption failed: %w", err)
+	}
+	return takeDataBlob(&out), nil
+}
+
+// keystoreSave cifra la clave con DPAPI y la guarda en keyDir
+func keystoreSave(keyDir string, key []byte) error {
+	in := dataBlob(key)
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return fmt.Errorf("DPAPI encryption failed: %w", err)
+	}
+	blob := takeDataBlob(&out)
+
+	if err := os.MkdirAll(keyDir, 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(dpapiKeyPath(keyDir), blob, 0600)
+}
+
+func dpapiKeyPath(keyDir string) string {
+	return filepath.Join(keyDir, "store.key.dpapi")
+}
+
+func dataBlob(data []byte) windows.DataBlob {
+	if len(data) == 0 {
+		return windows.DataBlob{}
+	}
+	return windows.DataBlob{Size: uint32(len(data)), Data: &data[0]}
+}
+
+// takeDataBlob copia la salida de DPAPI y libera el buffer que reservó el sistema
+func takeDataBlob(blob *windows.DataBlob) []byte {
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(blob.Data)))
+	return append([]byte(nil), unsafe.Slice(blob.Data, blob.Size)...)
+}
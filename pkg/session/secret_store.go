@@ -0,0 +1,195 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// ErrSecretNotFound indica que no existe un secreto con la clave solicitada
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretStore abstrae el almacenamiento persistente de secretos (tokens, credenciales)
+type SecretStore interface {
+	Save(name string, data []byte) error
+	Load(name string) ([]byte, error)
+	Delete(name string) error
+}
+
+// encryptedFileMagic identifica la versión del formato cifrado en disco
+var encryptedFileMagic = []byte("ERS1")
+
+// EncryptedFileStore guarda cada secreto en un archivo cifrado con AES-256-GCM.
+// La clave se deriva de una clave maestra aleatoria guardada en el almacén de claves
+// del sistema (ver loadOrCreateMasterKey) combinada con el ID de la máquina, de modo
+// que copiar el directorio no permite descifrarlo.
+type EncryptedFileStore struct {
+	dir string
+	key []byte
+}
+
+// NewEncryptedFileStore crea un store cifrado en el directorio indicado
+func NewEncryptedFileStore(dir string) (*EncryptedFileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create secret directory: %w", err)
+	}
+
+	key, err := deriveMachineKey(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncryptedFileStore{
+		dir: dir,
+		key: key,
+	}, nil
+}
+
+// Save cifra y guarda un secreto de forma atómica
+func (s *EncryptedFileStore) Save(name string, data []byte) error {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	// El nombre del secreto se autentica para impedir intercambiar archivos
+	sealed := gcm.Seal(nil, nonce, data, []byte(name))
+
+	out := make([]byte, 0, len(encryptedFileMagic)+len(nonce)+len(sealed))
+	out = append(out, encryptedFileMagic...)
+	out = append(out, nonce...)
+	out = append(out, sealed...)
+
+	return writeFileAtomic(s.path(name), out, 0600)
+}
+
+// Load lee y descifra un secreto
+func (s *EncryptedFileStore) Load(name string) ([]byte, error) {
+	raw, err := os.ReadFile(s.path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrSecretNotFound
+		}
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	headerLen := len(encryptedFileMagic) + gcm.NonceSize()
+	if len(raw) < headerLen || string(raw[:len(encryptedFileMagic)]) != string(encryptedFileMagic) {
+		return nil, fmt.Errorf("secret %s has an unknown format", name)
+	}
+
+	nonce := raw[len(encryptedFileMagic):headerLen]
+	data, err := gcm.Open(nil, nonce, raw[headerLen:], []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret %s: %w", name, err)
+	}
+
+	return data, nil
+}
+
+// Delete elimina un secreto; no es error si no existe
+func (s *EncryptedFileStore) Delete(name string) error {
+	if err := os.Remove(s.path(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *EncryptedFileStore) path(name string) string {
+	return filepath.Join(s.dir, name+".enc")
+}
+
+// deriveMachineKey combina la clave maestra con el ID de la máquina
+func deriveMachineKey(dir string) ([]byte, error) {
+	masterKey, err := loadOrCreateMasterKey(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, masterKey)
+	mac.Write([]byte("escritorio-remoto/secret-store/v1|"))
+	mac.Write([]byte(machineID()))
+	return mac.Sum(nil), nil
+}
+
+// machineID obtiene un identificador estable del equipo ("" si no está disponible)
+func machineID() string {
+	switch runtime.GOOS {
+	case "linux":
+		for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+			if data, err := os.ReadFile(path); err == nil {
+				return strings.TrimSpace(string(data))
+			}
+		}
+	case "windows":
+		out, err := exec.Command("reg", "query", `HKLM\SOFTWARE\Microsoft\Cryptography`, "/v", "MachineGuid").Output()
+		if err == nil {
+			fields := strings.Fields(string(out))
+			if len(fields) > 0 {
+				return fields[len(fields)-1]
+			}
+		}
+	case "darwin":
+		out, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+		if err == nil {
+			if match := regexp.MustCompile(`"IOPlatformUUID" = "([^"]+)"`).FindSubmatch(out); match != nil {
+				return string(match[1])
+			}
+		}
+	}
+
+	return ""
+}
+
+// writeFileAtomic escribe en un archivo temporal y lo renombra sobre el destino
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestStore crea un EncryptedFileStore sobre un almacén de claves en memoria
+func newTestStore(t *testing.T) *EncryptedFileStore {
+	t.Helper()
+	useFakeKeystore(t)
+
+	store, err := NewEncryptedFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestEncryptedFileStoreRoundTrip(t *testing.T) {
+	store := newTestStore(t)
+	secret := []byte(`{"token":"abc.def.ghi"}`)
+
+	if err := store.Save("session", secret); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(store.dir, "session.enc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("abc.def.ghi")) {
+		t.Error("the secret is stored in plaintext")
+	}
+
+	got, err := store.Load("session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, secret) {
+		t.Errorf("Load() = %q, want %q", got, secret)
+	}
+
+	// Un store nuevo sobre el mismo directorio obtiene la misma clave
+	reopened, err := NewEncryptedFileStore(store.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := reopened.Load("session"); err != nil || !bytes.Equal(got, secret) {
+		t.Errorf("reopened Load() = %q, %v", got, err)
+	}
+
+	if err := store.Delete("session"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load("session"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Load() after Delete error = %v, want ErrSecretNotFound", err)
+	}
+	if err := store.Delete("session"); err != nil {
+		t.Errorf("Delete() of a missing secret error = %v", err)
+	}
+}
+
+func TestEncryptedFileStoreRejectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(raw []byte) []byte
+	}{
+		{name: "flipped ciphertext byte", tamper: func(raw []byte) []byte { raw[len(raw)-20] ^= 0x01; return raw }},
+		{name: "flipped tag byte", tamper: func(raw []byte) []byte { raw[len(raw)-1] ^= 0x80; return raw }},
+		{name: "flipped nonce byte", tamper: func(raw []byte) []byte { raw[len(encryptedFileMagic)] ^= 0x01; return raw }},
+		{name: "truncated", tamper: func(raw []byte) []byte { return raw[:len(raw)-1] }},
+		{name: "header only", tamper: func(raw []byte) []byte { return raw[:len(encryptedFileMagic)+4] }},
+		{name: "unknown magic", tamper: func(raw []byte) []byte { copy(raw, "ERS9"); return raw }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			if err := store.Save("session", []byte(`{"token":"abc.def.ghi"}`)); err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(store.dir, "session.enc")
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, tt.tamper(raw), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			if got, err := store.Load("session"); err == nil {
+				t.Errorf("tampered secret was accepted: %q", got)
+			} else if errors.Is(err, ErrSecretNotFound) {
+				t.Errorf("tampered secret reported as missing: %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptedFileStoreBindsTheSecretName(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.Save("session", []byte(`{"token":"abc.def.ghi"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// El nombre es el dato autenticado: el mismo blob bajo otro nombre no se descifra
+	raw, err := os.ReadFile(filepath.Join(store.dir, "session.enc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(store.dir, "credentials.enc"), raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := store.Load("credentials"); err == nil {
+		t.Errorf("blob copied to another name was accepted: %q", got)
+	} else if !strings.Contains(err.Error(), "credentials") {
+		t.Errorf("error %v does not name the secret", err)
+	}
+}
+
+func TestEncryptedFileStoreRejectsAnotherKey(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.Save("session", []byte(`{"token":"abc.def.ghi"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Otra instalación (otra clave maestra) no puede leer el directorio copiado
+	other := &EncryptedFileStore{dir: store.dir, key: bytes.Repeat([]byte{9}, 32)}
+	if _, err := other.Load("session"); err == nil {
+		t.Error("secret decrypted with another key")
+	}
+}
@@ -2,11 +2,16 @@ package session
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"os"
 	"path/filepath"
 	"sync"
 )
 
+// sessionSecretName es el nombre del secreto donde se persiste la sesión
+const sessionSecretName = "session"
+
 // SessionData contiene la información de la sesión del cliente
 type SessionData struct {
 	Token    string `json:"token"`
@@ -17,12 +22,13 @@ type SessionData struct {
 
 // SessionManager maneja la persistencia de la sesión
 type SessionManager struct {
-	sessionFile string
-	data        *SessionData
-	mutex       sync.RWMutex
+	store      SecretStore
+	legacyFile string // session.json en texto plano de versiones anteriores
+	data       *SessionData
+	mutex      sync.RWMutex
 }
 
-// NewSessionManager crea un nuevo gestor de sesión
+// NewSessionManager crea un nuevo gestor de sesión con almacenamiento cifrado
 func NewSessionManager() *SessionManager {
 	// Obtener directorio de datos de usuario
 	homeDir, err := os.UserHomeDir()
@@ -31,14 +37,19 @@ func NewSessionManager() *SessionManager {
 	}
 
 	sessionDir := filepath.Join(homeDir, ".escritorio-remoto")
-	os.MkdirAll(sessionDir, 0755)
-
-	sessionFile := filepath.Join(sessionDir, "session.json")
 
 	sm := &SessionManager{
-		sessionFile: sessionFile,
-		data:        &SessionData{},
+		legacyFile: filepath.Join(sessionDir, "session.json"),
+		data:       &SessionData{},
+	}
+
+	store, err := NewEncryptedFileStore(sessionDir)
+	if err != nil {
+		// Sin almacenamiento cifrado la sesión solo vive en memoria
+		log.Printf("⚠️ Encrypted session store unavailable, session will not be persisted: %v", err)
+		return sm
 	}
+	sm.store = store
 
 	// Cargar sesión existente si existe
 	sm.loadSession()
@@ -46,6 +57,19 @@ func NewSessionManager() *SessionManager {
 	return sm
 }
 
+// NewSessionManagerWithStore crea un gestor de sesión sobre un SecretStore concreto.
+// Con store nil la sesión no se persiste.
+func NewSessionManagerWithStore(store SecretStore) *SessionManager {
+	sm := &SessionManager{
+		data: &SessionData{},
+	}
+	if store != nil {
+		sm.store = store
+		sm.loadSession()
+	}
+	return sm
+}
+
 // StoreToken guarda el token de autenticación
 func (sm *SessionManager) StoreToken(token, userID, username string) error {
 	sm.mutex.Lock()
@@ -131,29 +155,41 @@ func (sm *SessionManager) ClearSession() error {
 
 	sm.data = &SessionData{}
 
-	// Eliminar archivo de sesión
-	if err := os.Remove(sm.sessionFile); err != nil && !os.IsNotExist(err) {
-		return err
+	// Eliminar secreto de sesión
+	if sm.store == nil {
+		return nil
 	}
-
-	return nil
+	return sm.store.Delete(sessionSecretName)
 }
 
-// saveSession guarda la sesión en el archivo
+// saveSession guarda la sesión cifrada en el store
 func (sm *SessionManager) saveSession() error {
-	data, err := json.MarshalIndent(sm.data, "", "  ")
+	if sm.store == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(sm.data)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(sm.sessionFile, data, 0600)
+	return sm.store.Save(sessionSecretName, data)
 }
 
-// loadSession carga la sesión desde el archivo
+// loadSession carga la sesión desde el store, migrando el archivo en texto plano si existe
 func (sm *SessionManager) loadSession() {
-	data, err := os.ReadFile(sm.sessionFile)
+	if sm.store == nil {
+		return
+	}
+
+	sm.migrateLegacySession()
+
+	data, err := sm.store.Load(sessionSecretName)
 	if err != nil {
-		return // Archivo no existe o error de lectura, usar datos vacíos
+		if !errors.Is(err, ErrSecretNotFound) {
+			log.Printf("⚠️ Failed to load persisted session: %v", err)
+		}
+		return // Sin sesión o ilegible, usar datos vacíos
 	}
 
 	var sessionData SessionData
@@ -163,3 +199,29 @@ func (sm *SessionManager) loadSession() {
 
 	sm.data = &sessionData
 }
+
+// migrateLegacySession cifra el session.json en texto plano y lo elimina del disco
+func (sm *SessionManager) migrateLegacySession() {
+	if sm.legacyFile == "" {
+		return
+	}
+
+	data, err := os.ReadFile(sm.legacyFile)
+	if err != nil {
+		return // No hay sesión antigua
+	}
+
+	var sessionData SessionData
+	if err := json.Unmarshal(data, &sessionData); err == nil {
+		if err := sm.store.Save(sessionSecretName, data); err != nil {
+			log.Printf("⚠️ Failed to migrate plaintext session: %v", err)
+			return
+		}
+	}
+
+	if err := os.Remove(sm.legacyFile); err != nil {
+		log.Printf("⚠️ Failed to remove plaintext session file: %v", err)
+		return
+	}
+	log.Println("🔒 Plaintext session file migrated to encrypted store")
+}
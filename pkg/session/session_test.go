@@ -0,0 +1,68 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionManagerMigratesPlaintextSession(t *testing.T) {
+	store := newTestStore(t)
+	legacyFile := filepath.Join(store.dir, "session.json")
+
+	legacy := SessionData{Token: "abc.def.ghi", UserID: "42", Username: "ana", PCID: "pc-1"}
+	data, err := json.Marshal(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(legacyFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sm := &SessionManager{store: store, legacyFile: legacyFile, data: &SessionData{}}
+	sm.loadSession()
+
+	if got := sm.GetSessionData(); got != legacy {
+		t.Errorf("session = %+v, want %+v", got, legacy)
+	}
+	if _, err := os.Stat(legacyFile); !os.IsNotExist(err) {
+		t.Errorf("plaintext session.json was not removed (stat error: %v)", err)
+	}
+
+	// La sesión migrada queda cifrada en el store
+	encrypted, err := store.Load(sessionSecretName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored SessionData
+	if err := json.Unmarshal(encrypted, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored != legacy {
+		t.Errorf("stored session = %+v, want %+v", stored, legacy)
+	}
+
+	// Un gestor nuevo la encuentra sin el archivo antiguo
+	if got := NewSessionManagerWithStore(store).GetSessionData(); got != legacy {
+		t.Errorf("reloaded session = %+v, want %+v", got, legacy)
+	}
+}
+
+func TestSessionManagerRemovesUnreadablePlaintextSession(t *testing.T) {
+	store := newTestStore(t)
+	legacyFile := filepath.Join(store.dir, "session.json")
+	if err := os.WriteFile(legacyFile, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sm := &SessionManager{store: store, legacyFile: legacyFile, data: &SessionData{}}
+	sm.loadSession()
+
+	if _, err := os.Stat(legacyFile); !os.IsNotExist(err) {
+		t.Errorf("unreadable session.json was not removed (stat error: %v)", err)
+	}
+	if sm.IsAuthenticated() {
+		t.Error("an unreadable legacy session authenticated the manager")
+	}
+}